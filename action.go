@@ -1,19 +1,54 @@
 package livetemplate
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"log"
+	"mime"
 	"net/http"
+	"net/url"
 	"strings"
+	"time"
 
 	"github.com/go-playground/validator/v10"
-	"github.com/gorilla/websocket"
 )
 
 // message represents an action message from the client (internal protocol)
 type message struct {
 	Action string                 `json:"action"` // Action name, may include store prefix (e.g., "counter.increment")
 	Data   map[string]interface{} `json:"data"`   // All values from forms, inputs, data attributes, etc.
+
+	form          url.Values // Form fields of a form-encoded HTTP action, see ActionContext.BindForm
+	size          int        // Bytes of the data as sent, see WithMaxPayloadSize
+	nonce         string     // Client-supplied ID of the action, see WithNonceWindow
+	correlationID string     // Client-supplied ID echoed in the response, see ResponseMetadata.CorrelationID
+}
+
+// wireMessage is the JSON form of message, keeping the data as sent to measure it
+type wireMessage struct {
+	Action string          `json:"action"`
+	Data   json.RawMessage `json:"data"`
+	Nonce  string          `json:"nonce,omitempty"`
+
+	CorrelationID string `json:"correlation_id,omitempty"`
+}
+
+// decode returns the message m carries
+func (m wireMessage) decode() (message, error) {
+	msg := message{Action: m.Action, size: len(m.Data), nonce: m.Nonce, correlationID: m.CorrelationID}
+	if len(m.Data) > 0 {
+		if err := json.Unmarshal(m.Data, &msg.Data); err != nil {
+			return message{}, fmt.Errorf("failed to parse action data: %w", err)
+		}
+	}
+
+	// Ensure data map is initialized
+	if msg.Data == nil {
+		msg.Data = make(map[string]interface{})
+	}
+
+	return msg, nil
 }
 
 // ActionData wraps action data with utilities for binding and validation
@@ -106,6 +141,45 @@ func (a *ActionData) Get(key string) interface{} {
 type ActionContext struct {
 	Action string
 	Data   *ActionData
+
+	ctx  context.Context // Request or connection context, see Context()
+	url  *url.URL        // Page request or connection URL, see Path() and Query()
+	form url.Values      // Form fields when the action was a form-encoded HTTP POST
+
+	dispatch func(storeName, action string, data map[string]interface{}) error // See Dispatch
+	stores   func(name string) (Store, bool)                                   // See Store
+	signal   func(name string, payload interface{}, ttl time.Duration)         // See Signal
+	head     *HeadMeta                                                         // See SetTitle and SetMeta
+	redirect *string                                                           // See Redirect
+	loaders  *loaderSet                                                        // See Loader
+}
+
+// Context returns the context the action runs under. It carries the values of the
+// originating HTTP request and is cancelled when the request or WebSocket connection
+// ends, or when the action timeout (WithActionTimeout) expires.
+// Pass it to database and network calls so abandoned actions stop early.
+func (c *ActionContext) Context() context.Context {
+	if c.ctx == nil {
+		return context.Background()
+	}
+	return c.ctx
+}
+
+// Path returns the URL path of the page request or connection the action arrived on
+func (c *ActionContext) Path() string {
+	if c.url == nil {
+		return ""
+	}
+	return c.url.Path
+}
+
+// Query returns the URL query parameters of the page request or connection the action
+// arrived on, e.g. page=2 for /todos?page=2
+func (c *ActionContext) Query() url.Values {
+	if c.url == nil {
+		return url.Values{}
+	}
+	return c.url.Query()
 }
 
 // Bind is a convenience method that delegates to Data.Bind
@@ -113,11 +187,162 @@ func (c *ActionContext) Bind(v interface{}) error {
 	return c.Data.Bind(v)
 }
 
+// BindForm binds the action's form fields into the struct pointed to by v.
+// For a form-encoded HTTP POST (e.g. a plain HTML form without JavaScript) the fields are
+// decoded from the request body, converting strings to the field types; for actions sent
+// as JSON over WebSocket or HTTP it is equivalent to Bind, so one Change handles both.
+//
+// Fields match by `form` tag, then `json` tag, then name (case-insensitive):
+//
+//	var input struct {
+//		Title string `form:"title"`
+//		Done  bool   `form:"done"`
+//	}
+//	if err := ctx.BindForm(&input); err != nil { ... }
+func (c *ActionContext) BindForm(v interface{}) error {
+	if c.form == nil {
+		return c.Bind(v)
+	}
+	return bindValues(c.form, v)
+}
+
+// BindQuery binds the URL query parameters of the page request or connection the action
+// arrived on (see Query) into the struct pointed to by v, matching fields like BindForm
+func (c *ActionContext) BindQuery(v interface{}) error {
+	return bindValues(c.Query(), v)
+}
+
 // BindAndValidate is a convenience method
 func (c *ActionContext) BindAndValidate(v interface{}, validate *validator.Validate) error {
 	return c.Data.BindAndValidate(v, validate)
 }
 
+// Dispatch runs action on another store of the handler, e.g. from CounterState.Change:
+//
+//	return ctx.Dispatch("userstate", "recordactivity", map[string]interface{}{"source": "counter"})
+//
+// The store's Change runs synchronously with payload as its action data, before the update
+// is rendered, so changes to both stores reach the client in one update. The store name is
+// matched case-insensitively as in "store.action" actions; in single-store mode use "".
+// payload may be nil, a map[string]interface{} or any value that marshals to a JSON object.
+// The dispatched action's error is returned to the caller.
+func (c *ActionContext) Dispatch(storeName, action string, payload interface{}) error {
+	if c.dispatch == nil {
+		return fmt.Errorf("dispatch %s.%s: not running in a live handler", storeName, action)
+	}
+
+	data, err := payloadToData(payload)
+	if err != nil {
+		return fmt.Errorf("dispatch %s.%s: %w", storeName, action, err)
+	}
+	return c.dispatch(storeName, action, data)
+}
+
+// Store returns another store of the handler for reading its state, e.g. from DocState.Change:
+//
+//	if perms, ok := ctx.Store("permissions"); ok && !perms.(*PermissionsState).CanEdit {
+//	    return fmt.Errorf("editing is not allowed")
+//	}
+//
+// Names are matched as by Dispatch: case-insensitively, with "parent.child" for a child
+// store and "" for the store of a single-store handler. The store belongs to the same
+// session, and it is only safe to use within the action; to change it, use Dispatch so its
+// own Change runs. Reports false for unknown names and outside a live handler.
+func (c *ActionContext) Store(name string) (Store, bool) {
+	if c.stores == nil {
+		return nil, false
+	}
+	return c.stores(name)
+}
+
+// Signal sends an ephemeral event such as a typing indicator to the other connections of the
+// session group, e.g. from ChatState.Change:
+//
+//	case "type":
+//	    ctx.Signal("typing", map[string]string{"user": s.Name}, 3*time.Second)
+//
+// Signals bypass the stores and the update tree: nothing is rendered or persisted, and
+// clients that connect later never see them. The client dispatches an lvt:signal event with
+// the name and payload on the wrapper element, then lvt:signal-expired once ttl passes
+// without the signal being repeated. Repeats within half the ttl are dropped.
+func (c *ActionContext) Signal(name string, payload interface{}, ttl time.Duration) {
+	if c.signal == nil {
+		log.Printf("Signal %q: not running in a live handler", name)
+		return
+	}
+	c.signal(name, payload, ttl)
+}
+
+// SetTitle sets the document title, e.g. from Mount after server-driven navigation:
+//
+//	func (s *ProductState) Mount(ctx *livetemplate.ActionContext) error {
+//	    s.Product = s.load(ctx.Query().Get("id"))
+//	    ctx.SetTitle(s.Product.Name + " | Shop")
+//	    return nil
+//	}
+//
+// The head is outside the wrapper, so the title is sent in the metadata of the update the
+// action produces and the client sets document.title.
+func (c *ActionContext) SetTitle(title string) {
+	if c.head == nil {
+		log.Printf("SetTitle %q: not running in a live handler", title)
+		return
+	}
+	c.head.Title = title
+}
+
+// SetMeta sets the content of the document's <meta name="..."> tag, added if missing, like
+// SetTitle, e.g. ctx.SetMeta("description", s.Product.Summary).
+func (c *ActionContext) SetMeta(name, content string) {
+	if c.head == nil {
+		log.Printf("SetMeta %q: not running in a live handler", name)
+		return
+	}
+	if c.head.Meta == nil {
+		c.head.Meta = make(map[string]string)
+	}
+	c.head.Meta[name] = content
+}
+
+// Redirect sends the browser to url once the action succeeds, e.g. to the page of a record
+// the action created:
+//
+//	case "create":
+//	    id := s.create(ctx.GetString("title"))
+//	    ctx.Redirect("/todos/" + id)
+//
+// Over HTTP, a request not asking for JSON, such as a form post from a page without
+// JavaScript, gets a 302 redirect; the client library gets the URL in the metadata of the
+// update and navigates to it, over HTTP as over WebSocket. An action that fails doesn't
+// redirect, and Mount can't redirect (see Mounter).
+func (c *ActionContext) Redirect(url string) {
+	if c.redirect == nil {
+		log.Printf("Redirect %q: not running in a live handler", url)
+		return
+	}
+	*c.redirect = url
+}
+
+// payloadToData converts a Dispatch payload to action data
+func payloadToData(payload interface{}) (map[string]interface{}, error) {
+	switch p := payload.(type) {
+	case nil:
+		return make(map[string]interface{}), nil
+	case map[string]interface{}:
+		return p, nil
+	}
+
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal payload: %w", err)
+	}
+	var data map[string]interface{}
+	if err := json.Unmarshal(b, &data); err != nil {
+		return nil, fmt.Errorf("payload must marshal to a JSON object: %w", err)
+	}
+	return data, nil
+}
+
 // GetString is a convenience method
 func (c *ActionContext) GetString(key string) string {
 	return c.Data.GetString(key)
@@ -219,6 +444,52 @@ type StoreInitializer interface {
 	Init() error
 }
 
+// Mounter is an optional interface that stores can implement to load state for the
+// page being opened. Mount runs before the initial render of every page load and
+// WebSocket/SSE connection. ctx.Path() and ctx.Query() expose the requested route,
+// so a store can, for example, load the second page of results for /todos?page=2.
+//
+// Stores are mounted in the order of their names. Mount can read other stores with
+// ctx.Store and run their actions with ctx.Dispatch, but a store mounted later hasn't
+// loaded its route yet. The initial render isn't the response to an action, so
+// ctx.Redirect has no effect in Mount and Mount should render the page it can instead.
+//
+// Errors are reported to the template like errors from Change.
+type Mounter interface {
+	Mount(ctx *ActionContext) error
+}
+
+// ActionValidator is an optional interface that stores can implement to check business
+// rules centrally, before any action changes the store. Validate runs before every Change,
+// including actions dispatched from other stores; when it returns an error, Change is not
+// called and the error is reported to the template like an error from Change:
+//
+//	func (s *UsersState) Validate(ctx *livetemplate.ActionContext) error {
+//	    if ctx.Action == "delete" && s.isLastAdmin(ctx.GetString("id")) {
+//	        return livetemplate.NewFieldError("id", errors.New("can't delete the last admin"))
+//	    }
+//	    return nil
+//	}
+type ActionValidator interface {
+	Validate(ctx *ActionContext) error
+}
+
+// FieldVisibility is an optional interface that stores can implement to whitelist the
+// fields templates can see, so server-only fields (database handles, secrets) never reach
+// rendered HTML or updates. LiveVisible returns field names or json names; fields not listed
+// are hidden. Single fields can also be hidden with an `lvt:"-"` tag:
+//
+//	type TodoState struct {
+//	    Todos   []Todo
+//	    Queries *db.Queries `json:"-" lvt:"-"`
+//	}
+//
+// Visibility applies to the store's own fields. A store that hides fields is passed to the
+// template as a map of its visible fields, so its methods can't be called from the template.
+type FieldVisibility interface {
+	LiveVisible() []string
+}
+
 // Stores is a map of named stores
 type Stores map[string]Store
 
@@ -234,39 +505,71 @@ func parseAction(action string) (store string, actualAction string) {
 	return "", parts[0] // "", "increment" (single store)
 }
 
-// parseActionFromHTTP parses an action message from HTTP POST request body (internal protocol)
+// parseActionFromHTTP parses an action message from HTTP POST request body (internal protocol).
+// Besides the JSON protocol it accepts form-encoded bodies and query parameters, where the
+// action name is the "action" field, so plain HTML forms and links work without JavaScript.
 func parseActionFromHTTP(r *http.Request) (message, error) {
-	var msg message
-	if err := json.NewDecoder(r.Body).Decode(&msg); err != nil {
-		return message{}, fmt.Errorf("failed to parse action: %w", err)
+	if isFormRequest(r) || (r.ContentLength == 0 && r.URL.Query().Get("action") != "") {
+		return parseActionFromForm(r)
 	}
 
-	// Ensure data map is initialized
-	if msg.Data == nil {
-		msg.Data = make(map[string]interface{})
+	var wire wireMessage
+	if err := json.NewDecoder(r.Body).Decode(&wire); err != nil {
+		return message{}, fmt.Errorf("failed to parse action: %w", err)
 	}
+	return wire.decode()
+}
 
-	return msg, nil
+// correlationField is the form field carrying the correlation ID of a form-encoded HTTP
+// action, see ResponseMetadata.CorrelationID
+const correlationField = "lvt-correlation-id"
+
+// maxFormMemory is how much of a multipart action form is kept in memory; the rest goes to disk
+const maxFormMemory = 32 << 20
+
+// isFormRequest reports whether r has a form-encoded body
+func isFormRequest(r *http.Request) bool {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return mediaType == "application/x-www-form-urlencoded" || mediaType == "multipart/form-data"
 }
 
-// parseActionFromWebSocket parses an action message from WebSocket message bytes (internal protocol)
-func parseActionFromWebSocket(data []byte) (message, error) {
-	var msg message
-	if err := json.Unmarshal(data, &msg); err != nil {
-		return message{}, fmt.Errorf("failed to parse action: %w", err)
+// parseActionFromForm parses an action message from form fields and query parameters
+func parseActionFromForm(r *http.Request) (message, error) {
+	if err := r.ParseMultipartForm(maxFormMemory); err != nil && err != http.ErrNotMultipart {
+		return message{}, fmt.Errorf("failed to parse action form: %w", err)
 	}
 
-	// Ensure data map is initialized
-	if msg.Data == nil {
-		msg.Data = make(map[string]interface{})
+	// Form fields take precedence over query parameters of the same name
+	values := r.Form
+	action := values.Get("action")
+	if action == "" {
+		return message{}, fmt.Errorf("failed to parse action: missing action field")
 	}
 
-	return msg, nil
+	fields := make(url.Values, len(values))
+	for key, raw := range values {
+		if key != "action" && key != nonceField && key != correlationField {
+			fields[key] = raw
+		}
+	}
+
+	return message{
+		Action:        action,
+		Data:          valuesToData(fields),
+		form:          fields,
+		size:          len(fields.Encode()),
+		nonce:         values.Get(nonceField),
+		correlationID: values.Get(correlationField),
+	}, nil
 }
 
-// writeUpdateWebSocket writes a tree update to WebSocket connection (internal protocol)
-func writeUpdateWebSocket(conn *websocket.Conn, update []byte) error {
-	return conn.WriteMessage(websocket.TextMessage, update)
+// parseActionFromWebSocket parses an action message from WebSocket message bytes (internal protocol)
+func parseActionFromWebSocket(data []byte) (message, error) {
+	var wire wireMessage
+	if err := json.Unmarshal(data, &wire); err != nil {
+		return message{}, fmt.Errorf("failed to parse action: %w", err)
+	}
+	return wire.decode()
 }
 
 // Removed: Generic helper functions (getString, getInt, etc.)
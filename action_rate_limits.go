@@ -0,0 +1,68 @@
+package livetemplate
+
+import (
+	"maps"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// tagPattern matches an opening tag, whose attributes may hold template actions
+var tagPattern = regexp.MustCompile(`<[a-zA-Z][^<>]*(?:\{\{[^}]*\}\}[^<>]*)*>`)
+
+// rateLimitAttrPattern matches the lvt-debounce and lvt-throttle attributes read by the client
+var rateLimitAttrPattern = regexp.MustCompile(`(?:^|\s)lvt-(debounce|throttle)\s*=\s*(?:"([^"]*)"|'([^']*)')`)
+
+// ActionRateLimit is the rate limit a template declares for an action on the element
+// triggering it, e.g. <input lvt-input="search" lvt-debounce="300">. The client applies it
+// before sending; throttle takes precedence over debounce.
+type ActionRateLimit struct {
+	Debounce time.Duration // Wait after the last event before sending (lvt-debounce)
+	Throttle time.Duration // Minimum time between sends (lvt-throttle)
+}
+
+// ActionRateLimits returns the rate limits declared in the template by action name, read
+// during Parse, so server-side rate handling can default to what the page declares.
+// When several elements trigger an action, the first declaring a limit wins. Values
+// computed by the template (lvt-debounce="{{.Wait}}") are skipped. Returns nil before Parse.
+func (t *Template) ActionRateLimits() map[string]ActionRateLimit {
+	return maps.Clone(t.rateLimits) // Shared by instances parsed from the same source
+}
+
+// parseActionRateLimits collects the rate limits declared next to action attributes in text
+func parseActionRateLimits(text string) map[string]ActionRateLimit {
+	var limits map[string]ActionRateLimit
+	for _, tag := range tagPattern.FindAllString(text, -1) {
+		var limit ActionRateLimit
+		for _, match := range rateLimitAttrPattern.FindAllStringSubmatch(tag, -1) {
+			ms, err := strconv.Atoi(strings.TrimSpace(match[2] + match[3]))
+			if err != nil || ms <= 0 {
+				continue
+			}
+			if match[1] == "debounce" {
+				limit.Debounce = time.Duration(ms) * time.Millisecond
+			} else {
+				limit.Throttle = time.Duration(ms) * time.Millisecond
+			}
+		}
+		if limit == (ActionRateLimit{}) {
+			continue
+		}
+
+		for _, match := range actionAttrPattern.FindAllStringSubmatch(tag, -1) {
+			action := strings.TrimSpace(match[1] + match[2])
+			if action == "" || strings.Contains(action, "{{") {
+				continue
+			}
+			if _, ok := limits[action]; ok {
+				continue
+			}
+			if limits == nil {
+				limits = make(map[string]ActionRateLimit)
+			}
+			limits[action] = limit
+		}
+	}
+	return limits
+}
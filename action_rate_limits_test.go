@@ -0,0 +1,43 @@
+package livetemplate
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestTemplate_ActionRateLimits(t *testing.T) {
+	tmpl := New("rate-limits-test")
+	if tmpl.ActionRateLimits() != nil {
+		t.Error("Expected no rate limits before Parse")
+	}
+
+	_, err := tmpl.Parse(`<div>
+	<input name="q" lvt-input="search" lvt-debounce="300" value="{{.Query}}">
+	<button lvt-click="save" lvt-throttle='1000' {{if .Busy}}disabled{{end}}>Save</button>
+	<button lvt-click="search">Search now</button>
+	<button lvt-click="increment">+</button>
+	<input lvt-change="resize" lvt-debounce="{{.Wait}}">
+	<input lvt-keyup="filter" lvt-debounce="200" lvt-throttle="50">
+</div>`)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	want := map[string]ActionRateLimit{
+		"search": {Debounce: 300 * time.Millisecond},
+		"save":   {Throttle: time.Second},
+		"filter": {Debounce: 200 * time.Millisecond, Throttle: 50 * time.Millisecond},
+	}
+	if got := tmpl.ActionRateLimits(); !reflect.DeepEqual(got, want) {
+		t.Errorf("ActionRateLimits() = %v, want %v", got, want)
+	}
+
+	clone, err := tmpl.Clone()
+	if err != nil {
+		t.Fatalf("Clone failed: %v", err)
+	}
+	if got := clone.ActionRateLimits(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Clone ActionRateLimits() = %v, want %v", got, want)
+	}
+}
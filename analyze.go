@@ -0,0 +1,201 @@
+package livetemplate
+
+import (
+	"fmt"
+	"strings"
+	"text/template/parse"
+)
+
+// analysisSlotBytes estimates the JSON size of one changed slot in an update: its key,
+// quotes and separators, and a short value
+const analysisSlotBytes = 16
+
+// TemplateAnalysis is a static breakdown of a template, for documentation and template
+// galleries, see Analyze
+type TemplateAnalysis struct {
+	Constructs []TemplateConstruct // Control structures and function calls in source order
+	Fields     []string            // Field paths the template reads, as ReferencedFields
+	Slots      int                 // Dynamic slots: actions and control structures
+	Statics    int                 // Bytes of static markup, sent only with the first render
+	UpdateSize int                 // Estimated bytes of an update changing every slot
+}
+
+// TemplateConstruct is a control structure of a template and how updates handle it
+type TemplateConstruct struct {
+	Kind      string // "if", "switch", "range", "with", or "func" for an action calling a function
+	Source    string // Opening action, e.g. "{{with .User}}"
+	Line      int    // Line of the opening action in the (flattened) template
+	TreeBased bool   // Updated in place as a node of the tree
+	Unkeyed   bool   // A range whose items carry no key attribute, so they're matched by position
+	Note      string // How updates handle a construct that is not tree-based or is unkeyed
+}
+
+// Analyze returns the constructs the template uses and how updates handle each of them, the
+// field paths it reads and the estimated size of its updates. Named templates are resolved as
+// in tree generation. Returns an empty analysis before Parse.
+//
+// A {{with}} is reported as not tree-based: its body is inlined into the enclosing node,
+// so changing between its body and its else branch or nothing isn't diffed reliably. An
+// {{if}} on the same value is. A range is reported as unkeyed when its body has none of the
+// key attributes (WithKeyAttributes, or the built-in list). An action calling functions
+// is a "func" construct, tree-based when all of them are known to be pure, like printf,
+// len, index and the comparisons; {{call}} isn't.
+func (t *Template) Analyze() TemplateAnalysis {
+	if t.templateStr == "" {
+		return TemplateAnalysis{}
+	}
+
+	tree := parse.New("analyze")
+	tree.Mode = parse.SkipFuncCheck
+	if _, err := tree.Parse(t.templateStr, "", "", map[string]*parse.Tree{}); err != nil {
+		return TemplateAnalysis{}
+	}
+
+	a := &templateAnalyzer{source: t.templateStr, keyAttributes: defaultKeyAttributes.AttributeNames}
+	if len(t.config.KeyAttributes) > 0 {
+		a.keyAttributes = t.config.KeyAttributes
+	}
+	a.walk(tree.Root)
+	a.analysis.Fields = t.ReferencedFields()
+	a.analysis.UpdateSize = len("{}") + a.analysis.Slots*analysisSlotBytes
+	return a.analysis
+}
+
+// pureFuncs are the template functions whose output depends only on their arguments, so an
+// action calling one is a dynamic slot like a field: rendered and diffed with each update
+var pureFuncs = map[string]bool{
+	"and": true, "or": true, "not": true, "len": true, "index": true, "slice": true,
+	"print": true, "printf": true, "println": true, "html": true, "js": true, "urlquery": true,
+	"eq": true, "ne": true, "lt": true, "le": true, "gt": true, "ge": true,
+	"lvt_static": true, "lvt_value": true, "lvt_number": true, "lvt_date": true,
+	"lvt_id": true, "lvt_scoped_id": true, "lvt_locale_number": true, "lvt_locale_date": true,
+}
+
+// actionFuncs returns the functions an action's pipeline calls, e.g. [printf] for
+// {{.Price | printf "%.2f"}}
+func actionFuncs(node *parse.ActionNode) []string {
+	if node.Pipe == nil {
+		return nil
+	}
+	var funcs []string
+	for _, cmd := range node.Pipe.Cmds {
+		for _, arg := range cmd.Args {
+			if ident, ok := arg.(*parse.IdentifierNode); ok {
+				funcs = append(funcs, ident.Ident)
+			}
+		}
+	}
+	return funcs
+}
+
+// allPure reports whether every function of funcs is in pureFuncs
+func allPure(funcs []string) bool {
+	for _, name := range funcs {
+		if !pureFuncs[name] {
+			return false
+		}
+	}
+	return true
+}
+
+// templateAnalyzer builds a TemplateAnalysis from a template AST
+type templateAnalyzer struct {
+	source        string
+	keyAttributes []string
+	analysis      TemplateAnalysis
+}
+
+// walk adds node and its children to the analysis
+func (a *templateAnalyzer) walk(node parse.Node) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			a.walk(child)
+		}
+
+	case *parse.TextNode:
+		a.analysis.Statics += len(n.Text)
+
+	case *parse.ActionNode:
+		a.analysis.Slots++
+		if funcs := actionFuncs(n); len(funcs) > 0 {
+			pure := allPure(funcs)
+			note := ""
+			if !pure {
+				note = "calls a function not known to be pure; its output is only as current as the last update"
+			}
+			a.add("func", n.Position(), n.Pipe, pure, note)
+		}
+
+	case *parse.IfNode:
+		a.analysis.Slots++
+		if cases, elseList := switchCases(n); cases != nil {
+			a.add("switch", n.Position(), n.Pipe, true, "")
+			for _, c := range cases {
+				a.walk(c.List)
+			}
+			a.walk(elseList)
+			return
+		}
+		a.add("if", n.Position(), n.Pipe, true, "")
+		a.walk(n.List)
+		a.walk(n.ElseList)
+
+	case *parse.RangeNode:
+		a.analysis.Slots++
+		a.add("range", n.Position(), n.Pipe, true, "")
+		if !a.keyed(n.List) {
+			construct := &a.analysis.Constructs[len(a.analysis.Constructs)-1]
+			construct.Unkeyed = true
+			construct.Note = "items have no key attribute and are matched by position; add data-key so inserts and reorders stay minimal"
+		}
+		a.walk(n.List)
+		a.walk(n.ElseList)
+
+	case *parse.WithNode:
+		a.analysis.Slots++
+		a.add("with", n.Position(), n.Pipe, false,
+			"body is inlined into the enclosing node; use {{if}} when the value can become empty")
+		a.walk(n.List)
+		a.walk(n.ElseList)
+	}
+}
+
+// keyed reports whether a range body carries one of the key attributes
+func (a *templateAnalyzer) keyed(body *parse.ListNode) bool {
+	if body == nil {
+		return false
+	}
+	markup := body.String()
+	for _, attr := range a.keyAttributes {
+		if hasAttributeStart(markup, attr) {
+			return true
+		}
+	}
+	return false
+}
+
+// add records a construct opened at byte offset pos of the source
+func (a *templateAnalyzer) add(kind string, pos parse.Pos, pipe *parse.PipeNode, treeBased bool, note string) {
+	line := 1
+	if offset := int(pos); offset <= len(a.source) {
+		line += strings.Count(a.source[:offset], "\n")
+	}
+	source := fmt.Sprintf("{{%s %s}}", kind, pipe)
+	switch kind {
+	case "switch":
+		source = fmt.Sprintf("{{if %s}}", pipe) // The chain is written as if/else if
+	case "func":
+		source = fmt.Sprintf("{{%s}}", pipe)
+	}
+	a.analysis.Constructs = append(a.analysis.Constructs, TemplateConstruct{
+		Kind:      kind,
+		Source:    source,
+		Line:      line,
+		TreeBased: treeBased,
+		Note:      note,
+	})
+}
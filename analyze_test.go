@@ -0,0 +1,125 @@
+package livetemplate
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestTemplate_Analyze(t *testing.T) {
+	tmpl := New("analyze-test")
+	if got := tmpl.Analyze(); !reflect.DeepEqual(got, TemplateAnalysis{}) {
+		t.Errorf("Expected an empty analysis before Parse, got %+v", got)
+	}
+
+	_, err := tmpl.Parse(`<h1>{{.Title}}</h1>
+{{with .User}}<p>{{.Name}}</p>{{end}}
+<ul>{{range .Items}}<li data-key="{{.ID}}">{{.Label}}</li>{{end}}</ul>
+{{if eq .Status "open"}}Open{{else if eq .Status "closed"}}Closed{{end}}
+{{if .Flash}}<div>{{.Flash}}</div>{{end}}`)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	analysis := tmpl.Analyze()
+
+	wantConstructs := []TemplateConstruct{
+		{Kind: "with", Source: "{{with .User}}", Line: 2, Note: analysis.Constructs[0].Note},
+		{Kind: "range", Source: "{{range .Items}}", Line: 3, TreeBased: true},
+		{Kind: "switch", Source: `{{if eq .Status "open"}}`, Line: 4, TreeBased: true},
+		{Kind: "if", Source: "{{if .Flash}}", Line: 5, TreeBased: true},
+	}
+	if !reflect.DeepEqual(analysis.Constructs, wantConstructs) {
+		t.Errorf("Constructs = %+v, want %+v", analysis.Constructs, wantConstructs)
+	}
+	if analysis.Constructs[0].TreeBased || analysis.Constructs[0].Note == "" {
+		t.Errorf("Expected {{with}} to be flagged as not tree-based with a note, got %+v", analysis.Constructs[0])
+	}
+
+	wantFields := []string{".Flash", ".Items", ".Items.ID", ".Items.Label", ".Status", ".Title", ".User", ".User.Name"}
+	if !reflect.DeepEqual(analysis.Fields, wantFields) {
+		t.Errorf("Fields = %v, want %v", analysis.Fields, wantFields)
+	}
+
+	// .Title, with, .Name, range, .ID, .Label, the switch and if, .Flash
+	if analysis.Slots != 9 {
+		t.Errorf("Slots = %d, want 9", analysis.Slots)
+	}
+	if analysis.Statics == 0 || analysis.UpdateSize <= analysis.Slots {
+		t.Errorf("Expected statics and an update size estimate, got %+v", analysis)
+	}
+}
+
+func TestTemplate_AnalyzeUnkeyedRange(t *testing.T) {
+	source := `<ul>{{range .Items}}<li>{{.Label}}</li>{{end}}</ul>
+<ol>{{range .Rows}}<li data-row-id="{{.ID}}">{{.Label}}</li>{{end}}</ol>`
+
+	t.Run("built-in key attributes", func(t *testing.T) {
+		tmpl := New("unkeyed-test")
+		if _, err := tmpl.Parse(source); err != nil {
+			t.Fatalf("Parse failed: %v", err)
+		}
+		constructs := tmpl.Analyze().Constructs
+		if len(constructs) != 2 {
+			t.Fatalf("Expected 2 constructs, got %+v", constructs)
+		}
+		for _, c := range constructs {
+			if !c.Unkeyed || c.Note == "" || !c.TreeBased {
+				t.Errorf("Expected %s to be flagged as unkeyed with a note, got %+v", c.Source, c)
+			}
+		}
+	})
+
+	t.Run("custom key attributes", func(t *testing.T) {
+		tmpl := New("unkeyed-test", WithKeyAttributes([]string{"data-row-id"}))
+		if _, err := tmpl.Parse(source); err != nil {
+			t.Fatalf("Parse failed: %v", err)
+		}
+		constructs := tmpl.Analyze().Constructs
+		if !constructs[0].Unkeyed {
+			t.Errorf("Expected %s to be unkeyed, got %+v", constructs[0].Source, constructs[0])
+		}
+		if constructs[1].Unkeyed || constructs[1].Note != "" {
+			t.Errorf("Expected %s to be keyed by data-row-id, got %+v", constructs[1].Source, constructs[1])
+		}
+	})
+}
+
+func TestTemplate_AnalyzeFuncs(t *testing.T) {
+	tmpl := New("analyze-funcs-test")
+	if _, err := tmpl.Parse(`<p>{{len .Users}} users</p>
+<p>{{.Price | printf "%.2f"}}</p>
+<p>{{call .Greeting .Name}}</p>`); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	want := []TemplateConstruct{
+		{Kind: "func", Source: "{{len .Users}}", Line: 1, TreeBased: true},
+		{Kind: "func", Source: `{{.Price | printf "%.2f"}}`, Line: 2, TreeBased: true},
+		{Kind: "func", Source: "{{call .Greeting .Name}}", Line: 3},
+	}
+	got := tmpl.Analyze().Constructs
+	if len(got) == 3 {
+		want[2].Note = got[2].Note
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Constructs = %+v, want %+v", got, want)
+	}
+	if len(got) == 3 && got[2].Note == "" {
+		t.Error("Expected a note on the call of a function from the data")
+	}
+
+	// A builtin's slot updates like a field's
+	var buf bytes.Buffer
+	if err := tmpl.ExecuteUpdates(&buf, map[string]interface{}{"Users": []string{"ann"}, "Price": 1.5,
+		"Greeting": func(name string) string { return "hi " + name }, "Name": "ann"}); err != nil {
+		t.Fatalf("ExecuteUpdates failed: %v", err)
+	}
+	buf.Reset()
+	if err := tmpl.ExecuteUpdates(&buf, map[string]interface{}{"Users": []string{"ann", "bob"}, "Price": 1.5,
+		"Greeting": func(name string) string { return "hi " + name }, "Name": "ann"}); err != nil {
+		t.Fatalf("ExecuteUpdates failed: %v", err)
+	}
+	if got, want := buf.String(), `{"0":"2"}`; got != want {
+		t.Errorf("Update = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,69 @@
+package livetemplate
+
+import (
+	"regexp"
+	"strings"
+	"text/template/parse"
+)
+
+// lvtAttrPattern matches an lvt-* attribute name at the start of text or after whitespace
+var lvtAttrPattern = regexp.MustCompile(`(^|\s)lvt-[\w-]+`)
+
+// attributeContext is the slot context of attributes toggled between the attributes of a tag
+var attributeContext = slotContext{open: "<p ", close: ">"}
+
+// isAttributeToggle reports whether node is an {{if}} between the attributes of a tag that
+// adds or removes lvt-* attributes, e.g.
+//
+//	<button {{if .Enabled}}lvt-click="save"{{end}} class="btn">
+//
+// statics are those of the enclosing list so far. Such a conditional becomes a single slot
+// holding the rendered attributes, so toggling the binding sends only the attribute text
+// instead of a branch with its own statics. The client patches attributes in place, and
+// reads lvt-* attributes when events fire, so the element keeps its state and binds or
+// unbinds with the attribute.
+func isAttributeToggle(node *parse.IfNode, statics []string) bool {
+	if !togglesLvtAttribute(node.List) && !togglesLvtAttribute(node.ElseList) {
+		return false
+	}
+
+	text := strings.Join(statics, "0")
+	tagStart := strings.LastIndex(text, "<")
+	if tagStart < 0 || strings.LastIndex(text, ">") > tagStart {
+		return false
+	}
+	tag := text[tagStart:]
+	if tagName(tag[1:]) == "" || !strings.ContainsAny(tag[len(tag)-1:], " \t\n\r") {
+		return false
+	}
+	// Inside a quoted value the conditional is part of the value, not an attribute
+	return strings.Count(tag, `"`)%2 == 0 && strings.Count(tag, "'")%2 == 0
+}
+
+// togglesLvtAttribute reports whether the text of branch declares an lvt-* attribute
+func togglesLvtAttribute(branch *parse.ListNode) bool {
+	if branch == nil {
+		return false
+	}
+	for _, n := range branch.Nodes {
+		if text, ok := n.(*parse.TextNode); ok && lvtAttrPattern.Match(text.Text) {
+			return true
+		}
+	}
+	return false
+}
+
+// handleAttributeToggle renders an attribute toggle (see isAttributeToggle) as one slot
+func handleAttributeToggle(node *parse.IfNode, data interface{}) (treeNode, error) {
+	value, err := attributeContext.render(node.String(), data)
+	if err != nil {
+		return nil, err
+	}
+	return treeNode{"s": []string{"", ""}, "0": value}, nil
+}
+
+// usesVariables reports whether node refers to variables such as $index or $, which
+// rendering it with dot alone can't resolve
+func usesVariables(node parse.Node) bool {
+	return strings.Contains(node.String(), "$")
+}
@@ -0,0 +1,97 @@
+package livetemplate
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestAttributeToggle(t *testing.T) {
+	type item struct {
+		ID   string
+		Open bool
+	}
+	type state struct {
+		Enabled bool
+		Action  string
+		Active  bool
+		Items   []item
+	}
+	const source = `<button {{if .Enabled}}lvt-click="{{.Action}}"{{end}} class="{{if .Active}}lvt-active{{end}}">Save</button>` +
+		`<ul>{{range .Items}}<li data-key="{{.ID}}"><button {{if .Open}}lvt-click="close"{{else}}disabled{{end}}>x</button></li>{{end}}</ul>`
+
+	tmpl := New("attr-toggle-test")
+	if _, err := tmpl.Parse(source); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	update := func(t *testing.T, data state) map[string]interface{} {
+		t.Helper()
+		var buf bytes.Buffer
+		if err := tmpl.ExecuteUpdates(&buf, data); err != nil {
+			t.Fatalf("ExecuteUpdates failed: %v", err)
+		}
+		var tree map[string]interface{}
+		if err := json.Unmarshal(buf.Bytes(), &tree); err != nil {
+			t.Fatalf("Failed to decode %s: %v", buf.String(), err)
+		}
+		return tree
+	}
+
+	disabled := state{Action: "save", Items: []item{{ID: "a"}}}
+	initial := update(t, disabled)
+	if initial["0"] != "" {
+		t.Fatalf("Expected an empty attribute slot while disabled, got %v", initial["0"])
+	}
+	if _, nested := initial["1"].(map[string]interface{}); !nested && initial["1"] != "" {
+		t.Errorf("Expected the conditional inside the class value to stay a regular conditional, got %v", initial["1"])
+	}
+
+	t.Run("enable", func(t *testing.T) {
+		enabled := disabled
+		enabled.Enabled = true
+		if tree := update(t, enabled); !reflect.DeepEqual(tree, map[string]interface{}{"0": `lvt-click="save"`}) {
+			t.Errorf("Expected only the attribute to change, got %v", tree)
+		}
+		enabled.Action = "publish"
+		if tree := update(t, enabled); !reflect.DeepEqual(tree, map[string]interface{}{"0": `lvt-click="publish"`}) {
+			t.Errorf("Expected the new action in the attribute, got %v", tree)
+		}
+	})
+
+	t.Run("disable", func(t *testing.T) {
+		if tree := update(t, disabled); !reflect.DeepEqual(tree, map[string]interface{}{"0": ""}) {
+			t.Errorf("Expected only the attribute to be removed, got %v", tree)
+		}
+	})
+
+	t.Run("range items", func(t *testing.T) {
+		open := disabled
+		open.Items = []item{{ID: "a", Open: true}}
+		tree := update(t, open)
+		encoded, _ := json.Marshal(tree)
+		if !strings.Contains(string(encoded), `"lvt-click=\"close\""`) || strings.Contains(string(encoded), `"s"`) {
+			t.Errorf("Expected the item's attribute as a value without statics, got %s", encoded)
+		}
+	})
+
+	t.Run("matches Execute", func(t *testing.T) {
+		var html bytes.Buffer
+		data := state{Enabled: true, Action: `a"b`, Items: []item{{ID: "a"}}}
+		if err := tmpl.Execute(&html, data); err != nil {
+			t.Fatalf("Execute failed: %v", err)
+		}
+		want := `<button lvt-click="a&#34;b" class="">`
+		if !strings.Contains(html.String(), want) {
+			t.Fatalf("Expected %s in %s", want, html.String())
+		}
+		tree, err := parseTemplateToTree(source, tmpl.addLvtToData(data, nil), newKeyGenerator())
+		if err != nil {
+			t.Fatalf("parseTemplateToTree failed: %v", err)
+		}
+		if tree["0"] != `lvt-click="a&#34;b"` {
+			t.Errorf("Expected the attribute escaped as Execute escapes it, got %v", tree["0"])
+		}
+	})
+}
@@ -36,6 +36,19 @@ type Authenticator interface {
 	GetSessionGroup(r *http.Request, userID string) (groupID string, err error)
 }
 
+// RoleProvider is an optional interface for Authenticators that know the roles of a user.
+//
+// The roles of the user identified for a request are available to templates through the
+// lvt namespace, so markup can be gated by role:
+//
+//	{{if .lvt.HasRole "admin"}}<button lvt-click="delete">Delete</button>{{end}}
+//
+// Roles only control what is rendered; actions must still check permissions themselves.
+type RoleProvider interface {
+	// Roles returns the roles of userID. It is called once per connection or HTTP request.
+	Roles(r *http.Request, userID string) ([]string, error)
+}
+
 // AnonymousAuthenticator provides browser-based session grouping for anonymous users.
 //
 // This is the default authenticator and implements the most common use case:
@@ -101,6 +114,9 @@ type BasicAuthenticator struct {
 	// Returns true if credentials are valid, false otherwise.
 	// Returns error for system failures (e.g., database connection error).
 	ValidateFunc func(username, password string) (bool, error)
+
+	// RolesFunc optionally returns the roles of an authenticated user (see RoleProvider).
+	RolesFunc func(username string) ([]string, error)
 }
 
 // NewBasicAuthenticator creates a BasicAuthenticator with the given validation function.
@@ -153,6 +169,14 @@ func (a *BasicAuthenticator) GetSessionGroup(r *http.Request, userID string) (st
 	return userID, nil
 }
 
+// Roles returns the roles of userID from RolesFunc, or no roles if it is nil.
+func (a *BasicAuthenticator) Roles(r *http.Request, userID string) ([]string, error) {
+	if a.RolesFunc == nil || userID == "" {
+		return nil, nil
+	}
+	return a.RolesFunc(userID)
+}
+
 // generateSessionID creates a cryptographically secure random identifier for session groups.
 //
 // Uses crypto/rand (not math/rand) to generate 32 bytes (256 bits) of entropy,
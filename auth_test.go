@@ -1,12 +1,17 @@
 package livetemplate
 
 import (
+	"bufio"
 	"encoding/base64"
+	"encoding/json"
 	"errors"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+
+	"github.com/gorilla/websocket"
 )
 
 // TestAnonymousAuthenticator_Identify tests that anonymous authenticator always returns empty userID
@@ -333,3 +338,96 @@ func TestAuthenticator_Interface(t *testing.T) {
 	var _ Authenticator = (*AnonymousAuthenticator)(nil)
 	var _ Authenticator = (*BasicAuthenticator)(nil)
 }
+
+// TestBasicAuthenticator_RoleGatedContent tests that lvt.HasRole renders role-gated content only for users with the role
+func TestBasicAuthenticator_RoleGatedContent(t *testing.T) {
+	auth := NewBasicAuthenticator(func(username, password string) (bool, error) {
+		return password == "secret", nil
+	})
+	auth.RolesFunc = func(username string) ([]string, error) {
+		if username == "alice" {
+			return []string{"admin"}, nil
+		}
+		return []string{"viewer"}, nil
+	}
+
+	tmpl := New("role-test", WithAuthenticator(auth))
+	if _, err := tmpl.Parse(`<p>Signed in as {{.lvt.UserID}}</p>{{if .lvt.HasRole "admin"}}<a href="/admin">Admin panel</a>{{end}}`); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	server := httptest.NewServer(tmpl.Handle(&SlowState{}))
+	defer server.Close()
+
+	render := func(t *testing.T, username string) string {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+		req.SetBasicAuth(username, "secret")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("GET failed: %v", err)
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatalf("Failed to read body: %v", err)
+		}
+		return string(body)
+	}
+
+	t.Run("admin", func(t *testing.T) {
+		html := render(t, "alice")
+		if !strings.Contains(html, "Signed in as alice") {
+			t.Errorf("Expected user ID in page, got %s", html)
+		}
+		if !strings.Contains(html, "Admin panel") {
+			t.Errorf("Expected admin content for an admin, got %s", html)
+		}
+	})
+
+	t.Run("viewer", func(t *testing.T) {
+		html := render(t, "bob")
+		if !strings.Contains(html, "Signed in as bob") {
+			t.Errorf("Expected user ID in page, got %s", html)
+		}
+		if strings.Contains(html, "Admin panel") {
+			t.Errorf("Admin content rendered for a viewer: %s", html)
+		}
+	})
+
+	t.Run("websocket", func(t *testing.T) {
+		header := http.Header{}
+		header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte("alice:secret")))
+		conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), header)
+		if err != nil {
+			t.Fatalf("WebSocket dial failed: %v", err)
+		}
+		defer conn.Close()
+
+		_, initial, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatalf("Failed to read initial tree: %v", err)
+		}
+		if !strings.Contains(string(initial), "Admin panel") {
+			t.Errorf("Expected admin content in initial tree, got %s", initial)
+		}
+	})
+
+	t.Run("server-sent events", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+		req.SetBasicAuth("alice", "secret")
+		req.Header.Set("Accept", "text/event-stream")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("SSE request failed: %v", err)
+		}
+		defer resp.Body.Close()
+
+		initial, err := json.Marshal(readSSEUpdate(t, bufio.NewReader(resp.Body)).Tree)
+		if err != nil {
+			t.Fatalf("Failed to encode initial tree: %v", err)
+		}
+		if !strings.Contains(string(initial), "alice") || !strings.Contains(string(initial), "Admin panel") {
+			t.Errorf("Expected the user ID and admin content in initial tree, got %s", initial)
+		}
+	})
+}
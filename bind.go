@@ -0,0 +1,164 @@
+package livetemplate
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// bindValues decodes url.Values (form fields or query parameters) into the struct pointed to by v.
+//
+// A field is filled from the value whose name matches its `form` tag, else its `json` tag,
+// else the field name, compared case-insensitively. Supported field types are strings, bools,
+// integers, floats and slices of those; fields without a matching value are left unchanged.
+func bindValues(values url.Values, v interface{}) error {
+	ptr := reflect.ValueOf(v)
+	if ptr.Kind() != reflect.Ptr || ptr.IsNil() || ptr.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("bind target must be a non-nil pointer to a struct, got %T", v)
+	}
+	target := ptr.Elem()
+	targetType := target.Type()
+
+	for i := 0; i < targetType.NumField(); i++ {
+		field := targetType.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name := valueName(field)
+		if name == "-" {
+			continue
+		}
+
+		raw, ok := lookupValue(values, name)
+		if !ok {
+			continue
+		}
+
+		if err := setFieldFromStrings(target.Field(i), raw); err != nil {
+			return fmt.Errorf("failed to bind %s: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+// valueName returns the form/query name a struct field binds from
+func valueName(field reflect.StructField) string {
+	for _, tag := range []string{"form", "json"} {
+		if name, _, _ := strings.Cut(field.Tag.Get(tag), ","); name != "" {
+			return name
+		}
+	}
+	return field.Name
+}
+
+// lookupValue finds the values for name, falling back to a case-insensitive match
+func lookupValue(values url.Values, name string) ([]string, bool) {
+	if raw, ok := values[name]; ok {
+		return raw, true
+	}
+	for key, raw := range values {
+		if strings.EqualFold(key, name) {
+			return raw, true
+		}
+	}
+	return nil, false
+}
+
+// setFieldFromStrings converts raw into field's type and assigns it
+func setFieldFromStrings(field reflect.Value, raw []string) error {
+	if field.Kind() == reflect.Slice {
+		slice := reflect.MakeSlice(field.Type(), len(raw), len(raw))
+		for i, s := range raw {
+			if err := setFieldFromString(slice.Index(i), s); err != nil {
+				return err
+			}
+		}
+		field.Set(slice)
+		return nil
+	}
+
+	if len(raw) == 0 {
+		return nil
+	}
+	return setFieldFromString(field, raw[0])
+}
+
+// setFieldFromString converts s into field's type and assigns it
+func setFieldFromString(field reflect.Value, s string) error {
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(s)
+
+	case reflect.Bool:
+		// Checkboxes submit "on" when checked
+		if s == "on" {
+			field.SetBool(true)
+			return nil
+		}
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if s == "" {
+			field.SetInt(0)
+			return nil
+		}
+		n, err := strconv.ParseInt(s, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetInt(n)
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if s == "" {
+			field.SetUint(0)
+			return nil
+		}
+		n, err := strconv.ParseUint(s, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetUint(n)
+
+	case reflect.Float32, reflect.Float64:
+		if s == "" {
+			field.SetFloat(0)
+			return nil
+		}
+		f, err := strconv.ParseFloat(s, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetFloat(f)
+
+	default:
+		return fmt.Errorf("unsupported field type %s", field.Type())
+	}
+
+	return nil
+}
+
+// valuesToData converts form values into action data: single values become strings,
+// repeated values (e.g. multi-selects) string lists
+func valuesToData(values url.Values) map[string]interface{} {
+	data := make(map[string]interface{}, len(values))
+	for key, raw := range values {
+		if len(raw) == 1 {
+			data[key] = raw[0]
+			continue
+		}
+		list := make([]interface{}, len(raw))
+		for i, s := range raw {
+			list[i] = s
+		}
+		data[key] = list
+	}
+	return data
+}
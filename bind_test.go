@@ -0,0 +1,109 @@
+package livetemplate
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type bindInput struct {
+	Title    string   `form:"title"`
+	Priority int      `json:"priority"`
+	Done     bool     `form:"done"`
+	Score    float64  // Matched by field name
+	Tags     []string `form:"tag"`
+}
+
+// actionContextFromHTTP builds the ActionContext a store would receive for r
+func actionContextFromHTTP(t *testing.T, r *http.Request) *ActionContext {
+	t.Helper()
+
+	msg, err := parseActionFromHTTP(r)
+	if err != nil {
+		t.Fatalf("parseActionFromHTTP failed: %v", err)
+	}
+	if msg.Action != "save" {
+		t.Fatalf("Expected action %q, got %q", "save", msg.Action)
+	}
+	return &ActionContext{
+		Action: msg.Action,
+		Data:   newActionData(msg.Data),
+		url:    r.URL,
+		form:   msg.form,
+	}
+}
+
+func TestActionContext_BindForm(t *testing.T) {
+	want := bindInput{Title: "Write docs", Priority: 2, Done: true, Score: 4.5, Tags: []string{"a", "b"}}
+
+	t.Run("form-encoded body", func(t *testing.T) {
+		body := "action=save&title=Write+docs&priority=2&done=on&score=4.5&tag=a&tag=b"
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+		ctx := actionContextFromHTTP(t, r)
+		var got bindInput
+		if err := ctx.BindForm(&got); err != nil {
+			t.Fatalf("BindForm failed: %v", err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("BindForm = %+v, want %+v", got, want)
+		}
+		if ctx.GetString("title") != "Write docs" {
+			t.Errorf("Form fields should also be available as action data, got %v", ctx.Data.Raw())
+		}
+	})
+
+	t.Run("JSON falls back to Bind", func(t *testing.T) {
+		body := `{"action":"save","data":{"title":"Write docs","priority":2}}`
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		r.Header.Set("Content-Type", "application/json")
+
+		ctx := actionContextFromHTTP(t, r)
+		var got bindInput
+		if err := ctx.BindForm(&got); err != nil {
+			t.Fatalf("BindForm failed: %v", err)
+		}
+		if got.Title != "Write docs" || got.Priority != 2 {
+			t.Errorf("BindForm = %+v, want title and priority from JSON data", got)
+		}
+	})
+
+	t.Run("invalid number", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("action=save&priority=high"))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+		var got bindInput
+		if err := actionContextFromHTTP(t, r).BindForm(&got); err == nil {
+			t.Error("Expected an error binding a non-numeric priority")
+		}
+	})
+}
+
+func TestActionContext_BindQuery(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/todos?action=save&title=Write+docs&priority=2&done=true&score=4.5&tag=a&tag=b", nil)
+
+	ctx := actionContextFromHTTP(t, r)
+	var got bindInput
+	if err := ctx.BindQuery(&got); err != nil {
+		t.Fatalf("BindQuery failed: %v", err)
+	}
+
+	want := bindInput{Title: "Write docs", Priority: 2, Done: true, Score: 4.5, Tags: []string{"a", "b"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("BindQuery = %+v, want %+v", got, want)
+	}
+}
+
+func TestBindValues_InvalidTarget(t *testing.T) {
+	var notStruct string
+	if err := bindValues(url.Values{}, &notStruct); err == nil {
+		t.Error("Expected an error binding into a non-struct")
+	}
+	if err := bindValues(url.Values{}, bindInput{}); err == nil {
+		t.Error("Expected an error binding into a non-pointer")
+	}
+}
@@ -0,0 +1,107 @@
+package livetemplate
+
+import (
+	"encoding/hex"
+	"strings"
+)
+
+// Statics references
+//
+// An update replacing a subtree by one of another structure, like an {{if}} switching
+// branches, sends the new subtree with its statics. With WithBranchStaticsCache, the statics
+// carry an ID the first time ("si"), and later updates showing the same statics again send
+// the ID instead ("sr"):
+//
+//	{"1": {"s": ["<div class=\"panel\">", "</div>"], "si": "9f86d081884c7d65", "0": "Hi"}}
+//	{"1": {"sr": "9f86d081884c7d65", "0": "Hello"}}
+//
+// The client keeps the statics of every "si" for the page, and restores "s" from them for
+// an "sr" before applying the update.
+const (
+	staticsIDKey  = "si" // ID the client caches the node's statics under
+	staticsRefKey = "sr" // ID of statics the client cached, standing in for "s"
+)
+
+// minCachedStatics is the length below which statics are sent as they are, as the ID would
+// take about as much space
+const minCachedStatics = 32
+
+// WithBranchStaticsCache lets the client cache the statics of subtrees an update replaces,
+// such as the branches of an {{if}}, so showing a branch again sends only its dynamics:
+// toggling a panel on, off and on sends the panel's HTML once. Branches rendered by the
+// first render are cached from their first update on.
+//
+// The template tracks what its client holds, so it requires one client per template
+// instance, as with the WebSocket connections of the live handler, and a client supporting
+// statics references ("si" and "sr", see docs/specifications/tree-update-specification.md).
+//
+// Default: disabled
+func WithBranchStaticsCache() Option {
+	return func(c *Config) {
+		c.BranchStatics = true
+	}
+}
+
+// cacheStatics returns value, a subtree sent with its statics, with a reference in place of
+// statics the client already holds, or with the ID to cache them under otherwise. New IDs
+// are pending until the update carrying them is written, see commitStatics.
+func (t *Template) cacheStatics(value interface{}) interface{} {
+	if !t.config.BranchStatics || isRangeConstruct(value) {
+		return value
+	}
+	node, ok := value.(treeNode)
+	if !ok {
+		if node, ok = value.(map[string]interface{}); !ok {
+			return value
+		}
+	}
+	statics, ok := node["s"].([]string)
+	if !ok || len(strings.Join(statics, "")) < minCachedStatics {
+		return value
+	}
+	if _, isSwitch := node[switchStaticsKey]; isSwitch {
+		return value
+	}
+
+	h := t.hasher()()
+	h.Write([]byte(strings.Join(statics, "\x00")))
+	id := hex.EncodeToString(h.Sum(nil))[:16]
+
+	cached := make(treeNode, len(node)+1)
+	for k, v := range node {
+		cached[k] = v
+	}
+	if t.sentStatics[id] || t.pendingStatics[id] {
+		delete(cached, "s")
+		cached[staticsRefKey] = id
+		return cached
+	}
+
+	if t.pendingStatics == nil {
+		t.pendingStatics = make(map[string]bool)
+	}
+	t.pendingStatics[id] = true
+	cached[staticsIDKey] = id
+	return cached
+}
+
+// commitStatics records the statics IDs of the update just written as cached by the client.
+// An update replaced by the full tree (see WithFullReplacementThreshold) or not sent at
+// all (see WithSuppressUnchanged) drops them instead, see discardStatics.
+func (t *Template) commitStatics() {
+	if len(t.pendingStatics) == 0 {
+		return
+	}
+	if t.sentStatics == nil {
+		t.sentStatics = make(map[string]bool, len(t.pendingStatics))
+	}
+	for id := range t.pendingStatics {
+		t.sentStatics[id] = true
+	}
+	t.pendingStatics = nil
+}
+
+// discardStatics drops the statics IDs of an update that isn't sent as diffed
+func (t *Template) discardStatics() {
+	t.pendingStatics = nil
+}
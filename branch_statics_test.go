@@ -0,0 +1,102 @@
+package livetemplate
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestWithBranchStaticsCache(t *testing.T) {
+	const src = `<p>{{.Name}}</p>{{if .Show}}<div class="panel"><b>{{.Name}}</b> has {{.Count}} items</div>{{else}}<i>hidden</i>{{end}}`
+
+	// toggle renders the states in order and returns the update of the conditional's slot
+	toggle := func(t *testing.T, tmpl *Template, shows ...bool) []map[string]interface{} {
+		t.Helper()
+		var slots []map[string]interface{}
+		for i, show := range shows {
+			var buf bytes.Buffer
+			if err := tmpl.ExecuteUpdates(&buf, map[string]interface{}{"Name": "Ann", "Show": show, "Count": i}); err != nil {
+				t.Fatalf("ExecuteUpdates failed: %v", err)
+			}
+			if err := ValidateWireMessage(buf.Bytes()); err != nil {
+				t.Fatalf("Update failed wire validation: %v\n%s", err, buf.String())
+			}
+			var tree map[string]interface{}
+			if err := json.Unmarshal(buf.Bytes(), &tree); err != nil {
+				t.Fatalf("Invalid JSON: %v", err)
+			}
+			slot, _ := tree["1"].(map[string]interface{})
+			slots = append(slots, slot)
+		}
+		return slots
+	}
+
+	tmpl := New("branch-statics-test", WithBranchStaticsCache())
+	if _, err := tmpl.Parse(src); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	slots := toggle(t, tmpl, false, true, false, true, false, true)
+
+	firstOn := slots[1]
+	if _, ok := firstOn["s"]; !ok {
+		t.Fatalf("Expected the first on to send the panel's statics, got %v", firstOn)
+	}
+	id, _ := firstOn[staticsIDKey].(string)
+	if id == "" {
+		t.Fatalf("Expected the first on to carry a statics ID, got %v", firstOn)
+	}
+	for _, i := range []int{3, 5} {
+		if _, ok := slots[i]["s"]; ok {
+			t.Errorf("Update %d: expected the panel without statics, got %v", i, slots[i])
+		}
+		if slots[i][staticsRefKey] != id {
+			t.Errorf("Update %d: expected a reference to %s, got %v", i, id, slots[i])
+		}
+		if slots[i]["1"] != fmt.Sprint(i) {
+			t.Errorf("Update %d: expected the panel's dynamics, got %v", i, slots[i])
+		}
+	}
+
+	// Short statics aren't worth an ID
+	if _, ok := slots[2][staticsIDKey]; ok {
+		t.Errorf("Expected the short else branch to be sent as it is, got %v", slots[2])
+	}
+
+	t.Run("full replacement", func(t *testing.T) {
+		tmpl := New("branch-statics-full", WithBranchStaticsCache(), WithFullReplacementThreshold(0.9))
+		if _, err := tmpl.Parse(src); err != nil {
+			t.Fatalf("Parse failed: %v", err)
+		}
+		slots := toggle(t, tmpl, false, true, false, true, false, true)
+		if _, ok := slots[1][staticsIDKey]; ok {
+			t.Fatalf("Expected the first on to be replaced by a full tree, got %v", slots[1])
+		}
+
+		// A reference must only follow an update that actually carried its ID
+		cached := map[interface{}]bool{}
+		for i, slot := range slots {
+			if ref, ok := slot[staticsRefKey]; ok && !cached[ref] {
+				t.Errorf("Update %d: references statics %v the client never received, got %v", i, ref, slot)
+			}
+			if id, ok := slot[staticsIDKey]; ok {
+				cached[id] = true
+			}
+			if slot[staticsRefKey] == nil && i%2 == 1 && slot["s"] == nil {
+				t.Errorf("Update %d: expected the panel's statics, got %v", i, slot)
+			}
+		}
+	})
+
+	t.Run("disabled by default", func(t *testing.T) {
+		tmpl := New("branch-statics-default")
+		if _, err := tmpl.Parse(src); err != nil {
+			t.Fatalf("Parse failed: %v", err)
+		}
+		slots := toggle(t, tmpl, false, true, false, true)
+		if !reflect.DeepEqual(slots[1]["s"], slots[3]["s"]) || slots[3][staticsRefKey] != nil {
+			t.Errorf("Expected the statics with every on, got %v and %v", slots[1], slots[3])
+		}
+	})
+}
@@ -4,6 +4,8 @@ import (
 	"sync"
 	"testing"
 	"time"
+
+	"github.com/gorilla/websocket"
 )
 
 // BroadcastState is a test store for broadcasting tests
@@ -338,3 +340,105 @@ func TestLiveHandler_BroadcastAfterDisconnect(t *testing.T) {
 		t.Errorf("Expected 1 connection after disconnect, got %d", h.registry.Count())
 	}
 }
+
+// TestLiveHandler_BroadcastCoalesce tests that broadcasts within the window reach each
+// connection as a single update with the latest data
+func TestLiveHandler_BroadcastCoalesce(t *testing.T) {
+	tmpl := New("broadcast-coalesce-test", WithBroadcastCoalesce(100*time.Millisecond))
+	if _, err := tmpl.Parse("<p>Value: {{.Value}}</p>"); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	handler := tmpl.Handle(&BroadcastState{})
+	conns := []*websocket.Conn{dialTestHandler(t, handler), dialTestHandler(t, handler)}
+
+	for i := 1; i <= 5; i++ {
+		if err := handler.Broadcast(&BroadcastState{Value: i}); err != nil {
+			t.Fatalf("Broadcast failed: %v", err)
+		}
+	}
+
+	for i, conn := range conns {
+		if err := conn.SetReadDeadline(time.Now().Add(2 * time.Second)); err != nil {
+			t.Fatalf("Failed to set read deadline: %v", err)
+		}
+		var update UpdateResponse
+		if err := conn.ReadJSON(&update); err != nil {
+			t.Fatalf("Connection %d: failed to read update: %v", i, err)
+		}
+		if tree, _ := update.Tree.(map[string]interface{}); tree["0"] != "5" {
+			t.Errorf("Connection %d: expected coalesced update with the latest value, got %v", i, update.Tree)
+		}
+
+		// No further updates for the merged broadcasts
+		if err := conn.SetReadDeadline(time.Now().Add(300 * time.Millisecond)); err != nil {
+			t.Fatalf("Failed to set read deadline: %v", err)
+		}
+		if err := conn.ReadJSON(&update); err == nil {
+			t.Errorf("Connection %d: expected one coalesced update, got another: %v", i, update.Tree)
+		}
+	}
+}
+
+// TestLiveHandler_BroadcastCoalesceDropped tests that a pending broadcast is discarded when
+// the connection renders newer state itself, or closes
+func TestLiveHandler_BroadcastCoalesceDropped(t *testing.T) {
+	tmpl := New("broadcast-coalesce-drop-test", WithBroadcastCoalesce(200*time.Millisecond))
+	if _, err := tmpl.Parse("<p>Count: {{.Count}}</p>"); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	handler := tmpl.Handle(&SlowState{})
+	h := handler.(*liveHandler)
+
+	t.Run("action response", func(t *testing.T) {
+		conn := dialTestHandler(t, handler)
+		if err := handler.Broadcast(&SlowState{Count: 99}); err != nil {
+			t.Fatalf("Broadcast failed: %v", err)
+		}
+
+		response := sendAction(t, conn, "increment", nil)
+		if tree, _ := response.Tree.(map[string]interface{}); tree["0"] != "1" {
+			t.Fatalf("Expected the action response to render the new count, got %v", response.Tree)
+		}
+
+		if err := conn.SetReadDeadline(time.Now().Add(400 * time.Millisecond)); err != nil {
+			t.Fatalf("Failed to set read deadline: %v", err)
+		}
+		var update UpdateResponse
+		if err := conn.ReadJSON(&update); err == nil {
+			t.Errorf("Expected the stale broadcast to be dropped, got %v", update.Tree)
+		}
+	})
+
+	t.Run("closed connection", func(t *testing.T) {
+		existing := make(map[*Connection]bool)
+		for _, c := range h.registry.GetAll() {
+			existing[c] = true
+		}
+		conn := dialTestHandler(t, handler)
+		var closed *Connection
+		for _, c := range h.registry.GetAll() {
+			if !existing[c] {
+				closed = c
+			}
+		}
+		if closed == nil {
+			t.Fatal("Expected the new connection to be registered")
+		}
+
+		if err := handler.Broadcast(&SlowState{Count: 7}); err != nil {
+			t.Fatalf("Broadcast failed: %v", err)
+		}
+		conn.Close()
+		deadline := time.Now().Add(time.Second)
+		for h.registry.GroupSize(closed.GroupID) > 0 && time.Now().Before(deadline) {
+			time.Sleep(5 * time.Millisecond)
+		}
+
+		h.coalesce.mu.Lock()
+		defer h.coalesce.mu.Unlock()
+		if _, pending := h.coalesce.pending[closed]; pending {
+			t.Error("Expected the closed connection's pending broadcast to be removed")
+		}
+	})
+}
@@ -0,0 +1,104 @@
+package livetemplate
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrUnknownFingerprint is returned by CatchUpTree when no initial tree with the
+// requested fingerprint has been rendered (or it has been evicted).
+var ErrUnknownFingerprint = errors.New("unknown tree fingerprint")
+
+// maxBaselines bounds how many initial trees a template and its clones remember
+const maxBaselines = 64
+
+// baselineCache remembers initial trees by fingerprint so late-joining clients that
+// already hold one (e.g. from a CDN-cached page) can catch up with a dynamics-only diff.
+// It is shared between a template and its clones.
+//
+// Thread-safe: safe for concurrent access from multiple goroutines.
+type baselineCache struct {
+	mu    sync.Mutex
+	trees map[string]treeNode
+	order []string // Fingerprints oldest first, for eviction
+}
+
+// newBaselineCache creates an empty baseline cache
+func newBaselineCache() *baselineCache {
+	return &baselineCache{
+		trees: make(map[string]treeNode),
+	}
+}
+
+// add remembers tree under fingerprint, evicting the oldest entry when full
+func (c *baselineCache) add(fingerprint string, tree treeNode) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, exists := c.trees[fingerprint]; exists {
+		return
+	}
+	if len(c.order) >= maxBaselines {
+		delete(c.trees, c.order[0])
+		c.order = c.order[1:]
+	}
+	c.trees[fingerprint] = tree
+	c.order = append(c.order, fingerprint)
+}
+
+// get returns the tree remembered under fingerprint
+func (c *baselineCache) get(fingerprint string) (treeNode, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	tree, ok := c.trees[fingerprint]
+	return tree, ok
+}
+
+// CatchUpTree returns the changes that bring a client holding the initial tree with the
+// given fingerprint up to date with currentData, without statics.
+//
+// Every initial render by this template or its clones (the first ExecuteUpdates call) is
+// remembered by its fingerprint, see Fingerprint. This lets statics be served from a cache
+// or CDN while a client that joins later only needs a small catch-up update:
+//
+//	// Once, when building the cached page
+//	tmpl.ExecuteUpdates(&buf, initialData)
+//	fingerprint := tmpl.Fingerprint()
+//
+//	// For each late-joining client holding that page
+//	changes, err := tmpl.CatchUpTree(fingerprint, currentData)
+//
+// Returns an empty tree if nothing changed, and ErrUnknownFingerprint if the fingerprint
+// isn't known - callers should then send a full render instead. CatchUpTree doesn't change
+// the template's own diff state.
+func (t *Template) CatchUpTree(fromFingerprint string, currentData interface{}) (TreeNode, error) {
+	baseline, ok := t.baselines.get(fromFingerprint)
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownFingerprint, fromFingerprint)
+	}
+
+	// Diff on a clone that treats the baseline as what the client last received,
+	// so this template's own baseline and key mappings are left alone
+	clone, err := t.Clone()
+	if err != nil {
+		return nil, fmt.Errorf("catch-up failed: %w", err)
+	}
+	clone.lastTree = baseline
+	clone.initialTree = baseline
+	clone.hasInitialTree = true
+	clone.lastData = currentData // Marks the first render as done; not used for diffing
+
+	changes, err := clone.generateTreeInternalWithErrors(currentData, nil)
+	if err != nil {
+		return nil, fmt.Errorf("catch-up failed: %w", err)
+	}
+	return TreeNode(changes), nil
+}
@@ -0,0 +1,42 @@
+package livetemplate
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// actionAttrPattern matches the lvt-* attributes whose value is an action name, e.g.
+// lvt-click="save" or lvt-window-keydown='close'. The event list mirrors the client's.
+var actionAttrPattern = regexp.MustCompile(`(?:^|\s)lvt-(?:click-away|click|submit|change|input|keydown|keyup|focus|blur|mouseenter|mouseleave|window-[a-z]+)\s*=\s*(?:"([^"]*)"|'([^']*)')`)
+
+// CheckActions returns the sorted action names referenced by lvt-* attributes in the
+// template that are not in handled, so a typo like lvt-click="incremnt" fails CI instead of
+// silently doing nothing:
+//
+//	if unknown := tmpl.CheckActions([]string{"increment", "decrement"}); len(unknown) > 0 {
+//	    t.Errorf("unhandled actions: %v", unknown)
+//	}
+//
+// Multi-store actions are compared as written, e.g. "counter.increment". Action names
+// computed by the template (lvt-click="{{.Action}}") can't be checked and are skipped.
+// Returns nil before Parse.
+func (t *Template) CheckActions(handled []string) []string {
+	known := make(map[string]bool, len(handled))
+	for _, action := range handled {
+		known[action] = true
+	}
+
+	seen := make(map[string]bool)
+	var unhandled []string
+	for _, match := range actionAttrPattern.FindAllStringSubmatch(t.templateStr, -1) {
+		action := strings.TrimSpace(match[1] + match[2])
+		if action == "" || strings.Contains(action, "{{") || known[action] || seen[action] {
+			continue
+		}
+		seen[action] = true
+		unhandled = append(unhandled, action)
+	}
+	sort.Strings(unhandled)
+	return unhandled
+}
@@ -0,0 +1,32 @@
+package livetemplate
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTemplate_CheckActions(t *testing.T) {
+	tmpl := New("check-actions-test")
+	_, err := tmpl.Parse(`{{define "toolbar"}}<button lvt-click="counter.reset">Reset</button>{{end}}
+<div lvt-window-keydown='close' lvt-key="Escape">
+	<button lvt-click="increment">+</button>
+	<button lvt-click="incremnt">+1</button>
+	<button lvt-click="decrement" data-lvt-click="ignored">-</button>
+	<form lvt-submit="save"><input name="title" lvt-input="search"></form>
+	{{range .Items}}<button lvt-click="{{.Action}}">{{.Label}}</button>{{end}}
+	{{template "toolbar" .}}
+</div>`)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	got := tmpl.CheckActions([]string{"increment", "decrement", "save", "close"})
+	want := []string{"counter.reset", "incremnt", "search"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("CheckActions() = %v, want %v", got, want)
+	}
+
+	if got := tmpl.CheckActions([]string{"increment", "incremnt", "decrement", "save", "close", "search", "counter.reset"}); got != nil {
+		t.Errorf("Expected no unhandled actions, got %v", got)
+	}
+}
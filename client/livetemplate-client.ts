@@ -39,6 +39,32 @@ export interface ResponseMetadata {
   success: boolean;      // true if no validation errors
   errors: { [key: string]: string };  // field errors
   action?: string;       // action name
+  resume_token?: string; // sent on connect; presented when resuming after a reconnect
+  fingerprint?: string;  // identifies the tree after this update
+  statics_version?: string; // statics version of the template, sent on connect
+  warnings?: string[];   // tree analyzer warnings (server DevMode with WithDevOverlay only)
+  input_bound?: string[]; // paths of changed slots rendered with lvt_value
+  config?: ClientConfig;  // server-side client settings, initial tree only
+  head?: HeadMeta;        // document title and meta tags set by the action or Mount
+  redirect?: string;      // URL to navigate to, set by the action with ActionContext.Redirect
+  correlation_id?: string; // correlation_id sent with the action this update answers
+}
+
+// Document head changes set with ActionContext.SetTitle and SetMeta
+export interface HeadMeta {
+  title?: string;
+  meta?: { [name: string]: string };
+}
+
+export interface ClientConfig {
+  reconnect?: ReconnectPolicy;
+}
+
+// Exponential reconnect backoff set on the server with WithReconnectPolicy
+export interface ReconnectPolicy {
+  min_ms: number;
+  max_ms: number;
+  factor: number;
 }
 
 export interface UpdateResponse {
@@ -46,6 +72,15 @@ export interface UpdateResponse {
   meta?: ResponseMetadata;
 }
 
+// Ephemeral event sent by ActionContext.Signal in place of an update
+export interface SignalMessage {
+  signal: {
+    name: string;
+    payload?: any;
+    ttl: number; // milliseconds until the signal expires unless repeated
+  };
+}
+
 export interface LiveTemplateClientOptions {
   wsUrl?: string;  // WebSocket URL (defaults to current host)
   liveUrl?: string; // HTTP endpoint URL (defaults to /live)
@@ -68,6 +103,14 @@ export class LiveTemplateClient {
   private reconnectTimer: number | null = null;
   private useHTTP: boolean = false; // True when WebSocket is unavailable
   private sessionCookie: string | null = null; // For HTTP mode session tracking
+  private resumeToken: string | null = null; // Lets a reconnect resume from the last update
+  private lastFingerprint: string | null = null; // Fingerprint of the last applied update
+  private staticsVersion: string | null = null; // Statics version the client holds (data-lvt-sv)
+  private signalTimers: Map<string, number> = new Map(); // Expiry timers of active signals by name
+  private reconnectPolicy: ReconnectPolicy | null = null; // Backoff from the server, if any
+  private reconnectAttempts: number = 0; // Failed reconnects since the last successful connection
+  private exitingKeys: Set<string> = new Set(); // Items removed with an animate flag, see runExitAnimation
+  private cachedStatics: Map<string, string[]> = new Map(); // Statics by ID ('si'), referenced by 'sr'
 
   // Form lifecycle tracking
   private activeForm: HTMLFormElement | null = null; // The form that submitted the current action
@@ -147,6 +190,40 @@ export class LiveTemplateClient {
     }
   }
 
+  /**
+   * Delay before the next reconnect attempt: the server's backoff policy if it sent one,
+   * otherwise the fixed reconnectDelay option
+   */
+  private nextReconnectDelay(): number {
+    const policy = this.reconnectPolicy;
+    if (!policy) {
+      return this.options.reconnectDelay ?? 1000;
+    }
+    const delay = policy.min_ms * Math.pow(policy.factor, this.reconnectAttempts);
+    this.reconnectAttempts++;
+    return Math.min(delay, policy.max_ms);
+  }
+
+  /**
+   * Dispatch lvt:signal for an ephemeral signal, and lvt:signal-expired once its TTL
+   * passes without the signal being repeated
+   */
+  private handleSignal(message: SignalMessage): void {
+    const { name, payload, ttl } = message.signal;
+    if (!this.wrapperElement) return;
+
+    const previous = this.signalTimers.get(name);
+    if (previous !== undefined) {
+      window.clearTimeout(previous);
+    }
+    this.signalTimers.set(name, window.setTimeout(() => {
+      this.signalTimers.delete(name);
+      this.wrapperElement?.dispatchEvent(new CustomEvent('lvt:signal-expired', { detail: { name } }));
+    }, ttl));
+
+    this.wrapperElement.dispatchEvent(new CustomEvent('lvt:signal', { detail: { name, payload, ttl } }));
+  }
+
   /**
    * Disable all forms within the wrapper element
    */
@@ -510,13 +587,41 @@ export class LiveTemplateClient {
    */
   private connectWebSocket(): void {
     // Determine WebSocket URL
-    const wsUrl = this.options.wsUrl || `ws://${window.location.host}${this.options.liveUrl || '/live'}`;
+    // Carry the page's query string so stores implementing Mount see the same route
+    const liveUrl = this.options.liveUrl || '/live';
+    const wsUrl = this.options.wsUrl ||
+      `ws://${window.location.host}${liveUrl}${liveUrl.includes('?') ? '' : window.location.search}`;
+
+    // After a disconnect, ask the server to resume from the last update we applied
+    // so it only sends what changed instead of the full tree
+    const resume = this.isInitialized && this.resumeToken && this.lastFingerprint
+      ? {
+          type: 'resume',
+          token: this.resumeToken,
+          fingerprint: this.lastFingerprint,
+          statics_version: this.staticsVersion ?? this.wrapperElement?.getAttribute('data-lvt-sv') ?? undefined,
+        }
+      : null;
+
+    // Echo the page's CSRF token (data-lvt-csrf) when the server requires one
+    const params: string[] = [];
+    const csrfToken = this.csrfToken();
+    if (csrfToken) {
+      params.push(`lvt-csrf=${encodeURIComponent(csrfToken)}`);
+    }
+    if (resume) {
+      params.push('lvt-resume');
+    }
 
     // Create WebSocket connection
-    this.ws = new WebSocket(wsUrl);
+    this.ws = new WebSocket(params.length ? `${wsUrl}${wsUrl.includes('?') ? '&' : '?'}${params.join('&')}` : wsUrl);
 
     this.ws.onopen = () => {
       console.log('LiveTemplate: WebSocket connected');
+      this.reconnectAttempts = 0;
+      if (resume && this.ws) {
+        this.ws.send(JSON.stringify(resume));
+      }
       if (this.options.onConnect) {
         this.options.onConnect();
       }
@@ -528,7 +633,25 @@ export class LiveTemplateClient {
 
     this.ws.onmessage = (event) => {
       try {
-        const response: UpdateResponse = JSON.parse(event.data);
+        const message = JSON.parse(event.data);
+        if (message.signal) {
+          this.handleSignal(message as SignalMessage);
+          return;
+        }
+        const response: UpdateResponse = message;
+
+        if (response.meta?.resume_token) {
+          this.resumeToken = response.meta.resume_token;
+        }
+        if (response.meta?.fingerprint) {
+          this.lastFingerprint = response.meta.fingerprint;
+        }
+        if (response.meta?.statics_version) {
+          this.staticsVersion = response.meta.statics_version;
+        }
+        if (response.meta?.config?.reconnect) {
+          this.reconnectPolicy = response.meta.config.reconnect;
+        }
 
         // On first message, remove loading indicator and enable forms
         if (!this.isInitialized) {
@@ -549,7 +672,7 @@ export class LiveTemplateClient {
       }
     };
 
-    this.ws.onclose = () => {
+    this.ws.onclose = (event: CloseEvent) => {
       console.log('LiveTemplate: WebSocket disconnected');
       if (this.options.onDisconnect) {
         this.options.onDisconnect();
@@ -559,11 +682,19 @@ export class LiveTemplateClient {
         this.wrapperElement.dispatchEvent(new Event('lvt:disconnected'));
       }
 
+      // 1008: rejected by server policy, e.g. too many connections in the session group;
+      // 4401: rejected by the server's authenticator. Retrying won't help with either.
+      // Shutdown (1001) and idle timeout (4408) closes reconnect like dropped connections.
+      if (event.code === 1008 || event.code === 4401) {
+        console.warn(`LiveTemplate: connection rejected: ${event.reason}`);
+        return;
+      }
+
       if (this.options.autoReconnect) {
         this.reconnectTimer = window.setTimeout(() => {
           console.log('LiveTemplate: Attempting to reconnect...');
           this.connectWebSocket();
-        }, this.options.reconnectDelay);
+        }, this.nextReconnectDelay());
       }
     };
 
@@ -670,6 +801,13 @@ export class LiveTemplateClient {
 
         if (!inWrapper) return;
 
+        // Forms added for pages without JavaScript (WithProgressiveEnhancement) are not
+        // submitted: the button's lvt-click already sends the action
+        if (eventType === 'submit' && target.hasAttribute('lvt-fallback')) {
+          e.preventDefault();
+          return;
+        }
+
         // Check if target or any parent has the lvt-* attribute
         const attrName = `lvt-${eventType}`;
         element = target;
@@ -1229,19 +1367,31 @@ export class LiveTemplateClient {
     }
   }
 
+  /**
+   * CSRF token the server embedded in the wrapper (data-lvt-csrf), if any
+   */
+  private csrfToken(): string | null {
+    return this.wrapperElement?.getAttribute('data-lvt-csrf') ?? null;
+  }
+
   /**
    * Send action via HTTP POST
    */
   private async sendHTTP(message: any): Promise<void> {
     try {
       const liveUrl = this.options.liveUrl || '/live';
+      const headers: Record<string, string> = {
+        'Content-Type': 'application/json',
+        'Accept': 'application/json'
+      };
+      const csrfToken = this.csrfToken();
+      if (csrfToken) {
+        headers['X-LiveTemplate-CSRF'] = csrfToken;
+      }
       const response = await fetch(liveUrl, {
         method: 'POST',
         credentials: 'include', // Include cookies for session
-        headers: {
-          'Content-Type': 'application/json',
-          'Accept': 'application/json'
-        },
+        headers,
         body: JSON.stringify(message)
       });
 
@@ -1296,6 +1446,17 @@ export class LiveTemplateClient {
       return update;
     }
 
+    // Switch node selecting another branch: the update holds the branch's dynamics,
+    // its statics come from the branch statics ('bs') sent with the first render
+    if (typeof update.b === 'number' && update.b !== existing.b) {
+      const branches = update.bs || existing.bs;
+      const switched: any = { ...update, bs: branches };
+      if (!update.s && Array.isArray(branches)) {
+        switched.s = branches[update.b];
+      }
+      return switched;
+    }
+
     // Start with a copy of existing (to preserve 's' and 'f' if update doesn't have them)
     const merged: any = { ...existing };
 
@@ -1314,6 +1475,37 @@ export class LiveTemplateClient {
     return merged;
   }
 
+  /**
+   * Resolve statics references in an update, in place: statics sent with an ID ('si') are
+   * cached under it, and a reference ('sr') is replaced by the cached statics ('s')
+   * @param node - Tree update node
+   */
+  private resolveStatics(node: any): void {
+    if (Array.isArray(node)) {
+      node.forEach(item => this.resolveStatics(item));
+      return;
+    }
+    if (typeof node !== 'object' || node === null) {
+      return;
+    }
+    if (typeof node.si === 'string' && Array.isArray(node.s)) {
+      this.cachedStatics.set(node.si, node.s);
+      delete node.si;
+    }
+    if (typeof node.sr === 'string') {
+      const statics = this.cachedStatics.get(node.sr);
+      if (statics) {
+        node.s = statics;
+      } else {
+        console.error(`LiveTemplate: unknown statics reference ${node.sr}`);
+      }
+      delete node.sr;
+    }
+    for (const value of Object.values(node)) {
+      this.resolveStatics(value);
+    }
+  }
+
   /**
    * Apply an update to the current state and reconstruct HTML
    * @param update - Tree update object from LiveTemplate server
@@ -1322,6 +1514,9 @@ export class LiveTemplateClient {
   applyUpdate(update: TreeNode): UpdateResult {
     let changed = false;
 
+    // Restore statics the update references instead of sending them again
+    this.resolveStatics(update);
+
     // Merge the update into our tree state
     for (const [key, value] of Object.entries(update)) {
       // Check if this is a differential operations array
@@ -1539,9 +1734,13 @@ export class LiveTemplateClient {
   }
 
   /**
-   * Get item key from item data using statics to find correct position
+   * Get item key from item data using statics to find correct position.
+   * The reserved "_k" field (set for map-keyed ranges) takes precedence.
    */
   private getItemKey(item: any, statics: any[]): string | null {
+    if (item && typeof item._k === "string") {
+      return item._k;
+    }
     const keyPos = this.findKeyPositionFromStatics(statics);
     const keyPosStr = keyPos.toString();
     return item[keyPosStr] || null;
@@ -1581,6 +1780,10 @@ export class LiveTemplateClient {
           if (removeIndex >= 0) {
             currentItems.splice(removeIndex, 1);
           }
+          // ["r", key, {"animate": true}]: animate the element out when morphdom discards it
+          if (operation[2]?.animate) {
+            this.exitingKeys.add(removeKey);
+          }
           break;
 
         case 'u': // Update: ["u", key, changes]
@@ -1782,7 +1985,36 @@ export class LiveTemplateClient {
    * @param update - Tree update object from LiveTemplate server
    * @param meta - Optional metadata about the update (action, success, errors)
    */
+  /**
+   * Apply the document title and <meta name> contents sent with an update. The head is
+   * outside the wrapper, so it isn't part of the tree.
+   */
+  private applyHead(head: HeadMeta): void {
+    if (head.title !== undefined && head.title !== '') {
+      document.title = head.title;
+    }
+    for (const [name, content] of Object.entries(head.meta || {})) {
+      let tag = document.head.querySelector(`meta[name="${CSS.escape(name)}"]`);
+      if (!tag) {
+        tag = document.createElement('meta');
+        tag.setAttribute('name', name);
+        document.head.appendChild(tag);
+      }
+      tag.setAttribute('content', content);
+    }
+  }
+
   updateDOM(element: Element, update: TreeNode, meta?: ResponseMetadata): void {
+    // The action sent the browser elsewhere; the update is for a page being left
+    if (meta?.redirect) {
+      window.location.assign(meta.redirect);
+      return;
+    }
+
+    if (meta?.head) {
+      this.applyHead(meta.head);
+    }
+
     // Apply update to internal state and get reconstructed HTML
     const result = this.applyUpdate(update);
 
@@ -1816,6 +2048,8 @@ export class LiveTemplateClient {
     console.log('[updateDOM] tempWrapper has <tbody>:', tempWrapper.innerHTML.includes('<tbody>'));
     console.log('[updateDOM] tempWrapper has <tr>:', tempWrapper.innerHTML.includes('<tr'));
 
+    const hasInputBound = !!(meta?.input_bound && meta.input_bound.length > 0);
+
     // Use morphdom to efficiently update the element
     morphdom(element, tempWrapper, {
       childrenOnly: true,  // Only update children, preserve the wrapper element itself
@@ -1826,11 +2060,19 @@ export class LiveTemplateClient {
         }
       },
       onBeforeElUpdated: (fromEl, toEl) => {
+        // Regions marked data-lvt-static are rendered once and never patched
+        if (fromEl.hasAttribute('data-lvt-static')) {
+          return false;
+        }
+
         // Preserve value for the last focused textual input
         if (this.lastFocusedElement && this.isTextualInput(fromEl)) {
           if (fromEl === this.lastFocusedElement) {
-            // Preserve the current value being typed
-            (toEl as any).value = (fromEl as any).value;
+            // An input-bound value (lvt_value) the server changed is merged in; the caret
+            // is restored afterwards. Otherwise preserve the current value being typed.
+            if (!(hasInputBound && this.renderedValueChanged(fromEl, toEl))) {
+              (toEl as any).value = (fromEl as any).value;
+            }
           }
         }
 
@@ -1851,7 +2093,15 @@ export class LiveTemplateClient {
       onBeforeNodeDiscarded: (node) => {
         // Execute lvt-destroyed lifecycle hook
         if (node.nodeType === Node.ELEMENT_NODE) {
-          this.executeLifecycleHook(node as Element, 'lvt-destroyed');
+          const el = node as Element;
+          this.executeLifecycleHook(el, 'lvt-destroyed');
+
+          // Keep an item removed with an animate flag until its exit transition ends
+          const key = el.getAttribute('data-key') || el.getAttribute('data-lvt-key');
+          if (key && this.exitingKeys.delete(key)) {
+            this.runExitAnimation(el as HTMLElement);
+            return false;
+          }
         }
         return true;
       }
@@ -1873,6 +2123,59 @@ export class LiveTemplateClient {
     if (meta) {
       this.handleFormLifecycle(meta);
     }
+
+    // Show analyzer warnings forwarded by a server in dev mode
+    if (meta?.warnings && meta.warnings.length > 0) {
+      this.showDevOverlay(meta.warnings);
+    }
+  }
+
+  /**
+   * Show tree analyzer warnings in a dismissible overlay (dev mode only)
+   * @param warnings - Warnings from the update metadata
+   */
+  private showDevOverlay(warnings: string[]): void {
+    let overlay = document.getElementById('lvt-dev-overlay');
+    if (!overlay) {
+      overlay = document.createElement('div');
+      overlay.id = 'lvt-dev-overlay';
+      overlay.style.cssText = 'position:fixed;bottom:0;left:0;right:0;max-height:40vh;overflow:auto;' +
+        'z-index:10000;background:#1e1e1e;color:#f0c674;font:12px/1.4 monospace;' +
+        'padding:8px 12px;border-top:3px solid #f0c674';
+
+      const close = document.createElement('button');
+      close.textContent = '×';
+      close.title = 'Dismiss';
+      close.style.cssText = 'float:right;background:none;border:none;color:inherit;font-size:16px;cursor:pointer';
+      close.addEventListener('click', () => overlay?.remove());
+      overlay.appendChild(close);
+
+      const title = document.createElement('strong');
+      title.textContent = 'LiveTemplate tree analyzer';
+      overlay.appendChild(title);
+
+      document.body.appendChild(overlay);
+    }
+
+    // Keep the latest warnings only
+    overlay.querySelectorAll('pre').forEach(pre => pre.remove());
+    warnings.forEach(warning => {
+      const pre = document.createElement('pre');
+      pre.style.cssText = 'white-space:pre-wrap;margin:6px 0 0';
+      pre.textContent = warning;
+      overlay!.appendChild(pre);
+    });
+  }
+
+  /**
+   * Check whether the server-rendered value of a form control changed, as opposed to
+   * the value the user typed (value attribute for inputs, content for textareas)
+   */
+  private renderedValueChanged(fromEl: Element, toEl: Element): boolean {
+    if (fromEl.tagName === 'TEXTAREA') {
+      return fromEl.textContent !== toEl.textContent;
+    }
+    return fromEl.getAttribute('value') !== toEl.getAttribute('value');
   }
 
   /**
@@ -2054,6 +2357,29 @@ export class LiveTemplateClient {
     });
   }
 
+  /**
+   * Animate out an item removed with ["r", key, {"animate": true}], then remove it.
+   * Uses the item's lvt-animate mode (default fade) and lvt-animate-duration.
+   */
+  private runExitAnimation(element: HTMLElement): void {
+    const animation = element.getAttribute('lvt-animate') || 'fade';
+    const duration = parseInt(element.getAttribute('lvt-animate-duration') || '300', 10);
+
+    let removed = false;
+    const remove = () => {
+      if (!removed) {
+        removed = true;
+        element.remove();
+      }
+    };
+
+    element.style.setProperty('--lvt-animate-duration', `${duration}ms`);
+    element.style.animation = `lvt-${animation}-out var(--lvt-animate-duration) ease-in forwards`;
+    element.addEventListener('animationend', remove, { once: true });
+    // Remove the item even if the animation never runs, e.g. for an unknown mode
+    setTimeout(remove, duration + 50);
+  }
+
   /**
    * Handle animate directives on elements with lvt-animate attribute
    * Applies entry/exit animations when elements are inserted or updated
@@ -2121,6 +2447,30 @@ export class LiveTemplateClient {
             transform: scale(1);
           }
         }
+        @keyframes lvt-fade-out {
+          from { opacity: 1; }
+          to { opacity: 0; }
+        }
+        @keyframes lvt-slide-out {
+          from {
+            opacity: 1;
+            transform: translateY(0);
+          }
+          to {
+            opacity: 0;
+            transform: translateY(-10px);
+          }
+        }
+        @keyframes lvt-scale-out {
+          from {
+            opacity: 1;
+            transform: scale(1);
+          }
+          to {
+            opacity: 0;
+            transform: scale(0.95);
+          }
+        }
       `;
       document.head.appendChild(style);
     }
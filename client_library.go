@@ -0,0 +1,65 @@
+package livetemplate
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"os"
+)
+
+// clientLibraryCDN is where production pages load the client library from
+const clientLibraryCDN = "https://cdn.jsdelivr.net/npm/@livefir/livetemplate-client/dist/livetemplate-client.browser.js"
+
+// clientLibraryPaths are where DevMode looks for the client bundle built with npm, relative
+// to the working directory of the repository root, an example, or a command
+var clientLibraryPaths = []string{
+	"client/dist/livetemplate-client.browser.js",
+	"../client/dist/livetemplate-client.browser.js",
+	"../../client/dist/livetemplate-client.browser.js",
+}
+
+// ClientLibraryHandler serves the client library for the page's script tag:
+//
+//	http.Handle("/livetemplate-client.js", tmpl.ClientLibraryHandler())
+//
+// In DevMode it serves the locally built bundle (client/dist), read on every request so a
+// rebuild is picked up, with an ETag for revalidation. Otherwise it redirects to the CDN.
+func (t *Template) ClientLibraryHandler() http.Handler {
+	devMode := t.config.DevMode
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !devMode {
+			http.Redirect(w, r, clientLibraryCDN, http.StatusFound)
+			return
+		}
+
+		content, err := readClientLibrary()
+		if err != nil {
+			http.Error(w, "Client library not found: build it with npm run build in client/, or use "+clientLibraryCDN, http.StatusNotFound)
+			return
+		}
+
+		sum := sha256.Sum256(content)
+		etag := `"` + hex.EncodeToString(sum[:8]) + `"`
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Cache-Control", "no-cache") // Revalidate, the bundle changes with every build
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/javascript; charset=utf-8")
+		_, _ = w.Write(content)
+	})
+}
+
+// readClientLibrary returns the first client bundle found in clientLibraryPaths
+func readClientLibrary() ([]byte, error) {
+	var err error
+	for _, path := range clientLibraryPaths {
+		var content []byte
+		if content, err = os.ReadFile(path); err == nil {
+			return content, nil
+		}
+	}
+	return nil, err
+}
@@ -0,0 +1,84 @@
+package livetemplate
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTemplate_ClientLibraryHandler(t *testing.T) {
+	t.Run("dev mode serves the local bundle", func(t *testing.T) {
+		dir := t.TempDir()
+		if err := os.MkdirAll(filepath.Join(dir, "client", "dist"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		bundle := filepath.Join(dir, "client", "dist", "livetemplate-client.browser.js")
+		if err := os.WriteFile(bundle, []byte("var LiveTemplateClient = {};"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		t.Chdir(dir)
+
+		handler := New("client-lib-test", WithDevMode(true)).ClientLibraryHandler()
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/livetemplate-client.js", nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		if got := rec.Header().Get("Content-Type"); got != "application/javascript; charset=utf-8" {
+			t.Errorf("Content-Type = %q, want JavaScript", got)
+		}
+		etag := rec.Header().Get("ETag")
+		if etag == "" {
+			t.Fatal("Expected an ETag")
+		}
+		if rec.Header().Get("Cache-Control") == "" {
+			t.Error("Expected a Cache-Control header")
+		}
+		if rec.Body.String() != "var LiveTemplateClient = {};" {
+			t.Errorf("Unexpected body %q", rec.Body.String())
+		}
+
+		req := httptest.NewRequest(http.MethodGet, "/livetemplate-client.js", nil)
+		req.Header.Set("If-None-Match", etag)
+		rec = httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != http.StatusNotModified || rec.Body.Len() != 0 {
+			t.Errorf("Expected 304 without a body for a matching ETag, got %d", rec.Code)
+		}
+
+		// A rebuild changes the ETag
+		if err := os.WriteFile(bundle, []byte("var LiveTemplateClient = {v: 2};"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		rec = httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK || rec.Header().Get("ETag") == etag {
+			t.Errorf("Expected the rebuilt bundle with a new ETag, got %d %s", rec.Code, rec.Header().Get("ETag"))
+		}
+	})
+
+	t.Run("dev mode without a bundle", func(t *testing.T) {
+		t.Chdir(t.TempDir())
+		rec := httptest.NewRecorder()
+		New("client-lib-missing", WithDevMode(true)).ClientLibraryHandler().
+			ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/livetemplate-client.js", nil))
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("Expected 404, got %d", rec.Code)
+		}
+	})
+
+	t.Run("production redirects to the CDN", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		New("client-lib-prod").ClientLibraryHandler().
+			ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/livetemplate-client.js", nil))
+		if rec.Code != http.StatusFound {
+			t.Fatalf("Expected a redirect, got %d", rec.Code)
+		}
+		if got := rec.Header().Get("Location"); got != clientLibraryCDN {
+			t.Errorf("Location = %q, want %q", got, clientLibraryCDN)
+		}
+	})
+}
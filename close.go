@@ -0,0 +1,52 @@
+package livetemplate
+
+import (
+	"log"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Close codes the handler sends when it closes a WebSocket connection, each with a reason
+// string. Clients reconnect after CloseShutdown and CloseIdleTimeout, but not after
+// CloseUnauthorized or a policy violation (1008, e.g. WithMaxConnectionsPerGroup).
+const (
+	CloseShutdown     = websocket.CloseGoingAway // 1001: the server is shutting down, see Shutdown
+	CloseUnauthorized = 4401                     // The Authenticator rejected the connection
+	CloseIdleTimeout  = 4408                     // No activity for the idle timeout, see WithIdleTimeout
+)
+
+// closeWriteTimeout bounds how long writing a close message may take
+const closeWriteTimeout = time.Second
+
+// writeClose sends a close message with code and reason; the caller closes conn
+func writeClose(conn *websocket.Conn, code int, reason string) {
+	_ = conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(closeWriteTimeout))
+}
+
+// closeWith sends a close message with code and reason on a WebSocket connection and
+// closes it, which ends its read loop and with it the handler
+func (c *Connection) closeWith(code int, reason string) {
+	if c.Conn == nil {
+		return
+	}
+	writeClose(c.Conn, code, reason)
+	if c.queue != nil {
+		c.queue.drop()
+	} else {
+		_ = c.Conn.Close()
+	}
+}
+
+// Shutdown closes every WebSocket connection with CloseShutdown, so clients reconnect,
+// e.g. to another instance. http.Server.Shutdown doesn't close WebSocket connections, as
+// they are hijacked, so call Shutdown before it.
+//
+// Server-sent event streams end with the server's shutdown.
+func (h *liveHandler) Shutdown() {
+	connections := h.registry.GetAll()
+	for _, conn := range connections {
+		conn.closeWith(CloseShutdown, "server shutting down")
+	}
+	log.Printf("Shutdown: closed %d connections", len(connections))
+}
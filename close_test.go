@@ -0,0 +1,86 @@
+package livetemplate
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// rejectingAuthenticator is a test authenticator rejecting every request
+type rejectingAuthenticator struct{}
+
+func (rejectingAuthenticator) Identify(r *http.Request) (string, error) {
+	return "", errors.New("no session")
+}
+
+func (rejectingAuthenticator) GetSessionGroup(r *http.Request, userID string) (string, error) {
+	return "", errors.New("no session")
+}
+
+// expectClose reads from conn until it is closed and checks the close code and reason
+func expectClose(t *testing.T, conn *websocket.Conn, code int, reason string) {
+	t.Helper()
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	for {
+		_, _, err := conn.ReadMessage()
+		if err == nil {
+			continue
+		}
+		var closeErr *websocket.CloseError
+		if !errors.As(err, &closeErr) {
+			t.Fatalf("Expected a close message, got %v", err)
+		}
+		if closeErr.Code != code || closeErr.Text != reason {
+			t.Errorf("Close = %d %q, want %d %q", closeErr.Code, closeErr.Text, code, reason)
+		}
+		return
+	}
+}
+
+func TestCloseCodes(t *testing.T) {
+	newHandler := func(t *testing.T, opts ...Option) LiveHandler {
+		t.Helper()
+		tmpl := New("close-codes-test", opts...)
+		if _, err := tmpl.Parse("<p>Count: {{.Count}}</p>"); err != nil {
+			t.Fatalf("Parse failed: %v", err)
+		}
+		return tmpl.Handle(&SlowState{})
+	}
+
+	t.Run("unauthorized", func(t *testing.T) {
+		server := httptest.NewServer(newHandler(t, WithAuthenticator(rejectingAuthenticator{})))
+		defer server.Close()
+
+		conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+		if err != nil {
+			t.Fatalf("WebSocket dial failed: %v", err)
+		}
+		defer conn.Close()
+		expectClose(t, conn, CloseUnauthorized, "unauthorized")
+	})
+
+	t.Run("idle timeout", func(t *testing.T) {
+		conn := dialTestHandler(t, newHandler(t, WithIdleTimeout(100*time.Millisecond)))
+		expectClose(t, conn, CloseIdleTimeout, "idle timeout")
+	})
+
+	t.Run("shutdown", func(t *testing.T) {
+		handler := newHandler(t)
+		conn := dialTestHandler(t, handler)
+		handler.Shutdown()
+		expectClose(t, conn, CloseShutdown, "server shutting down")
+
+		deadline := time.Now().Add(time.Second)
+		for len(handler.ConnectionStats()) > 0 && time.Now().Before(deadline) {
+			time.Sleep(10 * time.Millisecond)
+		}
+		if stats := handler.ConnectionStats(); len(stats) != 0 {
+			t.Errorf("Expected no connections after Shutdown, got %d", len(stats))
+		}
+	})
+}
@@ -170,7 +170,29 @@ func GenView(args []string) error {
 	}
 	cssFramework := kitInfo.Manifest.CSSFramework
 
-	viewName := args[0]
+	// Parse flags
+	var actions []string
+	var filteredArgs []string
+	for i := 0; i < len(args); i++ {
+		if args[i] == "--actions" && i+1 < len(args) {
+			actions = parseActionList(args[i+1])
+			i++ // skip next arg
+		} else {
+			filteredArgs = append(filteredArgs, args[i])
+		}
+	}
+
+	if len(filteredArgs) < 1 {
+		return fmt.Errorf("view name required")
+	}
+
+	for _, action := range actions {
+		if !isValidActionName(action) {
+			return fmt.Errorf("invalid action name: %q (use letters, digits and underscores, starting with a letter)", action)
+		}
+	}
+
+	viewName := filteredArgs[0]
 
 	// Get module name from go.mod
 	moduleName, err := getModuleName()
@@ -182,7 +204,7 @@ func GenView(args []string) error {
 	fmt.Printf("Kit: %s\n", kit)
 	fmt.Printf("CSS Framework: %s\n", cssFramework)
 
-	if err := generator.GenerateView(basePath, moduleName, viewName, kit, cssFramework); err != nil {
+	if err := generator.GenerateView(basePath, moduleName, viewName, kit, cssFramework, actions); err != nil {
 		return err
 	}
 
@@ -195,6 +217,10 @@ func GenView(args []string) error {
 	fmt.Printf("  internal/app/%s/%s.go\n", viewNameLower, viewNameLower)
 	fmt.Printf("  internal/app/%s/%s.tmpl\n", viewNameLower, viewNameLower)
 	fmt.Printf("  internal/app/%s/%s_test.go\n", viewNameLower, viewNameLower)
+	if len(actions) > 0 {
+		fmt.Println()
+		fmt.Printf("Actions: %s\n", strings.Join(actions, ", "))
+	}
 	fmt.Println()
 	fmt.Println("Route auto-injected:")
 	fmt.Printf("  http.Handle(\"/%s\", %s.Handler())\n", viewNameLower, viewNameLower)
@@ -348,3 +374,27 @@ func getModuleName() (string, error) {
 
 	return "", fmt.Errorf("module name not found in go.mod")
 }
+
+// parseActionList splits a comma-separated --actions value, dropping empty entries
+func parseActionList(value string) []string {
+	var actions []string
+	for _, action := range strings.Split(value, ",") {
+		if action = strings.TrimSpace(action); action != "" {
+			actions = append(actions, action)
+		}
+	}
+	return actions
+}
+
+// isValidActionName reports whether name can be used as an action in generated Go and HTML
+func isValidActionName(name string) bool {
+	for i, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z':
+		case i > 0 && (r >= '0' && r <= '9' || r == '_'):
+		default:
+			return false
+		}
+	}
+	return name != ""
+}
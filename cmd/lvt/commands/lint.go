@@ -0,0 +1,80 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/livefir/livetemplate"
+)
+
+// Lint reports template constructs that updates can't handle as tree nodes and ranges
+// without item keys, one line per finding. Returns an error when there are findings, so
+// the command exits non-zero in CI.
+func Lint(args []string) error {
+	return lint(os.Stdout, args)
+}
+
+func lint(w io.Writer, args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("template glob required\nUsage: lvt lint <glob>...")
+	}
+
+	var files []string
+	for _, pattern := range args {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return fmt.Errorf("invalid glob %q: %w", pattern, err)
+		}
+		files = append(files, matches...)
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("no templates match %s", strings.Join(args, " "))
+	}
+
+	findings := 0
+	for _, file := range files {
+		n, err := lintFile(w, file)
+		if err != nil {
+			return err
+		}
+		findings += n
+	}
+
+	if findings > 0 {
+		return fmt.Errorf("%d lint finding(s) in %d template(s)", findings, len(files))
+	}
+	fmt.Fprintf(w, "%d template(s) checked, no findings\n", len(files))
+	return nil
+}
+
+// lintFile writes the findings for one template and returns how many there were
+func lintFile(w io.Writer, file string) (int, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read template: %w", err)
+	}
+
+	baseName := filepath.Base(file)
+	tmpl := livetemplate.New(strings.TrimSuffix(baseName, filepath.Ext(baseName)))
+	if _, err := tmpl.Parse(string(content)); err != nil {
+		fmt.Fprintf(w, "%s: parse error: %v\n", file, err)
+		return 1, nil
+	}
+
+	findings := 0
+	for _, c := range tmpl.Analyze().Constructs {
+		switch {
+		case !c.TreeBased:
+			fmt.Fprintf(w, "%s:%d: %s is not tree-based: %s\n", file, c.Line, c.Source, c.Note)
+		case c.Unkeyed:
+			fmt.Fprintf(w, "%s:%d: %s is unkeyed: %s\n", file, c.Line, c.Source, c.Note)
+		default:
+			continue
+		}
+		findings++
+	}
+	return findings, nil
+}
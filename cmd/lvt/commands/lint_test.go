@@ -0,0 +1,62 @@
+package commands
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLint(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	write("profile.tmpl", `<h1>{{.Title}}</h1>
+{{with .User}}<p>{{.Name}}</p>{{end}}
+<ul>{{range .Items}}<li>{{.}}</li>{{end}}</ul>`)
+	write("clean.tmpl", `<ul>{{range .Items}}<li data-key="{{.ID}}">{{.Label}}</li>{{end}}</ul>
+{{if .Flash}}<p>{{.Flash}}</p>{{end}}`)
+
+	t.Run("findings", func(t *testing.T) {
+		var out bytes.Buffer
+		err := lint(&out, []string{filepath.Join(dir, "*.tmpl")})
+		if err == nil {
+			t.Fatalf("Expected an error for a template with {{with}}, output:\n%s", out.String())
+		}
+		if !strings.Contains(err.Error(), "2 lint finding(s)") {
+			t.Errorf("Expected the error to count the findings, got %v", err)
+		}
+
+		output := out.String()
+		profile := filepath.Join(dir, "profile.tmpl")
+		for _, want := range []string{
+			profile + ":2: {{with .User}} is not tree-based: ",
+			profile + ":3: {{range .Items}} is unkeyed: ",
+		} {
+			if !strings.Contains(output, want) {
+				t.Errorf("Expected output to contain %q, got:\n%s", want, output)
+			}
+		}
+		if strings.Contains(output, "clean.tmpl") {
+			t.Errorf("Expected no findings for clean.tmpl, got:\n%s", output)
+		}
+	})
+
+	t.Run("clean", func(t *testing.T) {
+		var out bytes.Buffer
+		if err := lint(&out, []string{filepath.Join(dir, "clean.tmpl")}); err != nil {
+			t.Fatalf("Expected no findings, got %v:\n%s", err, out.String())
+		}
+	})
+
+	t.Run("no matches", func(t *testing.T) {
+		if err := lint(&bytes.Buffer{}, []string{filepath.Join(dir, "*.html")}); err == nil {
+			t.Error("Expected an error when the glob matches nothing")
+		}
+	})
+}
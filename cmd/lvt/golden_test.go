@@ -85,7 +85,7 @@ func TestResourceHandlerGolden(t *testing.T) {
 func TestViewHandlerGolden(t *testing.T) {
 	tmpDir := t.TempDir()
 
-	if err := generator.GenerateView(tmpDir, "testmodule", "Counter", "multi", "tailwind"); err != nil {
+	if err := generator.GenerateView(tmpDir, "testmodule", "Counter", "multi", "tailwind", nil); err != nil {
 		t.Fatalf("Failed to generate view: %v", err)
 	}
 
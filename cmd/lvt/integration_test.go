@@ -1,6 +1,9 @@
 package main
 
 import (
+	"go/ast"
+	goparser "go/parser"
+	"go/token"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -32,7 +35,7 @@ func TestGeneratedCodeSyntax(t *testing.T) {
 	}
 
 	// Generate view
-	if err := generator.GenerateView(tmpDir, "testmodule", "Counter", "multi", "tailwind"); err != nil {
+	if err := generator.GenerateView(tmpDir, "testmodule", "Counter", "multi", "tailwind", nil); err != nil {
 		t.Fatalf("Failed to generate view: %v", err)
 	}
 
@@ -57,6 +60,60 @@ func TestGeneratedCodeSyntax(t *testing.T) {
 	t.Log("✅ Generated code has valid Go syntax")
 }
 
+// TestGenerateViewWithActions validates that --actions are wired into Change and the template
+func TestGenerateViewWithActions(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	actions := []string{"increment", "decrement", "reset"}
+	if err := generator.GenerateView(tmpDir, "testmodule", "Counter", "multi", "tailwind", actions); err != nil {
+		t.Fatalf("Failed to generate view: %v", err)
+	}
+
+	viewDir := filepath.Join(tmpDir, "internal", "app", "counter")
+
+	handler, err := os.ReadFile(filepath.Join(viewDir, "counter.go"))
+	if err != nil {
+		t.Fatalf("Failed to read handler: %v", err)
+	}
+
+	fset := token.NewFileSet()
+	file, err := goparser.ParseFile(fset, "counter.go", handler, 0)
+	if err != nil {
+		t.Fatalf("Generated handler does not parse: %v\n%s", err, handler)
+	}
+
+	var cases []string
+	ast.Inspect(file, func(n ast.Node) bool {
+		clause, ok := n.(*ast.CaseClause)
+		if !ok {
+			return true
+		}
+		for _, expr := range clause.List {
+			if lit, ok := expr.(*ast.BasicLit); ok {
+				cases = append(cases, strings.Trim(lit.Value, `"`))
+			}
+		}
+		return true
+	})
+	if strings.Join(cases, ",") != strings.Join(actions, ",") {
+		t.Errorf("Expected switch cases %v, got %v", actions, cases)
+	}
+
+	tmpl, err := os.ReadFile(filepath.Join(viewDir, "counter.tmpl"))
+	if err != nil {
+		t.Fatalf("Failed to read template: %v", err)
+	}
+
+	if got := strings.Count(string(tmpl), "<button"); got != len(actions) {
+		t.Errorf("Expected %d buttons, got %d", len(actions), got)
+	}
+	for _, action := range actions {
+		if !strings.Contains(string(tmpl), `lvt-click="`+action+`"`) {
+			t.Errorf("Expected button with lvt-click=%q", action)
+		}
+	}
+}
+
 // TestGeneratedFilesExist validates that all expected files are generated
 func TestGeneratedFilesExist(t *testing.T) {
 	tmpDir := t.TempDir()
@@ -126,7 +183,7 @@ func TestGeneratedFilesExist(t *testing.T) {
 	}
 
 	// Generate view
-	if err := generator.GenerateView(appDir, "testapp", "Dashboard", "multi", "tailwind"); err != nil {
+	if err := generator.GenerateView(appDir, "testapp", "Dashboard", "multi", "tailwind", nil); err != nil {
 		t.Fatalf("Failed to generate view: %v", err)
 	}
 
@@ -19,9 +19,12 @@ type ViewData struct {
 	Kit           *kits.KitInfo // CSS framework kit (new)
 	CSSFramework  string        // CSS framework: "tailwind", "bulma", "pico", "none" (for backward compatibility)
 	DevMode       bool          // Use local client library instead of CDN
+	Actions       []string      // Action names wired into Change and rendered as lvt-click buttons
 }
 
-func GenerateView(basePath, moduleName, viewName string, kitName, cssFramework string) error {
+// GenerateView generates a view-only handler. Each of actions becomes a case in the
+// generated Change switch and a button in the template.
+func GenerateView(basePath, moduleName, viewName string, kitName, cssFramework string, actions []string) error {
 	// Load kit using KitLoader
 	kitLoader := kits.DefaultLoader()
 	kit, err := kitLoader.Load(kitName)
@@ -51,6 +54,7 @@ func GenerateView(basePath, moduleName, viewName string, kitName, cssFramework s
 		Kit:           kit,
 		CSSFramework:  cssFramework, // Keep for backward compatibility
 		DevMode:       devMode,
+		Actions:       actions,
 	}
 
 	// Create view directory
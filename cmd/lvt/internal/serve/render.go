@@ -0,0 +1,75 @@
+package serve
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/livefir/livetemplate"
+)
+
+const dryRenderPath = "/__lvt/render"
+
+type dryRenderRequest struct {
+	Template string      `json:"template"`
+	Data     interface{} `json:"data"`
+}
+
+type dryRenderResponse struct {
+	HTML        string          `json:"html"`
+	InitialTree json.RawMessage `json:"initialTree"`
+}
+
+// handleDryRender renders a template source with arbitrary JSON data, returning the HTML
+// and the initial tree a client would receive, so components can be previewed without a store.
+func handleDryRender(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req dryRenderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid JSON: %v", err), http.StatusBadRequest)
+		return
+	}
+	if req.Template == "" {
+		http.Error(w, "Missing template", http.StatusBadRequest)
+		return
+	}
+
+	tmpl := livetemplate.New("dry-render")
+	if _, err := tmpl.Parse(req.Template); err != nil {
+		http.Error(w, fmt.Sprintf("Template parse error: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	// Execute records the render as the baseline for updates, so the initial tree
+	// comes from a fresh clone
+	treeTmpl, err := tmpl.Clone()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to clone template: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	var html bytes.Buffer
+	if err := tmpl.Execute(&html, req.Data); err != nil {
+		http.Error(w, fmt.Sprintf("Template execution error: %v", err), http.StatusUnprocessableEntity)
+		return
+	}
+
+	var tree bytes.Buffer
+	if err := treeTmpl.ExecuteUpdates(&tree, req.Data); err != nil {
+		http.Error(w, fmt.Sprintf("Tree generation error: %v", err), http.StatusUnprocessableEntity)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(dryRenderResponse{
+		HTML:        html.String(),
+		InitialTree: tree.Bytes(),
+	})
+}
@@ -0,0 +1,70 @@
+package serve
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHandleDryRender(t *testing.T) {
+	body := `{"template": "<ul>{{range .Items}}<li>{{.}}</li>{{end}}</ul><p>{{.Title}}</p>", "data": {"Title": "Groceries", "Items": ["milk", "eggs"]}}`
+	req := httptest.NewRequest(http.MethodPost, dryRenderPath, strings.NewReader(body))
+	rec := httptest.NewRecorder()
+
+	handleDryRender(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var resp struct {
+		HTML        string                 `json:"html"`
+		InitialTree map[string]interface{} `json:"initialTree"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	for _, want := range []string{"<li>milk</li>", "<li>eggs</li>", "<p>Groceries</p>"} {
+		if !strings.Contains(resp.HTML, want) {
+			t.Errorf("Expected HTML to contain %q, got %s", want, resp.HTML)
+		}
+	}
+
+	if _, ok := resp.InitialTree["s"].([]interface{}); !ok {
+		t.Errorf("Expected initial tree with statics, got %v", resp.InitialTree)
+	}
+	tree, _ := json.Marshal(resp.InitialTree)
+	if !strings.Contains(string(tree), "Groceries") || !strings.Contains(string(tree), "milk") {
+		t.Errorf("Expected initial tree to contain the data, got %s", tree)
+	}
+}
+
+func TestHandleDryRender_InvalidRequests(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		body   string
+		status int
+	}{
+		{"wrong method", http.MethodGet, "", http.StatusMethodNotAllowed},
+		{"invalid JSON", http.MethodPost, "{", http.StatusBadRequest},
+		{"missing template", http.MethodPost, `{"data": {}}`, http.StatusBadRequest},
+		{"parse error", http.MethodPost, `{"template": "{{if}}"}`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, dryRenderPath, strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+
+			handleDryRender(rec, req)
+
+			if rec.Code != tt.status {
+				t.Errorf("Expected status %d, got %d: %s", tt.status, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
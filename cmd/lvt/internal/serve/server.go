@@ -103,6 +103,7 @@ func NewServer(config *ServerConfig) (*Server, error) {
 
 func (s *Server) setupRoutes() {
 	s.mux.HandleFunc(s.config.WebSocketPath, s.wsManager.HandleWebSocket)
+	s.mux.HandleFunc(dryRenderPath, handleDryRender)
 
 	switch s.config.Mode {
 	case ModeComponent:
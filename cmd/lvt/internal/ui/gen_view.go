@@ -214,7 +214,7 @@ func (m genViewModel) generateView() tea.Msg {
 	}
 	cssFramework := kitInfo.Manifest.CSSFramework
 
-	if err := generator.GenerateView(m.basePath, m.moduleName, viewNameLower, kit, cssFramework); err != nil {
+	if err := generator.GenerateView(m.basePath, m.moduleName, viewNameLower, kit, cssFramework, nil); err != nil {
 		m.err = err
 		m.stage = 0
 		return m
@@ -7,6 +7,7 @@ import (
 	"go/token"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/livefir/livetemplate/cmd/lvt/internal/kits"
 )
@@ -168,15 +169,30 @@ func validateKitHelpers(path string) *ValidationResult {
 		result.AddWarning("Missing NewHelpers() constructor function", path, 0)
 	}
 
-	// Check for key CSSHelpers interface methods (sample check)
-	requiredMethods := []string{
+	// Every class token the generator emits must be implemented, otherwise
+	// generated apps render those components unstyled
+	missingTokens := false
+	for _, group := range requiredTokenGroups {
+		missing := []string{}
+		for _, method := range group.Methods {
+			if !implementedMethods[method] {
+				missing = append(missing, method)
+			}
+		}
+		if len(missing) > 0 {
+			result.AddError(fmt.Sprintf("Missing %s tokens: %s", group.Component, strings.Join(missing, ", ")), path, 0)
+			missingTokens = true
+		}
+	}
+
+	// Check for other key CSSHelpers interface methods (sample check)
+	recommendedMethods := []string{
 		"ContainerClass", "BoxClass", "TitleClass",
-		"ButtonClass", "InputClass", "TableClass",
 		"CSSCDN",
 	}
 
 	missingMethods := []string{}
-	for _, method := range requiredMethods {
+	for _, method := range recommendedMethods {
 		if !implementedMethods[method] {
 			missingMethods = append(missingMethods, method)
 		}
@@ -184,12 +200,26 @@ func validateKitHelpers(path string) *ValidationResult {
 
 	if len(missingMethods) > 0 {
 		result.AddWarning(fmt.Sprintf("Missing some key helper methods: %v", missingMethods), path, 0)
+	}
+
+	if missingTokens || len(missingMethods) > 0 {
 		result.AddInfo("Run 'lvt kits create' to generate a complete helpers.go template", "", 0)
 	}
 
 	return result
 }
 
+// requiredTokenGroups lists the CSSHelpers methods used by generated templates, by component
+var requiredTokenGroups = []struct {
+	Component string
+	Methods   []string
+}{
+	{"button", []string{"ButtonClass"}},
+	{"input", []string{"FieldClass", "LabelClass", "InputClass", "TextareaClass", "SelectClass", "CheckboxClass"}},
+	{"table", []string{"TableClass", "TableContainerClass"}},
+	{"pagination", []string{"PaginationClass", "PaginationButtonClass", "PaginationListClass"}},
+}
+
 // validateKitReadme checks for README.md
 func validateKitReadme(path string) *ValidationResult {
 	result := NewValidationResult()
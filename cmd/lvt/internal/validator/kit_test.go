@@ -3,6 +3,7 @@ package validator
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -43,8 +44,17 @@ func (h *Helpers) ContainerClass() string { return "container" }
 func (h *Helpers) BoxClass() string { return "box" }
 func (h *Helpers) TitleClass(level int) string { return "title" }
 func (h *Helpers) ButtonClass(variant string) string { return "btn" }
+func (h *Helpers) FieldClass() string { return "field" }
+func (h *Helpers) LabelClass() string { return "label" }
 func (h *Helpers) InputClass() string { return "input" }
+func (h *Helpers) TextareaClass() string { return "textarea" }
+func (h *Helpers) SelectClass() string { return "select" }
+func (h *Helpers) CheckboxClass() string { return "checkbox" }
 func (h *Helpers) TableClass() string { return "table" }
+func (h *Helpers) TableContainerClass() string { return "table-container" }
+func (h *Helpers) PaginationClass() string { return "pagination" }
+func (h *Helpers) PaginationButtonClass(state string) string { return "page" }
+func (h *Helpers) PaginationListClass() string { return "pagination-list" }
 func (h *Helpers) CSSCDN() string { return "https://example.com/test.css" }
 `
 	if err := os.WriteFile(filepath.Join(kitDir, "helpers.go"), []byte(helpers), 0644); err != nil {
@@ -196,16 +206,150 @@ func (h *Helpers) ContainerClass() string { return "container" }
 
 	result := ValidateKit(kitDir)
 
-	if !result.Valid {
-		t.Error("Expected valid kit (warnings only for missing methods)")
+	// Missing class tokens used by generated templates make the kit invalid
+	if result.Valid {
+		t.Error("Expected invalid kit with missing class tokens")
 	}
 
-	// Should have warnings for missing methods
+	// Should also have warnings for missing recommended methods
 	if result.WarningCount() == 0 {
 		t.Error("Expected warnings for missing methods")
 	}
 }
 
+func TestValidateKit_MissingPaginationTokens(t *testing.T) {
+	tmpDir := t.TempDir()
+	kitDir := filepath.Join(tmpDir, "test-kit")
+	if err := os.MkdirAll(kitDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	kitYAML := `name: test-kit
+version: 1.0.0
+description: A test CSS kit
+framework: none
+`
+	if err := os.WriteFile(filepath.Join(kitDir, "kit.yaml"), []byte(kitYAML), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Complete kit except for pagination
+	helpers := `package testkit
+
+import "github.com/livefir/livetemplate/cmd/lvt/internal/kits"
+
+type Helpers struct{}
+
+func NewHelpers() kits.CSSHelpers {
+	return &Helpers{}
+}
+
+func (h *Helpers) ContainerClass() string { return "container" }
+func (h *Helpers) BoxClass() string { return "box" }
+func (h *Helpers) TitleClass(level int) string { return "title" }
+func (h *Helpers) ButtonClass(variant string) string { return "btn" }
+func (h *Helpers) FieldClass() string { return "field" }
+func (h *Helpers) LabelClass() string { return "label" }
+func (h *Helpers) InputClass() string { return "input" }
+func (h *Helpers) TextareaClass() string { return "textarea" }
+func (h *Helpers) SelectClass() string { return "select" }
+func (h *Helpers) CheckboxClass() string { return "checkbox" }
+func (h *Helpers) TableClass() string { return "table" }
+func (h *Helpers) TableContainerClass() string { return "table-container" }
+func (h *Helpers) CSSCDN() string { return "https://example.com/test.css" }
+`
+	if err := os.WriteFile(filepath.Join(kitDir, "helpers.go"), []byte(helpers), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	result := ValidateKit(kitDir)
+
+	if result.Valid {
+		t.Fatal("Expected invalid kit with missing pagination tokens")
+	}
+	if result.ErrorCount() != 1 {
+		t.Errorf("Expected exactly 1 error, got %d: %s", result.ErrorCount(), result.Format())
+	}
+
+	found := false
+	for _, issue := range result.Issues {
+		if issue.Level != LevelError {
+			continue
+		}
+		found = true
+		for _, token := range []string{"pagination", "PaginationClass", "PaginationButtonClass", "PaginationListClass"} {
+			if !strings.Contains(issue.Message, token) {
+				t.Errorf("Expected error to mention %q, got %q", token, issue.Message)
+			}
+		}
+	}
+	if !found {
+		t.Errorf("Expected a missing token error, got: %s", result.Format())
+	}
+}
+
+func TestValidateKit_MissingTableContainerToken(t *testing.T) {
+	tmpDir := t.TempDir()
+	kitDir := filepath.Join(tmpDir, "test-kit")
+	if err := os.MkdirAll(kitDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	kitYAML := `name: test-kit
+version: 1.0.0
+description: A test CSS kit
+framework: none
+`
+	if err := os.WriteFile(filepath.Join(kitDir, "kit.yaml"), []byte(kitYAML), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Complete kit except for the table wrapper of generated tables
+	helpers := `package testkit
+
+import "github.com/livefir/livetemplate/cmd/lvt/internal/kits"
+
+type Helpers struct{}
+
+func NewHelpers() kits.CSSHelpers {
+	return &Helpers{}
+}
+
+func (h *Helpers) ContainerClass() string { return "container" }
+func (h *Helpers) BoxClass() string { return "box" }
+func (h *Helpers) TitleClass(level int) string { return "title" }
+func (h *Helpers) ButtonClass(variant string) string { return "btn" }
+func (h *Helpers) FieldClass() string { return "field" }
+func (h *Helpers) LabelClass() string { return "label" }
+func (h *Helpers) InputClass() string { return "input" }
+func (h *Helpers) TextareaClass() string { return "textarea" }
+func (h *Helpers) SelectClass() string { return "select" }
+func (h *Helpers) CheckboxClass() string { return "checkbox" }
+func (h *Helpers) TableClass() string { return "table" }
+func (h *Helpers) PaginationClass() string { return "pagination" }
+func (h *Helpers) PaginationButtonClass(state string) string { return "page" }
+func (h *Helpers) PaginationListClass() string { return "pagination-list" }
+func (h *Helpers) CSSCDN() string { return "https://example.com/test.css" }
+`
+	if err := os.WriteFile(filepath.Join(kitDir, "helpers.go"), []byte(helpers), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	result := ValidateKit(kitDir)
+
+	if result.Valid {
+		t.Fatal("Expected invalid kit with missing table tokens")
+	}
+	if result.ErrorCount() != 1 {
+		t.Errorf("Expected exactly 1 error, got %d: %s", result.ErrorCount(), result.Format())
+	}
+	for _, issue := range result.Issues {
+		if issue.Level == LevelError && (!strings.Contains(issue.Message, "table") || !strings.Contains(issue.Message, "TableContainerClass")) {
+			t.Errorf("Expected error to mention the table container, got %q", issue.Message)
+		}
+	}
+}
+
 func TestValidateKit_MissingREADME(t *testing.T) {
 	tmpDir := t.TempDir()
 	kitDir := filepath.Join(tmpDir, "test-kit")
@@ -53,6 +53,8 @@ func main() {
 		err = commands.Migration(args)
 	case "parse":
 		err = commands.Parse(args)
+	case "lint":
+		err = commands.Lint(args)
 	case "resource", "res":
 		err = commands.Resource(args)
 	case "seed":
@@ -149,6 +151,7 @@ func printUsage() {
 	fmt.Println("  lvt kits <command>                        Manage CSS framework kits")
 	fmt.Println("  lvt serve [options]                       Start development server with hot reload")
 	fmt.Println("  lvt parse <template-file>                 Validate and analyze template file")
+	fmt.Println("  lvt lint <glob>...                        Flag {{with}} and unkeyed ranges (exit 1 on findings)")
 	fmt.Println("  lvt version                               Show version information")
 	fmt.Println()
 	fmt.Println("Interactive Mode (no arguments):")
@@ -162,6 +165,7 @@ func printUsage() {
 	fmt.Println("  lvt gen users name:string email:string age:int")
 	fmt.Println("  lvt gen users name email age              (types inferred)")
 	fmt.Println("  lvt gen view counter                      (view-only handler)")
+	fmt.Println("  lvt gen view counter --actions increment,decrement,reset")
 	fmt.Println()
 	fmt.Println("Migration Commands:")
 	fmt.Println("  lvt migration up                          Run pending migrations")
@@ -0,0 +1,68 @@
+package livetemplate
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// broadcastCoalescer delays broadcast updates so that all broadcasts a connection receives
+// within the window are sent as a single diff against the latest data (see WithBroadcastCoalesce).
+// The window starts with the first pending broadcast and is not extended by later ones.
+type broadcastCoalescer struct {
+	window  time.Duration
+	send    func(conn *Connection, data interface{}) error
+	mu      sync.Mutex
+	pending map[*Connection]*pendingBroadcast // Latest data per connection with a scheduled send
+}
+
+// pendingBroadcast is the data of a scheduled send, replaced by later broadcasts
+type pendingBroadcast struct {
+	data interface{}
+}
+
+func newBroadcastCoalescer(window time.Duration, send func(conn *Connection, data interface{}) error) *broadcastCoalescer {
+	return &broadcastCoalescer{
+		window:  window,
+		send:    send,
+		pending: make(map[*Connection]*pendingBroadcast),
+	}
+}
+
+// add schedules an update of conn with data, replacing data of a broadcast still pending
+func (c *broadcastCoalescer) add(conn *Connection, data interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if entry, scheduled := c.pending[conn]; scheduled {
+		entry.data = data
+		return
+	}
+	entry := &pendingBroadcast{data: data}
+	c.pending[conn] = entry
+	time.AfterFunc(c.window, func() { c.flush(conn, entry) })
+}
+
+// drop discards the pending update of conn, when the connection renders newer state itself
+// or closes. Its scheduled send then does nothing.
+func (c *broadcastCoalescer) drop(conn *Connection) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.pending, conn)
+}
+
+// flush sends the pending update of conn, unless it was dropped since entry was scheduled
+func (c *broadcastCoalescer) flush(conn *Connection, entry *pendingBroadcast) {
+	c.mu.Lock()
+	if c.pending[conn] != entry {
+		c.mu.Unlock()
+		return
+	}
+	delete(c.pending, conn)
+	data := entry.data
+	c.mu.Unlock()
+
+	if err := c.send(conn, data); err != nil {
+		log.Printf("Coalesced broadcast failed for connection in group %s: %v", conn.GroupID, err)
+	}
+}
@@ -0,0 +1,77 @@
+package livetemplate
+
+import (
+	"crypto/hmac"
+	cryptorand "crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const (
+	csrfParam     = "lvt-csrf"            // Query parameter of the WebSocket handshake and form field of form POSTs
+	csrfHeader    = "X-LiveTemplate-CSRF" // Header of HTTP action POSTs sent by the client
+	csrfAttribute = "data-lvt-csrf"       // Wrapper attribute carrying the token in the initial page
+)
+
+// WithCSRFProtection requires a per-session CSRF token on the WebSocket handshake and on
+// HTTP action POSTs, as a defense beyond WithAllowedOrigins and SameSite cookies. The
+// handler embeds the token in the wrapper of the initial page as data-lvt-csrf, and the
+// client echoes it as the lvt-csrf query parameter or the X-LiveTemplate-CSRF header.
+// Forms posting actions without the client library send it as an lvt-csrf field, which the
+// handler adds to the forms of WithProgressiveEnhancement.
+//
+// Tokens are an HMAC of the session group keyed by secret, so servers behind a load
+// balancer must share the secret. A nil secret generates a random one, which invalidates
+// open pages on restart:
+//
+//	tmpl := livetemplate.New("app", livetemplate.WithCSRFProtection([]byte(os.Getenv("CSRF_SECRET"))))
+func WithCSRFProtection(secret []byte) Option {
+	return func(c *Config) {
+		if len(secret) == 0 {
+			secret = make([]byte, 32)
+			if _, err := cryptorand.Read(secret); err != nil {
+				panic(fmt.Sprintf("failed to generate CSRF secret: %v", err))
+			}
+		}
+		c.CSRFSecret = secret
+	}
+}
+
+// csrfGuard issues and checks the CSRF tokens of session groups
+type csrfGuard struct {
+	secret []byte
+}
+
+// token returns the CSRF token of a session group
+func (g *csrfGuard) token(groupID string) string {
+	mac := hmac.New(sha256.New, g.secret)
+	mac.Write([]byte(groupID))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// valid reports whether token is the CSRF token of a session group
+func (g *csrfGuard) valid(groupID, token string) bool {
+	return token != "" && hmac.Equal([]byte(token), []byte(g.token(groupID)))
+}
+
+// requestToken returns the CSRF token sent with an HTTP action POST
+func requestToken(r *http.Request) string {
+	if token := r.Header.Get(csrfHeader); token != "" {
+		return token
+	}
+	if isFormRequest(r) {
+		return r.FormValue(csrfParam)
+	}
+	return ""
+}
+
+// injectCSRFToken adds the data-lvt-csrf attribute to the wrapper div of a rendered page,
+// and an lvt-csrf field to its fallback forms (see WithProgressiveEnhancement)
+func injectCSRFToken(page, wrapperID, token string) string {
+	idAttr := `data-lvt-id="` + wrapperID + `"`
+	page = strings.Replace(page, idAttr, idAttr+` `+csrfAttribute+`="`+token+`"`, 1)
+	return strings.ReplaceAll(page, fallbackFormTag, fallbackFormTag+`<input type="hidden" name="`+csrfParam+`" value="`+token+`">`)
+}
@@ -0,0 +1,117 @@
+package livetemplate
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestWithCSRFProtection(t *testing.T) {
+	tmpl := New("csrf-test", WithCSRFProtection([]byte("test-secret")))
+	if _, err := tmpl.Parse("<div><p>Count: {{.Count}}</p></div>"); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	server := httptest.NewServer(tmpl.Handle(&SlowState{}))
+	defer server.Close()
+
+	cookie := (&http.Cookie{Name: "livetemplate-id", Value: "group-csrf"}).String()
+
+	// do sends a request in the test's session group
+	do := func(t *testing.T, req *http.Request) (*http.Response, string) {
+		t.Helper()
+		req.Header.Set("Cookie", cookie)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp, string(body)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+	_, page := do(t, req)
+	match := regexp.MustCompile(`data-lvt-csrf="([0-9a-f]+)"`).FindStringSubmatch(page)
+	if match == nil {
+		t.Fatalf("Expected the page to embed a CSRF token, got %s", page)
+	}
+	token := match[1]
+
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http")
+	dial := func(query string) (*websocket.Conn, *http.Response, error) {
+		header := http.Header{}
+		header.Add("Cookie", cookie)
+		return websocket.DefaultDialer.Dial(wsURL+query, header)
+	}
+
+	t.Run("WebSocket", func(t *testing.T) {
+		for name, query := range map[string]string{"missing": "", "invalid": "?lvt-csrf=0123abcd"} {
+			conn, resp, err := dial(query)
+			if err == nil {
+				conn.Close()
+				t.Errorf("%s token: expected handshake to be rejected", name)
+				continue
+			}
+			if resp == nil || resp.StatusCode != http.StatusForbidden {
+				t.Errorf("%s token: expected 403, got %v", name, resp)
+			}
+		}
+
+		conn, _, err := dial("?lvt-csrf=" + token)
+		if err != nil {
+			t.Fatalf("Valid token: handshake failed: %v", err)
+		}
+		defer conn.Close()
+		if tree, _ := readUpdate(t, conn); tree["s"] == nil {
+			t.Errorf("Expected initial tree after a valid handshake, got %v", tree)
+		}
+	})
+
+	t.Run("HTTP actions", func(t *testing.T) {
+		post := func(t *testing.T, header string) int {
+			req, _ := http.NewRequest(http.MethodPost, server.URL, strings.NewReader(`{"action":"increment"}`))
+			req.Header.Set("Content-Type", "application/json")
+			if header != "" {
+				req.Header.Set("X-LiveTemplate-CSRF", header)
+			}
+			resp, _ := do(t, req)
+			return resp.StatusCode
+		}
+
+		if status := post(t, ""); status != http.StatusForbidden {
+			t.Errorf("Missing token: expected 403, got %d", status)
+		}
+		if status := post(t, "0123abcd"); status != http.StatusForbidden {
+			t.Errorf("Invalid token: expected 403, got %d", status)
+		}
+		if status := post(t, token); status != http.StatusOK {
+			t.Errorf("Valid token: expected 200, got %d", status)
+		}
+
+		form := url.Values{"action": {"increment"}, "lvt-csrf": {token}}
+		req, _ := http.NewRequest(http.MethodPost, server.URL, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if resp, body := do(t, req); resp.StatusCode != http.StatusOK {
+			t.Errorf("Valid form token: expected 200, got %d: %s", resp.StatusCode, body)
+		}
+	})
+
+	t.Run("token is bound to the session group", func(t *testing.T) {
+		header := http.Header{}
+		header.Add("Cookie", (&http.Cookie{Name: "livetemplate-id", Value: "group-other"}).String())
+		conn, resp, err := websocket.DefaultDialer.Dial(wsURL+"?lvt-csrf="+token, header)
+		if err == nil {
+			conn.Close()
+			t.Fatal("Expected another session's token to be rejected")
+		}
+		if resp == nil || resp.StatusCode != http.StatusForbidden {
+			t.Errorf("Expected 403, got %v", resp)
+		}
+	})
+}
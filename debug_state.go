@@ -0,0 +1,115 @@
+package livetemplate
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+// debugStatePath is the path, below the handler's mount point, of the DevMode endpoint
+// returning the state of a live connection: GET /__lvt/state?token=<resume token>
+const debugStatePath = "/__lvt/state"
+
+// debugState is the response of the state endpoint
+type debugState struct {
+	GroupID     string          `json:"group_id"`
+	UserID      string          `json:"user_id"`
+	Fingerprint string          `json:"fingerprint"`
+	Tree        json.RawMessage `json:"tree"`
+	Data        json.RawMessage `json:"data"`
+}
+
+// isDebugStateRequest reports whether r asks for the state endpoint, served in DevMode only
+// since it exposes the template data of any connection whose token is known
+func (h *liveHandler) isDebugStateRequest(r *http.Request) bool {
+	return h.config.Template.config.DevMode && r.Method == http.MethodGet &&
+		strings.HasSuffix(r.URL.Path, debugStatePath)
+}
+
+// handleDebugState writes the tree and data a connection last rendered. The token is the
+// resume_token the client received with its initial tree, so `lvt serve` and browser tools
+// can inspect the page they are looking at.
+func (h *liveHandler) handleDebugState(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	conn := h.registry.getByToken(token)
+	if token == "" || conn == nil {
+		http.Error(w, "No live connection for token", http.StatusNotFound)
+		return
+	}
+
+	tree, data := conn.Template.debugSnapshot()
+	state := debugState{
+		GroupID:     conn.GroupID,
+		UserID:      conn.UserID,
+		Fingerprint: conn.Template.Fingerprint(),
+		Tree:        json.RawMessage("null"),
+		Data:        json.RawMessage("null"),
+	}
+	if tree != nil {
+		encoded, err := marshalOrderedJSON(tree, conn.Template.jsonEncoder())
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to encode tree: %v", err), http.StatusInternalServerError)
+			return
+		}
+		state.Tree = encoded
+	}
+	if encoded, err := json.Marshal(data); err == nil {
+		state.Data = encoded
+	} else {
+		// Data that isn't JSON, e.g. with channels or funcs, is shown as Go syntax
+		state.Data, _ = json.Marshal(fmt.Sprintf("%+v", data))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	_ = json.NewEncoder(w).Encode(state)
+}
+
+// getByToken returns the connection whose client holds resume token, or nil
+func (r *ConnectionRegistry) getByToken(token string) *Connection {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, conns := range r.byGroup {
+		for _, conn := range conns {
+			if conn.token == token {
+				return conn
+			}
+		}
+	}
+	return nil
+}
+
+// debugSnapshot returns the tree and data of the last render. The data is reduced to the
+// fields templates see, without the lvt namespace, so `lvt:"-"` fields and FieldVisibility
+// stay private.
+func (t *Template) debugSnapshot() (treeNode, interface{}) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.lastTree, visibleData(t.lastData)
+}
+
+// visibleData returns the template-visible fields of data as a map, applying the same
+// visibility to the stores of a multi-store map. Data without fields is returned as is.
+func visibleData(data interface{}) interface{} {
+	if data == nil {
+		return nil
+	}
+	kind := reflect.Indirect(reflect.ValueOf(data)).Kind()
+	if kind != reflect.Struct && kind != reflect.Map {
+		return data
+	}
+
+	fields := make(map[string]interface{})
+	copyTemplateFields(fields, data)
+	delete(fields, "lvt")
+	for name, value := range fields {
+		if hidesFields(value) {
+			nested := make(map[string]interface{})
+			copyTemplateFields(nested, value)
+			fields[name] = nested
+		}
+	}
+	return fields
+}
@@ -0,0 +1,157 @@
+package livetemplate
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestLiveHandler_DebugState(t *testing.T) {
+	newServer := func(t *testing.T, opts ...Option) *httptest.Server {
+		t.Helper()
+		tmpl := New("debug-state-test", opts...)
+		if _, err := tmpl.Parse("<div><p>Count: {{.Count}}</p></div>"); err != nil {
+			t.Fatalf("Parse failed: %v", err)
+		}
+		server := httptest.NewServer(tmpl.Handle(&SlowState{Count: 7}))
+		t.Cleanup(server.Close)
+		return server
+	}
+
+	// get requests the state endpoint for token
+	get := func(t *testing.T, server *httptest.Server, token string) (*http.Response, []byte) {
+		t.Helper()
+		resp, err := http.Get(server.URL + "/__lvt/state?token=" + token)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		defer resp.Body.Close()
+		var body json.RawMessage
+		_ = json.NewDecoder(resp.Body).Decode(&body)
+		return resp, body
+	}
+
+	t.Run("returns the connection's tree and data", func(t *testing.T) {
+		server := newServer(t, WithDevMode(true))
+		conn := dialResumeTest(t, server, "", "group-debug")
+		_, meta := readUpdate(t, conn)
+		if meta == nil || meta.ResumeToken == "" {
+			t.Fatalf("Expected a resume token in the initial metadata, got %+v", meta)
+		}
+
+		resp, body := get(t, server, meta.ResumeToken)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("Expected 200, got %d", resp.StatusCode)
+		}
+		var state struct {
+			GroupID     string                 `json:"group_id"`
+			Fingerprint string                 `json:"fingerprint"`
+			Tree        map[string]interface{} `json:"tree"`
+			Data        map[string]interface{} `json:"data"`
+		}
+		if err := json.Unmarshal(body, &state); err != nil {
+			t.Fatalf("Invalid response %s: %v", body, err)
+		}
+		if state.GroupID != "group-debug" {
+			t.Errorf("Expected group %q, got %q", "group-debug", state.GroupID)
+		}
+		if _, ok := state.Tree["s"]; !ok || !strings.Contains(string(body), "Count: ") {
+			t.Errorf("Expected the current tree with statics, got %s", body)
+		}
+		if state.Data["Count"] != float64(7) {
+			t.Errorf("Expected data with Count 7, got %v", state.Data)
+		}
+		if _, ok := state.Data["lvt"]; ok {
+			t.Errorf("Data should not include the lvt namespace, got %v", state.Data)
+		}
+		if state.Fingerprint == "" {
+			t.Error("Expected the tree fingerprint")
+		}
+	})
+
+	t.Run("hidden fields are left out", func(t *testing.T) {
+		tmpl := New("debug-state-hidden-test", WithDevMode(true))
+		if _, err := tmpl.Parse("<p>{{.Name}}</p>"); err != nil {
+			t.Fatalf("Parse failed: %v", err)
+		}
+		server := httptest.NewServer(tmpl.Handle(&debugSecretState{Name: "Ada", APIKey: "secret-key", Load: func() {}}))
+		t.Cleanup(server.Close)
+
+		conn := dialResumeTest(t, server, "", "group-debug-hidden")
+		_, meta := readUpdate(t, conn)
+		resp, body := get(t, server, meta.ResumeToken)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("Expected 200, got %d", resp.StatusCode)
+		}
+		if strings.Contains(string(body), "secret-key") || strings.Contains(string(body), "APIKey") {
+			t.Errorf("Expected lvt:\"-\" fields to be left out, got %s", body)
+		}
+		var state struct {
+			Data map[string]interface{} `json:"data"`
+		}
+		if err := json.Unmarshal(body, &state); err != nil || state.Data["Name"] != "Ada" {
+			t.Errorf("Expected the visible fields as JSON, got %s", body)
+		}
+	})
+
+	t.Run("unknown token", func(t *testing.T) {
+		server := newServer(t, WithDevMode(true))
+		if resp, _ := get(t, server, "unknown"); resp.StatusCode != http.StatusNotFound {
+			t.Errorf("Expected 404, got %d", resp.StatusCode)
+		}
+	})
+
+	t.Run("disabled outside DevMode", func(t *testing.T) {
+		server := newServer(t)
+		conn := dialResumeTest(t, server, "", "group-debug")
+		_, meta := readUpdate(t, conn)
+
+		resp, _ := get(t, server, meta.ResumeToken)
+		if ct := resp.Header.Get("Content-Type"); strings.Contains(ct, "application/json") {
+			t.Errorf("Expected the state endpoint to be disabled, got %s response", ct)
+		}
+	})
+}
+
+// debugSecretState is a test store with fields hidden from templates, one of which
+// can't be marshalled to JSON
+type debugSecretState struct {
+	Name   string
+	APIKey string `lvt:"-"`
+	Load   func() `lvt:"-"`
+}
+
+func (s *debugSecretState) Change(ctx *ActionContext) error {
+	return nil
+}
+
+func TestTemplate_DebugSnapshot(t *testing.T) {
+	tmpl := New("debug-snapshot-test")
+	if _, err := tmpl.Parse("<p>{{.Name}}</p>"); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	store := &debugSecretState{Name: "Ada", APIKey: "secret-key", Load: func() {}}
+
+	for name, data := range map[string]interface{}{
+		"single store": store,
+		"multi store":  map[string]interface{}{"profile": store},
+	} {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := tmpl.Execute(&buf, data); err != nil {
+				t.Fatalf("Execute failed: %v", err)
+			}
+			_, snapshot := tmpl.debugSnapshot()
+			encoded, err := json.Marshal(snapshot)
+			if err != nil {
+				t.Fatalf("Expected the visible fields to marshal, got %v", err)
+			}
+			if strings.Contains(string(encoded), "APIKey") || !strings.Contains(string(encoded), `"Name":"Ada"`) {
+				t.Errorf("Expected only the visible fields, got %s", encoded)
+			}
+		})
+	}
+}
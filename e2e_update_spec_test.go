@@ -391,9 +391,8 @@ func TestUpdateSpecification_RangeOperations(t *testing.T) {
 				if len(ops) != 1 {
 					t.Fatalf("Expected 1 operation, got %d", len(ops))
 				}
-				op := ops[0].([]interface{})
-				if op[0] != "i" {
-					t.Errorf("Expected insert 'i', got %v", op[0])
+				if _, ok := ops[0].(InsertOp); !ok {
+					t.Errorf("Expected insert 'i', got %#v", ops[0])
 				}
 			},
 		},
@@ -410,12 +409,12 @@ func TestUpdateSpecification_RangeOperations(t *testing.T) {
 				if len(ops) != 1 {
 					t.Fatalf("Expected 1 operation, got %d", len(ops))
 				}
-				op := ops[0].([]interface{})
-				if op[0] != "r" {
-					t.Errorf("Expected remove 'r', got %v", op[0])
+				op, ok := ops[0].(RemoveOp)
+				if !ok {
+					t.Fatalf("Expected remove 'r', got %#v", ops[0])
 				}
-				if op[1] != "2" {
-					t.Errorf("Expected to remove ID '2', got %v", op[1])
+				if op.Key != "2" {
+					t.Errorf("Expected to remove ID '2', got %v", op.Key)
 				}
 			},
 		},
@@ -431,12 +430,12 @@ func TestUpdateSpecification_RangeOperations(t *testing.T) {
 				if len(ops) != 1 {
 					t.Fatalf("Expected 1 operation, got %d", len(ops))
 				}
-				op := ops[0].([]interface{})
-				if op[0] != "u" {
-					t.Errorf("Expected update 'u', got %v", op[0])
+				op, ok := ops[0].(UpdateOp)
+				if !ok {
+					t.Fatalf("Expected update 'u', got %#v", ops[0])
 				}
-				if op[1] != "1" {
-					t.Errorf("Expected to update ID '1', got %v", op[1])
+				if op.Key != "1" {
+					t.Errorf("Expected to update ID '1', got %v", op.Key)
 				}
 			},
 		},
@@ -456,11 +455,11 @@ func TestUpdateSpecification_RangeOperations(t *testing.T) {
 				if len(ops) != 1 {
 					t.Fatalf("Expected 1 operation, got %d", len(ops))
 				}
-				op := ops[0].([]interface{})
-				if op[0] != "o" {
-					t.Errorf("Expected order 'o', got %v", op[0])
+				op, ok := ops[0].(OrderOp)
+				if !ok {
+					t.Fatalf("Expected order 'o', got %#v", ops[0])
 				}
-				order := op[1].([]string)
+				order := op.Keys
 				if len(order) != 3 {
 					t.Errorf("Expected 3 items in order, got %d", len(order))
 				}
@@ -490,9 +489,7 @@ func TestUpdateSpecification_RangeOperations(t *testing.T) {
 				foundUpdate := false
 
 				for _, op := range ops {
-					opArray := op.([]interface{})
-					opType := opArray[0].(string)
-					switch opType {
+					switch op.(RangeOp).Opcode() {
 					case "r":
 						foundRemove = true
 					case "i":
@@ -694,9 +691,9 @@ func TestUserJourney_TodoApp(t *testing.T) {
 				for _, v := range tree {
 					if ops, ok := v.([]interface{}); ok {
 						for _, op := range ops {
-							if opArr, ok := op.([]interface{}); ok && len(opArr) > 0 {
+							if rangeOp, ok := op.(RangeOp); ok {
 								// Accept both "i" (insert) and "a" (append) as valid granular operations
-								if opArr[0] == "i" || opArr[0] == "a" {
+								if rangeOp.Opcode() == "i" || rangeOp.Opcode() == "a" {
 									foundRangeOps = true
 								}
 							}
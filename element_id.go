@@ -0,0 +1,47 @@
+package livetemplate
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+// lvtID implements {{lvt_id "suffix"}}: it outputs an element ID unique to the template's
+// wrapper, so labels, inputs and error messages can reference each other without IDs
+// clashing between several instances of a template on a page:
+//
+//	<label for="{{lvt_id "email"}}">Email</label>
+//	<input id="{{lvt_id "email"}}" aria-describedby="{{lvt_id "email-error"}}">
+//	<p id="{{lvt_id "email-error"}}">{{.lvt.Error "email"}}</p>
+//
+// The ID is the wrapper ID (data-lvt-id) and the suffix, e.g. "lvt-3f2a9c1b-email", and
+// is the same across updates and per-connection clones. Parse scopes each call to the
+// template's wrapper (see scopeElementIDs); this unscoped form only outputs the suffix.
+func lvtID(suffix interface{}) string {
+	return fmt.Sprint(suffix)
+}
+
+// lvtScopedID is lvt_id scoped to the wrapper with ID scope
+func lvtScopedID(scope string, suffix interface{}) string {
+	if scope == "" {
+		return lvtID(suffix)
+	}
+	return scope + "-" + fmt.Sprint(suffix)
+}
+
+var (
+	// templateActionPattern matches the actions of a template
+	templateActionPattern = regexp.MustCompile(`(?s)\{\{.*?\}\}`)
+	// lvtIDCallPattern matches a call of lvt_id in an action, with the character before it
+	lvtIDCallPattern = regexp.MustCompile(`(^|[^\w.$])lvt_id\b`)
+)
+
+// scopeElementIDs rewrites the {{lvt_id ...}} calls of text to pass the template's wrapper
+// ID. Template functions are shared by all templates, and the lvt namespace isn't reachable
+// inside {{range}} and {{with}}, so the scope is written into the calls themselves.
+func (t *Template) scopeElementIDs(text string) string {
+	scope := "${1}lvt_scoped_id " + strconv.Quote(t.wrapperID)
+	return templateActionPattern.ReplaceAllStringFunc(text, func(action string) string {
+		return lvtIDCallPattern.ReplaceAllString(action, scope)
+	})
+}
@@ -0,0 +1,88 @@
+package livetemplate
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestTemplate_ElementIDs(t *testing.T) {
+	const source = `<label for="{{lvt_id "email"}}">Email</label>` +
+		`<input id="{{lvt_id "email"}}" aria-describedby="{{ lvt_id "email-error" }}" value="{{.Email}}">` +
+		`<ul>{{range .Items}}<li aria-labelledby="{{lvt_id .}}"><span id="{{lvt_id .}}">{{.}}</span></li>{{end}}</ul>`
+	type form struct {
+		Email string
+		Items []string
+	}
+
+	tmpl := New("element-id-test")
+	if _, err := tmpl.Parse(source); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	id := func(suffix string) string { return tmpl.wrapperID + "-" + suffix }
+
+	t.Run("same suffix same ID", func(t *testing.T) {
+		var html bytes.Buffer
+		if err := tmpl.Execute(&html, form{Items: []string{"a", "b"}}); err != nil {
+			t.Fatalf("Execute failed: %v", err)
+		}
+		for _, want := range []string{
+			fmt.Sprintf(`<label for="%s">`, id("email")),
+			fmt.Sprintf(`<input id="%s" aria-describedby="%s"`, id("email"), id("email-error")),
+			fmt.Sprintf(`<li aria-labelledby="%s"><span id="%s">b</span>`, id("b"), id("b")),
+		} {
+			if !strings.Contains(html.String(), want) {
+				t.Errorf("Expected %s in %s", want, html.String())
+			}
+		}
+	})
+
+	t.Run("stable across updates", func(t *testing.T) {
+		clone, err := tmpl.Clone()
+		if err != nil {
+			t.Fatalf("Clone failed: %v", err)
+		}
+
+		var initial bytes.Buffer
+		if err := clone.ExecuteUpdates(&initial, form{Email: "a@example.com"}); err != nil {
+			t.Fatalf("ExecuteUpdates failed: %v", err)
+		}
+		if !strings.Contains(initial.String(), id("email-error")) {
+			t.Fatalf("Expected a clone to render the same IDs, got %s", initial.String())
+		}
+
+		var update bytes.Buffer
+		if err := clone.ExecuteUpdates(&update, form{Email: "b@example.com"}); err != nil {
+			t.Fatalf("ExecuteUpdates failed: %v", err)
+		}
+		if strings.Contains(update.String(), tmpl.wrapperID) || !strings.Contains(update.String(), "b@example.com") {
+			t.Errorf("Expected an update of the email only, got %s", update.String())
+		}
+	})
+
+	t.Run("scoped per template", func(t *testing.T) {
+		other := New("element-id-other-test")
+		if _, err := other.Parse(source); err != nil {
+			t.Fatalf("Parse failed: %v", err)
+		}
+		var html bytes.Buffer
+		if err := other.Execute(&html, form{}); err != nil {
+			t.Fatalf("Execute failed: %v", err)
+		}
+		if strings.Contains(html.String(), id("email")) || !strings.Contains(html.String(), other.wrapperID+"-email") {
+			t.Errorf("Expected IDs scoped to the other template's wrapper, got %s", html.String())
+		}
+	})
+
+	t.Run("scoping", func(t *testing.T) {
+		scoped := tmpl.scopeElementIDs(`{{lvt_id "a"}} lvt_id {{.lvt_id}} {{printf "%s" (lvt_id "b")}}`)
+		want := fmt.Sprintf(`{{lvt_scoped_id %q "a"}} lvt_id {{.lvt_id}} {{printf "%%s" (lvt_scoped_id %q "b")}}`, tmpl.wrapperID, tmpl.wrapperID)
+		if scoped != want {
+			t.Errorf("Expected %s, got %s", want, scoped)
+		}
+		if again := tmpl.scopeElementIDs(scoped); again != scoped {
+			t.Errorf("Expected scoping to be idempotent, got %s", again)
+		}
+	})
+}
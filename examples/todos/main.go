@@ -48,7 +48,7 @@ type PaginationInput struct {
 
 type TodoState struct {
 	Title          string      `json:"title"`
-	Queries        *db.Queries `json:"-"` // Database queries (exported but not in JSON)
+	Queries        *db.Queries `json:"-" lvt:"-"` // Database queries (server-only, hidden from templates)
 	SearchQuery    string      `json:"search_query"`
 	SortBy         string      `json:"sort_by"`
 	FilteredTodos  []TodoItem  `json:"filtered_todos"`
@@ -63,7 +63,7 @@ type TodoState struct {
 }
 
 func (s *TodoState) Change(ctx *livetemplate.ActionContext) error {
-	dbCtx := context.Background()
+	dbCtx := ctx.Context()
 
 	switch ctx.Action {
 	case "add":
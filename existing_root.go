@@ -0,0 +1,101 @@
+package livetemplate
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// WithExistingRoot makes the element of the template matching selector the live root,
+// instead of a wrapper div around the template. No wrapper is injected: the root gets the
+// data-lvt-id and other wrapper attributes, and updates cover only its content. Use it when
+// a layout owns the page structure and a wrapper div would break styling or markup such as
+// <tbody> or a CSS grid:
+//
+//	tmpl := livetemplate.New("app", livetemplate.WithExistingRoot("#app"))
+//	tmpl.Parse(`<html><body><header>...</header><main id="app">{{.Count}}</main></body></html>`)
+//
+// Only ID selectors ("#app") are supported, and the id attribute must be written literally
+// in the template. Content outside the root is rendered by Execute but never updated.
+// Parse fails if the template has no element with the ID.
+//
+// Default: "" (the template is wrapped in a div)
+func WithExistingRoot(selector string) Option {
+	return func(c *Config) {
+		c.ExistingRoot = selector
+	}
+}
+
+// rootID returns the ID of the configured existing root, or "" without one
+func (t *Template) rootID() string {
+	return strings.TrimPrefix(t.config.ExistingRoot, "#")
+}
+
+// wrap adds the wrapper to text for execution: the wrapper attributes on the existing root
+// if there is one, or a wrapper div otherwise
+func (t *Template) wrap(text string, isFullHTML bool) (string, error) {
+	if t.config.ExistingRoot != "" {
+		if !strings.HasPrefix(t.config.ExistingRoot, "#") || t.rootID() == "" {
+			return "", fmt.Errorf("unsupported existing root selector %q: only #id selectors are supported", t.config.ExistingRoot)
+		}
+		start, _, ok := findRootElement(text, t.rootID())
+		if !ok {
+			return "", fmt.Errorf("existing root %q not found in template", t.config.ExistingRoot)
+		}
+		nameEnd := start + 1 + len(tagName(text[start+1:]))
+		attrs := fmt.Sprintf(` data-lvt-id="%s"%s`, t.wrapperID, t.wrapperAttrs())
+		return text[:nameEnd] + attrs + text[nameEnd:], nil
+	}
+	if isFullHTML {
+		// Inject wrapper div around body content
+		return injectWrapperDiv(text, t.wrapperID, t.wrapperAttrs())
+	}
+	// For standalone templates, wrap the entire content
+	return fmt.Sprintf(`<div data-lvt-id="%s"%s>%s</div>`, t.wrapperID, t.wrapperAttrs(), text), nil
+}
+
+// treeSource returns the template source that trees are generated from: the content of the
+// existing root, or of the body, with the formatting calls localized (see localizeCalls)
+func (t *Template) treeSource() string {
+	if id := t.rootID(); id != "" {
+		if start, openEnd, ok := findRootElement(t.templateStr, id); ok {
+			name := tagName(t.templateStr[start+1:])
+			if closeEnd, found := matchingCloseTag(t.templateStr, openEnd, name); found {
+				closeStart := strings.LastIndex(t.templateStr[:closeEnd], "<")
+				return t.localizeCalls(strings.TrimSpace(t.templateStr[openEnd:closeStart]))
+			}
+		}
+	}
+	return t.localizeCalls(extractTemplateBodyContent(t.templateStr))
+}
+
+// rootIDAttr matches an id attribute, capturing its quoted value
+var rootIDAttr = regexp.MustCompile(`\sid\s*=\s*(?:"([^"]*)"|'([^']*)')`)
+
+// findRootElement returns the bounds of the opening tag of the element of text with the
+// given id
+func findRootElement(text, id string) (start, openEnd int, ok bool) {
+	for _, m := range rootIDAttr.FindAllStringSubmatchIndex(text, -1) {
+		val := ""
+		if m[2] >= 0 {
+			val = text[m[2]:m[3]]
+		} else {
+			val = text[m[4]:m[5]]
+		}
+		if val != id {
+			continue
+		}
+		// The attribute must be inside a tag, not in text or a template action
+		start = strings.LastIndex(text[:m[0]], "<")
+		if start < 0 || strings.Contains(text[start:m[0]], ">") {
+			continue
+		}
+		name := tagName(text[start+1:])
+		end := strings.Index(text[m[1]:], ">")
+		if name == "" || end < 0 {
+			continue
+		}
+		return start, m[1] + end + 1, true
+	}
+	return 0, 0, false
+}
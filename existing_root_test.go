@@ -0,0 +1,95 @@
+package livetemplate
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestWithExistingRoot(t *testing.T) {
+	const source = `<!DOCTYPE html><html><head><title>App</title></head><body>
+<header>{{.Title}}</header>
+<main class="grid" id="app"><h1>{{.Title}}</h1><p>Count: {{.Count}}</p><section id="nested"><main>{{.Count}}</main></section></main>
+<script src="/client.js"></script>
+</body></html>`
+
+	t.Run("no wrapper", func(t *testing.T) {
+		tmpl := New("existing-root-test", WithExistingRoot("#app"), WithLoadingDisabled())
+		if _, err := tmpl.Parse(source); err != nil {
+			t.Fatalf("Parse failed: %v", err)
+		}
+
+		var html bytes.Buffer
+		if err := tmpl.Execute(&html, map[string]interface{}{"Title": "Todos", "Count": 1}); err != nil {
+			t.Fatalf("Execute failed: %v", err)
+		}
+		if strings.Contains(html.String(), "<div data-lvt-id") {
+			t.Errorf("Expected no wrapper div, got %s", html.String())
+		}
+		root := fmt.Sprintf(`<main data-lvt-id="%s" data-lvt-sv="%s" class="grid" id="app">`, tmpl.wrapperID, tmpl.StaticsVersion())
+		if !strings.Contains(html.String(), root) {
+			t.Errorf("Expected the existing root to carry the wrapper attributes %s, got %s", root, html.String())
+		}
+		if strings.Count(html.String(), "data-lvt-id") != 1 {
+			t.Errorf("Expected a single live root, got %s", html.String())
+		}
+	})
+
+	t.Run("updates target the root", func(t *testing.T) {
+		tmpl := New("existing-root-update-test", WithExistingRoot("#app"))
+		if _, err := tmpl.Parse(source); err != nil {
+			t.Fatalf("Parse failed: %v", err)
+		}
+
+		var initial bytes.Buffer
+		if err := tmpl.ExecuteUpdates(&initial, map[string]interface{}{"Title": "Todos", "Count": 1}); err != nil {
+			t.Fatalf("ExecuteUpdates failed: %v", err)
+		}
+		var tree map[string]interface{}
+		if err := json.Unmarshal(initial.Bytes(), &tree); err != nil {
+			t.Fatalf("Failed to decode the initial tree: %v", err)
+		}
+		statics := fmt.Sprint(tree["s"])
+		if strings.Contains(statics, "<header>") || strings.Contains(statics, "<script") || strings.Contains(statics, `id="app"`) {
+			t.Errorf("Expected the tree to cover only the root's content, got statics %s", statics)
+		}
+		if !strings.Contains(statics, "<h1>") || !strings.Contains(statics, "</section>") {
+			t.Errorf("Expected the root's content in the statics, got %s", statics)
+		}
+
+		var update bytes.Buffer
+		if err := tmpl.ExecuteUpdates(&update, map[string]interface{}{"Title": "Todos", "Count": 2}); err != nil {
+			t.Fatalf("ExecuteUpdates failed: %v", err)
+		}
+		var changed map[string]interface{}
+		if err := json.Unmarshal(update.Bytes(), &changed); err != nil {
+			t.Fatalf("Failed to decode the update: %v", err)
+		}
+		if len(changed) != 2 || changed["s"] != nil {
+			t.Errorf("Expected a dynamics-only update of both counts, got %s", update.String())
+		}
+		for key, value := range changed {
+			if value != "2" {
+				t.Errorf("Expected slot %s to carry the new count, got %v", key, value)
+			}
+		}
+
+		if content := extractTemplateContent(`<body><main data-lvt-id="x" id="app"><p>1</p></main></body>`, "x", tmpl.rootID()); content != "<p>1</p>" {
+			t.Errorf("Expected the content of the root, got %q", content)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		for selector, src := range map[string]string{
+			"#missing":   source,
+			".app":       source,
+			"#in-action": `<p>{{if .X}} id="in-action" {{end}}</p>`,
+		} {
+			if _, err := New("existing-root-invalid-test", WithExistingRoot(selector)).Parse(src); err == nil {
+				t.Errorf("Expected Parse to fail for root %q", selector)
+			}
+		}
+	})
+}
@@ -0,0 +1,102 @@
+package livetemplate
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// ExplainOps describes the range operations of an update, one line per op, for debugging
+// range diffs:
+//
+//	update todo-1: set field 2 to 'x'
+//	remove todo-3
+//	insert after todo-4: {"0":"Buy milk","_k":"todo-5"}
+//
+// ops are the items of a range in an update tree, either RangeOp values as produced by
+// ExecuteUpdates or the arrays of the wire format as decoded from JSON.
+func ExplainOps(ops []interface{}) string {
+	lines := make([]string, len(ops))
+	for i, op := range ops {
+		lines[i] = explainOp(op)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// explainOp describes one range operation (see ExplainOps)
+func explainOp(raw interface{}) string {
+	if rangeOp, ok := raw.(RangeOp); ok {
+		encoded, err := rangeOp.MarshalJSON()
+		if err == nil {
+			var decoded []interface{}
+			if json.Unmarshal(encoded, &decoded) == nil {
+				raw = decoded
+			}
+		}
+	}
+
+	op, ok := raw.([]interface{})
+	if !ok || len(op) == 0 {
+		return "unknown op " + explainValue(raw)
+	}
+
+	opcode, _ := op[0].(string)
+	switch {
+	case opcode == "r" && len(op) >= 2:
+		if len(op) == 3 {
+			return fmt.Sprintf("remove %v (animated)", op[1])
+		}
+		return fmt.Sprintf("remove %v", op[1])
+
+	case opcode == "u" && len(op) >= 2:
+		changes, _ := op[len(op)-1].(map[string]interface{})
+		if len(op) == 2 || len(changes) == 0 {
+			return fmt.Sprintf("update %v: no changes", op[1])
+		}
+		sets := make([]string, 0, len(changes))
+		for _, key := range sortedTreeKeys(changes) {
+			sets = append(sets, fmt.Sprintf("set field %s to %s", key, explainValue(changes[key])))
+		}
+		return fmt.Sprintf("update %v: %s", op[1], strings.Join(sets, ", "))
+
+	case opcode == "a" && len(op) >= 2:
+		items, _ := op[1].([]interface{})
+		described := make([]string, len(items))
+		for i, item := range items {
+			described[i] = explainValue(item)
+		}
+		line := fmt.Sprintf("append %d item(s): %s", len(items), strings.Join(described, ", "))
+		if len(op) == 3 {
+			line += " (with statics)"
+		}
+		return line
+
+	case opcode == "i" && len(op) == 4:
+		if op[1] == nil {
+			return fmt.Sprintf("insert at %v: %s", op[2], explainValue(op[3]))
+		}
+		return fmt.Sprintf("insert %v %v: %s", op[2], op[1], explainValue(op[3]))
+
+	case opcode == "o" && len(op) == 2:
+		keys, _ := op[1].([]interface{})
+		described := make([]string, len(keys))
+		for i, key := range keys {
+			described[i] = fmt.Sprint(key)
+		}
+		return "reorder to " + strings.Join(described, ", ")
+	}
+
+	return "unknown op " + explainValue(op)
+}
+
+// explainValue formats a value of a range operation: strings quoted as 'x', anything else as JSON
+func explainValue(value interface{}) string {
+	if s, ok := value.(string); ok {
+		return "'" + s + "'"
+	}
+	encoded, err := marshalValue(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(encoded)
+}
@@ -0,0 +1,53 @@
+package livetemplate
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestExplainOps(t *testing.T) {
+	ops := []interface{}{
+		UpdateOp{Key: "todo-1", Changes: map[string]interface{}{"2": "x", "10": 3, "1": map[string]interface{}{"0": "y"}}},
+		UpdateOp{Key: "todo-2"},
+		RemoveOp{Key: "todo-3"},
+		RemoveOp{Key: "todo-6", Animate: true},
+		InsertOp{Target: "todo-4", Position: "after", Item: map[string]interface{}{"_k": "todo-5", "0": "Buy milk"}},
+		InsertOp{Position: "start", Item: map[string]interface{}{"_k": "todo-0", "0": "First"}},
+		AppendOp{Items: []interface{}{map[string]interface{}{"_k": "todo-7", "0": "a"}, map[string]interface{}{"_k": "todo-8", "0": "b"}}, Statics: []string{"<li>", "</li>"}},
+		OrderOp{Keys: []string{"todo-5", "todo-1"}},
+	}
+	want := `update todo-1: set field 1 to {"0":"y"}, set field 2 to 'x', set field 10 to 3
+update todo-2: no changes
+remove todo-3
+remove todo-6 (animated)
+insert after todo-4: {"0":"Buy milk","_k":"todo-5"}
+insert at start: {"0":"First","_k":"todo-0"}
+append 2 item(s): {"0":"a","_k":"todo-7"}, {"0":"b","_k":"todo-8"} (with statics)
+reorder to todo-5, todo-1`
+
+	t.Run("range ops", func(t *testing.T) {
+		if got := ExplainOps(ops); got != want {
+			t.Errorf("Expected:\n%s\ngot:\n%s", want, got)
+		}
+	})
+
+	t.Run("decoded wire format", func(t *testing.T) {
+		encoded, err := json.Marshal(ops)
+		if err != nil {
+			t.Fatalf("Marshal failed: %v", err)
+		}
+		var decoded []interface{}
+		if err := json.Unmarshal(encoded, &decoded); err != nil {
+			t.Fatalf("Unmarshal failed: %v", err)
+		}
+		if got := ExplainOps(decoded); got != want {
+			t.Errorf("Expected:\n%s\ngot:\n%s", want, got)
+		}
+	})
+
+	t.Run("unknown op", func(t *testing.T) {
+		if got := ExplainOps([]interface{}{[]interface{}{"z", 1}, "todo"}); got != "unknown op [\"z\",1]\nunknown op 'todo'" {
+			t.Errorf("Expected unknown ops to be shown as is, got %q", got)
+		}
+	})
+}
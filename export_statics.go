@@ -0,0 +1,82 @@
+package livetemplate
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// exportedStatics is the document ExportStatics writes
+type exportedStatics struct {
+	StaticsVersion string   `json:"sv"`   // See StaticsVersion
+	Fingerprint    string   `json:"f"`    // Hash of Tree
+	Tree           treeNode `json:"tree"` // The initial tree with only its statics
+}
+
+// ExportStatics renders data as an initial tree and writes its statics as JSON, for a build
+// step publishing them to a CDN so clients load them without asking the app:
+//
+//	{"sv": "<StaticsVersion>", "f": "<hash of tree>", "tree": {"s": [...], "0": {"s": [...]}}}
+//
+// tree has the shape of the initial tree with every dynamic value and range item left out;
+// nested nodes keep their statics. Which branches of conditionals appear depends on data,
+// so export with data representative of what clients first see. The template's own diff
+// state is left unchanged.
+func (t *Template) ExportStatics(data interface{}, w io.Writer) error {
+	if t.tmpl == nil {
+		return fmt.Errorf("template not parsed")
+	}
+	clone, err := t.Clone()
+	if err != nil {
+		return err
+	}
+	if data == nil {
+		data = clone.defaultData
+	}
+
+	clone.mu.Lock()
+	defer clone.mu.Unlock()
+	if err := clone.freezeStaticRegions(data, nil); err != nil {
+		return err
+	}
+	tree, err := clone.generateTreeInternalWithErrors(data, nil)
+	if err != nil {
+		return fmt.Errorf("tree generation failed: %w", err)
+	}
+
+	statics := staticsOnly(tree)
+	doc := exportedStatics{
+		StaticsVersion: clone.StaticsVersion(),
+		Fingerprint:    calculateFingerprint(statics, t.hasher()),
+		Tree:           statics,
+	}
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	return enc.Encode(doc)
+}
+
+// staticsOnly returns the statics of node and of the nodes nested in it, without dynamic
+// values and range items
+func staticsOnly(node map[string]interface{}) treeNode {
+	out := make(treeNode)
+	for key, value := range node {
+		if key == "s" {
+			out[key] = value
+			continue
+		}
+		if key == "d" {
+			continue // Range items are data
+		}
+		var nested treeNode
+		switch v := value.(type) {
+		case treeNode:
+			nested = staticsOnly(v)
+		case map[string]interface{}:
+			nested = staticsOnly(v)
+		}
+		if len(nested) > 0 {
+			out[key] = nested
+		}
+	}
+	return out
+}
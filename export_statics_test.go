@@ -0,0 +1,60 @@
+package livetemplate
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestTemplate_ExportStatics(t *testing.T) {
+	tmpl := New("export-statics-test")
+	if _, err := tmpl.Parse(`<h1>{{.Title}}</h1>
+{{if .Flash}}<div class="flash">{{.Flash}}</div>{{end}}
+<ul>{{range .Items}}<li data-key="{{.ID}}">{{.Label}}</li>{{end}}</ul>`); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	data := map[string]interface{}{
+		"Title": "Dashboard-4711",
+		"Flash": "Saved-4712",
+		"Items": []map[string]string{{"ID": "item-4713", "Label": "First-4714"}},
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.ExportStatics(data, &buf); err != nil {
+		t.Fatalf("ExportStatics failed: %v", err)
+	}
+	exported := buf.String()
+
+	var doc struct {
+		StaticsVersion string                 `json:"sv"`
+		Fingerprint    string                 `json:"f"`
+		Tree           map[string]interface{} `json:"tree"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("Failed to decode the export: %v", err)
+	}
+	if doc.StaticsVersion != tmpl.StaticsVersion() || doc.Fingerprint == "" {
+		t.Errorf("Expected the statics version and a fingerprint, got %q and %q", doc.StaticsVersion, doc.Fingerprint)
+	}
+	if _, ok := doc.Tree["s"]; !ok {
+		t.Errorf("Expected the root statics in the tree, got %v", doc.Tree)
+	}
+	for _, static := range []string{"<h1>", `<div class=\"flash\">`, `<li data-key=\"`} {
+		if !strings.Contains(exported, static) {
+			t.Errorf("Expected static %q in the export, got %s", static, exported)
+		}
+	}
+	if strings.Contains(exported, "-471") {
+		t.Errorf("Expected no dynamic values in the export, got %s", exported)
+	}
+
+	// Exporting leaves the template's diff state alone: the next update is a full tree
+	var update bytes.Buffer
+	if err := tmpl.ExecuteUpdates(&update, data); err != nil {
+		t.Fatalf("ExecuteUpdates failed: %v", err)
+	}
+	if !strings.Contains(update.String(), `"s":`) {
+		t.Errorf("Expected the first update after ExportStatics to be a full tree, got %s", update.String())
+	}
+}
@@ -0,0 +1,115 @@
+package livetemplate
+
+import (
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// fallbackAttribute marks the forms added by WithProgressiveEnhancement. The client cancels
+// their submission, as the button's lvt-click already sends the action.
+const fallbackAttribute = "lvt-fallback"
+
+// fallbackFormTag opens the forms added by WithProgressiveEnhancement
+const fallbackFormTag = `<form method="post" ` + fallbackAttribute + ` style="display:contents">`
+
+// fallbackButtonPattern matches a button with an lvt-click action and its content
+var fallbackButtonPattern = regexp.MustCompile(`(?is)<button(\s[^>]*?)?\slvt-click\s*=\s*"([^"]*)"([^>]*)>.*?</button\s*>`)
+
+// fallbackSkipPattern matches the attributes of buttons left without a fallback form
+var fallbackSkipPattern = regexp.MustCompile(`(?i)\s(?:name|type)\s*=`)
+
+// fallbackDataPattern matches the lvt-data-* attributes of a button
+var fallbackDataPattern = regexp.MustCompile(`(?i)\slvt-data-([a-z0-9_-]+)\s*=\s*"([^"]*)"`)
+
+// WithProgressiveEnhancement makes pages work without JavaScript: every button with an
+// lvt-click action is wrapped in a form posting the action, with its lvt-data-* values as
+// hidden fields, to the page's URL.
+//
+//	<button lvt-click="delete" lvt-data-id="{{.ID}}">Delete</button>
+//
+// renders as
+//
+//	<form method="post" lvt-fallback style="display:contents"><input type="hidden" name="id" value="3"><button name="action" value="delete" lvt-click="delete" lvt-data-id="3">Delete</button></form>
+//
+// The handler answers such posts with a redirect back to the page (or the page itself with
+// the action's errors), while the client library keeps sending actions over its connection.
+// Buttons inside a form, or with a name or type attribute, are left as they are. With
+// WithCSRFProtection, pages rendered over HTTP add the session's token to each form as an
+// lvt-csrf field.
+func WithProgressiveEnhancement(enabled bool) Option {
+	return func(c *Config) {
+		c.ActionFallbacks = enabled
+	}
+}
+
+// addActionFallbacks wraps the lvt-click buttons of template text in forms posting their
+// action, see WithProgressiveEnhancement. Buttons already wrapped are left alone, so the
+// text of a parsed template can be parsed again.
+func addActionFallbacks(text string) string {
+	var out strings.Builder
+	last := 0
+	for _, m := range fallbackButtonPattern.FindAllStringSubmatchIndex(text, -1) {
+		button := text[m[0]:m[1]]
+		openTag := button[:strings.Index(button, ">")+1]
+		if insideForm(text[:m[0]]) || fallbackSkipPattern.MatchString(openTag) {
+			continue
+		}
+		action := text[m[4]:m[5]]
+
+		out.WriteString(text[last:m[0]])
+		out.WriteString(fallbackFormTag)
+		for _, data := range fallbackDataPattern.FindAllStringSubmatch(openTag, -1) {
+			out.WriteString(`<input type="hidden" name="` + data[1] + `" value="` + data[2] + `">`)
+		}
+		out.WriteString(`<button name="action" value="` + action + `"`)
+		out.WriteString(button[len("<button"):])
+		out.WriteString(`</form>`)
+		last = m[1]
+	}
+	if last == 0 {
+		return text
+	}
+	out.WriteString(text[last:])
+	return out.String()
+}
+
+// insideForm reports whether text, the markup before some position, leaves a form open
+func insideForm(text string) bool {
+	lower := strings.ToLower(text)
+	return strings.LastIndex(lower, "<form") > strings.LastIndex(lower, "</form")
+}
+
+// isFallbackRequest reports whether r is a form post from a browser without the client
+// library, which expects a page in response rather than a tree update
+func isFallbackRequest(r *http.Request) bool {
+	return isFormRequest(r) && !acceptsJSON(r)
+}
+
+// acceptsJSON reports whether r asks for a JSON response, as the client library does
+func acceptsJSON(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), "application/json")
+}
+
+// respondFallback answers a fallback form post: with a redirect back to the page, so a
+// reload doesn't repeat the action, or with the page showing the action's errors
+func (h *liveHandler) respondFallback(w http.ResponseWriter, r *http.Request, tmpl *Template, state *connState) {
+	if len(state.getErrors()) == 0 {
+		http.Redirect(w, r, r.URL.RequestURI(), http.StatusSeeOther)
+		return
+	}
+
+	page, err := tmpl.RenderString(h.getTemplateData(state.stores), state.getErrors())
+	if err != nil {
+		log.Printf("Fallback page render failed: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if h.csrf != nil {
+		page = injectCSRFToken(page, tmpl.wrapperID, h.csrf.token(state.groupID))
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusUnprocessableEntity)
+	_, _ = w.Write([]byte(page))
+}
@@ -0,0 +1,150 @@
+package livetemplate
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestWithProgressiveEnhancement(t *testing.T) {
+	const src = `<div><p>Count: {{.Count}}</p>` +
+		`<button lvt-click="increment" lvt-data-step="1">+</button>` +
+		`<button type="button" lvt-click="reset">Reset</button>` +
+		`<form lvt-submit="save"><button lvt-click="preview">Save</button></form></div>`
+
+	tmpl := New("fallback-test", WithProgressiveEnhancement(true))
+	if _, err := tmpl.Parse(src); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	t.Run("buttons are wrapped in forms posting the action", func(t *testing.T) {
+		page, err := tmpl.RenderString(&SlowState{})
+		if err != nil {
+			t.Fatalf("Execute failed: %v", err)
+		}
+		want := `<form method="post" lvt-fallback style="display:contents"><input type="hidden" name="step" value="1">` +
+			`<button name="action" value="increment" lvt-click="increment" lvt-data-step="1">+</button></form>`
+		if !strings.Contains(page, want) {
+			t.Errorf("Expected the button inside a fallback form, got %s", page)
+		}
+		if strings.Count(page, "lvt-fallback") != 1 {
+			t.Errorf("Expected buttons with a type or inside a form to be left alone, got %s", page)
+		}
+
+		clone, err := tmpl.Clone()
+		if err != nil {
+			t.Fatalf("Clone failed: %v", err)
+		}
+		if clonePage, _ := clone.RenderString(&SlowState{}); !strings.Contains(clonePage, want) || strings.Count(clonePage, "lvt-fallback") != 1 {
+			t.Errorf("Expected a clone to render the same forms, got %s", clonePage)
+		}
+	})
+
+	t.Run("form posts redirect back to the page", func(t *testing.T) {
+		server := httptest.NewServer(tmpl.Handle(&SlowState{}))
+		defer server.Close()
+
+		client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		}}
+		do := func(t *testing.T, req *http.Request) (*http.Response, string) {
+			t.Helper()
+			req.Header.Set("Cookie", (&http.Cookie{Name: "livetemplate-id", Value: "group-fallback"}).String())
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Fatalf("Request failed: %v", err)
+			}
+			defer resp.Body.Close()
+			body, _ := io.ReadAll(resp.Body)
+			return resp, string(body)
+		}
+
+		form := url.Values{"action": {"increment"}, "step": {"1"}}
+		req, _ := http.NewRequest(http.MethodPost, server.URL+"/?tab=1", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		resp, _ := do(t, req)
+		if resp.StatusCode != http.StatusSeeOther || resp.Header.Get("Location") != "/?tab=1" {
+			t.Fatalf("Expected a redirect back to the page, got %d %q", resp.StatusCode, resp.Header.Get("Location"))
+		}
+
+		req, _ = http.NewRequest(http.MethodGet, server.URL+"/?tab=1", nil)
+		if _, page := do(t, req); !strings.Contains(page, "Count: 1") {
+			t.Errorf("Expected the action to have run, got %s", page)
+		}
+
+		// The client library asks for JSON and still gets a tree update
+		req, _ = http.NewRequest(http.MethodPost, server.URL, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("Accept", "application/json")
+		if resp, body := do(t, req); resp.StatusCode != http.StatusOK || !strings.Contains(body, `"tree"`) {
+			t.Errorf("Expected a tree update for the client, got %d %s", resp.StatusCode, body)
+		}
+	})
+
+	t.Run("form posts carry the CSRF token", func(t *testing.T) {
+		protected := New("fallback-csrf-test", WithProgressiveEnhancement(true), WithCSRFProtection([]byte("test-secret")))
+		if _, err := protected.Parse(src); err != nil {
+			t.Fatalf("Parse failed: %v", err)
+		}
+		server := httptest.NewServer(protected.Handle(&SlowState{}))
+		defer server.Close()
+
+		client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		}}
+		do := func(t *testing.T, req *http.Request) (*http.Response, string) {
+			t.Helper()
+			req.Header.Set("Cookie", (&http.Cookie{Name: "livetemplate-id", Value: "group-fallback-csrf"}).String())
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Fatalf("Request failed: %v", err)
+			}
+			defer resp.Body.Close()
+			body, _ := io.ReadAll(resp.Body)
+			return resp, string(body)
+		}
+
+		req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+		_, page := do(t, req)
+		match := regexp.MustCompile(`<form method="post" lvt-fallback style="display:contents"><input type="hidden" name="lvt-csrf" value="([0-9a-f]+)">`).FindStringSubmatch(page)
+		if match == nil {
+			t.Fatalf("Expected the fallback form to carry a CSRF token, got %s", page)
+		}
+
+		post := func(t *testing.T, token string) *http.Response {
+			form := url.Values{"action": {"increment"}, "step": {"1"}}
+			if token != "" {
+				form.Set("lvt-csrf", token)
+			}
+			req, _ := http.NewRequest(http.MethodPost, server.URL, strings.NewReader(form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			resp, _ := do(t, req)
+			return resp
+		}
+		if resp := post(t, ""); resp.StatusCode != http.StatusForbidden {
+			t.Errorf("Expected a post without the token to be rejected, got %d", resp.StatusCode)
+		}
+		if resp := post(t, match[1]); resp.StatusCode != http.StatusSeeOther {
+			t.Fatalf("Expected the form post to be accepted, got %d", resp.StatusCode)
+		}
+
+		req, _ = http.NewRequest(http.MethodGet, server.URL, nil)
+		if _, page := do(t, req); !strings.Contains(page, "Count: 1") {
+			t.Errorf("Expected the action to have run, got %s", page)
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		plain := New("fallback-disabled-test")
+		if _, err := plain.Parse(src); err != nil {
+			t.Fatalf("Parse failed: %v", err)
+		}
+		if page, _ := plain.RenderString(&SlowState{}); strings.Contains(page, "lvt-fallback") {
+			t.Errorf("Expected no fallback forms without the option, got %s", page)
+		}
+	})
+}
@@ -0,0 +1,89 @@
+package livetemplate
+
+import (
+	"reflect"
+	"strings"
+)
+
+// fieldWatch is a callback registered with WithFieldWatch
+type fieldWatch struct {
+	path []string
+	fn   func(old, new interface{})
+}
+
+// WithFieldWatch calls fn whenever the value at path differs from the previous render of
+// the template, e.g. for audit logs:
+//
+//	WithFieldWatch(".Counter", func(old, new interface{}) {
+//	    log.Printf("counter changed from %v to %v", old, new)
+//	})
+//
+// path is a field path into the data passed to Execute or ExecuteUpdates, with struct fields
+// and map keys separated by dots; in multi-store mode it starts with the store name
+// (".cart.Total"). The first render only records the value. Values are compared with
+// reflect.DeepEqual against the value last seen, so watch fields that are replaced rather
+// than mutated in place: a slice appended to within its capacity or a pointer's target
+// changing goes unnoticed.
+//
+// The live handler renders a template per connection, so fn runs once per connection
+// showing the change. It runs synchronously during rendering and must not block.
+func WithFieldWatch(path string, fn func(old, new interface{})) Option {
+	return func(c *Config) {
+		c.fieldWatches = append(c.fieldWatches, fieldWatch{
+			path: strings.Split(strings.TrimPrefix(path, "."), "."),
+			fn:   fn,
+		})
+	}
+}
+
+// checkFieldWatches calls the watches whose value in data changed since the last render
+func (t *Template) checkFieldWatches(data interface{}) {
+	if len(t.config.fieldWatches) == 0 {
+		return
+	}
+	if t.watchedValues == nil {
+		t.watchedValues = make(map[int]interface{}, len(t.config.fieldWatches))
+	}
+
+	for i, watch := range t.config.fieldWatches {
+		value := lookupFieldPath(data, watch.path)
+		old, seen := t.watchedValues[i]
+		t.watchedValues[i] = value
+		if seen && !reflect.DeepEqual(old, value) {
+			watch.fn(old, value)
+		}
+	}
+}
+
+// lookupFieldPath returns the value at path in data, following pointers, struct fields and
+// string map keys, or nil if the path doesn't exist
+func lookupFieldPath(data interface{}, path []string) interface{} {
+	v := reflect.ValueOf(data)
+	for _, name := range path {
+		for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+			if v.IsNil() {
+				return nil
+			}
+			v = v.Elem()
+		}
+
+		switch v.Kind() {
+		case reflect.Struct:
+			v = v.FieldByName(name)
+		case reflect.Map:
+			if v.Type().Key().Kind() != reflect.String {
+				return nil
+			}
+			v = v.MapIndex(reflect.ValueOf(name).Convert(v.Type().Key()))
+		default:
+			return nil
+		}
+		if !v.IsValid() || !v.CanInterface() {
+			return nil
+		}
+	}
+	if !v.IsValid() {
+		return nil
+	}
+	return v.Interface()
+}
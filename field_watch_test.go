@@ -0,0 +1,91 @@
+package livetemplate
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWithFieldWatch(t *testing.T) {
+	type change struct{ old, new interface{} }
+	var counterChanges, nameChanges []change
+
+	type Profile struct{ Name string }
+	type CounterPage struct {
+		Counter int
+		Title   string
+		Profile *Profile
+	}
+
+	tmpl := New("field-watch-test",
+		WithFieldWatch(".Counter", func(old, new interface{}) {
+			counterChanges = append(counterChanges, change{old, new})
+		}),
+		WithFieldWatch(".Profile.Name", func(old, new interface{}) {
+			nameChanges = append(nameChanges, change{old, new})
+		}),
+	)
+	if _, err := tmpl.Parse(`<h1>{{.Title}}</h1><p>{{.Counter}}</p>`); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	page := &CounterPage{Title: "Counter", Profile: &Profile{Name: "alice"}}
+	render := func() {
+		t.Helper()
+		var buf bytes.Buffer
+		if err := tmpl.ExecuteUpdates(&buf, page); err != nil {
+			t.Fatalf("ExecuteUpdates failed: %v", err)
+		}
+	}
+
+	render()
+	if len(counterChanges) != 0 {
+		t.Fatalf("Expected no callback on the first render, got %v", counterChanges)
+	}
+
+	page.Counter++
+	render()
+	if len(counterChanges) != 1 || counterChanges[0] != (change{0, 1}) {
+		t.Errorf("Expected one change from 0 to 1, got %v", counterChanges)
+	}
+
+	page.Title = "Renamed"
+	render()
+	if len(counterChanges) != 1 {
+		t.Errorf("Expected no callback when another field changes, got %v", counterChanges)
+	}
+
+	// Fields the template doesn't render can be watched as well
+	page.Profile = &Profile{Name: "bob"}
+	render()
+	if len(nameChanges) != 1 || nameChanges[0] != (change{"alice", "bob"}) {
+		t.Errorf("Expected a name change from alice to bob, got %v", nameChanges)
+	}
+}
+
+func TestLookupFieldPath(t *testing.T) {
+	type Inner struct{ Value int }
+	data := map[string]interface{}{
+		"cart": &struct {
+			Total int
+			Inner Inner
+			Tags  map[string]string
+		}{Total: 42, Inner: Inner{Value: 7}, Tags: map[string]string{"color": "red"}},
+	}
+
+	tests := []struct {
+		path []string
+		want interface{}
+	}{
+		{[]string{"cart", "Total"}, 42},
+		{[]string{"cart", "Inner", "Value"}, 7},
+		{[]string{"cart", "Tags", "color"}, "red"},
+		{[]string{"cart", "Missing"}, nil},
+		{[]string{"cart", "Total", "Deeper"}, nil},
+		{[]string{"other"}, nil},
+	}
+	for _, tt := range tests {
+		if got := lookupFieldPath(data, tt.path); got != tt.want {
+			t.Errorf("lookupFieldPath(%v) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package livetemplate
+
+import (
+	"reflect"
+	"slices"
+)
+
+// RangeDiff is the change between two versions of a keyed list, as computed by
+// DiffFilteredRange
+type RangeDiff struct {
+	Removed []string // Keys of the previous list missing from the next, in previous order
+	Added   []string // Keys of the next list missing from the previous, in next order
+	Moved   bool     // Whether the kept items changed their relative order
+
+	prev, next []string
+}
+
+// RangeDiffer is an optional interface for stores that compute how their ranged-over lists
+// changed, e.g. when filtering, so updates use that instead of matching the items of the
+// last render. RangeDiffs returns the diffs by field path as written in the template
+// without the leading dot, like WithRangeItemLifecycle.
+type RangeDiffer interface {
+	RangeDiffs() map[string]RangeDiff
+}
+
+// DiffFilteredRange computes the change from prev to next, with items identified by keyFn.
+// The keys must be the values of the items' key attribute (data-key) in the template, so
+// the diff can be applied to the range:
+//
+//	func (s *TodoState) applyFilter() {
+//	    next := s.filter(s.Todos)
+//	    s.diff = livetemplate.DiffFilteredRange(s.FilteredTodos, next, func(t Todo) string { return t.ID })
+//	    s.FilteredTodos = next
+//	}
+//
+//	func (s *TodoState) RangeDiffs() map[string]livetemplate.RangeDiff {
+//	    return map[string]livetemplate.RangeDiff{"FilteredTodos": s.diff}
+//	}
+//
+// A diff is only used while the range's items still have the keys of prev and next, so a
+// diff left over from an earlier change is ignored. Kept items are still compared, so
+// changes to them are sent either way.
+func DiffFilteredRange[T any](prev, next []T, keyFn func(T) string) RangeDiff {
+	diff := RangeDiff{prev: make([]string, len(prev)), next: make([]string, len(next))}
+	for i, item := range prev {
+		diff.prev[i] = keyFn(item)
+	}
+	for i, item := range next {
+		diff.next[i] = keyFn(item)
+	}
+
+	inPrev := make(map[string]bool, len(prev))
+	for _, key := range diff.prev {
+		inPrev[key] = true
+	}
+	inNext := make(map[string]bool, len(next))
+	for _, key := range diff.next {
+		inNext[key] = true
+	}
+
+	var keptPrev, keptNext []string
+	for _, key := range diff.prev {
+		if inNext[key] {
+			keptPrev = append(keptPrev, key)
+		} else {
+			diff.Removed = append(diff.Removed, key)
+		}
+	}
+	for _, key := range diff.next {
+		if inPrev[key] {
+			keptNext = append(keptNext, key)
+		} else {
+			diff.Added = append(diff.Added, key)
+		}
+	}
+	diff.Moved = !slices.Equal(keptPrev, keptNext)
+	return diff
+}
+
+// collectRangeDiffs records the diffs of data's stores for the next render, tracking the
+// fields of range nodes to look them up
+func (t *Template) collectRangeDiffs(data interface{}) {
+	t.rangeDiffs = nil
+	add := func(prefix string, value interface{}) {
+		differ, ok := value.(RangeDiffer)
+		if !ok {
+			return
+		}
+		for field, diff := range differ.RangeDiffs() {
+			if t.rangeDiffs == nil {
+				t.rangeDiffs = make(map[string]RangeDiff)
+			}
+			t.rangeDiffs[prefix+field] = diff
+		}
+	}
+
+	add("", data)
+	if stores, ok := data.(map[string]interface{}); ok {
+		for name, store := range stores {
+			add(name+".", store)
+		}
+	}
+
+	if len(t.rangeDiffs) > 0 && t.keyGen != nil && !t.keyGen.trackRanges {
+		t.keyGen.trackRanges = true
+		t.keyGen.rangeFields = make(map[uintptr]string)
+	}
+}
+
+// rangeOperations returns the operations updating the range oldValue to newValue: those of
+// the store's RangeDiff for the range if it applies, else those of matching the items
+func (t *Template) rangeOperations(oldValue, newValue interface{}, stripStatics bool) []interface{} {
+	if ops, ok := t.rangeDiffOperations(oldValue, newValue, stripStatics); ok {
+		return ops
+	}
+	return generateRangeDifferentialOperations(oldValue, newValue, stripStatics, t.hasher())
+}
+
+// rangeDiffOperations builds the operations of the RangeDiff recorded for the range, if
+// the keys of its items are those the diff was computed from
+func (t *Template) rangeDiffOperations(oldValue, newValue interface{}, stripStatics bool) ([]interface{}, bool) {
+	if len(t.rangeDiffs) == 0 {
+		return nil, false
+	}
+	field, ok := t.keyGen.rangeFieldOf(newValue)
+	if !ok {
+		return nil, false
+	}
+	diff, ok := t.rangeDiffs[field]
+	if !ok || len(diff.prev) == 0 {
+		return nil, false
+	}
+
+	oldKeys := t.rangeItemKeys(oldValue)
+	newKeys := t.rangeItemKeys(newValue)
+	if !slices.Equal(oldKeys, diff.prev) || !slices.Equal(newKeys, diff.next) {
+		return nil, false
+	}
+
+	oldNode, newNode := rangeNodeMap(oldValue), rangeNodeMap(newValue)
+	oldItems, _ := oldNode["d"].([]interface{})
+	newItems, _ := newNode["d"].([]interface{})
+	oldByKey := make(map[string]interface{}, len(oldItems))
+	for i, key := range oldKeys {
+		oldByKey[key] = oldItems[i]
+	}
+
+	var operations []interface{}
+	for _, key := range diff.Removed {
+		operations = append(operations, RemoveOp{Key: key})
+	}
+	for i, key := range newKeys {
+		oldItem, kept := oldByKey[key]
+		if !kept || reflect.DeepEqual(oldItem, newItems[i]) {
+			continue
+		}
+		if changes := compareRangeItemsForChanges(oldItem, newItems[i], newNode["s"]); len(changes) > 0 {
+			operations = append(operations, UpdateOp{Key: key, Changes: changes})
+		}
+	}
+	for i, key := range newKeys {
+		if _, kept := oldByKey[key]; kept {
+			continue
+		}
+		if i == 0 {
+			operations = append(operations, InsertOp{Position: "start", Item: newItems[i]})
+		} else {
+			operations = append(operations, InsertOp{Target: newKeys[i-1], Position: "after", Item: newItems[i]})
+		}
+	}
+	if diff.Moved {
+		operations = append(operations, OrderOp{Keys: newKeys})
+	}
+	if len(operations) == 0 {
+		return nil, false
+	}
+
+	if stripStatics {
+		for i, op := range operations {
+			operations[i] = stripStaticsRecursively(op)
+		}
+	}
+	return operations, true
+}
+
+// rangeNodeMap returns a range node as a map
+func rangeNodeMap(value interface{}) map[string]interface{} {
+	if node, ok := value.(treeNode); ok {
+		return node
+	}
+	node, _ := value.(map[string]interface{})
+	return node
+}
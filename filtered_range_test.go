@@ -0,0 +1,94 @@
+package livetemplate
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type filterItem struct {
+	ID    string
+	Label string
+}
+
+type filterState struct {
+	All     []filterItem
+	Visible []filterItem
+	diff    RangeDiff
+}
+
+func (s *filterState) Change(ctx *ActionContext) error { return nil }
+
+func (s *filterState) RangeDiffs() map[string]RangeDiff {
+	return map[string]RangeDiff{"Visible": s.diff}
+}
+
+// show filters All to the items with the given IDs, in All's order
+func (s *filterState) show(ids ...string) {
+	var next []filterItem
+	for _, item := range s.All {
+		for _, id := range ids {
+			if item.ID == id {
+				next = append(next, item)
+			}
+		}
+	}
+	s.diff = DiffFilteredRange(s.Visible, next, func(item filterItem) string { return item.ID })
+	s.Visible = next
+}
+
+func TestDiffFilteredRange(t *testing.T) {
+	key := func(s string) string { return s }
+	diff := DiffFilteredRange([]string{"a", "b", "c", "d"}, []string{"a", "c", "e"}, key)
+	if !reflect.DeepEqual(diff.Removed, []string{"b", "d"}) || !reflect.DeepEqual(diff.Added, []string{"e"}) || diff.Moved {
+		t.Errorf("Unexpected diff %+v", diff)
+	}
+	if diff := DiffFilteredRange([]string{"a", "b", "c"}, []string{"c", "a"}, key); !diff.Moved {
+		t.Errorf("Expected kept items changing order to be reported as moved, got %+v", diff)
+	}
+}
+
+func TestRangeDiffer(t *testing.T) {
+	tmpl := New("filtered-range-test")
+	if _, err := tmpl.Parse(`<ul>{{range .Visible}}<li data-key="{{.ID}}">{{.Label}}</li>{{end}}</ul>`); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	state := &filterState{All: []filterItem{{"1", "one"}, {"2", "two"}, {"3", "three"}, {"4", "four"}, {"5", "five"}}}
+	state.show("1", "2", "3", "4", "5")
+
+	render := func() string {
+		t.Helper()
+		var buf bytes.Buffer
+		if err := tmpl.ExecuteUpdates(&buf, state); err != nil {
+			t.Fatalf("ExecuteUpdates failed: %v", err)
+		}
+		return buf.String()
+	}
+	render()
+
+	state.show("1", "3", "5")
+	if got, want := render(), `{"0":[["r","2"],["r","4"]]}`; got != want {
+		t.Errorf("Hiding items: got %s, want %s", got, want)
+	}
+
+	state.show("1", "2", "3", "5")
+	if got, want := render(), `{"0":[["i","1","after",{"0":"2","1":"two"}]]}`; got != want {
+		t.Errorf("Showing an item: got %s, want %s", got, want)
+	}
+
+	// A kept item changing is still sent, and a stale diff is ignored
+	state.Visible[0].Label = "uno"
+	update := render()
+	if !strings.Contains(update, `["u","1",{"1":"uno"}]`) || strings.Contains(update, `"i"`) {
+		t.Errorf("Expected only the changed item to update, got %s", update)
+	}
+
+	// Hiding and reordering at once: matching the items alone misses the new order
+	next := []filterItem{state.Visible[3], state.Visible[2], state.Visible[0]}
+	state.diff = DiffFilteredRange(state.Visible, next, func(item filterItem) string { return item.ID })
+	state.Visible = next
+	if got, want := render(), `{"0":[["r","2"],["o",["5","3","1"]]]}`; got != want {
+		t.Errorf("Hiding and reordering: got %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,84 @@
+package livetemplate
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Fragment is the rendered HTML of one live region of a page
+type Fragment struct {
+	ID   string // Wrapper ID of the region (data-lvt-id)
+	HTML string // Content of the wrapper div, without the div itself
+}
+
+// RenderFragments renders data as plain HTML for each live region of the page, for search
+// engines and clients without JavaScript. A template has a single live region, its wrapper.
+//
+// Unlike Execute it bypasses the tree format and leaves the diff state alone, so it can be
+// called at any time without affecting the updates of live clients. Returns ctx's error if
+// ctx is done before rendering.
+func (t *Template) RenderFragments(ctx context.Context, data interface{}) ([]Fragment, error) {
+	if t.tmpl == nil {
+		return nil, fmt.Errorf("template not parsed")
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if data == nil {
+		data = t.defaultData
+	}
+
+	htmlBytes, err := executeTemplateWithContext(t.tmpl, data, t.templateContext(make(map[string]string)))
+	if err != nil {
+		return nil, err
+	}
+
+	fragment, err := wrapperInnerHTML(string(htmlBytes), t.wrapperID)
+	if err != nil {
+		return nil, err
+	}
+	return []Fragment{{ID: t.wrapperID, HTML: fragment}}, nil
+}
+
+// RenderFragmentsHTML is RenderFragments keyed by the regions' wrapper IDs:
+//
+//	fragments, _ := tmpl.RenderFragmentsHTML(state)
+//	body := fragments[wrapperID]
+func (t *Template) RenderFragmentsHTML(data interface{}) (map[string]string, error) {
+	fragments, err := t.RenderFragments(context.Background(), data)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[string]string, len(fragments))
+	for _, fragment := range fragments {
+		byID[fragment.ID] = fragment.HTML
+	}
+	return byID, nil
+}
+
+// wrapperInnerHTML returns the content of the wrapper div with wrapperID in htmlDoc, escaped
+// as HTML. Unlike extractTemplateContent, which normalizes content for diffing, the result
+// is safe to serve as-is.
+func wrapperInnerHTML(htmlDoc, wrapperID string) (string, error) {
+	doc, err := html.Parse(strings.NewReader(htmlDoc))
+	if err != nil {
+		return "", fmt.Errorf("failed to parse rendered HTML: %w", err)
+	}
+
+	wrapper := findElementByAttr(doc, "data-lvt-id", wrapperID)
+	if wrapper == nil {
+		return "", fmt.Errorf("wrapper %q not found in rendered HTML", wrapperID)
+	}
+
+	var buf bytes.Buffer
+	for child := wrapper.FirstChild; child != nil; child = child.NextSibling {
+		if err := html.Render(&buf, child); err != nil {
+			return "", fmt.Errorf("failed to render fragment: %w", err)
+		}
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,122 @@
+package livetemplate
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestTemplate_RenderFragmentsHTML(t *testing.T) {
+	type Item struct {
+		ID   string
+		Name string
+	}
+	type State struct {
+		Title string
+		Items []Item
+	}
+
+	templates := map[string]string{
+		"standalone": `<h1>{{.Title}}</h1><ul>{{range .Items}}<li data-key="{{.ID}}">{{.Name}}</li>{{end}}</ul>`,
+		"full document": `<!DOCTYPE html><html><head><title>{{.Title}}</title></head>` +
+			`<body><h1>{{.Title}}</h1><ul>{{range .Items}}<li data-key="{{.ID}}">{{.Name}}</li>{{end}}</ul></body></html>`,
+	}
+	state := State{Title: "Tools & <parts>", Items: []Item{{"1", "Hammer"}, {"2", "Saw"}}}
+
+	for name, src := range templates {
+		t.Run(name, func(t *testing.T) {
+			tmpl := New("fragments-test")
+			if _, err := tmpl.Parse(src); err != nil {
+				t.Fatalf("Parse failed: %v", err)
+			}
+
+			fragments, err := tmpl.RenderFragmentsHTML(state)
+			if err != nil {
+				t.Fatalf("RenderFragmentsHTML failed: %v", err)
+			}
+			if len(fragments) != 1 {
+				t.Fatalf("Expected one fragment, got %v", fragments)
+			}
+			fragment, ok := fragments[tmpl.wrapperID]
+			if !ok {
+				t.Fatalf("Expected fragment keyed by wrapper ID %q, got %v", tmpl.wrapperID, fragments)
+			}
+
+			// The fragment is the rendered content of the wrapper
+			var buf bytes.Buffer
+			if err := tmpl.Execute(&buf, state); err != nil {
+				t.Fatalf("Execute failed: %v", err)
+			}
+			rendered := buf.String()
+			start := strings.Index(rendered, `data-lvt-id="`+tmpl.wrapperID+`"`)
+			if start < 0 {
+				t.Fatalf("Wrapper not found in %s", rendered)
+			}
+			start += strings.Index(rendered[start:], ">") + 1
+			end := strings.LastIndex(rendered, "</div>")
+			if region := rendered[start:end]; fragment != region {
+				t.Errorf("Fragment does not match the rendered region\nfragment: %s\nregion:   %s", fragment, region)
+			}
+
+			if !strings.Contains(fragment, "<h1>Tools &amp; &lt;parts&gt;</h1>") {
+				t.Errorf("Expected escaped title in fragment, got %s", fragment)
+			}
+			if strings.Contains(fragment, "data-lvt-id") {
+				t.Errorf("Fragment should not include the wrapper, got %s", fragment)
+			}
+		})
+	}
+
+	t.Run("leaves diff state alone", func(t *testing.T) {
+		tmpl := New("fragments-state-test")
+		if _, err := tmpl.Parse(templates["standalone"]); err != nil {
+			t.Fatalf("Parse failed: %v", err)
+		}
+		if _, err := tmpl.RenderFragmentsHTML(state); err != nil {
+			t.Fatalf("RenderFragmentsHTML failed: %v", err)
+		}
+
+		var buf bytes.Buffer
+		if err := tmpl.ExecuteUpdates(&buf, state); err != nil {
+			t.Fatalf("ExecuteUpdates failed: %v", err)
+		}
+		if !strings.Contains(buf.String(), `"s"`) {
+			t.Errorf("First update after RenderFragmentsHTML should still be a full tree, got %s", buf.String())
+		}
+	})
+}
+
+func TestTemplate_RenderFragments(t *testing.T) {
+	tmpl := New("render-fragments-test")
+	if _, err := tmpl.Parse(`<h1>{{.Title}}</h1>{{if .Flash}}<p>{{.Flash}}</p>{{end}}`); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	data := map[string]interface{}{"Title": "Inbox", "Flash": "Saved"}
+
+	fragments, err := tmpl.RenderFragments(context.Background(), data)
+	if err != nil {
+		t.Fatalf("RenderFragments failed: %v", err)
+	}
+	byID, err := tmpl.RenderFragmentsHTML(data)
+	if err != nil {
+		t.Fatalf("RenderFragmentsHTML failed: %v", err)
+	}
+	if len(fragments) != len(byID) {
+		t.Fatalf("Expected as many fragments as RenderFragmentsHTML, got %v and %v", fragments, byID)
+	}
+	for _, fragment := range fragments {
+		if byID[fragment.ID] != fragment.HTML {
+			t.Errorf("Fragment %s = %q, RenderFragmentsHTML has %q", fragment.ID, fragment.HTML, byID[fragment.ID])
+		}
+	}
+	if want := "<h1>Inbox</h1><p>Saved</p>"; fragments[0].HTML != want || fragments[0].ID != tmpl.wrapperID {
+		t.Errorf("Fragment = %+v, want %q in wrapper %s", fragments[0], want, tmpl.wrapperID)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := tmpl.RenderFragments(ctx, data); err != context.Canceled {
+		t.Errorf("Expected context.Canceled for a done context, got %v", err)
+	}
+}
@@ -0,0 +1,61 @@
+package livetemplate
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// WithIdleTimeout closes WebSocket connections after d without any message read from or
+// written to them, freeing what a silent client holds on the server. Broadcasts count as
+// activity, so a page receiving updates stays connected. A closed client reconnects when
+// it becomes active again (see WithResumeWindow). The connection is closed with
+// CloseIdleTimeout (4408).
+//
+// This applies to WebSocket connections only. Server-sent event streams read no messages,
+// so they stay open until the client goes away.
+//
+// The last activity of each connection is reported by ConnectionStats.
+//
+// Default: 0 (connections are never closed for inactivity)
+func WithIdleTimeout(d time.Duration) Option {
+	return func(c *Config) {
+		c.IdleTimeout = d
+	}
+}
+
+// touch records activity on the connection
+func (c *Connection) touch() {
+	c.mu.Lock()
+	c.lastActivity = time.Now()
+	c.mu.Unlock()
+}
+
+// idleFor returns how long the connection has been without activity
+func (c *Connection) idleFor() time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return time.Since(c.lastActivity)
+}
+
+// closeWhenIdle closes c once it has been idle for timeout, until ctx is done. Closing the
+// connection ends its read loop and with it the handler.
+func (c *Connection) closeWhenIdle(ctx context.Context, timeout time.Duration) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+			idle := c.idleFor()
+			if idle < timeout {
+				timer.Reset(timeout - idle)
+				continue
+			}
+			log.Printf("Closing connection in group %s: idle for %v", c.GroupID, idle.Round(time.Millisecond))
+			c.closeWith(CloseIdleTimeout, "idle timeout")
+			return
+		}
+	}
+}
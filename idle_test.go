@@ -0,0 +1,44 @@
+package livetemplate
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWithIdleTimeout(t *testing.T) {
+	const timeout = 200 * time.Millisecond
+
+	tmpl := New("idle-timeout-test", WithIdleTimeout(timeout))
+	if _, err := tmpl.Parse("<p>Count: {{.Count}}</p>"); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	handler := tmpl.Handle(&SlowState{})
+
+	idle := dialTestHandler(t, handler)
+	active := dialTestHandler(t, handler)
+
+	// Keep one connection busy for several timeouts while the other stays silent
+	deadline := time.Now().Add(3 * timeout)
+	for time.Now().Before(deadline) {
+		sendAction(t, active, "increment", nil)
+		time.Sleep(timeout / 4)
+	}
+
+	_ = idle.SetReadDeadline(time.Now().Add(time.Second))
+	if _, _, err := idle.ReadMessage(); err == nil {
+		t.Errorf("Expected the idle connection to be closed")
+	}
+
+	// The active connection still gets updates
+	if response := sendAction(t, active, "increment", nil); response.Meta == nil || !response.Meta.Success {
+		t.Errorf("Expected the active connection to stay open, got %+v", response)
+	}
+
+	stats := handler.ConnectionStats()
+	if len(stats) != 1 {
+		t.Fatalf("Expected only the active connection to remain, got %d", len(stats))
+	}
+	if since := time.Since(stats[0].LastActivity); since > timeout {
+		t.Errorf("Expected recent activity on the active connection, last was %v ago", since)
+	}
+}
@@ -0,0 +1,76 @@
+package livetemplate
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"sort"
+	"text/template/parse"
+)
+
+// lvtValue implements {{lvt_value .Field}}: it outputs its argument unchanged and marks the
+// slot as bound to a form control's value, e.g.
+//
+//	<textarea name="text">{{lvt_value .ComposerText}}</textarea>
+//	<input name="title" value="{{lvt_value .Title}}">
+//
+// Updates that change an input-bound slot list its path in ResponseMetadata.InputBound, so
+// the client can merge the new value into a focused control without moving the caret.
+// Slots inside ranges are not flagged.
+func lvtValue(value interface{}) interface{} {
+	return value
+}
+
+// inputBoundValue is the rendered value of an input-bound slot in a tree.
+// It marshals as a plain string; the type only lets inputBoundPaths find the slot.
+type inputBoundValue string
+
+// handleInputBoundAction renders a {{lvt_value ...}} action as an input-bound dynamic
+func handleInputBoundAction(node *parse.ActionNode, data interface{}) (treeNode, error) {
+	tmpl, err := template.New("value").Funcs(builtinFuncs).Parse(node.String())
+	if err != nil {
+		return nil, fmt.Errorf("action parse error: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("action execute error: %w", err)
+	}
+
+	return treeNode{
+		"s": []string{"", ""},
+		"0": inputBoundValue(buf.String()),
+	}, nil
+}
+
+// inputBoundPaths returns the dot-separated paths (e.g. "2" or "1.0") of the input-bound
+// slots in tree, sorted. Range items are not searched.
+func inputBoundPaths(tree map[string]interface{}) []string {
+	var paths []string
+	collectInputBoundPaths(tree, "", &paths)
+	sort.Strings(paths)
+	return paths
+}
+
+// collectInputBoundPaths walks nested tree nodes for inputBoundPaths
+func collectInputBoundPaths(tree map[string]interface{}, prefix string, paths *[]string) {
+	for key, value := range tree {
+		if key == "s" || key == "f" || key == "d" {
+			continue
+		}
+
+		path := key
+		if prefix != "" {
+			path = prefix + "." + key
+		}
+
+		switch v := value.(type) {
+		case inputBoundValue:
+			*paths = append(*paths, path)
+		case treeNode:
+			collectInputBoundPaths(v, path, paths)
+		case map[string]interface{}:
+			collectInputBoundPaths(v, path, paths)
+		}
+	}
+}
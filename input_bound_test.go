@@ -0,0 +1,83 @@
+package livetemplate
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLvtValue(t *testing.T) {
+	type Composer struct {
+		Title        string
+		ComposerText string
+	}
+
+	tmpl := New("input-bound-test")
+	_, err := tmpl.Parse(`<h1>{{.Title}}</h1><form><textarea name="text">{{lvt_value .ComposerText}}</textarea></form>`)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.ExecuteUpdates(&buf, &Composer{Title: "Chat", ComposerText: "Hel"}); err != nil {
+		t.Fatalf("ExecuteUpdates failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), `"1":"Hel"`) {
+		t.Errorf("Input-bound value should render as a plain string, got %s", buf.String())
+	}
+	if got, want := tmpl.InputBoundSlots(), []string{"1"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("InputBoundSlots() = %v, want %v", got, want)
+	}
+
+	t.Run("flagged when changed", func(t *testing.T) {
+		buf.Reset()
+		if err := tmpl.ExecuteUpdates(&buf, &Composer{Title: "Chat", ComposerText: "Hello"}); err != nil {
+			t.Fatalf("ExecuteUpdates failed: %v", err)
+		}
+		if buf.String() != `{"1":"Hello"}` {
+			t.Errorf("Expected only the composer value, got %s", buf.String())
+		}
+		if got, want := tmpl.InputBoundSlots(), []string{"1"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("InputBoundSlots() = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("not flagged when unchanged", func(t *testing.T) {
+		buf.Reset()
+		if err := tmpl.ExecuteUpdates(&buf, &Composer{Title: "Chat room", ComposerText: "Hello"}); err != nil {
+			t.Fatalf("ExecuteUpdates failed: %v", err)
+		}
+		if slots := tmpl.InputBoundSlots(); len(slots) != 0 {
+			t.Errorf("Expected no input-bound slots, got %v", slots)
+		}
+	})
+}
+
+// ComposerState is a test store whose "type" action sets the composer text
+type ComposerState struct {
+	Text string
+}
+
+func (s *ComposerState) Change(ctx *ActionContext) error {
+	if ctx.Action == "type" {
+		s.Text = ctx.GetString("text")
+	}
+	return nil
+}
+
+func TestLiveHandler_InputBoundMeta(t *testing.T) {
+	tmpl := New("input-bound-handler-test")
+	if _, err := tmpl.Parse(`<input name="text" value="{{lvt_value .Text}}">`); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	conn := dialTestHandler(t, tmpl.Handle(&ComposerState{}))
+	response := sendAction(t, conn, "type", map[string]interface{}{"text": "Hi there"})
+	if response.Meta == nil {
+		t.Fatal("Expected response metadata")
+	}
+	if got, want := response.Meta.InputBound, []string{"0"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected input-bound slots %v in meta, got %v", want, got)
+	}
+}
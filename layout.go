@@ -0,0 +1,147 @@
+package livetemplate
+
+import (
+	"fmt"
+	"log"
+	"sync"
+)
+
+// layoutContentBlock is the block of a layout that WithLayout fills with the page
+const layoutContentBlock = "content"
+
+// WithLayout returns a template rendering t as the "content" block of layout, so many pages
+// can share one layout parsed once:
+//
+//	layout.Parse(`<!DOCTYPE html><html><body><nav>...</nav>{{block "content" .}}{{end}}</body></html>`)
+//	home := homePage.WithLayout(layout)
+//	about := aboutPage.WithLayout(layout)
+//
+// The pages of a layout share the wrapper and the diff state of one client: after home
+// renders, ExecuteUpdates on about diffs against home, so a navigation only sends the page
+// region while the client keeps the layout's statics. Connections navigating independently
+// each need their own layout, e.g. a Clone. Pages are fragments, not full HTML documents.
+//
+// A layout without a "content" block, or a page that doesn't fit it, is logged like a parse
+// error in New and leaves the returned template unparsed.
+func (t *Template) WithLayout(layout *Template) *Template {
+	analyzer := NewTreeUpdateAnalyzer()
+	analyzer.Enabled = t.config.DevMode
+
+	composed := &Template{
+		name:        t.name,
+		wrapperID:   layout.wrapperID, // Pages of a layout patch the same wrapper
+		keyGen:      newKeyGeneratorFor(t.config),
+		config:      t.config,
+		analyzer:    analyzer,
+		baselines:   layout.baselines,
+		defaultData: t.defaultData,
+		navigation:  layout.pageNavigation(),
+	}
+
+	if err := composed.parseLayout(layout, t); err != nil {
+		log.Printf("Warning: failed to render %q with layout %q: %v", t.name, layout.name, err)
+	}
+	return composed
+}
+
+// parseLayout parses the sources of layout with page as its content block. The page is
+// wrapped in a conditional so it becomes one node of the tree: pages of the layout then
+// differ in that node only, not in the layout's statics.
+func (t *Template) parseLayout(layout, page *Template) error {
+	if len(layout.sources) == 0 || page.tmpl == nil {
+		return fmt.Errorf("layout and page must be parsed first")
+	}
+
+	declared := false
+	for _, source := range layout.sources {
+		for _, match := range blockDeclPattern.FindAllStringSubmatch(source, -1) {
+			declared = declared || match[1] == layoutContentBlock
+		}
+	}
+	if !declared {
+		return fmt.Errorf("layout has no {{block %q .}}", layoutContentBlock)
+	}
+
+	var names, texts []string
+	for _, source := range layout.sources {
+		names = append(names, layout.name)
+		texts = append(texts, source)
+	}
+	names = append(names, page.name)
+	texts = append(texts, fmt.Sprintf(`{{define %q}}{{if true}}%s{{end}}{{end}}`, layoutContentBlock, page.templateStr))
+
+	_, err := t.parseSources(names, texts)
+	return err
+}
+
+// layoutNavigation tracks the page of a layout that rendered last, and the diff state it
+// left, so the next page diffs against what the client holds
+type layoutNavigation struct {
+	mu    sync.Mutex
+	page  *Template  // Page that rendered last
+	state *diffState // Diff state after its last render
+}
+
+// diffState is the part of a template that the next update is diffed against
+type diffState struct {
+	lastData        interface{}
+	lastHTML        string
+	lastTree        treeNode
+	initialTree     treeNode
+	hasInitialTree  bool
+	lastFingerprint string
+	keyGen          *keyGenerator
+}
+
+// pageNavigation returns the navigation shared by the pages of layout t
+func (t *Template) pageNavigation() *layoutNavigation {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.pages == nil {
+		t.pages = &layoutNavigation{}
+	}
+	return t.pages
+}
+
+// adoptNavigation takes over the diff state of the page rendered last under the same
+// layout, when that was another page. Called with t.mu held.
+func (t *Template) adoptNavigation() {
+	if t.navigation == nil {
+		return
+	}
+	nav := t.navigation
+	nav.mu.Lock()
+	defer nav.mu.Unlock()
+	if nav.page == nil || nav.page == t {
+		return
+	}
+
+	state := nav.state
+	t.lastData = state.lastData
+	t.lastHTML = state.lastHTML
+	t.lastTree = state.lastTree
+	t.initialTree = state.initialTree
+	t.hasInitialTree = state.hasInitialTree
+	t.lastFingerprint = state.lastFingerprint
+	t.keyGen = state.keyGen
+}
+
+// recordNavigation makes t the page rendered last under its layout. Called with t.mu held.
+func (t *Template) recordNavigation() {
+	if t.navigation == nil {
+		return
+	}
+	nav := t.navigation
+	nav.mu.Lock()
+	defer nav.mu.Unlock()
+	nav.page = t
+	nav.state = &diffState{
+		lastData:        t.lastData,
+		lastHTML:        t.lastHTML,
+		lastTree:        t.lastTree,
+		initialTree:     t.initialTree,
+		hasInitialTree:  t.hasInitialTree,
+		lastFingerprint: t.lastFingerprint,
+		keyGen:          t.keyGen,
+	}
+}
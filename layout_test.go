@@ -0,0 +1,88 @@
+package livetemplate
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestTemplate_WithLayout(t *testing.T) {
+	layout := New("layout-test")
+	if _, err := layout.Parse(`<!DOCTYPE html><html><body><header><nav>Site navigation</nav></header>` +
+		`<main>{{block "content" .}}{{end}}</main><footer>&copy; {{.Year}}</footer></body></html>`); err != nil {
+		t.Fatalf("Parse layout failed: %v", err)
+	}
+
+	home := New("home-test")
+	if _, err := home.Parse(`<h1>Welcome, {{.User}}</h1>`); err != nil {
+		t.Fatalf("Parse home failed: %v", err)
+	}
+	about := New("about-test")
+	if _, err := about.Parse(`<section><h2>About</h2><ul>{{range .Team}}<li>{{.}}</li>{{end}}</ul></section>`); err != nil {
+		t.Fatalf("Parse about failed: %v", err)
+	}
+
+	homePage := home.WithLayout(layout)
+	aboutPage := about.WithLayout(layout)
+	data := map[string]interface{}{"User": "Ada", "Year": 2024, "Team": []string{"Grace", "Linus"}}
+
+	// update renders page and returns the update with its top-level keys
+	update := func(t *testing.T, page *Template) (string, map[string]json.RawMessage) {
+		t.Helper()
+		var buf bytes.Buffer
+		if err := page.ExecuteUpdates(&buf, data); err != nil {
+			t.Fatalf("ExecuteUpdates failed: %v", err)
+		}
+		var tree map[string]json.RawMessage
+		if err := json.Unmarshal(buf.Bytes(), &tree); err != nil {
+			t.Fatalf("Invalid update %s: %v", buf.String(), err)
+		}
+		return buf.String(), tree
+	}
+
+	initial, _ := update(t, homePage)
+	for _, want := range []string{"Site navigation", "Welcome, "} {
+		if !strings.Contains(initial, want) {
+			t.Errorf("Expected initial update to contain %q, got %s", want, initial)
+		}
+	}
+
+	// Navigating sends the new page region only
+	navigated, tree := update(t, aboutPage)
+	if strings.Contains(navigated, "Site navigation") || strings.Contains(navigated, "<footer>") {
+		t.Errorf("Navigation resent the layout statics: %s", navigated)
+	}
+	if _, ok := tree["s"]; ok {
+		t.Errorf("Navigation resent the root statics: %s", navigated)
+	}
+	if !strings.Contains(navigated, "<h2>About</h2>") || !strings.Contains(navigated, "Grace") {
+		t.Errorf("Expected navigation to send the about page, got %s", navigated)
+	}
+
+	// Navigating back resends the home page, which the client no longer holds
+	back, tree := update(t, homePage)
+	if len(tree) != 1 || !strings.Contains(back, "<h1>Welcome, ") || strings.Contains(back, "Site navigation") {
+		t.Errorf("Expected navigating back to send the home page region only, got %s", back)
+	}
+
+	// Updates within a page diff as usual
+	data["User"] = "Grace"
+	if changed, _ := update(t, homePage); strings.Contains(changed, "Welcome") || !strings.Contains(changed, "Grace") {
+		t.Errorf("Expected a dynamics-only update, got %s", changed)
+	}
+
+	if homePage.wrapperID != layout.wrapperID || aboutPage.wrapperID != layout.wrapperID {
+		t.Errorf("Expected pages to share the layout's wrapper %q, got %q and %q", layout.wrapperID, homePage.wrapperID, aboutPage.wrapperID)
+	}
+
+	t.Run("layout without content block", func(t *testing.T) {
+		plain := New("plain-layout-test")
+		if _, err := plain.Parse(`<header>Site</header>`); err != nil {
+			t.Fatalf("Parse failed: %v", err)
+		}
+		if err := home.WithLayout(plain).Execute(&bytes.Buffer{}, data); err == nil {
+			t.Error("Expected a page under a layout without a content block to fail")
+		}
+	})
+}
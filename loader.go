@@ -0,0 +1,124 @@
+package livetemplate
+
+import (
+	"context"
+	"slices"
+	"sync"
+)
+
+// BatchFunc loads the values of keys in one call, e.g. one SELECT ... WHERE id IN (...).
+// Keys missing from the result load as nil.
+type BatchFunc func(ctx context.Context, keys []string) (map[string]interface{}, error)
+
+// Thunk returns the value of a key requested with Loader.Load
+type Thunk func() (interface{}, error)
+
+// Loader batches and caches keyed lookups for one action, so a store filling N items
+// with related records makes one query instead of N. Load only registers the key: the
+// first Thunk called loads every key registered so far in a single BatchFunc call.
+//
+//	authors := ctx.Loader("authors", db.AuthorsByID)
+//	thunks := make([]livetemplate.Thunk, len(s.Posts))
+//	for i, post := range s.Posts {
+//	    thunks[i] = authors.Load(post.AuthorID)
+//	}
+//	for i, thunk := range thunks {
+//	    author, err := thunk()
+//	    ...
+//	}
+//
+// Values and errors are cached for the action, including actions it dispatches, and
+// dropped when it returns; the next action loads fresh values.
+//
+// Concurrency: A Loader is safe for use by multiple goroutines. The batch function is
+// called with the Loader locked, so it must not use the Loader itself.
+type Loader struct {
+	ctx   context.Context
+	batch BatchFunc
+
+	mu      sync.Mutex
+	pending []string
+	loaded  map[string]loaderResult
+}
+
+// loaderResult is the cached outcome of loading one key
+type loaderResult struct {
+	value interface{}
+	err   error
+}
+
+// Load registers key with the next batch and returns a Thunk for its value
+func (l *Loader) Load(key string) Thunk {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok := l.loaded[key]; !ok && !slices.Contains(l.pending, key) {
+		l.pending = append(l.pending, key)
+	}
+	return func() (interface{}, error) {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if _, ok := l.loaded[key]; !ok {
+			l.flush()
+		}
+		result := l.loaded[key]
+		return result.value, result.err
+	}
+}
+
+// LoadMany returns the values of keys, loading those not yet cached in one batch
+func (l *Loader) LoadMany(keys ...string) ([]interface{}, error) {
+	thunks := make([]Thunk, len(keys))
+	for i, key := range keys {
+		thunks[i] = l.Load(key)
+	}
+	values := make([]interface{}, len(keys))
+	for i, thunk := range thunks {
+		value, err := thunk()
+		if err != nil {
+			return nil, err
+		}
+		values[i] = value
+	}
+	return values, nil
+}
+
+// flush loads the pending keys. Called with l.mu held.
+func (l *Loader) flush() {
+	keys := l.pending
+	l.pending = nil
+	if len(keys) == 0 {
+		return
+	}
+
+	values, err := l.batch(l.ctx, keys)
+	for _, key := range keys {
+		l.loaded[key] = loaderResult{value: values[key], err: err}
+	}
+}
+
+// loaderSet holds the Loaders of one action and the actions it dispatches
+type loaderSet struct {
+	mu     sync.Mutex
+	byName map[string]*Loader
+}
+
+// Loader returns the Loader named name for this action, created with batch on first use.
+// Later calls with the same name, also from dispatched actions, share its batches and
+// cache, and ignore their batch argument. See Loader.
+func (c *ActionContext) Loader(name string, batch BatchFunc) *Loader {
+	if c.loaders == nil {
+		c.loaders = &loaderSet{}
+	}
+	c.loaders.mu.Lock()
+	defer c.loaders.mu.Unlock()
+
+	if loader, ok := c.loaders.byName[name]; ok {
+		return loader
+	}
+	if c.loaders.byName == nil {
+		c.loaders.byName = make(map[string]*Loader)
+	}
+	loader := &Loader{ctx: c.Context(), batch: batch, loaded: make(map[string]loaderResult)}
+	c.loaders.byName[name] = loader
+	return loader
+}
@@ -0,0 +1,140 @@
+package livetemplate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// countingBatch returns a BatchFunc loading "name-<key>" for each key, recording its calls
+func countingBatch(calls *[][]string) BatchFunc {
+	return func(ctx context.Context, keys []string) (map[string]interface{}, error) {
+		*calls = append(*calls, append([]string(nil), keys...))
+		values := make(map[string]interface{}, len(keys))
+		for _, key := range keys {
+			values[key] = "name-" + key
+		}
+		return values, nil
+	}
+}
+
+func TestActionContext_Loader(t *testing.T) {
+	t.Run("distinct loads collapse to one batch", func(t *testing.T) {
+		var calls [][]string
+		ctx := &ActionContext{}
+		authors := ctx.Loader("authors", countingBatch(&calls))
+
+		keys := []string{"1", "2", "3", "2", "4"}
+		thunks := make([]Thunk, len(keys))
+		for i, key := range keys {
+			thunks[i] = authors.Load(key)
+		}
+		if len(calls) != 0 {
+			t.Fatalf("Expected Load to defer the batch, got %d calls", len(calls))
+		}
+		for i, thunk := range thunks {
+			value, err := thunk()
+			if err != nil {
+				t.Fatalf("Thunk failed: %v", err)
+			}
+			if value != "name-"+keys[i] {
+				t.Errorf("Expected name-%s, got %v", keys[i], value)
+			}
+		}
+
+		if len(calls) != 1 {
+			t.Fatalf("Expected 1 batch call, got %d: %v", len(calls), calls)
+		}
+		got := append([]string(nil), calls[0]...)
+		sort.Strings(got)
+		if strings.Join(got, ",") != "1,2,3,4" {
+			t.Errorf("Expected distinct keys 1,2,3,4 in the batch, got %v", calls[0])
+		}
+	})
+
+	t.Run("cached values skip the batch", func(t *testing.T) {
+		var calls [][]string
+		ctx := &ActionContext{}
+		authors := ctx.Loader("authors", countingBatch(&calls))
+
+		if _, err := authors.LoadMany("1", "2"); err != nil {
+			t.Fatalf("LoadMany failed: %v", err)
+		}
+		values, err := ctx.Loader("authors", nil).LoadMany("2", "3")
+		if err != nil {
+			t.Fatalf("LoadMany failed: %v", err)
+		}
+		if fmt.Sprint(values) != "[name-2 name-3]" {
+			t.Errorf("Expected [name-2 name-3], got %v", values)
+		}
+		if len(calls) != 2 || fmt.Sprint(calls[1]) != "[3]" {
+			t.Errorf("Expected a second batch of the uncached key only, got %v", calls)
+		}
+	})
+
+	t.Run("errors are cached", func(t *testing.T) {
+		calls := 0
+		failing := errors.New("database unavailable")
+		ctx := &ActionContext{}
+		loader := ctx.Loader("failing", func(ctx context.Context, keys []string) (map[string]interface{}, error) {
+			calls++
+			return nil, failing
+		})
+
+		for i := 0; i < 2; i++ {
+			if _, err := loader.Load("1")(); !errors.Is(err, failing) {
+				t.Errorf("Expected %v, got %v", failing, err)
+			}
+		}
+		if calls != 1 {
+			t.Errorf("Expected the error to be cached, got %d batch calls", calls)
+		}
+	})
+}
+
+// LoaderState is a test store whose "show" action loads its authors through a Loader,
+// and dispatches "detail" to load one of them again
+type LoaderState struct {
+	Calls   *[][]string
+	Authors string
+}
+
+func (s *LoaderState) Change(ctx *ActionContext) error {
+	authors := ctx.Loader("authors", countingBatch(s.Calls))
+	switch ctx.Action {
+	case "show":
+		values, err := authors.LoadMany("1", "2", "3")
+		if err != nil {
+			return err
+		}
+		s.Authors = fmt.Sprint(values...)
+		return ctx.Dispatch("", "detail", nil)
+	case "detail":
+		_, err := authors.Load("2")()
+		return err
+	}
+	return nil
+}
+
+func TestLiveHandler_LoaderPerAction(t *testing.T) {
+	tmpl := New("loader-test")
+	if _, err := tmpl.Parse("<p>{{.Authors}}</p>"); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	var calls [][]string
+	conn := dialTestHandler(t, tmpl.Handle(&LoaderState{Calls: &calls}))
+
+	sendAction(t, conn, "show", nil)
+	if len(calls) != 1 {
+		t.Fatalf("Expected the dispatched action to share the cache, got batches %v", calls)
+	}
+
+	sendAction(t, conn, "show", nil)
+	if len(calls) != 2 {
+		t.Errorf("Expected the next action to load fresh values, got batches %v", calls)
+	}
+}
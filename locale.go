@@ -0,0 +1,128 @@
+package livetemplate
+
+import (
+	"fmt"
+	"html/template"
+	"regexp"
+	"strconv"
+	"time"
+
+	"golang.org/x/text/language"
+	xmessage "golang.org/x/text/message"
+	"golang.org/x/text/number"
+)
+
+// lvtNumber implements {{lvt_number .Price}}: it formats a number with the digit grouping
+// and decimal separator of the template's locale (see WithLocaleResolver), e.g. 1234.5 is
+// "1,234.5" for "en-US" and "1.234,5" for "de-DE":
+//
+//	<td>{{.Total | lvt_number}}</td>
+//
+// This unbound form formats as English; templates with a locale bind it, see bindLocale.
+func lvtNumber(value interface{}) (string, error) {
+	return formatNumber("", value)
+}
+
+// lvtDate implements {{lvt_date .CreatedAt}}: it formats the date of a time in the numeric
+// short form of the template's locale, e.g. "3/14/2025" for "en-US", "14/03/2025" for
+// "en-GB" and "14.03.2025" for "de-DE". This unbound form formats as en-US.
+func lvtDate(t time.Time) string {
+	return formatDate("", t)
+}
+
+// formatNumber formats a number for a BCP 47 locale. An empty or unknown locale formats
+// as English.
+func formatNumber(locale string, value interface{}) (string, error) {
+	switch value.(type) {
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+	default:
+		return "", fmt.Errorf("lvt_number: expected a number, got %T", value)
+	}
+	return xmessage.NewPrinter(parseLocale(locale)).Sprint(number.Decimal(value)), nil
+}
+
+// formatDate formats the date of t for a BCP 47 locale. An empty or unknown locale
+// formats as en-US.
+func formatDate(locale string, t time.Time) string {
+	tag := parseLocale(locale)
+	base, _ := tag.Base()
+	if region, confidence := tag.Region(); base.String() == "en" && confidence == language.Exact {
+		if layout, ok := englishDateLayoutsByRegion[region.String()]; ok {
+			return t.Format(layout)
+		}
+	}
+	if layout, ok := dateLayoutsByLanguage[base.String()]; ok {
+		return t.Format(layout)
+	}
+	return t.Format(dateLayoutsByLanguage["en"])
+}
+
+// localeCallPattern matches a call of lvt_number or lvt_date in an action, with the
+// character before it
+var localeCallPattern = regexp.MustCompile(`(^|[^\w.$])lvt_(number|date)\b`)
+
+// bindLocale makes lvt_number and lvt_date of the template format in its locale. Template
+// functions are shared by all templates, so each instance with a locale, such as the clone
+// of a connection or request, overrides them with functions bound to it.
+func (t *Template) bindLocale() {
+	if t.tmpl == nil || t.locale == "" {
+		return
+	}
+	locale := t.locale
+	t.tmpl.Funcs(template.FuncMap{
+		"lvt_number": func(value interface{}) (string, error) { return formatNumber(locale, value) },
+		"lvt_date":   func(t time.Time) string { return formatDate(locale, t) },
+	})
+}
+
+// localizeCalls rewrites the lvt_number and lvt_date calls of text, the source of the
+// update tree, to pass the template's locale, as the tree is built from fragments of the
+// source executed with the shared functions (see bindLocale)
+func (t *Template) localizeCalls(text string) string {
+	if t.locale == "" {
+		return text
+	}
+	replacement := "${1}lvt_locale_${2} " + strconv.Quote(t.locale)
+	return templateActionPattern.ReplaceAllStringFunc(text, func(action string) string {
+		return localeCallPattern.ReplaceAllString(action, replacement)
+	})
+}
+
+// dateLayoutsByLanguage are the numeric short date layouts of common languages
+var dateLayoutsByLanguage = map[string]string{
+	"en": "1/2/2006",
+	"de": "02.01.2006",
+	"ru": "02.01.2006",
+	"pl": "02.01.2006",
+	"tr": "02.01.2006",
+	"cs": "2. 1. 2006",
+	"fr": "02/01/2006",
+	"es": "2/1/2006",
+	"it": "2/1/2006",
+	"pt": "02/01/2006",
+	"nl": "2-1-2006",
+	"sv": "2006-01-02",
+	"ja": "2006/01/02",
+	"zh": "2006/1/2",
+	"ko": "2006. 1. 2.",
+}
+
+// englishDateLayoutsByRegion are the layouts of English-speaking regions that don't write
+// the month first
+var englishDateLayoutsByRegion = map[string]string{
+	"GB": "02/01/2006",
+	"AU": "2/1/2006",
+	"NZ": "2/01/2006",
+	"IE": "2/1/2006",
+	"IN": "2/1/2006",
+	"CA": "2006-01-02",
+}
+
+// parseLocale parses a BCP 47 locale, falling back to English
+func parseLocale(locale string) language.Tag {
+	tag, err := language.Parse(locale)
+	if err != nil {
+		return language.English
+	}
+	return tag
+}
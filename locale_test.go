@@ -0,0 +1,170 @@
+package livetemplate
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFormatNumber(t *testing.T) {
+	tests := []struct {
+		locale string
+		value  interface{}
+		want   string
+	}{
+		{"en-US", 1234567.5, "1,234,567.5"},
+		{"de-DE", 1234567.5, "1.234.567,5"},
+		{"fr-FR", 1234, "1 234"},
+		{"", 1234, "1,234"},
+		{"not a locale", 1234, "1,234"},
+	}
+	for _, tt := range tests {
+		got, err := formatNumber(tt.locale, tt.value)
+		if err != nil {
+			t.Fatalf("formatNumber(%q, %v) failed: %v", tt.locale, tt.value, err)
+		}
+		if got != tt.want {
+			t.Errorf("formatNumber(%q, %v) = %q, want %q", tt.locale, tt.value, got, tt.want)
+		}
+	}
+
+	if _, err := formatNumber("en-US", "12"); err == nil {
+		t.Error("Expected an error for a non-number")
+	}
+}
+
+func TestFormatDate(t *testing.T) {
+	date := time.Date(2025, time.March, 4, 15, 0, 0, 0, time.UTC)
+	tests := []struct {
+		locale string
+		want   string
+	}{
+		{"en-US", "3/4/2025"},
+		{"en", "3/4/2025"},
+		{"en-GB", "04/03/2025"},
+		{"de-DE", "04.03.2025"},
+		{"de-AT", "04.03.2025"},
+		{"ja", "2025/03/04"},
+		{"", "3/4/2025"},
+	}
+	for _, tt := range tests {
+		if got := formatDate(tt.locale, date); got != tt.want {
+			t.Errorf("formatDate(%q) = %q, want %q", tt.locale, got, tt.want)
+		}
+	}
+}
+
+// InvoiceState is a test store with numbers and dates to format
+type InvoiceState struct {
+	Total float64
+	Due   time.Time
+	Lines []float64
+}
+
+func (s *InvoiceState) Change(ctx *ActionContext) error {
+	return nil
+}
+
+func TestWithLocaleResolver(t *testing.T) {
+	invoice := &InvoiceState{
+		Total: 1234.5,
+		Due:   time.Date(2025, time.March, 4, 0, 0, 0, 0, time.UTC),
+		Lines: []float64{1000.25},
+	}
+
+	tmpl := New("locale-test", WithLocaleResolver(func(r *http.Request) string {
+		return r.Header.Get("Accept-Language")
+	}))
+	if _, err := tmpl.Parse(`<p lang="{{.lvt.Locale}}">{{lvt_number .Total}} due {{.Due | lvt_date}}</p><ul>{{range .Lines}}<li>{{lvt_number .}}</li>{{end}}</ul>`); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	server := httptest.NewServer(tmpl.Handle(invoice))
+	defer server.Close()
+
+	render := func(t *testing.T, locale string) string {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+		req.Header.Set("Accept-Language", locale)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("GET failed: %v", err)
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatalf("Failed to read body: %v", err)
+		}
+		return string(body)
+	}
+
+	tests := []struct {
+		locale string
+		want   []string
+	}{
+		{"en-US", []string{`lang="en-US"`, "1,234.5 due 3/4/2025", "<li>1,000.25</li>"}},
+		{"de-DE", []string{`lang="de-DE"`, "1.234,5 due 04.03.2025", "<li>1.000,25</li>"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.locale, func(t *testing.T) {
+			html := render(t, tt.locale)
+			for _, want := range tt.want {
+				if !strings.Contains(html, want) {
+					t.Errorf("Expected %q in page, got %s", want, html)
+				}
+			}
+		})
+	}
+
+	t.Run("server-sent events", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+		req.Header.Set("Accept-Language", "de-DE")
+		req.Header.Set("Accept", "text/event-stream")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("SSE request failed: %v", err)
+		}
+		defer resp.Body.Close()
+
+		initial, err := json.Marshal(readSSEUpdate(t, bufio.NewReader(resp.Body)).Tree)
+		if err != nil {
+			t.Fatalf("Failed to encode initial tree: %v", err)
+		}
+		for _, want := range []string{"de-DE", "1.234,5", "04.03.2025", "1.000,25"} {
+			if !strings.Contains(string(initial), want) {
+				t.Errorf("Expected %q in initial tree, got %s", want, initial)
+			}
+		}
+	})
+
+	t.Run("update tree", func(t *testing.T) {
+		clone, err := tmpl.Clone()
+		if err != nil {
+			t.Fatalf("Clone failed: %v", err)
+		}
+		clone.setLocale("de-DE")
+
+		var buf bytes.Buffer
+		if err := clone.ExecuteUpdates(&buf, invoice); err != nil {
+			t.Fatalf("ExecuteUpdates failed: %v", err)
+		}
+		for _, want := range []string{"1.234,5", "04.03.2025", "1.000,25"} {
+			if !strings.Contains(buf.String(), want) {
+				t.Errorf("Expected %q in tree, got %s", want, buf.String())
+			}
+		}
+
+		buf.Reset()
+		if err := clone.ExecuteUpdates(&buf, &InvoiceState{Total: 99999.5, Due: invoice.Due, Lines: invoice.Lines}); err != nil {
+			t.Fatalf("ExecuteUpdates failed: %v", err)
+		}
+		if !strings.Contains(buf.String(), "99.999,5") || strings.Contains(buf.String(), "04.03.2025") {
+			t.Errorf("Expected only the total in the update, got %s", buf.String())
+		}
+	})
+}
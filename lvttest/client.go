@@ -0,0 +1,230 @@
+// Package lvttest provides helpers for testing LiveTemplate handlers without a browser.
+//
+// A Client connects to a LiveHandler over an in-memory WebSocket, sends actions the
+// same way the browser client does, and applies every update it receives to a local
+// copy of the tree, so tests can assert on the resulting state:
+//
+//	tmpl := livetemplate.New("counter")
+//	client := lvttest.NewClient(tmpl.Handle(&CounterState{}))
+//	defer client.Close()
+//
+//	if _, err := client.Mount(); err != nil {
+//	    t.Fatal(err)
+//	}
+//	if err := client.SendAction("increment", nil); err != nil {
+//	    t.Fatal(err)
+//	}
+//	update, err := client.NextUpdate()
+//	// update.Changes holds the diff, update.Tree the applied tree
+package lvttest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/livefir/livetemplate"
+)
+
+// DefaultTimeout is how long NextUpdate waits for a message before failing
+const DefaultTimeout = 5 * time.Second
+
+// Update is a single message received from the server
+type Update struct {
+	Changes map[string]interface{}         // Tree update exactly as sent by the server
+	Tree    map[string]interface{}         // Client tree after applying Changes
+	Meta    *livetemplate.ResponseMetadata // Action metadata (success, errors, action name)
+}
+
+// Client simulates a browser connected to a LiveHandler over WebSocket
+type Client struct {
+	// Header is sent with the WebSocket handshake. Set cookies here to join an
+	// existing session group, e.g. to simulate a second tab.
+	Header http.Header
+
+	// Timeout bounds how long NextUpdate waits for a message. Default: DefaultTimeout
+	Timeout time.Duration
+
+	handler  livetemplate.LiveHandler
+	listener *pipeListener
+	server   *http.Server
+	conn     *websocket.Conn
+	cookies  []*http.Cookie
+
+	keyAttrs []string // Attributes identifying range items, see WithKeyAttributes
+
+	mu      sync.Mutex
+	tree    map[string]interface{}
+	statics map[string]interface{} // Statics cached by ID, see resolveStatics
+}
+
+// keyAttributesReporter is implemented by the handlers of livetemplate, which report the
+// key attributes of their template
+type keyAttributesReporter interface {
+	KeyAttributes() []string
+}
+
+// NewClient creates a client for handler. Call Mount to connect.
+func NewClient(handler livetemplate.LiveHandler) *Client {
+	keyAttrs := defaultKeyAttributes
+	if reporter, ok := handler.(keyAttributesReporter); ok {
+		keyAttrs = reporter.KeyAttributes()
+	}
+	return &Client{
+		Header:   make(http.Header),
+		Timeout:  DefaultTimeout,
+		handler:  handler,
+		keyAttrs: keyAttrs,
+	}
+}
+
+// Mount connects to the handler and returns the initial tree
+func (c *Client) Mount() (*Update, error) {
+	if c.conn != nil {
+		return nil, fmt.Errorf("client already mounted")
+	}
+
+	c.listener = newPipeListener()
+	c.server = &http.Server{Handler: c.handler}
+	go func() {
+		_ = c.server.Serve(c.listener)
+	}()
+
+	dialer := &websocket.Dialer{
+		NetDialContext:   c.listener.DialContext,
+		HandshakeTimeout: c.timeout(),
+	}
+
+	conn, resp, err := dialer.Dial("ws://lvttest/", c.Header)
+	if err != nil {
+		c.shutdown()
+		return nil, fmt.Errorf("websocket dial failed: %w", err)
+	}
+	if resp != nil {
+		c.cookies = resp.Cookies()
+	}
+	c.conn = conn
+
+	return c.NextUpdate()
+}
+
+// Cookies returns the cookies set by the handler during the handshake.
+// Pass them to another client's Header to share the session group.
+func (c *Client) Cookies() []*http.Cookie {
+	return c.cookies
+}
+
+// SendAction sends an action message, as triggered by lvt-* attributes in the browser.
+// In multi-store mode, name must include the store prefix (e.g., "counter.increment").
+func (c *Client) SendAction(name string, payload map[string]interface{}) error {
+	if c.conn == nil {
+		return fmt.Errorf("client not mounted")
+	}
+
+	if payload == nil {
+		payload = make(map[string]interface{})
+	}
+
+	msg := map[string]interface{}{
+		"action": name,
+		"data":   payload,
+	}
+
+	if err := c.conn.WriteJSON(msg); err != nil {
+		return fmt.Errorf("failed to send action %q: %w", name, err)
+	}
+	return nil
+}
+
+// NextUpdate waits for the next message from the server and applies it to the client tree.
+// An update the client can't apply, like a range operation for a key no item has, is an error.
+func (c *Client) NextUpdate() (*Update, error) {
+	if c.conn == nil {
+		return nil, fmt.Errorf("client not mounted")
+	}
+
+	if err := c.conn.SetReadDeadline(time.Now().Add(c.timeout())); err != nil {
+		return nil, fmt.Errorf("failed to set read deadline: %w", err)
+	}
+
+	_, data, err := c.conn.ReadMessage()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read update: %w", err)
+	}
+
+	var response struct {
+		Tree map[string]interface{}         `json:"tree"`
+		Meta *livetemplate.ResponseMetadata `json:"meta"`
+	}
+	if err := json.Unmarshal(data, &response); err != nil {
+		return nil, fmt.Errorf("failed to decode update: %w", err)
+	}
+
+	c.mu.Lock()
+	if c.statics == nil {
+		c.statics = make(map[string]interface{})
+	}
+	resolved, _ := resolveStatics(copyValue(response.Tree), c.statics).(map[string]interface{})
+	tree, err := applyTree(c.tree, resolved, c.keyAttrs)
+	if err != nil {
+		c.mu.Unlock()
+		return nil, fmt.Errorf("failed to apply update %s: %w", data, err)
+	}
+	c.tree = tree
+	c.mu.Unlock()
+
+	return &Update{
+		Changes: response.Tree,
+		Tree:    c.Tree(),
+		Meta:    response.Meta,
+	}, nil
+}
+
+// Tree returns a copy of the client tree with all received updates applied
+func (c *Client) Tree() map[string]interface{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.tree == nil {
+		return nil
+	}
+	return copyValue(c.tree).(map[string]interface{})
+}
+
+// Close disconnects the client and stops the in-memory server
+func (c *Client) Close() error {
+	var err error
+	if c.conn != nil {
+		_ = c.conn.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""),
+			time.Now().Add(time.Second))
+		err = c.conn.Close()
+		c.conn = nil
+	}
+	c.shutdown()
+	return err
+}
+
+// shutdown stops the in-memory server
+func (c *Client) shutdown() {
+	if c.server == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout())
+	defer cancel()
+	_ = c.server.Shutdown(ctx)
+	c.listener.Close()
+	c.server = nil
+}
+
+// timeout returns the configured timeout or the default
+func (c *Client) timeout() time.Duration {
+	if c.Timeout > 0 {
+		return c.Timeout
+	}
+	return DefaultTimeout
+}
@@ -0,0 +1,254 @@
+package lvttest
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/livefir/livetemplate"
+)
+
+type counterState struct {
+	Count int
+}
+
+func (s *counterState) Change(ctx *livetemplate.ActionContext) error {
+	if ctx.Action == "increment" {
+		s.Count++
+	}
+	return nil
+}
+
+func TestClient_SendAction(t *testing.T) {
+	tmpl := livetemplate.New("counter")
+	if _, err := tmpl.Parse("<p>Count: {{.Count}}</p>"); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	client := NewClient(tmpl.Handle(&counterState{}))
+	defer client.Close()
+
+	initial, err := client.Mount()
+	if err != nil {
+		t.Fatalf("Mount failed: %v", err)
+	}
+	if _, ok := initial.Tree["s"]; !ok {
+		t.Fatalf("Initial tree should include statics, got %v", initial.Tree)
+	}
+
+	if err := client.SendAction("increment", nil); err != nil {
+		t.Fatalf("SendAction failed: %v", err)
+	}
+	update, err := client.NextUpdate()
+	if err != nil {
+		t.Fatalf("NextUpdate failed: %v", err)
+	}
+
+	if update.Meta == nil || !update.Meta.Success {
+		t.Fatalf("Expected successful action, got %+v", update.Meta)
+	}
+	if update.Changes["0"] != "1" {
+		t.Errorf("Expected change \"1\" at key 0, got %v", update.Changes)
+	}
+	if _, ok := update.Changes["s"]; ok {
+		t.Errorf("Update should not resend statics, got %v", update.Changes)
+	}
+
+	tree := client.Tree()
+	if tree["0"] != "1" {
+		t.Errorf("Expected applied tree to hold \"1\" at key 0, got %v", tree["0"])
+	}
+	if _, ok := tree["s"]; !ok {
+		t.Errorf("Applied tree should keep statics from the initial render")
+	}
+}
+
+func TestApplyTree_RangeOperations(t *testing.T) {
+	current := map[string]interface{}{
+		"s": []interface{}{"<ul>", "</ul>"},
+		"0": map[string]interface{}{
+			"s": []interface{}{`<li data-key="`, `">`, "</li>"},
+			"d": []interface{}{
+				map[string]interface{}{"0": "a", "1": "Alpha"},
+				map[string]interface{}{"0": "b", "1": "Beta"},
+			},
+		},
+	}
+
+	update := map[string]interface{}{
+		"0": []interface{}{
+			[]interface{}{"u", "a", map[string]interface{}{"1": "Alpha!"}},
+			[]interface{}{"r", "b"},
+			[]interface{}{"i", "a", "before", map[string]interface{}{"0": "c", "1": "Gamma"}},
+		},
+	}
+
+	result, err := applyTree(current, update, defaultKeyAttributes)
+	if err != nil {
+		t.Fatalf("applyTree failed: %v", err)
+	}
+
+	items := result["0"].(map[string]interface{})["d"].([]interface{})
+	if len(items) != 2 {
+		t.Fatalf("Expected 2 items, got %d: %v", len(items), items)
+	}
+	if first := items[0].(map[string]interface{}); first["0"] != "c" {
+		t.Errorf("Expected inserted item first, got %v", first)
+	}
+	if second := items[1].(map[string]interface{}); second["1"] != "Alpha!" {
+		t.Errorf("Expected updated item second, got %v", second)
+	}
+
+	// The original tree must not be mutated
+	original := current["0"].(map[string]interface{})["d"].([]interface{})
+	if len(original) != 2 || original[0].(map[string]interface{})["1"] != "Alpha" {
+		t.Errorf("applyTree mutated its input: %v", original)
+	}
+}
+
+func TestResolveStatics(t *testing.T) {
+	cache := make(map[string]interface{})
+	statics := []interface{}{"<div>", "</div>"}
+
+	first := map[string]interface{}{"1": map[string]interface{}{"s": statics, "si": "abc", "0": "x"}}
+	resolveStatics(first, cache)
+	if node := first["1"].(map[string]interface{}); node["si"] != nil {
+		t.Errorf("Expected the statics ID to be consumed, got %v", node)
+	}
+
+	later := map[string]interface{}{"1": map[string]interface{}{"sr": "abc", "0": "y"}}
+	resolveStatics(later, cache)
+	node := later["1"].(map[string]interface{})
+	if node["sr"] != nil || len(node["s"].([]interface{})) != 2 {
+		t.Errorf("Expected the reference replaced by the cached statics, got %v", node)
+	}
+
+	tree, _ := applyTree(nil, first, defaultKeyAttributes)
+	tree, err := applyTree(tree, later, defaultKeyAttributes)
+	if err != nil {
+		t.Fatalf("applyTree failed: %v", err)
+	}
+	if got := tree["1"].(map[string]interface{})["0"]; got != "y" {
+		t.Errorf("Expected the referenced node applied, got %v", tree)
+	}
+}
+
+func TestApplyTree_KeyAttributes(t *testing.T) {
+	current := map[string]interface{}{
+		"0": map[string]interface{}{
+			"s": []interface{}{`<tr id="row-`, `" data-row="`, `">`, "</tr>"},
+			"d": []interface{}{
+				map[string]interface{}{"0": "1", "1": "a", "2": "Alpha"},
+				map[string]interface{}{"0": "2", "1": "b", "2": "Beta"},
+			},
+		},
+	}
+	update := map[string]interface{}{"0": []interface{}{[]interface{}{"r", "a"}}}
+
+	result, err := applyTree(current, update, []string{"data-row"})
+	if err != nil {
+		t.Fatalf("applyTree failed: %v", err)
+	}
+	items := result["0"].(map[string]interface{})["d"].([]interface{})
+	if len(items) != 1 || items[0].(map[string]interface{})["1"] != "b" {
+		t.Errorf("Expected the item keyed by data-row to be removed, got %v", items)
+	}
+
+	// With the default attributes, id identifies the items and "a" is no item's key
+	if _, err := applyTree(current, update, defaultKeyAttributes); err == nil || !strings.Contains(err.Error(), `no range item with key "a"`) {
+		t.Errorf("Expected an error naming the unknown key, got %v", err)
+	}
+}
+
+func TestApplyTree_Shapes(t *testing.T) {
+	t.Run("switch branches", func(t *testing.T) {
+		branches := []interface{}{[]interface{}{"<p>", "</p>"}, []interface{}{"<b>", "</b>"}, []interface{}{"none"}}
+		current := map[string]interface{}{
+			"s": []interface{}{"", ""},
+			"0": map[string]interface{}{"s": branches[0], "0": "apple", "b": float64(0), "bs": branches},
+		}
+
+		tree, err := applyTree(current, map[string]interface{}{"0": map[string]interface{}{"b": float64(1), "0": "banana"}}, defaultKeyAttributes)
+		if err != nil {
+			t.Fatalf("applyTree failed: %v", err)
+		}
+		node := tree["0"].(map[string]interface{})
+		if !reflect.DeepEqual(node["s"], branches[1]) || node["0"] != "banana" || node["bs"] == nil {
+			t.Errorf("Expected the node to take the statics of branch 1, got %v", node)
+		}
+
+		tree, err = applyTree(tree, map[string]interface{}{"0": map[string]interface{}{"b": float64(2)}}, defaultKeyAttributes)
+		if err != nil {
+			t.Fatalf("applyTree failed: %v", err)
+		}
+		if node := tree["0"].(map[string]interface{}); !reflect.DeepEqual(node["s"], branches[2]) || node["0"] != nil {
+			t.Errorf("Expected the else branch without the dynamics of branch 1, got %v", node)
+		}
+
+		if _, err := applyTree(tree, map[string]interface{}{"0": map[string]interface{}{"b": float64(3)}}, defaultKeyAttributes); err == nil {
+			t.Error("Expected an error for a branch out of range")
+		}
+	})
+
+	t.Run("animated removal", func(t *testing.T) {
+		current := map[string]interface{}{
+			"s": []interface{}{`<li data-key="`, `">`, "</li>"},
+			"d": []interface{}{map[string]interface{}{"0": "a", "1": "Alpha"}, map[string]interface{}{"0": "b", "1": "Beta"}},
+		}
+		update := map[string]interface{}{"d": []interface{}{[]interface{}{"r", "a", map[string]interface{}{"animate": true}}}}
+
+		tree, err := applyTree(current, update, defaultKeyAttributes)
+		if err != nil {
+			t.Fatalf("applyTree failed: %v", err)
+		}
+		if items := tree["d"].([]interface{}); len(items) != 1 {
+			t.Errorf("Expected the item removed after its transition, got %v", items)
+		}
+	})
+
+	t.Run("unknown operation", func(t *testing.T) {
+		current := map[string]interface{}{"s": []interface{}{"<li>", "</li>"}, "d": []interface{}{}}
+		update := map[string]interface{}{"d": []interface{}{[]interface{}{"x", "a"}}}
+		if _, err := applyTree(current, update, defaultKeyAttributes); err == nil || !strings.Contains(err.Error(), "unknown range operation") {
+			t.Errorf("Expected an unknown operation to be rejected, got %v", err)
+		}
+	})
+}
+
+type rowsState struct {
+	Rows []string
+}
+
+func (s *rowsState) Change(ctx *livetemplate.ActionContext) error {
+	if ctx.Action == "drop" {
+		s.Rows = s.Rows[1:]
+	}
+	return nil
+}
+
+func TestClient_KeyAttributes(t *testing.T) {
+	tmpl := livetemplate.New("rows", livetemplate.WithKeyAttributes([]string{"data-row"}))
+	if _, err := tmpl.Parse(`<ul>{{range .Rows}}<li data-row="{{.}}">{{.}}</li>{{end}}</ul>`); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	client := NewClient(tmpl.Handle(&rowsState{Rows: []string{"a", "b", "c"}}))
+	defer client.Close()
+
+	if !reflect.DeepEqual(client.keyAttrs, []string{"data-row"}) {
+		t.Errorf("Expected the key attributes of the template, got %v", client.keyAttrs)
+	}
+	if _, err := client.Mount(); err != nil {
+		t.Fatalf("Mount failed: %v", err)
+	}
+	if err := client.SendAction("drop", nil); err != nil {
+		t.Fatalf("SendAction failed: %v", err)
+	}
+	update, err := client.NextUpdate()
+	if err != nil {
+		t.Fatalf("NextUpdate failed: %v", err)
+	}
+	items, _ := update.Tree["0"].(map[string]interface{})["d"].([]interface{})
+	if len(items) != 2 {
+		t.Errorf("Expected 2 rows after the drop, got %v", update.Tree)
+	}
+}
@@ -0,0 +1,70 @@
+package lvttest
+
+import (
+	"context"
+	"net"
+	"sync"
+)
+
+// pipeListener is an in-memory net.Listener whose connections are net.Pipe pairs.
+// It lets a real http.Server serve WebSocket upgrades without opening a port.
+type pipeListener struct {
+	conns     chan net.Conn
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+// newPipeListener creates a listener ready to accept in-memory connections
+func newPipeListener() *pipeListener {
+	return &pipeListener{
+		conns:  make(chan net.Conn),
+		closed: make(chan struct{}),
+	}
+}
+
+// Accept waits for the next dialed connection
+func (l *pipeListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case <-l.closed:
+		return nil, net.ErrClosed
+	}
+}
+
+// Close stops accepting connections. Established pipes are unaffected.
+func (l *pipeListener) Close() error {
+	l.closeOnce.Do(func() {
+		close(l.closed)
+	})
+	return nil
+}
+
+// Addr returns a placeholder address for the in-memory listener
+func (l *pipeListener) Addr() net.Addr {
+	return pipeAddr{}
+}
+
+// DialContext creates a new pipe and hands the server end to Accept
+func (l *pipeListener) DialContext(ctx context.Context, _, _ string) (net.Conn, error) {
+	server, client := net.Pipe()
+
+	select {
+	case l.conns <- server:
+		return client, nil
+	case <-l.closed:
+		server.Close()
+		client.Close()
+		return nil, net.ErrClosed
+	case <-ctx.Done():
+		server.Close()
+		client.Close()
+		return nil, ctx.Err()
+	}
+}
+
+// pipeAddr is the net.Addr of an in-memory pipe
+type pipeAddr struct{}
+
+func (pipeAddr) Network() string { return "pipe" }
+func (pipeAddr) String() string  { return "lvttest" }
@@ -0,0 +1,315 @@
+package lvttest
+
+import (
+	"fmt"
+	"strings"
+)
+
+// defaultKeyAttributes identify range items for handlers that don't report their own,
+// see Client.keyAttrs
+var defaultKeyAttributes = []string{"data-lvt-key", "data-key", "key", "id"}
+
+// applyTree merges a tree update into the current tree the same way the browser client does:
+// nodes carrying statics replace what was there, switch nodes selecting another branch are
+// replaced with the statics of that branch, nodes without statics are merged into the
+// existing node, and range operation lists are applied to the existing range items, found by
+// keyAttrs. Updates of a shape the client doesn't know are rejected.
+func applyTree(current, update map[string]interface{}, keyAttrs []string) (map[string]interface{}, error) {
+	if update == nil {
+		return current, nil
+	}
+	if current == nil {
+		return copyValue(update).(map[string]interface{}), nil
+	}
+	if _, hasStatics := update["s"]; hasStatics {
+		if _, isRange := update["d"]; !isRange || !isRangeOperations(update["d"]) {
+			return copyValue(update).(map[string]interface{}), nil
+		}
+	}
+	if _, switched := update["b"]; switched {
+		return applySwitch(current, update)
+	}
+
+	result := copyValue(current).(map[string]interface{})
+
+	// Top-level range updates arrive as {"d": [operations]}
+	if ops, ok := update["d"].([]interface{}); ok && isRangeOperations(ops) && isRange(result) {
+		if err := applyRangeOperations(result, ops, keyAttrs); err != nil {
+			return nil, err
+		}
+		for k, v := range update {
+			if k != "d" {
+				result[k] = copyValue(v)
+			}
+		}
+		return result, nil
+	}
+
+	for k, v := range update {
+		switch value := v.(type) {
+		case map[string]interface{}:
+			if existing, ok := result[k].(map[string]interface{}); ok {
+				applied, err := applyTree(existing, value, keyAttrs)
+				if err != nil {
+					return nil, fmt.Errorf("%s: %w", k, err)
+				}
+				result[k] = applied
+			} else {
+				result[k] = copyValue(value)
+			}
+		case []interface{}:
+			if existing, ok := result[k].(map[string]interface{}); ok && isRange(existing) && isRangeOperations(value) {
+				if err := applyRangeOperations(existing, value, keyAttrs); err != nil {
+					return nil, fmt.Errorf("%s: %w", k, err)
+				}
+			} else {
+				result[k] = copyValue(value)
+			}
+		default:
+			result[k] = value
+		}
+	}
+
+	return result, nil
+}
+
+// applySwitch replaces a switch node by an update selecting another branch ("b"), whose
+// statics are those of the branch in the node's "bs"
+func applySwitch(current, update map[string]interface{}) (map[string]interface{}, error) {
+	branches, ok := current["bs"].([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("branch update %v for a node that isn't a switch", update["b"])
+	}
+	index, ok := update["b"].(float64)
+	if !ok || index < 0 || int(index) >= len(branches) {
+		return nil, fmt.Errorf("switch update selects branch %v of %d", update["b"], len(branches))
+	}
+
+	result := copyValue(update).(map[string]interface{})
+	result["s"] = copyValue(branches[int(index)])
+	result["bs"] = copyValue(current["bs"])
+	return result, nil
+}
+
+// resolveStatics caches the statics of nodes sent with an ID ("si") and replaces references
+// ("sr") by the cached statics, as the browser client does; value is modified in place
+func resolveStatics(value interface{}, cache map[string]interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if id, ok := v["si"].(string); ok {
+			cache[id] = v["s"]
+			delete(v, "si")
+		}
+		if id, ok := v["sr"].(string); ok {
+			v["s"] = copyValue(cache[id])
+			delete(v, "sr")
+		}
+		for k, child := range v {
+			v[k] = resolveStatics(child, cache)
+		}
+	case []interface{}:
+		for i, child := range v {
+			v[i] = resolveStatics(child, cache)
+		}
+	}
+	return value
+}
+
+// applyRangeOperations applies ["u"|"i"|"r"|"a"|"o", ...] operations to a range node in place,
+// finding items by keyAttrs. Operations of an unknown shape or for unknown keys are rejected.
+func applyRangeOperations(rangeNode map[string]interface{}, ops []interface{}, keyAttrs []string) error {
+	items, _ := rangeNode["d"].([]interface{})
+
+	for _, raw := range ops {
+		op, ok := raw.([]interface{})
+		if !ok || len(op) < 2 {
+			return fmt.Errorf("invalid range operation %v", raw)
+		}
+		opType, _ := op[0].(string)
+		statics := rangeNode["s"]
+
+		switch opType {
+		case "r": // ["r", key] or ["r", key, {"animate": true}]
+			// An animated removal ends with the item removed once its exit transition has run
+			if len(op) > 2 {
+				if _, ok := op[2].(map[string]interface{}); !ok {
+					return fmt.Errorf("invalid options in range operation %v", op)
+				}
+			}
+			key := fmt.Sprint(op[1])
+			idx := indexOfKey(items, key, statics, keyAttrs)
+			if idx < 0 {
+				return fmt.Errorf("remove: no range item with key %q (key attributes %v)", key, keyAttrs)
+			}
+			items = append(items[:idx], items[idx+1:]...)
+
+		case "u": // ["u", key, changes]
+			if len(op) < 3 {
+				return fmt.Errorf("invalid range operation %v", op)
+			}
+			key := fmt.Sprint(op[1])
+			changes, ok := op[2].(map[string]interface{})
+			if !ok {
+				return fmt.Errorf("invalid changes in range operation %v", op)
+			}
+			idx := indexOfKey(items, key, statics, keyAttrs)
+			if idx < 0 {
+				return fmt.Errorf("update: no range item with key %q (key attributes %v)", key, keyAttrs)
+			}
+			if item, ok := items[idx].(map[string]interface{}); ok {
+				applied, err := applyTree(item, changes, keyAttrs)
+				if err != nil {
+					return fmt.Errorf("update %q: %w", key, err)
+				}
+				items[idx] = applied
+			}
+
+		case "a": // ["a", items] or ["a", items, statics]
+			if len(op) > 2 && op[2] != nil {
+				rangeNode["s"] = copyValue(op[2])
+			}
+			items = append(items, toItems(op[1])...)
+
+		case "i": // ["i", targetKey, position, item]
+			if len(op) < 4 {
+				return fmt.Errorf("invalid range operation %v", op)
+			}
+			position, _ := op[2].(string)
+			toInsert := toItems(op[3])
+
+			if op[1] == nil {
+				if position == "start" {
+					items = append(toInsert, items...)
+				} else {
+					items = append(items, toInsert...)
+				}
+				continue
+			}
+
+			idx := indexOfKey(items, fmt.Sprint(op[1]), statics, keyAttrs)
+			if idx < 0 {
+				return fmt.Errorf("insert: no range item with key %q (key attributes %v)", fmt.Sprint(op[1]), keyAttrs)
+			}
+			if position != "before" {
+				idx++
+			}
+			items = append(items[:idx], append(toInsert, items[idx:]...)...)
+
+		case "o": // ["o", [key1, key2, ...]]
+			order, ok := op[1].([]interface{})
+			if !ok {
+				return fmt.Errorf("invalid range operation %v", op)
+			}
+			byKey := make(map[string]interface{}, len(items))
+			for _, item := range items {
+				if itemMap, ok := item.(map[string]interface{}); ok {
+					byKey[itemKey(itemMap, statics, keyAttrs)] = item
+				}
+			}
+			reordered := make([]interface{}, 0, len(order))
+			for _, key := range order {
+				item, ok := byKey[fmt.Sprint(key)]
+				if !ok {
+					return fmt.Errorf("reorder: no range item with key %q (key attributes %v)", fmt.Sprint(key), keyAttrs)
+				}
+				reordered = append(reordered, item)
+			}
+			items = reordered
+
+		default:
+			return fmt.Errorf("unknown range operation %v", op)
+		}
+	}
+
+	rangeNode["d"] = items
+	return nil
+}
+
+// indexOfKey returns the position of the item with the given key, or -1
+func indexOfKey(items []interface{}, key string, statics interface{}, keyAttrs []string) int {
+	for i, item := range items {
+		if itemMap, ok := item.(map[string]interface{}); ok && itemKey(itemMap, statics, keyAttrs) == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// itemKey finds a range item's key using the same rules as the server:
+// the reserved "_k" field, then the dynamic following one of keyAttrs in the statics
+func itemKey(item map[string]interface{}, statics interface{}, keyAttrs []string) string {
+	if k, ok := item["_k"].(string); ok {
+		return k
+	}
+	return fmt.Sprint(item[fmt.Sprintf("%d", keyPosition(statics, keyAttrs))])
+}
+
+// keyPosition returns the index of the dynamic holding the item key
+func keyPosition(statics interface{}, keyAttrs []string) int {
+	list, _ := statics.([]interface{})
+	for i, s := range list {
+		str, _ := s.(string)
+		for _, attr := range keyAttrs {
+			if strings.Contains(str, attr+`="`) {
+				return i
+			}
+		}
+	}
+	return 0
+}
+
+// isRange reports whether a node is a range comprehension ({"s": [...], "d": [...]})
+func isRange(node map[string]interface{}) bool {
+	_, hasD := node["d"]
+	_, hasS := node["s"]
+	return hasD && hasS
+}
+
+// isRangeOperations reports whether a value is a list of range operations
+func isRangeOperations(value interface{}) bool {
+	list, ok := value.([]interface{})
+	if !ok || len(list) == 0 {
+		return false
+	}
+	for _, raw := range list {
+		op, ok := raw.([]interface{})
+		if !ok || len(op) == 0 {
+			return false
+		}
+		if _, ok := op[0].(string); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// toItems normalizes an operation payload (single item or list) to a list of items
+func toItems(value interface{}) []interface{} {
+	if list, ok := value.([]interface{}); ok {
+		return copyValue(list).([]interface{})
+	}
+	if value == nil {
+		return nil
+	}
+	return []interface{}{copyValue(value)}
+}
+
+// copyValue deep-copies decoded JSON values so callers can't mutate client state
+func copyValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			result[k] = copyValue(item)
+		}
+		return result
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for i, item := range v {
+			result[i] = copyValue(item)
+		}
+		return result
+	default:
+		return v
+	}
+}
@@ -0,0 +1,60 @@
+package livetemplate
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// WithMainTemplate selects the main template of ParseFiles and ParseGlob by name instead
+// of taking the first file, so files can be passed in any order:
+//
+//	tmpl := livetemplate.New("app", livetemplate.WithMainTemplate("layout"))
+//	tmpl.ParseGlob("templates/*.tmpl") // pages.tmpl, layout.tmpl, ...
+//
+// name is the name of a {{define}} or {{block}} in one of the files, which renders with the
+// template's data as if the main file were {{template "layout" .}}. Failing that, it is the
+// base name of one of the files, with or without its extension ("layout.tmpl" or "layout"),
+// and that file is used as if it were passed first. ParseFiles fails if no definition or
+// file has the name.
+//
+// Default: "" (the first file is the main template)
+func WithMainTemplate(name string) Option {
+	return func(c *Config) {
+		c.MainTemplate = name
+	}
+}
+
+// selectMainTemplate arranges the files of ParseFiles so the main template of
+// WithMainTemplate comes first, or is preceded by an invocation of the definition
+func (t *Template) selectMainTemplate(names, texts []string) ([]string, []string, error) {
+	main := t.config.MainTemplate
+	if main == "" {
+		return names, texts, nil
+	}
+
+	defined := regexp.MustCompile(`\{\{-?\s*(?:define|block)\s+"` + regexp.QuoteMeta(main) + `"`)
+	for _, text := range texts {
+		if !defined.MatchString(text) {
+			continue
+		}
+		if main == t.name {
+			return nil, nil, fmt.Errorf("main template %q has the name of the template itself", main)
+		}
+		return append([]string{main}, names...), append([]string{fmt.Sprintf("{{template %q .}}", main)}, texts...), nil
+	}
+
+	for i, name := range names {
+		base := filepath.Base(name)
+		if base != main && strings.TrimSuffix(base, filepath.Ext(base)) != main {
+			continue
+		}
+		ordered, orderedTexts := []string{name}, []string{texts[i]}
+		ordered = append(append(ordered, names[:i]...), names[i+1:]...)
+		orderedTexts = append(append(orderedTexts, texts[:i]...), texts[i+1:]...)
+		return ordered, orderedTexts, nil
+	}
+
+	return nil, nil, fmt.Errorf("main template %q is neither defined in nor the name of one of %v", main, names)
+}
@@ -5,10 +5,15 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
+	"net/url"
 	"reflect"
+	"sort"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/gorilla/websocket"
 )
@@ -25,9 +30,9 @@ type BroadcastAware interface {
 	OnDisconnect()
 }
 
-// broadcaster implements the Broadcaster interface for a single WebSocket connection
+// broadcaster implements the Broadcaster interface for a single WebSocket or SSE connection
 type broadcaster struct {
-	conn     *websocket.Conn
+	conn     *Connection
 	template *Template
 	state    *connState
 	handler  *liveHandler
@@ -45,9 +50,14 @@ func (b *broadcaster) Send() error {
 		return fmt.Errorf("template update failed: %w", err)
 	}
 
+	// Nothing to send when the content is unchanged (WithSuppressUnchanged)
+	if buf.Len() == 0 {
+		return nil
+	}
+
 	// Parse tree from buffer
-	var tree treeNode
-	if err := json.Unmarshal(buf.Bytes(), &tree); err != nil {
+	tree, err := parseUpdateTree(buf.Bytes())
+	if err != nil {
 		return fmt.Errorf("failed to parse tree: %w", err)
 	}
 
@@ -55,8 +65,11 @@ func (b *broadcaster) Send() error {
 	response := UpdateResponse{
 		Tree: tree,
 		Meta: &ResponseMetadata{
-			Success: len(b.state.getErrors()) == 0,
-			Errors:  b.state.getErrors(),
+			Success:     len(b.state.getErrors()) == 0,
+			Errors:      b.state.getErrors(),
+			Fingerprint: b.template.Fingerprint(),
+			Warnings:    b.template.overlayWarnings(),
+			InputBound:  b.template.InputBoundSlots(),
 		},
 	}
 
@@ -66,7 +79,7 @@ func (b *broadcaster) Send() error {
 		return fmt.Errorf("failed to marshal response: %w", err)
 	}
 
-	return writeUpdateWebSocket(b.conn, responseBytes)
+	return b.conn.Send(websocket.TextMessage, responseBytes)
 }
 
 // LiveHandler is the interface returned by Template.Handle()
@@ -101,6 +114,22 @@ type LiveHandler interface {
 	// Example: Update all tabs for a specific session group
 	//   handler.BroadcastToGroup("session-abc", SessionState{...})
 	BroadcastToGroup(groupID string, data interface{}) error
+
+	// PushToUser runs an action on every WebSocket connection of a user, across session
+	// groups, and returns the number of connections reached.
+	//
+	// Example: Show a notification on all of a user's devices
+	//   handler.PushToUser("user-123", "notify", map[string]interface{}{"text": "Build finished"})
+	PushToUser(userID, action string, payload interface{}) int
+
+	// Shutdown closes every WebSocket connection with CloseShutdown (1001) so clients
+	// reconnect. Call it before http.Server.Shutdown, which doesn't close them.
+	Shutdown()
+
+	// ConnectionStats returns traffic counters for every active WebSocket and SSE connection:
+	// bytes and updates sent, and when the connection was established.
+	// Useful for debugging payload sizes or per-session usage accounting.
+	ConnectionStats() []ConnStat
 }
 
 // MountConfig configures the mount handler
@@ -113,6 +142,23 @@ type MountConfig struct {
 	Authenticator     Authenticator
 	AllowedOrigins    []string
 	WebSocketDisabled bool
+	ActionTimeout     time.Duration
+	SSEHeartbeat      time.Duration
+	ResumeWindow      time.Duration
+	NonceWindow       time.Duration
+	ClientPreloadURL  string
+	BroadcastCoalesce time.Duration
+	OutboundQueue     int
+	IdleTimeout       time.Duration
+	MaxGroupConns     int
+	MaxPayloadSize    int
+	ActionFallbacks   bool
+	LocaleResolver    func(r *http.Request) string
+	Reconnect         *ReconnectPolicy
+	CSRFSecret        []byte
+	ViewModel         func(store Store) interface{}
+
+	ActionPayloadSizes map[string]int
 }
 
 // MountConfig and related types are used internally by Template.Handle()
@@ -123,12 +169,74 @@ type MountOption func(*MountConfig)
 type liveHandler struct {
 	config   MountConfig
 	registry *ConnectionRegistry
+	resume   *resumeCache
+	coalesce *broadcastCoalescer // nil unless WithBroadcastCoalesce is set
+	csrf     *csrfGuard          // nil unless WithCSRFProtection is set
+	nonces   *nonceCache         // Nonces of HTTP actions by session group, see WithNonceWindow
+	changes  groupChanges        // Changes outliving their action timeout, see callChange
+}
+
+// groupChanges tracks, per session group, a Change that outlived its action timeout. The
+// group's stores belong to it until it returns, so actions on any connection or request of
+// the group wait for it first.
+type groupChanges struct {
+	mu      sync.Mutex
+	running map[string]chan struct{} // Closed when the group's Change returns
+}
+
+// start records a timed-out Change of groupID, returning the channel to pass to done
+func (g *groupChanges) start(groupID string) chan struct{} {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.running == nil {
+		g.running = make(map[string]chan struct{})
+	}
+	running := make(chan struct{})
+	g.running[groupID] = running
+	return running
+}
+
+// done records that the Change started with running has returned
+func (g *groupChanges) done(groupID string, running chan struct{}) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.running[groupID] == running {
+		delete(g.running, groupID)
+	}
+	close(running)
+}
+
+// wait blocks until no timed-out Change of groupID is running, or until ctx is done
+func (g *groupChanges) wait(ctx context.Context, groupID string) error {
+	for {
+		g.mu.Lock()
+		running := g.running[groupID]
+		g.mu.Unlock()
+		if running == nil {
+			return nil
+		}
+		select {
+		case <-running:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
 }
 
 type connState struct {
 	stores   Stores            // Each connection gets cloned stores
 	errors   map[string]string // Field errors from last action
 	errorsMu sync.RWMutex      // Mutex for thread-safe error access
+	url      *url.URL          // Page request or connection URL, exposed to stores via ActionContext
+	groupID  string            // Session group of the connection or request
+	conn     *Connection       // Connection actions arrive on (nil for HTTP requests)
+	head     *HeadMeta         // Head changes by the current action or Mount, see ActionContext.SetTitle
+	redirect string            // URL set by the current action, see ActionContext.Redirect
+	nonces   *nonceCache       // Nonces of the connection's actions (nil for HTTP requests)
+	timedOut bool              // The current action's Change outlived the action timeout, see callChange
+
+	signalsMu  sync.Mutex           // Protects lastSignal
+	lastSignal map[string]time.Time // When each signal was last sent, see ActionContext.Signal
 }
 
 func (c *connState) setError(field, message string) {
@@ -137,6 +245,38 @@ func (c *connState) setError(field, message string) {
 	c.errors[field] = message
 }
 
+// takeHead returns the head changes of the last action or Mount for its response, or nil
+// if there were none
+func (c *connState) takeHead() *HeadMeta {
+	head := c.head
+	c.head = nil
+	if head == nil || (head.Title == "" && len(head.Meta) == 0) {
+		return nil
+	}
+	return head
+}
+
+// takeRedirect returns the URL the last action redirects to, or "" if it doesn't
+func (c *connState) takeRedirect() string {
+	redirect := c.redirect
+	c.redirect = ""
+	return redirect
+}
+
+// setActionError records an error returned by Change or Mount
+func (c *connState) setActionError(err error) {
+	switch e := err.(type) {
+	case FieldError:
+		c.setError(e.Field, e.Message)
+	case MultiError:
+		for _, fieldErr := range e {
+			c.setError(fieldErr.Field, fieldErr.Message)
+		}
+	default:
+		c.setError("_general", err.Error())
+	}
+}
+
 func (c *connState) clearErrors() {
 	c.errorsMu.Lock()
 	defer c.errorsMu.Unlock()
@@ -163,12 +303,16 @@ func (h *liveHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("X-LiveTemplate-WebSocket", "enabled")
 	}
 
-	if websocket.IsWebSocketUpgrade(r) {
+	if h.isDebugStateRequest(r) {
+		h.handleDebugState(w, r)
+	} else if websocket.IsWebSocketUpgrade(r) {
 		if h.config.WebSocketDisabled {
 			http.Error(w, "WebSocket is disabled on this endpoint", http.StatusBadRequest)
 			return
 		}
 		h.handleWebSocket(w, r)
+	} else if isEventStreamRequest(r) {
+		h.handleSSE(w, r)
 	} else {
 		h.handleHTTP(w, r)
 	}
@@ -179,7 +323,14 @@ func (h *liveHandler) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 	userID, err := h.config.Authenticator.Identify(r)
 	if err != nil {
 		log.Printf("Authentication failed: %v", err)
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		// Browsers don't expose the status of a failed handshake, so the rejection is
+		// sent as a close code the client can tell from a dropped connection
+		conn, upgradeErr := h.config.Upgrader.Upgrade(w, r, nil)
+		if upgradeErr != nil {
+			return
+		}
+		defer conn.Close()
+		writeClose(conn, CloseUnauthorized, "unauthorized")
 		return
 	}
 
@@ -190,6 +341,19 @@ func (h *liveHandler) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	roles, err := h.userRoles(r, userID)
+	if err != nil {
+		log.Printf("Failed to get roles: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if h.csrf != nil && !h.csrf.valid(groupID, r.URL.Query().Get(csrfParam)) {
+		log.Printf("WebSocket CSRF token rejected: group=%q, addr=%s", groupID, r.RemoteAddr)
+		http.Error(w, "Invalid CSRF token", http.StatusForbidden)
+		return
+	}
+
 	// Set session cookie if this is a new session (cookie doesn't exist)
 	setCookieIfNew(w, r, groupID)
 
@@ -211,6 +375,45 @@ func (h *liveHandler) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 		log.Printf("Failed to clone template: %v", err)
 		return
 	}
+	connTmpl.startProgressive()
+
+	// A client reconnecting with ?lvt-resume sends its resume token before anything else.
+	// If its baseline is still known, keep diffing against it instead of starting over.
+	resumeToken := generateSessionID()
+	var pending []byte // First message, when it turned out not to be a resume request
+	if r.URL.Query().Has("lvt-resume") {
+		_ = conn.SetReadDeadline(time.Now().Add(resumeHandshakeTimeout))
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			log.Printf("Failed to read resume message: %v", err)
+			return
+		}
+		_ = conn.SetReadDeadline(time.Time{})
+
+		if msg, ok := parseResumeMessage(data); ok {
+			// A client holding statics of another template version needs a full tree
+			staleStatics := msg.StaticsVersion != "" && msg.StaticsVersion != h.config.Template.StaticsVersion()
+			if resumed := h.resume.take(msg.Token, groupID); resumed != nil && resumed.Fingerprint() == msg.Fingerprint && !staleStatics {
+				connTmpl = resumed
+				resumeToken = msg.Token
+				log.Printf("Resumed connection: user=%q, group=%q", userID, groupID)
+			}
+		} else {
+			pending = data
+		}
+	}
+
+	connTmpl.setUser(userID, roles)
+	connTmpl.setLocale(h.locale(r))
+
+	// Keep this connection's baseline after it closes so the client can resume
+	defer func() {
+		window := h.config.ResumeWindow
+		if window <= 0 {
+			window = defaultResumeWindow
+		}
+		h.resume.store(resumeToken, connTmpl, groupID, window)
+	}()
 
 	// Get or create stores for this session group
 	stores := h.config.SessionStore.Get(groupID)
@@ -227,25 +430,44 @@ func (h *liveHandler) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 		UserID:   userID,
 		Template: connTmpl,
 		Stores:   stores,
+		token:    resumeToken,
+		pushes:   make(chan message, pushBuffer),
+	}
+	if h.config.OutboundQueue > 0 {
+		connection.startOutboundQueue(h.config.OutboundQueue)
+		defer connection.queue.drop()
 	}
 
-	h.registry.Register(connection)
-	defer h.registry.Unregister(connection)
+	if limit := h.config.MaxGroupConns; !h.registry.TryRegister(connection, limit) {
+		log.Printf("Rejecting connection: group %q already has %d connections", groupID, limit)
+		writeClose(conn, websocket.ClosePolicyViolation, fmt.Sprintf("too many connections in session group (max %d)", limit))
+		return
+	}
+	defer h.unregister(connection)
 	log.Printf("Registered connection (total: %d, groups: %d)", h.registry.Count(), h.registry.GroupCount())
 
 	// Create connection state (errors are per-connection, not shared)
 	state := &connState{
-		stores: stores,
-		errors: make(map[string]string),
+		stores:  stores,
+		errors:  make(map[string]string),
+		url:     r.URL,
+		groupID: groupID,
+		conn:    connection,
+		nonces:  newNonceCache(h.config.NonceWindow),
 	}
 
-	// Create context for broadcaster lifecycle
-	ctx, cancel := context.WithCancel(context.Background())
+	// Create context for the connection lifecycle (broadcaster and actions).
+	// Derived from the request so request-scoped values reach Change handlers.
+	ctx, cancel := context.WithCancel(r.Context())
 	defer cancel()
 
+	if h.config.IdleTimeout > 0 {
+		go connection.closeWhenIdle(ctx, h.config.IdleTimeout)
+	}
+
 	// Create broadcaster for server-initiated updates
 	bc := &broadcaster{
-		conn:     conn,
+		conn:     connection,
 		template: connTmpl,
 		state:    state,
 		handler:  h,
@@ -262,6 +484,9 @@ func (h *liveHandler) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// Let stores load state for the requested route
+	h.mountStores(ctx, state)
+
 	// Send initial tree
 	var buf bytes.Buffer
 
@@ -272,57 +497,97 @@ func (h *liveHandler) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Parse tree from buffer
-	var tree treeNode
-	if err := json.Unmarshal(buf.Bytes(), &tree); err != nil {
+	tree, err := parseUpdateTree(buf.Bytes())
+	if err != nil {
 		log.Printf("Failed to parse initial tree: %v", err)
 		return
 	}
 
-	// Wrap with metadata (initial load has no action)
-	response := UpdateResponse{
-		Tree: tree,
-		Meta: &ResponseMetadata{
-			Success: len(state.getErrors()) == 0,
-			Errors:  state.getErrors(),
-		},
-	}
-
 	// Encode and send wrapped response
-	responseBytes, err := json.Marshal(response)
+	responseBytes, err := json.Marshal(h.initialResponse(connTmpl, state, tree, resumeToken))
 	if err != nil {
 		log.Printf("Failed to marshal initial response: %v", err)
 		return
 	}
 
-	err = writeUpdateWebSocket(conn, responseBytes)
+	err = connection.Send(websocket.TextMessage, responseBytes)
 	if err != nil {
 		log.Printf("Failed to send initial tree: %v", err)
 		return
 	}
 
-	// message loop
-	for {
-		_, data, err := conn.ReadMessage()
-		if err != nil {
-			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
-				log.Printf("WebSocket error: %v", err)
+	if err := h.streamProgressiveRanges(connection, state); err != nil {
+		log.Printf("Failed to stream progressive ranges: %v", err)
+		return
+	}
+
+	// Read in a separate goroutine so a closed connection cancels ctx,
+	// and with it any action still running, without waiting for the action to return
+	messages := make(chan []byte)
+	go func() {
+		defer close(messages)
+		defer cancel()
+		if pending != nil {
+			select {
+			case messages <- pending:
+			case <-ctx.Done():
+				return
 			}
-			break
 		}
+		for {
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
+					log.Printf("WebSocket error: %v", err)
+				}
+				return
+			}
+			connection.touch()
+			select {
+			case messages <- data:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
 
-		// Parse message
-		msg, err := parseActionFromWebSocket(data)
-		if err != nil {
-			log.Printf("Failed to parse message: %v", err)
-			continue
+	// message loop: actions from the client and actions pushed by PushToUser, in order
+loop:
+	for {
+		var msg message
+		select {
+		case data, ok := <-messages:
+			if !ok {
+				break loop
+			}
+			// Parse message
+			if msg, err = parseActionFromWebSocket(data); err != nil {
+				log.Printf("Failed to parse message: %v", err)
+				continue
+			}
+		case msg = <-connection.pushes:
 		}
 
 		// Handle action
-		if err := h.handleAction(msg, state); err != nil {
+		if err := h.handleAction(ctx, msg, state); err != nil {
 			log.Printf("Action error: %v", err)
 			continue
 		}
 
+		// A timed-out Change still owns the stores: report the timeout without rendering them
+		if state.timedOut {
+			responseBytes, err := json.Marshal(timedOutResponse(msg, state.getErrors()))
+			if err != nil {
+				log.Printf("Failed to marshal response: %v", err)
+				continue
+			}
+			if err := connection.Send(websocket.TextMessage, responseBytes); err != nil {
+				log.Printf("WebSocket write failed: %v", err)
+				break
+			}
+			continue
+		}
+
 		// Auto-broadcast to other connections in same session group
 		// This ensures all tabs in the same browser session stay in sync
 		go func() {
@@ -336,17 +601,33 @@ func (h *liveHandler) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 			}
 		}()
 
+		// This response renders the current state, newer than a broadcast still pending
+		if h.coalesce != nil {
+			h.coalesce.drop(connection)
+		}
+
 		// Generate tree update
 		buf.Reset()
-		err = connTmpl.ExecuteUpdates(&buf, h.getTemplateData(state.stores), state.getErrors())
+		templateData := h.getTemplateData(state.stores)
+		err = connTmpl.ExecuteUpdates(&buf, templateData, state.getErrors())
 		if err != nil {
-			log.Printf("Template update execution failed: %v", err)
+			// Keep the connection: report the failure and leave the client on its last good render
+			log.Printf("Template update execution failed for %s: %v", dataShape(templateData), err)
+			responseBytes, err := json.Marshal(renderFailedResponse(msg))
+			if err != nil {
+				log.Printf("Failed to marshal response: %v", err)
+				continue
+			}
+			if err := connection.Send(websocket.TextMessage, responseBytes); err != nil {
+				log.Printf("WebSocket write failed: %v", err)
+				break
+			}
 			continue
 		}
 
 		// Parse tree from buffer
-		var tree treeNode
-		if err := json.Unmarshal(buf.Bytes(), &tree); err != nil {
+		tree, err := parseUpdateTree(buf.Bytes())
+		if err != nil {
 			log.Printf("Failed to parse tree: %v", err)
 			continue
 		}
@@ -355,9 +636,16 @@ func (h *liveHandler) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 		response := UpdateResponse{
 			Tree: tree,
 			Meta: &ResponseMetadata{
-				Success: len(state.getErrors()) == 0,
-				Errors:  state.getErrors(),
-				Action:  msg.Action,
+				Success:     len(state.getErrors()) == 0,
+				Errors:      state.getErrors(),
+				Action:      msg.Action,
+				Fingerprint: connTmpl.Fingerprint(),
+				Warnings:    connTmpl.overlayWarnings(),
+				InputBound:  connTmpl.InputBoundSlots(),
+				Head:        state.takeHead(),
+				Redirect:    state.takeRedirect(),
+
+				CorrelationID: msg.correlationID,
 			},
 		}
 
@@ -368,7 +656,7 @@ func (h *liveHandler) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 			continue
 		}
 
-		err = writeUpdateWebSocket(conn, responseBytes)
+		err = connection.Send(websocket.TextMessage, responseBytes)
 		if err != nil {
 			log.Printf("WebSocket write failed: %v", err)
 			break
@@ -378,6 +666,50 @@ func (h *liveHandler) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 	log.Printf("Client disconnected: user=%q, group=%q (remaining: %d)", userID, groupID, h.registry.Count())
 }
 
+// initialResponse wraps the initial tree of a connection with the metadata of its first
+// render: Mount errors and head, the client config and, for WebSocket, the resume token
+func (h *liveHandler) initialResponse(tmpl *Template, state *connState, tree treeNode, resumeToken string) UpdateResponse {
+	return UpdateResponse{
+		Tree: tree,
+		Meta: &ResponseMetadata{
+			Success:        len(state.getErrors()) == 0,
+			Errors:         state.getErrors(),
+			ResumeToken:    resumeToken,
+			Fingerprint:    tmpl.Fingerprint(),
+			StaticsVersion: tmpl.StaticsVersion(),
+			Warnings:       tmpl.overlayWarnings(),
+			InputBound:     tmpl.InputBoundSlots(),
+			Config:         h.clientConfig(),
+			Head:           state.takeHead(),
+		},
+	}
+}
+
+// userRoles returns the roles of userID if the Authenticator is a RoleProvider
+func (h *liveHandler) userRoles(r *http.Request, userID string) ([]string, error) {
+	provider, ok := h.config.Authenticator.(RoleProvider)
+	if !ok {
+		return nil, nil
+	}
+	return provider.Roles(r, userID)
+}
+
+// clientConfig returns the client settings for the initial envelope, nil if all are defaults
+func (h *liveHandler) clientConfig() *ClientConfig {
+	if h.config.Reconnect == nil {
+		return nil
+	}
+	return &ClientConfig{Reconnect: h.config.Reconnect}
+}
+
+// locale returns the locale of a request from the LocaleResolver, "" without one
+func (h *liveHandler) locale(r *http.Request) string {
+	if h.config.LocaleResolver == nil {
+		return ""
+	}
+	return h.config.LocaleResolver(r)
+}
+
 // setCookieIfNew sets the livetemplate-id cookie if it doesn't already exist
 func setCookieIfNew(w http.ResponseWriter, r *http.Request, groupID string) {
 	// Check if cookie already exists
@@ -419,6 +751,26 @@ func (h *liveHandler) handleHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// The shared template renders for anonymous users without a locale only; other requests
+	// get a template of their own so the lvt namespace can't leak between requests
+	tmpl := h.config.Template
+	if locale := h.locale(r); userID != "" || locale != "" {
+		var roles []string
+		if userID != "" {
+			if roles, err = h.userRoles(r, userID); err != nil {
+				log.Printf("Failed to get roles for HTTP: %v", err)
+				http.Error(w, "Internal server error", http.StatusInternalServerError)
+				return
+			}
+		}
+		if tmpl, err = h.config.Template.Clone(); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		tmpl.setUser(userID, roles)
+		tmpl.setLocale(locale)
+	}
+
 	// Set session cookie if this is a new session (cookie doesn't exist)
 	setCookieIfNew(w, r, groupID)
 
@@ -432,8 +784,10 @@ func (h *liveHandler) handleHTTP(w http.ResponseWriter, r *http.Request) {
 
 	// Create connection state (errors are per-request, not persisted)
 	state := &connState{
-		stores: stores,
-		errors: make(map[string]string),
+		stores:  stores,
+		errors:  make(map[string]string),
+		url:     r.URL,
+		groupID: groupID,
 	}
 
 	// Handle GET request for initial HTML page
@@ -448,10 +802,27 @@ func (h *liveHandler) handleHTTP(w http.ResponseWriter, r *http.Request) {
 			}
 		}
 
-		err := h.config.Template.Execute(w, h.getTemplateData(state.stores), state.getErrors())
+		h.mountStores(r.Context(), state)
+
+		if h.config.ClientPreloadURL != "" {
+			w.Header().Add("Link", fmt.Sprintf("<%s>; rel=preload; as=script", h.config.ClientPreloadURL))
+		}
+
+		if h.csrf == nil {
+			err := tmpl.Execute(w, h.getTemplateData(state.stores), state.getErrors())
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+			}
+			return
+		}
+
+		// Embed the session's CSRF token for the client to echo
+		page, err := tmpl.RenderString(h.getTemplateData(state.stores), state.getErrors())
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
 		}
+		_, _ = io.WriteString(w, injectCSRFToken(page, tmpl.wrapperID, h.csrf.token(groupID)))
 		return
 	}
 
@@ -461,6 +832,12 @@ func (h *liveHandler) handleHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if h.csrf != nil && !h.csrf.valid(groupID, requestToken(r)) {
+		log.Printf("HTTP CSRF token rejected: group=%q, addr=%s", groupID, r.RemoteAddr)
+		http.Error(w, "Invalid CSRF token", http.StatusForbidden)
+		return
+	}
+
 	// Parse message
 	msg, err := parseActionFromHTTP(r)
 	if err != nil {
@@ -469,10 +846,17 @@ func (h *liveHandler) handleHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Handle action
-	if err := h.handleAction(msg, state); err != nil {
+	if err := h.handleAction(r.Context(), msg, state); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	if state.timedOut {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(timedOutResponse(msg, state.getErrors())); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
 
 	// Auto-broadcast to all WebSocket connections in same session group
 	// This ensures all tabs in the same browser session stay in sync
@@ -488,19 +872,37 @@ func (h *liveHandler) handleHTTP(w http.ResponseWriter, r *http.Request) {
 		}
 	}()
 
+	// Requests not asking for JSON follow the action's redirect themselves
+	redirect := state.takeRedirect()
+	if redirect != "" && !acceptsJSON(r) {
+		http.Redirect(w, r, redirect, http.StatusFound)
+		return
+	}
+
+	// A browser without the client library posted a fallback form, see WithProgressiveEnhancement
+	if h.config.ActionFallbacks && isFallbackRequest(r) {
+		h.respondFallback(w, r, tmpl, state)
+		return
+	}
+
 	// Note: No need to save session - stores are modified in-place and already in SessionStore
 
 	// Generate tree update
 	var buf bytes.Buffer
-	err = h.config.Template.ExecuteUpdates(&buf, h.getTemplateData(state.stores), state.getErrors())
+	templateData := h.getTemplateData(state.stores)
+	err = tmpl.ExecuteUpdates(&buf, templateData, state.getErrors())
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		log.Printf("HTTP template update execution failed for %s: %v", dataShape(templateData), err)
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(renderFailedResponse(msg)); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
 		return
 	}
 
 	// Parse tree from buffer
-	var tree treeNode
-	if err := json.Unmarshal(buf.Bytes(), &tree); err != nil {
+	tree, err := parseUpdateTree(buf.Bytes())
+	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -509,9 +911,15 @@ func (h *liveHandler) handleHTTP(w http.ResponseWriter, r *http.Request) {
 	response := UpdateResponse{
 		Tree: tree,
 		Meta: &ResponseMetadata{
-			Success: len(state.getErrors()) == 0,
-			Errors:  state.getErrors(),
-			Action:  msg.Action,
+			Success:    len(state.getErrors()) == 0,
+			Errors:     state.getErrors(),
+			Action:     msg.Action,
+			Warnings:   tmpl.overlayWarnings(),
+			InputBound: tmpl.InputBoundSlots(),
+			Head:       state.takeHead(),
+			Redirect:   redirect,
+
+			CorrelationID: msg.correlationID,
 		},
 	}
 
@@ -522,27 +930,111 @@ func (h *liveHandler) handleHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// handleAction routes the action to the correct store and captures errors
-func (h *liveHandler) handleAction(msg message, state *connState) error {
+// renderFailedResponse is sent instead of a tree update when the template fails to execute.
+// The tree is empty, so the client keeps its last good render, and the template's diff
+// baseline is unchanged, so the next successful update still applies cleanly.
+func renderFailedResponse(msg message) UpdateResponse {
+	return UpdateResponse{
+		Tree: treeNode{},
+		Meta: &ResponseMetadata{
+			Success:       false,
+			Errors:        map[string]string{"_general": "Failed to render update"},
+			Action:        msg.Action,
+			CorrelationID: msg.correlationID,
+		},
+	}
+}
+
+// timedOutResponse is sent instead of a tree update when Change timed out. The stores
+// can't be rendered while the Change still runs, so the tree is empty as in
+// renderFailedResponse and the errors carry the timeout.
+func timedOutResponse(msg message, errors map[string]string) UpdateResponse {
+	return UpdateResponse{
+		Tree: treeNode{},
+		Meta: &ResponseMetadata{
+			Success:       false,
+			Errors:        errors,
+			Action:        msg.Action,
+			CorrelationID: msg.correlationID,
+		},
+	}
+}
+
+// dataShape describes the type and top-level fields of template data for error logs,
+// e.g. "*main.State{Count int, Items []main.Item}"
+func dataShape(data interface{}) string {
+	if data == nil {
+		return "<nil>"
+	}
+
+	val := reflect.ValueOf(data)
+	for val.Kind() == reflect.Ptr && !val.IsNil() {
+		val = val.Elem()
+	}
+
+	var fields []string
+	switch val.Kind() {
+	case reflect.Struct:
+		typ := val.Type()
+		for i := 0; i < typ.NumField(); i++ {
+			if field := typ.Field(i); field.IsExported() {
+				fields = append(fields, field.Name+" "+field.Type.String())
+			}
+		}
+	case reflect.Map:
+		for _, key := range val.MapKeys() {
+			elem := val.MapIndex(key)
+			elemType := "<nil>"
+			if elem.IsValid() && !(elem.Kind() == reflect.Interface && elem.IsNil()) {
+				elemType = reflect.TypeOf(elem.Interface()).String()
+			}
+			fields = append(fields, fmt.Sprintf("%v %s", key.Interface(), elemType))
+		}
+		sort.Strings(fields)
+	default:
+		return fmt.Sprintf("%T", data)
+	}
+
+	return fmt.Sprintf("%T{%s}", data, strings.Join(fields, ", "))
+}
+
+// handleAction routes the action to the correct store and captures errors.
+// ctx is exposed to the store as ActionContext.Context().
+func (h *liveHandler) handleAction(ctx context.Context, msg message, state *connState) error {
 	// Clear previous errors
 	state.clearErrors()
 
+	// An oversized payload is reported to the client like an error from Change
+	if err := h.checkPayloadSize(msg); err != nil {
+		state.setActionError(err)
+		return nil
+	}
+
+	// A repeated nonce is a retry of an action that already ran: answer it without Change
+	if msg.nonce != "" && h.repeatedNonce(state, msg.nonce) {
+		log.Printf("Skipped action %q: nonce %q already seen", msg.Action, msg.nonce)
+		return nil
+	}
+
 	// Parse action to extract store name
 	storeName, action := parseAction(msg.Action)
 
 	var store Store
 	if h.config.IsSingleStore {
 		// Single store mode
+		store = state.stores[""]
+
+		// A prefix can only name a child store of the single store
 		if storeName != "" {
-			return fmt.Errorf(
-				"unexpected store prefix '%s' in single-store mode\n"+
-					"Use action '%s' instead of '%s'",
-				storeName, action, msg.Action)
+			if findChildStore(store, storeName) == nil {
+				return fmt.Errorf(
+					"unexpected store prefix '%s' in single-store mode\n"+
+						"Use action '%s' instead of '%s'",
+					storeName, action, msg.Action)
+			}
+			action = msg.Action
 		}
 
-		// Get the single store
-		store = state.stores[""]
-
 	} else {
 		// Multi-store mode
 		if storeName == "" {
@@ -563,29 +1055,248 @@ func (h *liveHandler) handleAction(msg message, state *connState) error {
 		}
 	}
 
-	// Create action context
-	ctx := &ActionContext{
-		Action: action,
-		Data:   newActionData(msg.Data),
+	// "parent.child.action" goes to the child store of parent
+	store, action = routeAction(store, action)
+
+	// A Change of the group that timed out has the stores until it returns
+	state.timedOut = false
+	if err := h.changes.wait(ctx, state.groupID); err != nil {
+		return fmt.Errorf("action %q: waiting for a timed-out action: %w", msg.Action, err)
 	}
 
+	// Create action context
+	state.head = &HeadMeta{}
+	state.redirect = ""
+	actionCtx := &ActionContext{
+		Action:   action,
+		Data:     newActionData(msg.Data),
+		ctx:      ctx,
+		url:      state.url,
+		form:     msg.form,
+		head:     state.head,
+		redirect: &state.redirect,
+		loaders:  &loaderSet{},
+	}
+	actionCtx.dispatch = h.dispatcher(actionCtx, state, 0)
+	actionCtx.signal = h.signaler(state)
+	actionCtx.stores = h.storeFinder(state)
+
 	// Call Change and capture error
-	err := store.Change(ctx)
+	err := h.callChange(store, actionCtx, state)
 
 	if err != nil {
-		// Process the error
-		switch e := err.(type) {
-		case FieldError:
-			state.setError(e.Field, e.Message)
-		case MultiError:
-			for _, fieldErr := range e {
-				state.setError(fieldErr.Field, fieldErr.Message)
-			}
-		default:
-			state.setError("_general", err.Error())
+		state.setActionError(err)
+		state.redirect = ""
+	}
+
+	return nil
+}
+
+// maxDispatchDepth bounds chains of ActionContext.Dispatch calls, so stores dispatching
+// to each other fail instead of recursing forever
+const maxDispatchDepth = 8
+
+// dispatcher returns the ActionContext.Dispatch implementation for actions started from
+// parent, running the target store's Change directly within the parent action
+func (h *liveHandler) dispatcher(parent *ActionContext, state *connState, depth int) func(string, string, map[string]interface{}) error {
+	return func(storeName, action string, data map[string]interface{}) error {
+		if depth >= maxDispatchDepth {
+			return fmt.Errorf("dispatch %s.%s: exceeded maximum depth of %d nested dispatches", storeName, action, maxDispatchDepth)
+		}
+
+		topName, childPath, nested := strings.Cut(storeName, ".")
+		store := h.findStore(state.stores, topName)
+		if store == nil {
+			return fmt.Errorf("dispatch %s.%s: unknown store, available stores: %v", storeName, action, h.getStoreNames())
+		}
+		if nested {
+			store, action = routeAction(store, childPath+"."+action)
+		}
+
+		actionCtx := &ActionContext{
+			Action:   action,
+			Data:     newActionData(data),
+			ctx:      parent.ctx,
+			url:      parent.url,
+			signal:   parent.signal,
+			stores:   parent.stores,
+			head:     parent.head,
+			redirect: parent.redirect,
+			loaders:  parent.loaders,
+		}
+		actionCtx.dispatch = h.dispatcher(actionCtx, state, depth+1)
+		return applyAction(store, actionCtx)
+	}
+}
+
+// storeFinder returns the ActionContext.Store implementation for the stores of state
+func (h *liveHandler) storeFinder(state *connState) func(string) (Store, bool) {
+	return func(name string) (Store, bool) {
+		topName, childPath, nested := strings.Cut(name, ".")
+		store := h.findStore(state.stores, topName)
+		if store == nil {
+			return nil, false
+		}
+		for nested && store != nil {
+			var childName string
+			childName, childPath, nested = strings.Cut(childPath, ".")
+			store = findChildStore(store, childName)
+		}
+		return store, store != nil
+	}
+}
+
+// mountStores calls Mount on every store implementing Mounter, before the initial render,
+// in the order of the store names
+func (h *liveHandler) mountStores(ctx context.Context, state *connState) {
+	if err := h.changes.wait(ctx, state.groupID); err != nil {
+		return
+	}
+	state.head = &HeadMeta{}
+	loaders := &loaderSet{}
+
+	names := make([]string, 0, len(state.stores))
+	for name := range state.stores {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		mounter, ok := state.stores[name].(Mounter)
+		if !ok {
+			continue
+		}
+
+		mountCtx := &ActionContext{
+			Action:  "mount",
+			Data:    newActionData(make(map[string]interface{})),
+			ctx:     ctx,
+			url:     state.url,
+			head:    state.head,
+			loaders: loaders,
+		}
+		mountCtx.dispatch = h.dispatcher(mountCtx, state, 0)
+		mountCtx.stores = h.storeFinder(state)
+		if err := mounter.Mount(mountCtx); err != nil {
+			log.Printf("Mount failed for store %q: %v", name, err)
+			state.setActionError(err)
+		}
+	}
+}
+
+// callChange invokes the action on store (see applyAction), bounded by the configured action timeout.
+// On timeout the Change keeps running in the background but its result is discarded,
+// so a slow handler can't stall the response to its action. Its response isn't rendered
+// from the stores (state.timedOut), and actions of the session group wait for it to return
+// (see groupChanges). The action's Context() is cancelled at the deadline so well-behaved
+// handlers can stop early.
+func (h *liveHandler) callChange(store Store, ctx *ActionContext, state *connState) error {
+	if h.config.ActionTimeout <= 0 {
+		return applyAction(store, ctx)
+	}
+
+	deadline, cancel := context.WithTimeout(ctx.Context(), h.config.ActionTimeout)
+	defer cancel()
+	ctx.ctx = deadline
+
+	// The Change sets a head and redirect of its own, taken over only if it returns in time
+	head, redirect := ctx.head, ctx.redirect
+	ctx.head, ctx.redirect = &HeadMeta{}, new(string)
+
+	// Buffered so the goroutine can finish even after we stop waiting
+	done := make(chan error, 1)
+	go func() {
+		done <- applyAction(store, ctx)
+	}()
+
+	select {
+	case err := <-done:
+		*head, *redirect = *ctx.head, *ctx.redirect
+		return err
+	case <-deadline.Done():
+		state.timedOut = true
+		running := h.changes.start(state.groupID)
+		go func() {
+			<-done
+			h.changes.done(state.groupID, running)
+		}()
+		if deadline.Err() != context.DeadlineExceeded {
+			return fmt.Errorf("action %q cancelled: %w", ctx.Action, deadline.Err())
+		}
+		log.Printf("Action %q timed out after %v", ctx.Action, h.config.ActionTimeout)
+		return fmt.Errorf("action %q timed out after %v", ctx.Action, h.config.ActionTimeout)
+	}
+}
+
+// applyAction runs an action on store: Validate, for stores implementing ActionValidator,
+// then Change if the action is valid
+func applyAction(store Store, ctx *ActionContext) error {
+	if validator, ok := store.(ActionValidator); ok {
+		if err := validator.Validate(ctx); err != nil {
+			return err
+		}
+	}
+	return store.Change(ctx)
+}
+
+// routeAction follows the dotted prefixes of action down the child stores of store, see
+// findChildStore, and returns the store handling the action with the action name left for
+// it: "cart.checkout" on a store with a Cart child store is "checkout" on the child. A
+// prefix not naming a child store is part of the action name.
+func routeAction(store Store, action string) (Store, string) {
+	for {
+		name, rest, ok := strings.Cut(action, ".")
+		if !ok {
+			return store, action
+		}
+		child := findChildStore(store, name)
+		if child == nil {
+			return store, action
+		}
+		store, action = child, rest
+	}
+}
+
+// findChildStore returns the exported field of parent named name, matched case-insensitively,
+// when the field (or its address) is a Store, so a parent store can compose child stores:
+//
+//	type PageState struct {
+//	    Cart    *CartState    // "page.cart.add" calls CartState.Change with action "add"
+//	    Filters FiltersState
+//	}
+func findChildStore(parent Store, name string) Store {
+	v := reflect.ValueOf(parent)
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
 		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil
 	}
 
+	normalized := normalizeStoreName(name)
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		if !field.IsExported() || normalizeStoreName(field.Name) != normalized {
+			continue
+		}
+		value := v.Field(i)
+		switch value.Kind() {
+		case reflect.Struct:
+			if !value.CanAddr() {
+				return nil
+			}
+			value = value.Addr()
+		case reflect.Ptr, reflect.Interface:
+			if value.IsNil() {
+				return nil
+			}
+		}
+		child, _ := value.Interface().(Store)
+		return child
+	}
 	return nil
 }
 
@@ -604,15 +1315,23 @@ func (h *liveHandler) findStore(stores Stores, name string) Store {
 
 // getTemplateData returns the data structure for template rendering
 func (h *liveHandler) getTemplateData(stores Stores) interface{} {
+	viewModel := h.config.ViewModel
 	if h.config.IsSingleStore {
 		// Return store directly for single store
+		if viewModel != nil {
+			return viewModel(stores[""])
+		}
 		return stores[""]
 	}
 
 	// Return map of stores for multi-store
 	data := make(map[string]interface{})
 	for name, store := range stores {
-		data[name] = store
+		if viewModel != nil {
+			data[name] = viewModel(store)
+			continue
+		}
+		data[name] = storeTemplateData(store)
 	}
 	return data
 }
@@ -638,6 +1357,7 @@ func cloneStore(store Store) Store {
 
 	// Copy field values
 	copyStruct(newStore, store)
+	cloneChildStores(newStore)
 
 	// Call Init() if the store implements StoreInitializer
 	if initializer, ok := newStore.(StoreInitializer); ok {
@@ -673,6 +1393,21 @@ func copyStruct(dst, src interface{}) {
 	}
 }
 
+// cloneChildStores replaces the child stores store points to (see findChildStore) with
+// clones, so session groups don't share them
+func cloneChildStores(store Store) {
+	v := reflect.ValueOf(store).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Field(i)
+		if !v.Type().Field(i).IsExported() || field.Kind() != reflect.Ptr || field.IsNil() || field.Elem().Kind() != reflect.Struct {
+			continue
+		}
+		if child, ok := field.Interface().(Store); ok {
+			field.Set(reflect.ValueOf(cloneStore(child)))
+		}
+	}
+}
+
 // getStoreNames returns the names of all stores
 func (h *liveHandler) getStoreNames() []string {
 	names := make([]string, 0, len(h.config.Stores))
@@ -819,8 +1554,63 @@ func (h *liveHandler) BroadcastToGroup(groupID string, data interface{}) error {
 	return nil
 }
 
-// sendUpdate generates and sends a template update to a single connection
+// KeyAttributes returns the attributes identifying range items in the handler's template,
+// see WithKeyAttributes. Test clients like lvttest use it to find range items the way the
+// server does.
+func (h *liveHandler) KeyAttributes() []string {
+	attrs := h.config.Template.config.KeyAttributes
+	if len(attrs) == 0 {
+		attrs = defaultKeyAttributes.AttributeNames
+	}
+	return append([]string(nil), attrs...)
+}
+
+// ConnectionStats returns traffic counters for all active connections, oldest first.
+//
+// Example usage:
+//
+//	for _, stat := range handler.ConnectionStats() {
+//	    log.Printf("group=%s sent=%d bytes in %d updates", stat.GroupID, stat.BytesSent, stat.UpdatesSent)
+//	}
+//
+// Concurrency: This method is safe to call from multiple goroutines concurrently.
+func (h *liveHandler) ConnectionStats() []ConnStat {
+	return h.registry.Stats()
+}
+
+// parseUpdateTree decodes the output of ExecuteUpdates. An empty write, for content
+// unchanged with WithSuppressUnchanged, is an empty tree.
+func parseUpdateTree(data []byte) (treeNode, error) {
+	tree := treeNode{}
+	if len(data) == 0 {
+		return tree, nil
+	}
+	if err := json.Unmarshal(data, &tree); err != nil {
+		return nil, err
+	}
+	return tree, nil
+}
+
+// unregister removes a closed connection from the registry, with its pending broadcast
+func (h *liveHandler) unregister(conn *Connection) {
+	h.registry.Unregister(conn)
+	if h.coalesce != nil {
+		h.coalesce.drop(conn)
+	}
+}
+
+// sendUpdate sends a broadcast update to a single connection, or schedules it when
+// broadcasts are coalesced (WithBroadcastCoalesce)
 func (h *liveHandler) sendUpdate(conn *Connection, data interface{}) error {
+	if h.coalesce != nil {
+		h.coalesce.add(conn, data)
+		return nil
+	}
+	return h.writeUpdate(conn, data)
+}
+
+// writeUpdate generates and sends a template update to a single connection
+func (h *liveHandler) writeUpdate(conn *Connection, data interface{}) error {
 	// Use the connection's cloned template for independent tree diffing
 	var buf bytes.Buffer
 
@@ -831,9 +1621,14 @@ func (h *liveHandler) sendUpdate(conn *Connection, data interface{}) error {
 		return fmt.Errorf("template update failed: %w", err)
 	}
 
+	// Nothing to send when the content is unchanged (WithSuppressUnchanged)
+	if buf.Len() == 0 {
+		return nil
+	}
+
 	// Parse tree from buffer
-	var tree treeNode
-	if err := json.Unmarshal(buf.Bytes(), &tree); err != nil {
+	tree, err := parseUpdateTree(buf.Bytes())
+	if err != nil {
 		return fmt.Errorf("failed to parse tree: %w", err)
 	}
 
@@ -841,8 +1636,11 @@ func (h *liveHandler) sendUpdate(conn *Connection, data interface{}) error {
 	response := UpdateResponse{
 		Tree: tree,
 		Meta: &ResponseMetadata{
-			Success: true,
-			Errors:  nil,
+			Success:     true,
+			Errors:      nil,
+			Fingerprint: conn.Template.Fingerprint(),
+			Warnings:    conn.Template.overlayWarnings(),
+			InputBound:  conn.Template.InputBoundSlots(),
 		},
 	}
 
@@ -854,7 +1652,7 @@ func (h *liveHandler) sendUpdate(conn *Connection, data interface{}) error {
 
 	// Send using the connection's Send method (thread-safe)
 	// Skip actual WebSocket send if Conn is nil (for testing)
-	if conn.Conn == nil {
+	if conn.Conn == nil && conn.events == nil {
 		return nil // Test mode - no actual send
 	}
 	return conn.Send(websocket.TextMessage, responseBytes)
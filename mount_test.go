@@ -0,0 +1,1194 @@
+package livetemplate
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// SlowState is a test store whose "slow" action blocks longer than the action timeout
+type SlowState struct {
+	Count int
+}
+
+func (s *SlowState) Change(ctx *ActionContext) error {
+	switch ctx.Action {
+	case "slow":
+		time.Sleep(500 * time.Millisecond)
+	case "increment":
+		s.Count++
+	}
+	return nil
+}
+
+// BlockingState is a test store whose "wait" action blocks until its context is done
+type BlockingState struct {
+	Started   chan struct{}
+	Cancelled chan error
+}
+
+func (s *BlockingState) Change(ctx *ActionContext) error {
+	if ctx.Action != "wait" {
+		return nil
+	}
+	close(s.Started)
+	select {
+	case <-ctx.Context().Done():
+		s.Cancelled <- ctx.Context().Err()
+	case <-time.After(5 * time.Second):
+		s.Cancelled <- nil
+	}
+	return nil
+}
+
+// dialTestHandler starts an httptest server for handler and opens a WebSocket to it.
+// The initial tree message is consumed before returning.
+func dialTestHandler(t *testing.T, handler LiveHandler) *websocket.Conn {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http")
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	if err != nil {
+		t.Fatalf("WebSocket dial failed: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	var initial UpdateResponse
+	if err := conn.ReadJSON(&initial); err != nil {
+		t.Fatalf("Failed to read initial tree: %v", err)
+	}
+
+	return conn
+}
+
+// sendAction writes an action message and reads the response
+func sendAction(t *testing.T, conn *websocket.Conn, action string, data map[string]interface{}) UpdateResponse {
+	t.Helper()
+
+	if err := conn.WriteJSON(map[string]interface{}{"action": action, "data": data}); err != nil {
+		t.Fatalf("Failed to send action %q: %v", action, err)
+	}
+
+	if err := conn.SetReadDeadline(time.Now().Add(2 * time.Second)); err != nil {
+		t.Fatalf("Failed to set read deadline: %v", err)
+	}
+
+	var response UpdateResponse
+	if err := conn.ReadJSON(&response); err != nil {
+		t.Fatalf("Failed to read response for action %q: %v", action, err)
+	}
+	return response
+}
+
+func TestLiveHandler_ActionTimeout(t *testing.T) {
+	tmpl := New("action-timeout-test", WithActionTimeout(50*time.Millisecond))
+	if _, err := tmpl.Parse("<p>Count: {{.Count}}</p>"); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	conn := dialTestHandler(t, tmpl.Handle(&SlowState{}))
+
+	start := time.Now()
+	response := sendAction(t, conn, "slow", nil)
+	if elapsed := time.Since(start); elapsed >= 500*time.Millisecond {
+		t.Errorf("Timed-out action should respond before Change returns, took %v", elapsed)
+	}
+
+	if response.Meta == nil || response.Meta.Success {
+		t.Fatalf("Expected unsuccessful response for timed-out action, got %+v", response.Meta)
+	}
+	if !strings.Contains(response.Meta.Errors["_general"], "timed out") {
+		t.Errorf("Expected timeout error, got %v", response.Meta.Errors)
+	}
+
+	// Connection should stay open and keep processing actions
+	response = sendAction(t, conn, "increment", nil)
+	if response.Meta == nil || !response.Meta.Success {
+		t.Errorf("Expected successful response after timeout, got %+v", response.Meta)
+	}
+	if response.Meta != nil && response.Meta.Action != "increment" {
+		t.Errorf("Expected action %q in meta, got %q", "increment", response.Meta.Action)
+	}
+}
+
+func TestActionContext_CancelledOnDisconnect(t *testing.T) {
+	tmpl := New("action-context-test")
+	if _, err := tmpl.Parse("<p>Waiting</p>"); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	// Channel fields are copied by reference into the per-session store clone
+	store := &BlockingState{
+		Started:   make(chan struct{}),
+		Cancelled: make(chan error, 1),
+	}
+	conn := dialTestHandler(t, tmpl.Handle(store))
+
+	if err := conn.WriteJSON(map[string]interface{}{"action": "wait"}); err != nil {
+		t.Fatalf("Failed to send action: %v", err)
+	}
+
+	select {
+	case <-store.Started:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Action never started")
+	}
+
+	conn.Close()
+
+	select {
+	case err := <-store.Cancelled:
+		if err != context.Canceled {
+			t.Errorf("Expected context.Canceled after disconnect, got %v", err)
+		}
+	case <-time.After(6 * time.Second):
+		t.Fatal("Action did not finish after disconnect")
+	}
+}
+
+func TestActionContext_DefaultsToBackground(t *testing.T) {
+	ctx := &ActionContext{Action: "test"}
+	if ctx.Context() == nil {
+		t.Fatal("Context() should never return nil")
+	}
+	if ctx.Context().Err() != nil {
+		t.Errorf("Default context should not be cancelled, got %v", ctx.Context().Err())
+	}
+}
+
+// PageState is a test store that loads the requested page in Mount
+type PageState struct {
+	Path string
+	Page string
+}
+
+func (s *PageState) Mount(ctx *ActionContext) error {
+	s.Path = ctx.Path()
+	s.Page = ctx.Query().Get("page")
+	return nil
+}
+
+func (s *PageState) Change(ctx *ActionContext) error {
+	return nil
+}
+
+func TestLiveHandler_Mount(t *testing.T) {
+	tmpl := New("mount-test")
+	if _, err := tmpl.Parse("<p>{{.Path}} page {{.Page}}</p>"); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	server := httptest.NewServer(tmpl.Handle(&PageState{}))
+	defer server.Close()
+
+	t.Run("page load", func(t *testing.T) {
+		resp, err := http.Get(server.URL + "/todos?page=3")
+		if err != nil {
+			t.Fatalf("GET failed: %v", err)
+		}
+		defer resp.Body.Close()
+
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatalf("Failed to read body: %v", err)
+		}
+		if !strings.Contains(string(body), "/todos page 3") {
+			t.Errorf("Expected Mount to load page 3, got %s", body)
+		}
+	})
+
+	t.Run("websocket connect", func(t *testing.T) {
+		wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + "/todos?page=2"
+		conn, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+		if err != nil {
+			t.Fatalf("WebSocket dial failed: %v", err)
+		}
+		defer conn.Close()
+
+		var initial UpdateResponse
+		if err := conn.ReadJSON(&initial); err != nil {
+			t.Fatalf("Failed to read initial tree: %v", err)
+		}
+		tree, _ := initial.Tree.(map[string]interface{})
+		if tree["0"] != "/todos" || tree["1"] != "2" {
+			t.Errorf("Expected initial render of /todos page 2, got %v", tree)
+		}
+	})
+}
+
+// LinkedState is a test store whose Mount reads and dispatches to the store named Other
+type LinkedState struct {
+	Name   string
+	Other  string
+	Seen   string
+	Visits int
+}
+
+func (s *LinkedState) Mount(ctx *ActionContext) error {
+	other, ok := ctx.Store(s.Other)
+	if !ok {
+		return fmt.Errorf("store %q not found", s.Other)
+	}
+	s.Seen = other.(*LinkedState).Name
+	return ctx.Dispatch(s.Other, "visit", nil)
+}
+
+func (s *LinkedState) Change(ctx *ActionContext) error {
+	if ctx.Action == "visit" {
+		s.Visits++
+	}
+	return nil
+}
+
+func TestLiveHandler_MountStoresAndDispatch(t *testing.T) {
+	tmpl := New("mount-linked-test")
+	if _, err := tmpl.Parse("<p>{{.cart.Seen}}-{{.cart.Visits}} {{.user.Seen}}-{{.user.Visits}}</p>"); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	server := httptest.NewServer(tmpl.HandleNamed(map[string]Store{
+		"cart": &LinkedState{Name: "cart", Other: "user"},
+		"user": &LinkedState{Name: "user", Other: "cart"},
+	}))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatalf("GET failed: %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Failed to read body: %v", err)
+	}
+	if !strings.Contains(string(body), "<p>user-1 cart-1</p>") {
+		t.Errorf("Expected each Mount to read and dispatch to the other store, got %s", body)
+	}
+}
+
+// TitledState is a test store that sets the document head in Mount and Change
+type TitledState struct {
+	Product string
+}
+
+func (s *TitledState) Mount(ctx *ActionContext) error {
+	s.Product = ctx.Query().Get("product")
+	ctx.SetTitle(s.Product + " | Shop")
+	return nil
+}
+
+func (s *TitledState) Change(ctx *ActionContext) error {
+	if ctx.Action == "open" {
+		s.Product = ctx.GetString("product")
+		ctx.SetTitle(s.Product + " | Shop")
+		ctx.SetMeta("description", "All about "+s.Product)
+	}
+	return nil
+}
+
+func TestLiveHandler_HeadMeta(t *testing.T) {
+	tmpl := New("head-test")
+	if _, err := tmpl.Parse("<h1>{{.Product}}</h1>"); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	server := httptest.NewServer(tmpl.Handle(&TitledState{}))
+	defer server.Close()
+
+	conn := dialResumeTest(t, server, "/?product=Lamp", "group-head")
+	if _, meta := readUpdate(t, conn); meta.Head == nil || meta.Head.Title != "Lamp | Shop" {
+		t.Fatalf("Expected the title set in Mount in the initial envelope, got %+v", meta.Head)
+	}
+
+	response := sendAction(t, conn, "open", map[string]interface{}{"product": "Chair"})
+	want := &HeadMeta{Title: "Chair | Shop", Meta: map[string]string{"description": "All about Chair"}}
+	if !reflect.DeepEqual(response.Meta.Head, want) {
+		t.Errorf("Head = %+v, want %+v", response.Meta.Head, want)
+	}
+
+	if response := sendAction(t, conn, "other", nil); response.Meta.Head != nil {
+		t.Errorf("Expected no head changes for an action not setting any, got %+v", response.Meta.Head)
+	}
+}
+
+// AdminsState is a test store whose Validate keeps at least one admin
+type AdminsState struct {
+	Admins  []string
+	Changes int
+}
+
+func (s *AdminsState) Validate(ctx *ActionContext) error {
+	if ctx.Action == "remove" && len(s.Admins) <= 1 {
+		return NewFieldError("admins", errors.New("can't remove the last admin"))
+	}
+	return nil
+}
+
+func (s *AdminsState) Change(ctx *ActionContext) error {
+	s.Changes++
+	if ctx.Action == "remove" {
+		s.Admins = s.Admins[1:]
+	}
+	return nil
+}
+
+func TestLiveHandler_ActionValidator(t *testing.T) {
+	tmpl := New("validator-test")
+	if _, err := tmpl.Parse("<p>{{len .Admins}} admins, {{.Changes}} changes</p>"); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	store := &AdminsState{Admins: []string{"ana", "bo"}}
+	conn := dialTestHandler(t, tmpl.Handle(store))
+
+	if response := sendAction(t, conn, "remove", nil); !response.Meta.Success {
+		t.Fatalf("Expected removing one of two admins to succeed, got %+v", response.Meta.Errors)
+	}
+
+	response := sendAction(t, conn, "remove", nil)
+	if response.Meta.Success {
+		t.Fatalf("Expected removing the last admin to be rejected")
+	}
+	if got := response.Meta.Errors["admins"]; got != "can't remove the last admin" {
+		t.Errorf("Expected the Validate error in meta, got %+v", response.Meta.Errors)
+	}
+
+	// Only the first remove and this action reached Change
+	response = sendAction(t, conn, "refresh", nil)
+	if tree, _ := response.Tree.(map[string]interface{}); tree["1"] != "2" {
+		t.Errorf("Expected Change to skip the rejected action and count 2 changes, got %v", response.Tree)
+	}
+}
+
+// RedirectState is a test store whose "create" action redirects to the new item
+type RedirectState struct {
+	Items int
+}
+
+func (s *RedirectState) Change(ctx *ActionContext) error {
+	ctx.Redirect(fmt.Sprintf("/items/%d", s.Items+1))
+	if ctx.Action == "invalid" {
+		return NewFieldError("title", errors.New("title is required"))
+	}
+	s.Items++
+	return nil
+}
+
+func TestActionContext_Redirect(t *testing.T) {
+	tmpl := New("redirect-test")
+	if _, err := tmpl.Parse("<p>{{.Items}} items</p>"); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	handler := tmpl.Handle(&RedirectState{})
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	post := func(t *testing.T, action, accept string) *http.Response {
+		t.Helper()
+		form := url.Values{"action": {action}}
+		req, _ := http.NewRequest(http.MethodPost, server.URL, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("Accept", accept)
+		req.AddCookie(&http.Cookie{Name: "livetemplate-id", Value: "group-redirect"})
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		t.Cleanup(func() { resp.Body.Close() })
+		return resp
+	}
+
+	t.Run("HTTP action redirects", func(t *testing.T) {
+		resp := post(t, "create", "text/html")
+		if resp.StatusCode != http.StatusFound || resp.Header.Get("Location") != "/items/1" {
+			t.Errorf("Expected a 302 to /items/1, got %d %q", resp.StatusCode, resp.Header.Get("Location"))
+		}
+	})
+
+	t.Run("client library gets the URL in meta", func(t *testing.T) {
+		resp := post(t, "create", "application/json")
+		var response UpdateResponse
+		if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		if resp.StatusCode != http.StatusOK || response.Meta.Redirect != "/items/2" {
+			t.Errorf("Expected a redirect to /items/2 in meta, got %d %+v", resp.StatusCode, response.Meta)
+		}
+	})
+
+	t.Run("failed action does not redirect", func(t *testing.T) {
+		if resp := post(t, "invalid", "text/html"); resp.StatusCode == http.StatusFound {
+			t.Errorf("Expected no redirect for a failed action, got %q", resp.Header.Get("Location"))
+		}
+	})
+
+	t.Run("WebSocket", func(t *testing.T) {
+		conn := dialTestHandler(t, handler)
+		if response := sendAction(t, conn, "create", nil); response.Meta.Redirect == "" {
+			t.Errorf("Expected a navigate URL in meta, got %+v", response.Meta)
+		}
+		if response := sendAction(t, conn, "invalid", nil); response.Meta.Redirect != "" {
+			t.Errorf("Expected no navigate URL for a failed action, got %q", response.Meta.Redirect)
+		}
+	})
+}
+
+func TestWithMaxConnectionsPerGroup(t *testing.T) {
+	const limit = 2
+
+	tmpl := New("max-conns-test", WithMaxConnectionsPerGroup(limit))
+	if _, err := tmpl.Parse("<p>Count: {{.Count}}</p>"); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	server := httptest.NewServer(tmpl.Handle(&SlowState{}))
+	defer server.Close()
+
+	for i := 0; i < limit; i++ {
+		conn := dialResumeTest(t, server, "", "group-tabs")
+		readUpdate(t, conn)
+	}
+
+	rejected := dialResumeTest(t, server, "", "group-tabs")
+	_ = rejected.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, _, err := rejected.ReadMessage()
+	if !websocket.IsCloseError(err, websocket.ClosePolicyViolation) {
+		t.Fatalf("Expected connection %d to be closed with a policy violation, got %v", limit+1, err)
+	}
+	if closeErr := err.(*websocket.CloseError); !strings.Contains(closeErr.Text, "too many connections") {
+		t.Errorf("Expected a clear close reason, got %q", closeErr.Text)
+	}
+
+	// Other session groups have their own limit
+	other := dialResumeTest(t, server, "", "group-other")
+	if _, meta := readUpdate(t, other); meta == nil || !meta.Success {
+		t.Errorf("Expected a connection in another group to be accepted, got %+v", meta)
+	}
+}
+
+func TestWithMaxPayloadSize(t *testing.T) {
+	tmpl := New("max-payload-test", WithMaxPayloadSize(64), WithActionPayloadSize("slow", 1024))
+	if _, err := tmpl.Parse("<p>Count: {{.Count}}</p>"); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	conn := dialTestHandler(t, tmpl.Handle(&SlowState{}))
+	large := map[string]interface{}{"text": strings.Repeat("x", 200)}
+
+	response := sendAction(t, conn, "increment", large)
+	if response.Meta == nil || response.Meta.Success {
+		t.Fatalf("Expected an oversized payload to fail, got %+v", response.Meta)
+	}
+	if msg := response.Meta.Errors["_general"]; !strings.Contains(msg, "exceeds the limit of 64 bytes") {
+		t.Errorf("Expected the error to name the limit, got %q", msg)
+	}
+	if tree, _ := response.Tree.(map[string]interface{}); len(tree) > 0 {
+		t.Errorf("Expected the rejected action to leave the state unchanged, got %v", tree)
+	}
+
+	// The connection survives and small payloads still reach the store
+	response = sendAction(t, conn, "increment", map[string]interface{}{"n": 1})
+	if response.Meta == nil || !response.Meta.Success {
+		t.Fatalf("Expected a small payload to succeed, got %+v", response.Meta)
+	}
+	tree, _ := response.Tree.(map[string]interface{})
+	if tree["0"] != "1" {
+		t.Errorf("Expected Count to become 1, got %v", response.Tree)
+	}
+
+	t.Run("per-action override", func(t *testing.T) {
+		if response := sendAction(t, conn, "slow", large); response.Meta == nil || !response.Meta.Success {
+			t.Errorf("Expected the override to allow the payload, got %+v", response.Meta)
+		}
+	})
+}
+
+func TestLiveHandler_ActionNonce(t *testing.T) {
+	tmpl := New("nonce-test")
+	if _, err := tmpl.Parse("<p>Count: {{.Count}}</p>"); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	store := &SlowState{}
+	handler := tmpl.Handle(store)
+	conn := dialTestHandler(t, handler)
+
+	send := func(t *testing.T, nonce string) UpdateResponse {
+		t.Helper()
+		if err := conn.WriteJSON(map[string]interface{}{"action": "increment", "nonce": nonce}); err != nil {
+			t.Fatalf("Failed to send action: %v", err)
+		}
+		_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		var response UpdateResponse
+		if err := conn.ReadJSON(&response); err != nil {
+			t.Fatalf("Failed to read response: %v", err)
+		}
+		return response
+	}
+
+	first := send(t, "nonce-1")
+	if tree, _ := first.Tree.(map[string]interface{}); tree["0"] != "1" {
+		t.Fatalf("Expected the first action to run, got %v", first.Tree)
+	}
+	retry := send(t, "nonce-1")
+	if retry.Meta == nil || !retry.Meta.Success {
+		t.Errorf("Expected the retry to be answered successfully, got %+v", retry.Meta)
+	}
+	if tree, _ := retry.Tree.(map[string]interface{}); len(tree) > 0 {
+		t.Errorf("Expected the retry to skip Change, got %v", retry.Tree)
+	}
+	if next := send(t, "nonce-2"); next.Tree.(map[string]interface{})["0"] != "2" {
+		t.Errorf("Expected a new nonce to run, got %v", next.Tree)
+	}
+
+	t.Run("HTTP actions", func(t *testing.T) {
+		server := httptest.NewServer(handler)
+		defer server.Close()
+
+		post := func() {
+			form := url.Values{"action": {"increment"}, "lvt-nonce": {"http-nonce"}}
+			req, _ := http.NewRequest(http.MethodPost, server.URL, strings.NewReader(form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			req.Header.Set("Accept", "application/json")
+			req.AddCookie(&http.Cookie{Name: "livetemplate-id", Value: "group-nonce"})
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("Request failed: %v", err)
+			}
+			resp.Body.Close()
+		}
+		post()
+		post()
+
+		stores := handler.(*liveHandler).config.SessionStore.Get("group-nonce")
+		if count := stores[""].(*SlowState).Count; count != 1 {
+			t.Errorf("Expected the repeated HTTP action to run once, got Count %d", count)
+		}
+	})
+}
+
+func TestLiveHandler_CorrelationID(t *testing.T) {
+	tmpl := New("correlation-test")
+	if _, err := tmpl.Parse("<p>Likes: {{.Count}}</p>"); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	handler := tmpl.Handle(&SlowState{})
+	conn := dialTestHandler(t, handler)
+
+	send := func(t *testing.T, msg map[string]interface{}) UpdateResponse {
+		t.Helper()
+		if err := conn.WriteJSON(msg); err != nil {
+			t.Fatalf("Failed to send action: %v", err)
+		}
+		_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		var response UpdateResponse
+		if err := conn.ReadJSON(&response); err != nil {
+			t.Fatalf("Failed to read response: %v", err)
+		}
+		return response
+	}
+
+	response := send(t, map[string]interface{}{"action": "increment", "correlation_id": "like-1"})
+	if response.Meta == nil || response.Meta.CorrelationID != "like-1" {
+		t.Fatalf("Expected the update to echo correlation ID like-1, got %+v", response.Meta)
+	}
+	if tree, _ := response.Tree.(map[string]interface{}); tree["0"] != "1" {
+		t.Errorf("Expected the authoritative count in the update, got %v", response.Tree)
+	}
+	if response := send(t, map[string]interface{}{"action": "increment"}); response.Meta.CorrelationID != "" {
+		t.Errorf("Expected no correlation ID for an action without one, got %q", response.Meta.CorrelationID)
+	}
+
+	t.Run("HTTP actions", func(t *testing.T) {
+		server := httptest.NewServer(handler)
+		defer server.Close()
+
+		post := func(t *testing.T, contentType, body string) UpdateResponse {
+			t.Helper()
+			req, _ := http.NewRequest(http.MethodPost, server.URL, strings.NewReader(body))
+			req.Header.Set("Content-Type", contentType)
+			req.Header.Set("Accept", "application/json")
+			req.AddCookie(&http.Cookie{Name: "livetemplate-id", Value: "group-correlation"})
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("Request failed: %v", err)
+			}
+			defer resp.Body.Close()
+			var response UpdateResponse
+			if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			return response
+		}
+
+		if response := post(t, "application/json", `{"action":"increment","correlation_id":"like-2"}`); response.Meta.CorrelationID != "like-2" {
+			t.Errorf("Expected the JSON action's correlation ID in meta, got %+v", response.Meta)
+		}
+		form := url.Values{"action": {"increment"}, "lvt-correlation-id": {"like-3"}}
+		if response := post(t, "application/x-www-form-urlencoded", form.Encode()); response.Meta.CorrelationID != "like-3" {
+			t.Errorf("Expected the form action's correlation ID in meta, got %+v", response.Meta)
+		}
+	})
+}
+
+func TestLiveHandler_ConnectionStats(t *testing.T) {
+	tmpl := New("connection-stats-test")
+	if _, err := tmpl.Parse("<p>Count: {{.Count}}</p>"); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	handler := tmpl.Handle(&SlowState{})
+
+	if stats := handler.ConnectionStats(); len(stats) != 0 {
+		t.Fatalf("Expected no stats before connecting, got %+v", stats)
+	}
+
+	before := time.Now()
+	conn := dialTestHandler(t, handler)
+
+	stats := handler.ConnectionStats()
+	if len(stats) != 1 {
+		t.Fatalf("Expected 1 connection, got %d", len(stats))
+	}
+	initial := stats[0]
+	if initial.UpdatesSent != 1 || initial.BytesSent == 0 {
+		t.Errorf("Expected the initial tree to be counted, got %+v", initial)
+	}
+	if initial.ConnectedAt.Before(before) {
+		t.Errorf("ConnectedAt %v should not be before dial at %v", initial.ConnectedAt, before)
+	}
+
+	var payload int64
+	for i := 0; i < 3; i++ {
+		if err := conn.WriteJSON(map[string]interface{}{"action": "increment"}); err != nil {
+			t.Fatalf("Failed to send action: %v", err)
+		}
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatalf("Failed to read update: %v", err)
+		}
+		payload += int64(len(data))
+	}
+
+	stat := handler.ConnectionStats()[0]
+	if stat.UpdatesSent != initial.UpdatesSent+3 {
+		t.Errorf("Expected %d updates, got %d", initial.UpdatesSent+3, stat.UpdatesSent)
+	}
+	if stat.BytesSent != initial.BytesSent+payload {
+		t.Errorf("Expected %d bytes, got %d", initial.BytesSent+payload, stat.BytesSent)
+	}
+	if !stat.ConnectedAt.Equal(initial.ConnectedAt) {
+		t.Errorf("ConnectedAt changed from %v to %v", initial.ConnectedAt, stat.ConnectedAt)
+	}
+}
+
+// FlakyState is a test store whose template fails to execute while Items is empty
+type FlakyState struct {
+	Items []string
+}
+
+func (s *FlakyState) Change(ctx *ActionContext) error {
+	switch ctx.Action {
+	case "clear":
+		s.Items = nil
+	case "add":
+		s.Items = append(s.Items, ctx.GetString("item"))
+	}
+	return nil
+}
+
+func TestLiveHandler_TemplateExecutionError(t *testing.T) {
+	tmpl := New("execution-error-test")
+	if _, err := tmpl.Parse("<p>First: {{index .Items 0}}</p>"); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	conn := dialTestHandler(t, tmpl.Handle(&FlakyState{Items: []string{"a"}}))
+
+	// index out of range on the new data shape
+	response := sendAction(t, conn, "clear", nil)
+	if response.Meta == nil || response.Meta.Success {
+		t.Fatalf("Expected unsuccessful response for failed render, got %+v", response.Meta)
+	}
+	if response.Meta.Errors["_general"] == "" {
+		t.Errorf("Expected _general error, got %v", response.Meta.Errors)
+	}
+	if response.Meta.Action != "clear" {
+		t.Errorf("Expected action %q in meta, got %q", "clear", response.Meta.Action)
+	}
+	if tree, _ := response.Tree.(map[string]interface{}); len(tree) != 0 {
+		t.Errorf("Expected empty tree for failed render, got %v", tree)
+	}
+
+	// Still failing, connection still answers
+	response = sendAction(t, conn, "clear", nil)
+	if response.Meta == nil || response.Meta.Success {
+		t.Fatalf("Expected render to keep failing, got %+v", response.Meta)
+	}
+
+	// Recovered: diff against the last good render
+	response = sendAction(t, conn, "add", map[string]interface{}{"item": "b"})
+	if response.Meta == nil || !response.Meta.Success {
+		t.Fatalf("Expected successful response after recovery, got %+v", response.Meta)
+	}
+	tree, _ := response.Tree.(map[string]interface{})
+	if tree["0"] != "b" {
+		t.Errorf("Expected update to b, got %v", tree)
+	}
+}
+
+func TestDataShape(t *testing.T) {
+	tests := []struct {
+		name string
+		data interface{}
+		want string
+	}{
+		{"nil", nil, "<nil>"},
+		{"struct pointer", &FlakyState{}, "*livetemplate.FlakyState{Items []string}"},
+		{"store map", map[string]interface{}{"b": &SlowState{}, "a": nil}, "map[string]interface {}{a <nil>, b *livetemplate.SlowState}"},
+		{"scalar", 42, "int"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := dataShape(tt.data); got != tt.want {
+				t.Errorf("dataShape() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// OverlayState is a test store whose "expand" action renders a large raw HTML chunk
+type OverlayState struct {
+	Body template.HTML
+}
+
+func (s *OverlayState) Change(ctx *ActionContext) error {
+	if ctx.Action == "expand" {
+		s.Body = template.HTML(strings.Repeat("<li>An item rendered as raw HTML</li>", 5))
+	}
+	return nil
+}
+
+func TestLiveHandler_DevOverlayWarnings(t *testing.T) {
+	tests := []struct {
+		name         string
+		options      []Option
+		wantWarnings bool
+	}{
+		{"dev mode with overlay", []Option{WithDevMode(true), WithDevOverlay(true)}, true},
+		{"dev mode without overlay", []Option{WithDevMode(true)}, false},
+		{"overlay without dev mode", []Option{WithDevOverlay(true)}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl := New("dev-overlay-test", tt.options...)
+			if _, err := tmpl.Parse("<ul>{{.Body}}</ul>"); err != nil {
+				t.Fatalf("Parse failed: %v", err)
+			}
+
+			conn := dialTestHandler(t, tmpl.Handle(&OverlayState{}))
+			response := sendAction(t, conn, "expand", nil)
+			if response.Meta == nil {
+				t.Fatal("Expected response metadata")
+			}
+
+			if tt.wantWarnings {
+				if len(response.Meta.Warnings) == 0 {
+					t.Fatal("Expected analyzer warnings in meta")
+				}
+				if !strings.Contains(response.Meta.Warnings[0], "HTML tags") {
+					t.Errorf("Expected HTML chunk warning, got %q", response.Meta.Warnings[0])
+				}
+			} else if len(response.Meta.Warnings) != 0 {
+				t.Errorf("Expected no warnings in meta, got %v", response.Meta.Warnings)
+			}
+		})
+	}
+}
+
+func TestLiveHandler_ClientPreload(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		opts []Option
+		want string
+	}{
+		{"enabled", []Option{WithClientPreload("/livetemplate-client.js")}, "</livetemplate-client.js>; rel=preload; as=script"},
+		{"disabled", nil, ""},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			tmpl := New("preload-test", tc.opts...)
+			if _, err := tmpl.Parse("<p>{{.Path}}</p>"); err != nil {
+				t.Fatalf("Parse failed: %v", err)
+			}
+
+			rec := httptest.NewRecorder()
+			tmpl.Handle(&PageState{}).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("Expected status 200, got %d", rec.Code)
+			}
+			if got := rec.Header().Get("Link"); got != tc.want {
+				t.Errorf("Link header = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestLiveHandler_ReconnectPolicy(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		opts []Option
+		want string
+	}{
+		{"configured", []Option{WithReconnectPolicy(500*time.Millisecond, 30*time.Second, 2)}, `{"reconnect":{"min_ms":500,"max_ms":30000,"factor":2}}`},
+		{"default", nil, ""},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			tmpl := New("reconnect-test", tc.opts...)
+			if _, err := tmpl.Parse("<p>{{.Count}}</p>"); err != nil {
+				t.Fatalf("Parse failed: %v", err)
+			}
+			server := httptest.NewServer(tmpl.Handle(&SlowState{}))
+			defer server.Close()
+
+			conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+			if err != nil {
+				t.Fatalf("WebSocket dial failed: %v", err)
+			}
+			defer conn.Close()
+
+			var initial struct {
+				Meta struct {
+					Config json.RawMessage `json:"config"`
+				} `json:"meta"`
+			}
+			if err := conn.ReadJSON(&initial); err != nil {
+				t.Fatalf("Failed to read initial tree: %v", err)
+			}
+			if got := string(initial.Meta.Config); got != tc.want {
+				t.Errorf("meta.config = %s, want %s", got, tc.want)
+			}
+
+			// Only the initial envelope carries the config
+			response := sendAction(t, conn, "increment", nil)
+			if response.Meta != nil && response.Meta.Config != nil {
+				t.Errorf("Expected no config in action responses, got %+v", response.Meta.Config)
+			}
+		})
+	}
+}
+
+func TestTemplate_HandleNamed(t *testing.T) {
+	expectPanic := func(t *testing.T, contains string, fn func()) {
+		t.Helper()
+		defer func() {
+			r := recover()
+			if r == nil {
+				t.Fatal("Expected a panic")
+			}
+			if msg := fmt.Sprint(r); !strings.Contains(msg, contains) {
+				t.Errorf("Expected panic mentioning %q, got %q", contains, msg)
+			}
+		}()
+		fn()
+	}
+
+	t.Run("same-typed stores in Handle", func(t *testing.T) {
+		expectPanic(t, `both named "SlowState" (type *livetemplate.SlowState); use HandleNamed`, func() {
+			New("duplicate-stores-test").Handle(&SlowState{}, &SlowState{})
+		})
+	})
+
+	t.Run("names differing in case", func(t *testing.T) {
+		expectPanic(t, "differ only in case", func() {
+			New("case-names-test").HandleNamed(map[string]Store{"inbox": &SlowState{}, "Inbox": &SlowState{}})
+		})
+	})
+
+	t.Run("disambiguated stores", func(t *testing.T) {
+		tmpl := New("named-stores-test")
+		if _, err := tmpl.Parse("<p>{{.inbox.Count}} / {{.archive.Count}}</p>"); err != nil {
+			t.Fatalf("Parse failed: %v", err)
+		}
+		conn := dialTestHandler(t, tmpl.HandleNamed(map[string]Store{
+			"inbox":   &SlowState{Count: 1},
+			"archive": &SlowState{Count: 5},
+		}))
+
+		response := sendAction(t, conn, "archive.increment", nil)
+		tree, _ := response.Tree.(map[string]interface{})
+		if tree["1"] != "6" {
+			t.Errorf("Expected archive count 6, got %v", response.Tree)
+		}
+		if _, changed := tree["0"]; changed {
+			t.Errorf("Inbox store should be unaffected, got %v", response.Tree)
+		}
+	})
+}
+
+// ShopState is a test store composed of child stores
+type ShopState struct {
+	Basket  *SlowState
+	Visits  SlowState
+	Actions []string
+}
+
+func (s *ShopState) Change(ctx *ActionContext) error {
+	s.Actions = append(s.Actions, ctx.Action)
+	return nil
+}
+
+func TestLiveHandler_NestedStores(t *testing.T) {
+	tmpl := New("nested-stores-test")
+	if _, err := tmpl.Parse("<p>{{.shop.Basket.Count}} {{.shop.Visits.Count}} {{len .shop.Actions}}</p>"); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	shop := &ShopState{Basket: &SlowState{}}
+	handler := tmpl.HandleNamed(map[string]Store{"shop": shop, "other": &SlowState{}})
+	conn := dialTestHandler(t, handler)
+
+	response := sendAction(t, conn, "shop.basket.increment", nil)
+	if tree, _ := response.Tree.(map[string]interface{}); tree["0"] != "1" || len(tree) != 1 {
+		t.Errorf("Expected only the basket count to change, got %v", response.Tree)
+	}
+
+	response = sendAction(t, conn, "shop.Visits.increment", nil)
+	if tree, _ := response.Tree.(map[string]interface{}); tree["1"] != "1" {
+		t.Errorf("Expected a child store held by value to be routed to, got %v", response.Tree)
+	}
+
+	// Prefixes not naming a child store stay part of the parent's action
+	response = sendAction(t, conn, "shop.gift.wrap", nil)
+	if tree, _ := response.Tree.(map[string]interface{}); tree["2"] != "1" {
+		t.Errorf("Expected the parent to handle the action, got %v", response.Tree)
+	}
+
+	if shop.Basket.Count != 0 {
+		t.Errorf("Expected the session to change its own clone of the child store, got %d on the original", shop.Basket.Count)
+	}
+}
+
+// CartState is a test store whose "add" action dispatches to StatsState
+type CartState struct {
+	Items int
+}
+
+func (s *CartState) Change(ctx *ActionContext) error {
+	switch ctx.Action {
+	case "add":
+		s.Items++
+		return ctx.Dispatch("statsstate", "record", map[string]interface{}{"item": ctx.GetString("item")})
+	case "misroute":
+		return ctx.Dispatch("nostore", "record", nil)
+	}
+	return nil
+}
+
+// StatsState is a test store counting the items recorded by CartState
+type StatsState struct {
+	Recorded int
+	Last     string
+}
+
+func (s *StatsState) Change(ctx *ActionContext) error {
+	if ctx.Action == "record" {
+		s.Recorded++
+		s.Last = ctx.GetString("item")
+	}
+	return nil
+}
+
+// PermsState is a test store read by DocState through ActionContext.Store
+type PermsState struct {
+	Role string
+}
+
+func (s *PermsState) Change(ctx *ActionContext) error {
+	if ctx.Action == "promote" {
+		s.Role = "editor"
+	}
+	return nil
+}
+
+// DocState is a test store whose "edit" action depends on the role in PermsState
+type DocState struct {
+	Status string
+}
+
+func (s *DocState) Change(ctx *ActionContext) error {
+	if ctx.Action != "edit" {
+		return nil
+	}
+	store, ok := ctx.Store("permsstate")
+	if !ok {
+		return fmt.Errorf("no permissions store")
+	}
+	perms := store.(*PermsState)
+	if perms.Role != "editor" {
+		s.Status = "read-only for " + perms.Role
+		return nil
+	}
+	s.Status = "edited by " + perms.Role
+	return nil
+}
+
+func TestActionContext_Dispatch(t *testing.T) {
+	tmpl := New("dispatch-test")
+	if _, err := tmpl.Parse("<p>{{.CartState.Items}} items, {{.StatsState.Recorded}} recorded, last {{.StatsState.Last}}</p>"); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	conn := dialTestHandler(t, tmpl.Handle(&CartState{}, &StatsState{}))
+
+	response := sendAction(t, conn, "cartstate.add", map[string]interface{}{"item": "apple"})
+	tree, _ := response.Tree.(map[string]interface{})
+	if tree["0"] != "1" || tree["1"] != "1" || tree["2"] != "apple" {
+		t.Errorf("Expected both stores updated in one render, got %v", response.Tree)
+	}
+
+	t.Run("unknown store", func(t *testing.T) {
+		response := sendAction(t, conn, "cartstate.misroute", nil)
+		if response.Meta == nil || response.Meta.Success {
+			t.Errorf("Expected the dispatch error to fail the action, got %+v", response.Meta)
+		}
+	})
+
+	t.Run("outside a handler", func(t *testing.T) {
+		ctx := &ActionContext{Action: "add", Data: newActionData(map[string]interface{}{})}
+		if err := (&CartState{}).Change(ctx); err == nil {
+			t.Error("Expected Dispatch to fail without a live handler")
+		}
+	})
+}
+
+func TestActionContext_Store(t *testing.T) {
+	tmpl := New("sibling-store-test")
+	if _, err := tmpl.Parse("<p>{{.DocState.Status}}</p>"); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	conn := dialTestHandler(t, tmpl.Handle(&DocState{}, &PermsState{Role: "viewer"}))
+
+	response := sendAction(t, conn, "docstate.edit", nil)
+	if tree, _ := response.Tree.(map[string]interface{}); tree["0"] != "read-only for viewer" {
+		t.Errorf("Expected the sibling's role in the update, got %v", response.Tree)
+	}
+
+	sendAction(t, conn, "permsstate.promote", nil)
+	response = sendAction(t, conn, "docstate.edit", nil)
+	if tree, _ := response.Tree.(map[string]interface{}); tree["0"] != "edited by editor" {
+		t.Errorf("Expected the sibling's changed role in the update, got %v", response.Tree)
+	}
+
+	t.Run("unknown store", func(t *testing.T) {
+		ctx := &ActionContext{stores: (&liveHandler{}).storeFinder(&connState{stores: Stores{"docstate": &DocState{}}})}
+		if _, ok := ctx.Store("nostore"); ok {
+			t.Error("Expected no store for an unknown name")
+		}
+	})
+
+	t.Run("outside a handler", func(t *testing.T) {
+		if _, ok := (&ActionContext{}).Store("permsstate"); ok {
+			t.Error("Expected no stores without a live handler")
+		}
+	})
+}
+
+// LateWriteState is a test store whose "slow" action writes its fields after the action timeout
+type LateWriteState struct {
+	Count int
+	Label string
+}
+
+func (s *LateWriteState) Change(ctx *ActionContext) error {
+	switch ctx.Action {
+	case "slow":
+		time.Sleep(200 * time.Millisecond)
+		s.Label = "late"
+		ctx.SetTitle("Late")
+		ctx.Redirect("/late")
+	case "increment":
+		s.Count++
+	}
+	return nil
+}
+
+func TestLiveHandler_ActionTimeoutKeepsStore(t *testing.T) {
+	tmpl := New("action-timeout-store-test", WithActionTimeout(50*time.Millisecond))
+	if _, err := tmpl.Parse("<p>{{.Label}} {{.Count}}</p>"); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	conn := dialTestHandler(t, tmpl.Handle(&LateWriteState{}))
+
+	response := sendAction(t, conn, "slow", nil)
+	if tree, _ := json.Marshal(response.Tree); response.Meta == nil || response.Meta.Success || string(tree) != "{}" {
+		t.Fatalf("Expected an unsuccessful response without a tree, got %+v %s", response.Meta, tree)
+	}
+
+	// Waits for the slow Change, whose field write shows up, but not its head or redirect
+	response = sendAction(t, conn, "increment", nil)
+	if response.Meta == nil || !response.Meta.Success {
+		t.Fatalf("Expected successful response after timeout, got %+v", response.Meta)
+	}
+	if response.Meta.Head != nil || response.Meta.Redirect != "" {
+		t.Errorf("Expected the timed-out head and redirect to be dropped, got %+v and %q", response.Meta.Head, response.Meta.Redirect)
+	}
+	tree, _ := json.Marshal(response.Tree)
+	if !strings.Contains(string(tree), "late") || !strings.Contains(string(tree), "1") {
+		t.Errorf("Expected the late write and the increment in the update, got %s", tree)
+	}
+}
+
+func TestLiveHandler_ActionTimeoutKeepsGroupStores(t *testing.T) {
+	tmpl := New("action-timeout-group-test", WithActionTimeout(50*time.Millisecond))
+	if _, err := tmpl.Parse("<p>{{.Label}} {{.Count}}</p>"); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	server := httptest.NewServer(tmpl.Handle(&LateWriteState{}))
+	defer server.Close()
+
+	// Every request of the session group gets a fresh connState, but shares the stores
+	post := func(t *testing.T, action string) UpdateResponse {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodPost, server.URL, strings.NewReader(`{"action":"`+action+`"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept", "application/json")
+		req.AddCookie(&http.Cookie{Name: "livetemplate-id", Value: "group-timeout"})
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		defer resp.Body.Close()
+		var response UpdateResponse
+		if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		return response
+	}
+
+	for i := 0; i < 2; i++ {
+		if response := post(t, "slow"); response.Meta == nil || response.Meta.Success {
+			t.Fatalf("Expected request %d to time out, got %+v", i, response.Meta)
+		}
+	}
+
+	response := post(t, "increment")
+	if response.Meta == nil || !response.Meta.Success {
+		t.Fatalf("Expected successful response after the timeouts, got %+v", response.Meta)
+	}
+	tree, _ := json.Marshal(response.Tree)
+	if !strings.Contains(string(tree), "late") || !strings.Contains(string(tree), "1") {
+		t.Errorf("Expected the late writes and the increment in the update, got %s", tree)
+	}
+}
@@ -0,0 +1,144 @@
+package livetemplate
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+)
+
+// WireFormat is the encoding of the updates written by ExecuteUpdatesAs
+type WireFormat int
+
+const (
+	// WireFormatJSON writes updates as JSON, like ExecuteUpdates
+	WireFormatJSON WireFormat = iota
+	// WireFormatMsgPack writes updates as MessagePack, for binary transports such as binary
+	// WebSocket frames. The structure is the same as the JSON form.
+	WireFormatMsgPack
+)
+
+// marshalMsgPack encodes a tree update as MessagePack, with the keys of tree nodes in the
+// same order as marshalOrderedJSON. Values of other types than the tree's own are encoded
+// through enc, so they match their JSON form.
+func marshalMsgPack(tree treeNode, enc JSONEncoder) ([]byte, error) {
+	return appendMsgPack(nil, map[string]interface{}(tree), enc)
+}
+
+// appendMsgPack appends the MessagePack encoding of v to b
+func appendMsgPack(b []byte, v interface{}, enc JSONEncoder) ([]byte, error) {
+	switch v := v.(type) {
+	case nil:
+		return append(b, 0xc0), nil
+	case bool:
+		if v {
+			return append(b, 0xc3), nil
+		}
+		return append(b, 0xc2), nil
+	case string:
+		return appendMsgPackString(b, v), nil
+	case int:
+		return appendMsgPackInt(b, int64(v)), nil
+	case int64:
+		return appendMsgPackInt(b, v), nil
+	case float64:
+		b = append(b, 0xcb)
+		return binary.BigEndian.AppendUint64(b, math.Float64bits(v)), nil
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return appendMsgPackInt(b, i), nil
+		}
+		f, err := v.Float64()
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q: %w", v, err)
+		}
+		return appendMsgPack(b, f, enc)
+	case []string:
+		b = appendMsgPackHeader(b, len(v), 0x90, 0xdc)
+		for _, s := range v {
+			b = appendMsgPackString(b, s)
+		}
+		return b, nil
+	case []interface{}:
+		b = appendMsgPackHeader(b, len(v), 0x90, 0xdc)
+		for _, item := range v {
+			var err error
+			if b, err = appendMsgPack(b, item, enc); err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+	case treeNode:
+		return appendMsgPack(b, map[string]interface{}(v), enc)
+	case map[string]interface{}:
+		b = appendMsgPackHeader(b, len(v), 0x80, 0xde)
+		for _, key := range sortedTreeKeys(v) {
+			b = appendMsgPackString(b, key)
+			var err error
+			if b, err = appendMsgPack(b, v[key], enc); err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+	}
+
+	// Range operations and other values: encode their JSON form
+	data, err := enc.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var generic interface{}
+	if err := dec.Decode(&generic); err != nil {
+		return nil, fmt.Errorf("failed to decode %T for MessagePack: %w", v, err)
+	}
+	return appendMsgPack(b, generic, enc)
+}
+
+// appendMsgPackString appends a MessagePack str
+func appendMsgPackString(b []byte, s string) []byte {
+	switch n := len(s); {
+	case n < 32:
+		b = append(b, 0xa0|byte(n))
+	case n <= math.MaxUint8:
+		b = append(b, 0xd9, byte(n))
+	case n <= math.MaxUint16:
+		b = binary.BigEndian.AppendUint16(append(b, 0xda), uint16(n))
+	default:
+		b = binary.BigEndian.AppendUint32(append(b, 0xdb), uint32(n))
+	}
+	return append(b, s...)
+}
+
+// appendMsgPackInt appends a MessagePack int in its shortest form
+func appendMsgPackInt(b []byte, i int64) []byte {
+	switch {
+	case i >= 0 && i <= 127:
+		return append(b, byte(i)) // positive fixint
+	case i < 0 && i >= -32:
+		return append(b, byte(i)) // negative fixint
+	case i >= math.MinInt8 && i <= math.MaxInt8:
+		return append(b, 0xd0, byte(i))
+	case i >= math.MinInt16 && i <= math.MaxInt16:
+		return binary.BigEndian.AppendUint16(append(b, 0xd1), uint16(i))
+	case i >= math.MinInt32 && i <= math.MaxInt32:
+		return binary.BigEndian.AppendUint32(append(b, 0xd2), uint32(i))
+	default:
+		return binary.BigEndian.AppendUint64(append(b, 0xd3), uint64(i))
+	}
+}
+
+// appendMsgPackHeader appends the header of an array or map of n elements, given the type's
+// fix prefix and 16-bit marker (the 32-bit marker follows it)
+func appendMsgPackHeader(b []byte, n int, fix, marker16 byte) []byte {
+	switch {
+	case n < 16:
+		return append(b, fix|byte(n))
+	case n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, marker16), uint16(n))
+	default:
+		return binary.BigEndian.AppendUint32(append(b, marker16+1), uint32(n))
+	}
+}
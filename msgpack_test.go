@@ -0,0 +1,179 @@
+package livetemplate
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestTemplate_ExecuteUpdatesAs(t *testing.T) {
+	const src = `<h1>{{.Title}}</h1><p>{{.Count}}</p><ul>{{range .Items}}<li data-key="{{.ID}}">{{.Name}}</li>{{end}}</ul>`
+	type item struct {
+		ID   string
+		Name string
+	}
+	states := []map[string]interface{}{
+		{"Title": "Todos", "Count": 2, "Items": []item{{"a", "Alpha"}, {"b", "Beta"}}},
+		{"Title": "Todos", "Count": -300, "Items": []item{{"a", "Alpha"}, {"b", "Beta"}, {"c", "Gamma"}}},
+		{"Title": "Done", "Count": 70000, "Items": []item{{"c", "Gamma"}}},
+	}
+
+	parse := func(t *testing.T, name string) *Template {
+		t.Helper()
+		tmpl := New(name)
+		if _, err := tmpl.Parse(src); err != nil {
+			t.Fatalf("Parse failed: %v", err)
+		}
+		return tmpl
+	}
+	jsonTmpl := parse(t, "msgpack-json")
+	msgpackTmpl := parse(t, "msgpack-binary")
+
+	for i, state := range states {
+		var jsonBuf, msgpackBuf bytes.Buffer
+		if err := jsonTmpl.ExecuteUpdates(&jsonBuf, state); err != nil {
+			t.Fatalf("Update %d: ExecuteUpdates failed: %v", i, err)
+		}
+		if err := msgpackTmpl.ExecuteUpdatesAs(&msgpackBuf, state, WireFormatMsgPack); err != nil {
+			t.Fatalf("Update %d: ExecuteUpdatesAs failed: %v", i, err)
+		}
+
+		var want interface{}
+		if err := json.Unmarshal(jsonBuf.Bytes(), &want); err != nil {
+			t.Fatalf("Update %d: invalid JSON: %v", i, err)
+		}
+		got, rest, err := decodeMsgPack(msgpackBuf.Bytes())
+		if err != nil {
+			t.Fatalf("Update %d: invalid MessagePack: %v", i, err)
+		}
+		if len(rest) != 0 {
+			t.Errorf("Update %d: %d trailing bytes after the MessagePack value", i, len(rest))
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Update %d: MessagePack decodes to\n%v\nwant the JSON form\n%v", i, got, want)
+		}
+	}
+
+	t.Run("JSON format matches ExecuteUpdates", func(t *testing.T) {
+		a := parse(t, "format-a")
+		b := parse(t, "format-b")
+		var bufA, bufB bytes.Buffer
+		if err := a.ExecuteUpdates(&bufA, states[0]); err != nil {
+			t.Fatalf("ExecuteUpdates failed: %v", err)
+		}
+		if err := b.ExecuteUpdatesAs(&bufB, states[0], WireFormatJSON); err != nil {
+			t.Fatalf("ExecuteUpdatesAs failed: %v", err)
+		}
+		if bufA.String() != bufB.String() {
+			t.Errorf("Expected identical JSON, got %s and %s", bufA.String(), bufB.String())
+		}
+	})
+}
+
+// decodeMsgPack decodes the MessagePack value at the start of b into the types
+// json.Unmarshal produces, returning the remaining bytes
+func decodeMsgPack(b []byte) (interface{}, []byte, error) {
+	if len(b) == 0 {
+		return nil, nil, fmt.Errorf("unexpected end of input")
+	}
+	c, b := b[0], b[1:]
+	switch {
+	case c <= 0x7f:
+		return float64(c), b, nil
+	case c >= 0xe0:
+		return float64(int8(c)), b, nil
+	case c&0xe0 == 0xa0:
+		return decodeMsgPackString(b, int(c&0x1f))
+	case c&0xf0 == 0x90:
+		return decodeMsgPackArray(b, int(c&0x0f))
+	case c&0xf0 == 0x80:
+		return decodeMsgPackMap(b, int(c&0x0f))
+	}
+
+	need := map[byte]int{0xca: 4, 0xcb: 8, 0xcc: 1, 0xcd: 2, 0xce: 4, 0xcf: 8, 0xd0: 1, 0xd1: 2,
+		0xd2: 4, 0xd3: 8, 0xd9: 1, 0xda: 2, 0xdb: 4, 0xdc: 2, 0xdd: 4, 0xde: 2, 0xdf: 4}[c]
+	if len(b) < need {
+		return nil, nil, fmt.Errorf("truncated value 0x%x", c)
+	}
+	length := func() int {
+		switch need {
+		case 1:
+			return int(b[0])
+		case 2:
+			return int(binary.BigEndian.Uint16(b))
+		default:
+			return int(binary.BigEndian.Uint32(b))
+		}
+	}
+	switch c {
+	case 0xc0:
+		return nil, b, nil
+	case 0xc2, 0xc3:
+		return c == 0xc3, b, nil
+	case 0xca:
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(b))), b[4:], nil
+	case 0xcb:
+		return math.Float64frombits(binary.BigEndian.Uint64(b)), b[8:], nil
+	case 0xcc, 0xcd, 0xce:
+		return float64(length()), b[need:], nil
+	case 0xcf:
+		return float64(binary.BigEndian.Uint64(b)), b[8:], nil
+	case 0xd0:
+		return float64(int8(b[0])), b[1:], nil
+	case 0xd1:
+		return float64(int16(binary.BigEndian.Uint16(b))), b[2:], nil
+	case 0xd2:
+		return float64(int32(binary.BigEndian.Uint32(b))), b[4:], nil
+	case 0xd3:
+		return float64(int64(binary.BigEndian.Uint64(b))), b[8:], nil
+	case 0xd9, 0xda, 0xdb:
+		return decodeMsgPackString(b[need:], length())
+	case 0xdc, 0xdd:
+		return decodeMsgPackArray(b[need:], length())
+	case 0xde, 0xdf:
+		return decodeMsgPackMap(b[need:], length())
+	}
+	return nil, nil, fmt.Errorf("unsupported MessagePack type 0x%x", c)
+}
+
+func decodeMsgPackString(b []byte, n int) (interface{}, []byte, error) {
+	if len(b) < n {
+		return nil, nil, fmt.Errorf("truncated string")
+	}
+	return string(b[:n]), b[n:], nil
+}
+
+func decodeMsgPackArray(b []byte, n int) (interface{}, []byte, error) {
+	items := make([]interface{}, 0, n)
+	for i := 0; i < n; i++ {
+		item, rest, err := decodeMsgPack(b)
+		if err != nil {
+			return nil, nil, err
+		}
+		items = append(items, item)
+		b = rest
+	}
+	return items, b, nil
+}
+
+func decodeMsgPackMap(b []byte, n int) (interface{}, []byte, error) {
+	m := make(map[string]interface{}, n)
+	for i := 0; i < n; i++ {
+		key, rest, err := decodeMsgPack(b)
+		if err != nil {
+			return nil, nil, err
+		}
+		s, ok := key.(string)
+		if !ok {
+			return nil, nil, fmt.Errorf("map key %v is not a string", key)
+		}
+		if m[s], b, err = decodeMsgPack(rest); err != nil {
+			return nil, nil, err
+		}
+	}
+	return m, b, nil
+}
@@ -0,0 +1,78 @@
+package livetemplate
+
+import (
+	"sync"
+	"time"
+)
+
+// defaultNonceWindow is used when WithNonceWindow isn't set
+const defaultNonceWindow = 2 * time.Minute
+
+// nonceField is the form field carrying the nonce of a form-encoded HTTP action
+const nonceField = "lvt-nonce"
+
+// WithNonceWindow sets how long the nonces of actions are remembered. A client that may
+// retry an action, e.g. after a network error, sends a unique nonce with it:
+//
+//	{"action": "checkout", "data": {...}, "nonce": "3f2c9a"}
+//
+// An action repeating a nonce seen within the window is answered without running Change,
+// so a retried checkout doesn't charge twice. Nonces are remembered per WebSocket
+// connection, and per session group for HTTP actions, which send it as the lvt-nonce form
+// field or in the JSON body. Actions without a nonce always run.
+//
+// Default: 2 minutes
+func WithNonceWindow(d time.Duration) Option {
+	return func(c *Config) {
+		c.NonceWindow = d
+	}
+}
+
+// nonceCache remembers the nonces of recent actions until the window elapses.
+//
+// Thread-safe: safe for concurrent access from multiple goroutines.
+type nonceCache struct {
+	mu      sync.Mutex
+	window  time.Duration
+	expires map[string]time.Time
+}
+
+// newNonceCache creates an empty nonce cache remembering nonces for window
+func newNonceCache(window time.Duration) *nonceCache {
+	if window <= 0 {
+		window = defaultNonceWindow
+	}
+	return &nonceCache{
+		window:  window,
+		expires: make(map[string]time.Time),
+	}
+}
+
+// seen reports whether nonce was recorded within the window, and records it otherwise
+func (c *nonceCache) seen(nonce string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if expires, ok := c.expires[nonce]; ok && now.Before(expires) {
+		return true
+	}
+
+	// Drop expired nonces so they don't accumulate
+	for n, expires := range c.expires {
+		if now.After(expires) {
+			delete(c.expires, n)
+		}
+	}
+
+	c.expires[nonce] = now.Add(c.window)
+	return false
+}
+
+// repeatedNonce reports whether the action carrying nonce already ran for state
+func (h *liveHandler) repeatedNonce(state *connState, nonce string) bool {
+	if state.nonces != nil {
+		return state.nonces.seen(nonce)
+	}
+	return h.nonces.seen(state.groupID + "\x00" + nonce)
+}
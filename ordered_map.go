@@ -0,0 +1,68 @@
+package livetemplate
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// MapEntry is a key/value pair of a map ranged over with lvt_ordered
+type MapEntry struct {
+	Key   interface{}
+	Value interface{}
+}
+
+// lvtOrdered implements {{lvt_ordered .Map [order...]}}: it returns the entries of a map with
+// the keys of the order hint first, in hint order, followed by the remaining keys sorted.
+//
+// Ranging over a map visits its keys sorted, so a key-value table can't keep a meaningful
+// field order. The hint is a list of strings, a []string or both; keys are matched by their
+// printed form and hint keys missing from the map are skipped:
+//
+//	{{range lvt_ordered .Profile "name" "email" "role"}}<tr><th>{{.Key}}</th><td>{{.Value}}</td></tr>{{end}}
+//	{{range lvt_ordered .Profile .FieldOrder}}...{{end}}
+//
+// Each entry is keyed by its map key in the update tree, as in a range over the map itself.
+func lvtOrdered(m interface{}, order ...interface{}) ([]MapEntry, error) {
+	mapValue := reflect.ValueOf(m)
+	if !mapValue.IsValid() {
+		return nil, nil
+	}
+	if mapValue.Kind() != reflect.Map {
+		return nil, fmt.Errorf("lvt_ordered: expected a map, got %T", m)
+	}
+
+	var hint []string
+	for _, o := range order {
+		switch v := o.(type) {
+		case string:
+			hint = append(hint, v)
+		case []string:
+			hint = append(hint, v...)
+		default:
+			return nil, fmt.Errorf("lvt_ordered: order hint must be strings or []string, got %T", o)
+		}
+	}
+
+	keys := sortedMapKeys(mapValue)
+	byName := make(map[string]reflect.Value, len(keys))
+	for _, key := range keys {
+		byName[fmt.Sprint(key.Interface())] = key
+	}
+
+	entries := make([]MapEntry, 0, len(keys))
+	used := make(map[string]bool, len(hint))
+	for _, name := range hint {
+		key, ok := byName[name]
+		if !ok || used[name] {
+			continue
+		}
+		used[name] = true
+		entries = append(entries, MapEntry{Key: key.Interface(), Value: mapValue.MapIndex(key).Interface()})
+	}
+	for _, key := range keys {
+		if !used[fmt.Sprint(key.Interface())] {
+			entries = append(entries, MapEntry{Key: key.Interface(), Value: mapValue.MapIndex(key).Interface()})
+		}
+	}
+	return entries, nil
+}
@@ -0,0 +1,76 @@
+package livetemplate
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLvtOrdered(t *testing.T) {
+	data := map[string]interface{}{
+		"Profile": map[string]string{"role": "admin", "email": "ann@example.com", "name": "Ann", "age": "30"},
+		"Order":   []string{"name", "email"},
+	}
+	want := []string{"name", "email", "age", "role"}
+
+	for _, tc := range []struct {
+		name string
+		src  string
+	}{
+		{"string hint", `<table>{{range lvt_ordered .Profile "name" "email"}}<tr><th>{{.Key}}</th><td>{{.Value}}</td></tr>{{end}}</table>`},
+		{"slice hint", `<table>{{range lvt_ordered .Profile .Order}}<tr><th>{{.Key}}</th><td>{{.Value}}</td></tr>{{end}}</table>`},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			tmpl := New("ordered-test")
+			if _, err := tmpl.Parse(tc.src); err != nil {
+				t.Fatalf("Parse failed: %v", err)
+			}
+
+			var buf bytes.Buffer
+			if err := tmpl.ExecuteUpdates(&buf, data); err != nil {
+				t.Fatalf("ExecuteUpdates failed: %v", err)
+			}
+
+			var tree struct {
+				Range struct {
+					D []map[string]string `json:"d"`
+				} `json:"0"`
+			}
+			if err := json.Unmarshal(buf.Bytes(), &tree); err != nil {
+				t.Fatalf("Invalid update JSON %s: %v", buf.String(), err)
+			}
+			var got []string
+			for _, item := range tree.Range.D {
+				got = append(got, item["0"])
+				if item["_k"] != item["0"] {
+					t.Errorf("Expected entry keyed by its map key %q, got %q", item["0"], item["_k"])
+				}
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("Emitted order = %v, want %v (update: %s)", got, want, buf.String())
+			}
+
+			// The initial HTML render follows the same order
+			buf.Reset()
+			htmlTmpl := New("ordered-html-test")
+			if _, err := htmlTmpl.Parse(tc.src); err != nil {
+				t.Fatalf("Parse failed: %v", err)
+			}
+			if err := htmlTmpl.Execute(&buf, data); err != nil {
+				t.Fatalf("Execute failed: %v", err)
+			}
+			if html := buf.String(); strings.Index(html, "<th>name</th>") > strings.Index(html, "<th>email</th>") ||
+				strings.Index(html, "<th>email</th>") > strings.Index(html, "<th>age</th>") {
+				t.Errorf("Rendered HTML is not in hint order: %s", html)
+			}
+		})
+	}
+
+	t.Run("not a map", func(t *testing.T) {
+		if _, err := lvtOrdered([]string{"a"}, "a"); err == nil {
+			t.Error("Expected an error for a non-map value")
+		}
+	})
+}
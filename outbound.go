@@ -0,0 +1,104 @@
+package livetemplate
+
+import (
+	"errors"
+	"log"
+	"sync"
+	"time"
+)
+
+// errOutboundQueueFull is returned by Send when a client fell too far behind and was dropped
+var errOutboundQueueFull = errors.New("outbound queue full: client is not keeping up")
+
+// errConnectionDropped is returned by Send after a connection was dropped
+var errConnectionDropped = errors.New("connection dropped")
+
+// WithOutboundQueue gives every WebSocket connection a queue of up to size outgoing messages,
+// written by a goroutine of its own, so a slow client no longer blocks the broadcasts and
+// actions producing its updates. A client falling more than size messages behind is
+// disconnected; its client reconnects and catches up (see WithResumeWindow).
+//
+// Server-sent event streams are written directly.
+//
+// Default: 0 (messages are written by the goroutine sending them)
+func WithOutboundQueue(size int) Option {
+	return func(c *Config) {
+		c.OutboundQueue = size
+	}
+}
+
+// outboundQueue holds the messages of a connection waiting to be written
+type outboundQueue struct {
+	messages chan outboundMessage
+	done     chan struct{} // Closed when the connection is dropped
+	once     sync.Once
+	write    func(messageType int, data []byte) error
+	close    func() error
+}
+
+type outboundMessage struct {
+	messageType int
+	data        []byte
+}
+
+func newOutboundQueue(size int, write func(messageType int, data []byte) error, closeConn func() error) *outboundQueue {
+	return &outboundQueue{
+		messages: make(chan outboundMessage, size),
+		done:     make(chan struct{}),
+		write:    write,
+		close:    closeConn,
+	}
+}
+
+// startOutboundQueue makes Send queue the messages of c for a writer goroutine, which runs
+// until the connection is dropped
+func (c *Connection) startOutboundQueue(size int) {
+	c.queue = newOutboundQueue(size, c.Conn.WriteMessage, c.Conn.Close)
+	go c.writeQueued()
+}
+
+// enqueue adds a message to the queue without blocking. A full queue drops the connection.
+func (c *Connection) enqueue(messageType int, data []byte) error {
+	select {
+	case <-c.queue.done:
+		return errConnectionDropped
+	default:
+	}
+
+	select {
+	case c.queue.messages <- outboundMessage{messageType: messageType, data: data}:
+		return nil
+	default:
+		log.Printf("Dropping connection in group %s: %d messages waiting to be written", c.GroupID, cap(c.queue.messages))
+		c.queue.drop()
+		return errOutboundQueueFull
+	}
+}
+
+// writeQueued writes queued messages in order until the connection is dropped
+func (c *Connection) writeQueued() {
+	for {
+		select {
+		case <-c.queue.done:
+			return
+		case msg := <-c.queue.messages:
+			if err := c.queue.write(msg.messageType, msg.data); err != nil {
+				c.queue.drop()
+				return
+			}
+			c.mu.Lock()
+			c.bytesSent += int64(len(msg.data))
+			c.updatesSent++
+			c.lastActivity = time.Now()
+			c.mu.Unlock()
+		}
+	}
+}
+
+// drop closes the connection, which ends its read loop and handler, and stops its writer
+func (q *outboundQueue) drop() {
+	q.once.Do(func() {
+		close(q.done)
+		_ = q.close()
+	})
+}
@@ -0,0 +1,84 @@
+package livetemplate
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestOutboundQueue(t *testing.T) {
+	t.Run("stalled client is dropped instead of blocking Send", func(t *testing.T) {
+		stall := make(chan struct{})
+		var closed atomic.Bool
+		conn := &Connection{GroupID: "group-1"}
+		conn.queue = newOutboundQueue(2,
+			func(messageType int, data []byte) error {
+				<-stall // The client never reads
+				return errors.New("connection closed")
+			},
+			func() error {
+				closed.Store(true)
+				close(stall)
+				return nil
+			})
+		go conn.writeQueued()
+
+		done := make(chan error, 1)
+		go func() {
+			for i := 0; i < 10; i++ {
+				if err := conn.Send(websocket.TextMessage, []byte("update")); err != nil {
+					done <- err
+					return
+				}
+			}
+			done <- nil
+		}()
+
+		select {
+		case err := <-done:
+			if !errors.Is(err, errOutboundQueueFull) {
+				t.Fatalf("Expected errOutboundQueueFull, got %v", err)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("Send blocked on a stalled client")
+		}
+		if !closed.Load() {
+			t.Error("Expected the connection to be closed when the queue overflowed")
+		}
+		if err := conn.Send(websocket.TextMessage, []byte("update")); !errors.Is(err, errConnectionDropped) {
+			t.Errorf("Expected errConnectionDropped after the drop, got %v", err)
+		}
+	})
+
+	t.Run("messages are written in order", func(t *testing.T) {
+		written := make(chan string, 3)
+		conn := &Connection{}
+		conn.queue = newOutboundQueue(3,
+			func(messageType int, data []byte) error {
+				written <- string(data)
+				return nil
+			},
+			func() error { return nil })
+		go conn.writeQueued()
+		defer conn.queue.drop()
+
+		for _, msg := range []string{"a", "b", "c"} {
+			if err := conn.Send(websocket.TextMessage, []byte(msg)); err != nil {
+				t.Fatalf("Send failed: %v", err)
+			}
+		}
+		for _, want := range []string{"a", "b", "c"} {
+			select {
+			case got := <-written:
+				if got != want {
+					t.Errorf("Written %q, want %q", got, want)
+				}
+			case <-time.After(2 * time.Second):
+				t.Fatalf("Timed out waiting for %q", want)
+			}
+		}
+	})
+}
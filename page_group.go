@@ -0,0 +1,85 @@
+package livetemplate
+
+import (
+	"fmt"
+	"log"
+	"sync"
+)
+
+// PageGroup fans updates of a shared data source out to several pages, each served by its
+// own LiveHandler and template. Instead of keeping the handlers in a map and broadcasting
+// to each:
+//
+//	group := livetemplate.NewPageGroup(dashboard.Handle(&stats), ticker.Handle(&stats))
+//	// ... when the data changes:
+//	group.Update(stats)
+//
+// Every connection of every member diffs the data against what it last sent, with its
+// page's template, so each client gets only its own changes.
+//
+// Concurrency: All methods are safe to call from multiple goroutines concurrently.
+type PageGroup struct {
+	mu    sync.RWMutex
+	pages []LiveHandler
+}
+
+// NewPageGroup creates a group of the given pages
+func NewPageGroup(pages ...LiveHandler) *PageGroup {
+	g := &PageGroup{}
+	for _, page := range pages {
+		g.Add(page)
+	}
+	return g
+}
+
+// Add registers page with the group. Adding a page twice has no effect.
+func (g *PageGroup) Add(page LiveHandler) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for _, p := range g.pages {
+		if p == page {
+			return
+		}
+	}
+	g.pages = append(g.pages, page)
+}
+
+// Remove unregisters page from the group
+func (g *PageGroup) Remove(page LiveHandler) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for i, p := range g.pages {
+		if p == page {
+			g.pages = append(g.pages[:i:i], g.pages[i+1:]...)
+			return
+		}
+	}
+}
+
+// Len returns the number of pages in the group
+func (g *PageGroup) Len() int {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return len(g.pages)
+}
+
+// Update broadcasts data to the group's pages (see LiveHandler.Broadcast). A page failing
+// to update some of its connections doesn't stop the update of the others.
+func (g *PageGroup) Update(data interface{}) error {
+	g.mu.RLock()
+	pages := append([]LiveHandler(nil), g.pages...)
+	g.mu.RUnlock()
+
+	var errCount int
+	for _, page := range pages {
+		if err := page.Broadcast(data); err != nil {
+			log.Printf("PageGroup: Failed to update page: %v", err)
+			errCount++
+		}
+	}
+
+	if errCount > 0 {
+		return fmt.Errorf("page group update failed for %d/%d pages", errCount, len(pages))
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package livetemplate
+
+import "testing"
+
+func TestPageGroup_Update(t *testing.T) {
+	counter := New("page-group-counter")
+	if _, err := counter.Parse(`<p>Count: {{.Count}}</p>`); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	badge := New("page-group-badge")
+	if _, err := badge.Parse(`<span class="badge">{{if .Count}}{{.Count}} new{{else}}none{{end}}</span>`); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	counterPage, badgePage := counter.Handle(&SlowState{}), badge.Handle(&SlowState{})
+	group := NewPageGroup(counterPage, badgePage, counterPage)
+	if group.Len() != 2 {
+		t.Fatalf("Expected a page added twice to be registered once, got %d pages", group.Len())
+	}
+
+	counterConn := dialTestHandler(t, counterPage)
+	badgeConn := dialTestHandler(t, badgePage)
+
+	if err := group.Update(&SlowState{Count: 3}); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+
+	tree, meta := readUpdate(t, counterConn)
+	if meta == nil || !meta.Success {
+		t.Fatalf("Expected a successful update, got %+v", meta)
+	}
+	if len(tree) != 1 || tree["0"] != "3" {
+		t.Errorf("Expected the counter page to get only the new count, got %v", tree)
+	}
+
+	tree, _ = readUpdate(t, badgeConn)
+	branch, ok := tree["0"].(map[string]interface{})
+	if !ok || len(tree) != 1 || branch["0"] != "3" {
+		t.Errorf("Expected the badge page to switch to the count branch, got %v", tree)
+	}
+
+	group.Remove(badgePage)
+	if group.Len() != 1 {
+		t.Fatalf("Expected one page after Remove, got %d", group.Len())
+	}
+	if err := group.Update(&SlowState{Count: 4}); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if tree, _ := readUpdate(t, counterConn); tree["0"] != "4" {
+		t.Errorf("Expected the remaining page to be updated, got %v", tree)
+	}
+}
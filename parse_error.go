@@ -0,0 +1,37 @@
+package livetemplate
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+// ParseError is a syntax error in one of the files given to ParseFiles or ParseGlob. Line
+// is relative to File, or 0 when the error isn't tied to a line.
+type ParseError struct {
+	File string
+	Line int
+	Msg  string
+}
+
+func (e ParseError) Error() string {
+	if e.Line == 0 {
+		return fmt.Sprintf("failed to parse file %s: %s", e.File, e.Msg)
+	}
+	return fmt.Sprintf("failed to parse file %s:%d: %s", e.File, e.Line, e.Msg)
+}
+
+// templateErrorPattern matches the parse errors of text/template, "template: name:line: msg"
+// or "template: name: msg". The name is the template's, which is the first file's for
+// every file of a set, so it's dropped.
+var templateErrorPattern = regexp.MustCompile(`^template: [^:]*:(?:(\d+):)? (.*)$`)
+
+// newParseError attributes a parse error of the text/template engine to file
+func newParseError(file string, err error) ParseError {
+	match := templateErrorPattern.FindStringSubmatch(err.Error())
+	if match == nil {
+		return ParseError{File: file, Msg: err.Error()}
+	}
+	line, _ := strconv.Atoi(match[1])
+	return ParseError{File: file, Line: line, Msg: match[2]}
+}
@@ -0,0 +1,42 @@
+package livetemplate
+
+import (
+	"fmt"
+	"log"
+)
+
+// WithMaxPayloadSize bounds the data of a single action, e.g. a huge form, to bytes as sent:
+// the JSON of the action's data, or the encoded fields of a form post. An action over the
+// limit doesn't reach the store; its response carries the error in the metadata and the
+// connection stays open. WithActionPayloadSize overrides the limit for single actions.
+//
+// Default: 0 (no limit)
+func WithMaxPayloadSize(bytes int) Option {
+	return func(c *Config) {
+		c.MaxPayloadSize = bytes
+	}
+}
+
+// WithActionPayloadSize overrides WithMaxPayloadSize for action, named as the client sends
+// it (e.g. "docs.upload" in multi-store mode). A limit of 0 or less allows any size.
+func WithActionPayloadSize(action string, bytes int) Option {
+	return func(c *Config) {
+		if c.ActionPayloadSizes == nil {
+			c.ActionPayloadSizes = make(map[string]int)
+		}
+		c.ActionPayloadSizes[action] = bytes
+	}
+}
+
+// checkPayloadSize returns an error when the data of msg is over its action's limit
+func (h *liveHandler) checkPayloadSize(msg message) error {
+	limit := h.config.MaxPayloadSize
+	if override, ok := h.config.ActionPayloadSizes[msg.Action]; ok {
+		limit = override
+	}
+	if limit <= 0 || msg.size <= limit {
+		return nil
+	}
+	log.Printf("Rejected action %q: payload of %d bytes over the limit of %d", msg.Action, msg.size, limit)
+	return fmt.Errorf("action %q payload of %d bytes exceeds the limit of %d bytes", msg.Action, msg.size, limit)
+}
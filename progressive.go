@@ -0,0 +1,152 @@
+package livetemplate
+
+import (
+	"log"
+	"reflect"
+	"strings"
+)
+
+// WithProgressiveRange streams a large range to live clients in batches: the initial tree
+// holds the first batchSize items of field, and the handler sends the rest as append
+// updates right after it, batchSize items at a time, so the page renders before the whole
+// range has been sent:
+//
+//	tmpl := livetemplate.New("report", livetemplate.WithProgressiveRange("Rows", 200))
+//
+// field is the path of a slice in the template data, e.g. "Rows", or "table.Rows" for the
+// stores of HandleNamed. Until its last batch is sent the template sees a copy of the data
+// with the slice truncated, so give the items a key attribute for the batches to be appended.
+// Pages rendered with Execute, e.g. the initial HTTP response, always hold the whole range.
+func WithProgressiveRange(field string, batchSize int) Option {
+	return func(c *Config) {
+		if batchSize <= 0 {
+			return
+		}
+		if c.ProgressiveRanges == nil {
+			c.ProgressiveRanges = make(map[string]int)
+		}
+		c.ProgressiveRanges[field] = batchSize
+	}
+}
+
+// startProgressive makes the next ExecuteUpdates calls send the ranges configured with
+// WithProgressiveRange one batch at a time
+func (t *Template) startProgressive() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.config.ProgressiveRanges) == 0 {
+		return
+	}
+	t.progress = make(map[string]int, len(t.config.ProgressiveRanges))
+	for field := range t.config.ProgressiveRanges {
+		t.progress[field] = 0
+	}
+}
+
+// progressPending reports whether a progressive range has batches left to send
+func (t *Template) progressPending() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.progress) > 0
+}
+
+// progressiveData returns data with every progressive range still being streamed truncated
+// to the next batch, and advances the streams. Called with t.mu held.
+func (t *Template) progressiveData(data interface{}) interface{} {
+	for field, sent := range t.progress {
+		limit := sent + t.config.ProgressiveRanges[field]
+		truncated, more := truncateRange(reflect.ValueOf(data), strings.Split(field, "."), limit)
+		if !more {
+			delete(t.progress, field)
+			continue
+		}
+		data = truncated.Interface()
+		t.progress[field] = limit
+	}
+	return data
+}
+
+// truncateRange returns a copy of v with the slice at path cut to limit items, copying the
+// structs and maps on the way so the caller's data is untouched. Reports false, with v
+// itself, when the slice doesn't exist or has no more than limit items.
+func truncateRange(v reflect.Value, path []string, limit int) (reflect.Value, bool) {
+	switch v.Kind() {
+	case reflect.Interface:
+		if v.IsNil() {
+			return v, false
+		}
+		return truncateRange(v.Elem(), path, limit)
+	case reflect.Ptr:
+		if v.IsNil() {
+			return v, false
+		}
+		elem, more := truncateRange(v.Elem(), path, limit)
+		if !more {
+			return v, false
+		}
+		// Keep a pointer so methods with pointer receivers still resolve
+		ptr := reflect.New(elem.Type())
+		ptr.Elem().Set(elem)
+		return ptr, true
+	}
+
+	if len(path) == 0 {
+		if v.Kind() == reflect.Slice && v.Len() > limit {
+			return v.Slice(0, limit), true
+		}
+		return v, false
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		field := v.FieldByName(path[0])
+		if !field.IsValid() {
+			return v, false
+		}
+		truncated, more := truncateRange(field, path[1:], limit)
+		if !more {
+			return v, false
+		}
+		copied := reflect.New(v.Type()).Elem()
+		copied.Set(v)
+		target := copied.FieldByName(path[0])
+		if !target.CanSet() || !truncated.Type().AssignableTo(target.Type()) {
+			log.Printf("Warning: progressive range %q can't be truncated", strings.Join(path, "."))
+			return v, false
+		}
+		target.Set(truncated)
+		return copied, true
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return v, false
+		}
+		key := reflect.ValueOf(path[0]).Convert(v.Type().Key())
+		elem := v.MapIndex(key)
+		if !elem.IsValid() {
+			return v, false
+		}
+		truncated, more := truncateRange(elem, path[1:], limit)
+		if !more || !truncated.Type().AssignableTo(v.Type().Elem()) {
+			return v, false
+		}
+		copied := reflect.MakeMapWithSize(v.Type(), v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			copied.SetMapIndex(iter.Key(), iter.Value())
+		}
+		copied.SetMapIndex(key, truncated)
+		return copied, true
+	}
+	return v, false
+}
+
+// streamProgressiveRanges sends the rest of the ranges streamed with WithProgressiveRange
+// after the initial tree, one batch per update
+func (h *liveHandler) streamProgressiveRanges(conn *Connection, state *connState) error {
+	for conn.Template.progressPending() {
+		if err := h.writeUpdate(conn, h.getTemplateData(state.stores)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+package livetemplate
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type ReportRow struct {
+	ID   string
+	Name string
+}
+
+// ReportState is a test store with a large range
+type ReportState struct {
+	Title string
+	Rows  []ReportRow
+}
+
+func (s *ReportState) Change(ctx *ActionContext) error {
+	if ctx.Action == "rename" {
+		s.Title = "Renamed"
+	}
+	return nil
+}
+
+func TestWithProgressiveRange(t *testing.T) {
+	tmpl := New("progressive-test", WithProgressiveRange("Rows", 4))
+	if _, err := tmpl.Parse(`<h1>{{.Title}}</h1><table>{{range .Rows}}<tr data-key="{{.ID}}"><td>{{.Name}}</td></tr>{{end}}</table>`); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	store := &ReportState{Title: "Report"}
+	for i := 0; i < 10; i++ {
+		store.Rows = append(store.Rows, ReportRow{ID: fmt.Sprintf("row-%d", i), Name: fmt.Sprintf("Row %d", i)})
+	}
+	server := httptest.NewServer(tmpl.Handle(store))
+	defer server.Close()
+
+	t.Run("WebSocket streams the range in batches", func(t *testing.T) {
+		conn := dialResumeTest(t, server, "", "group-progressive")
+
+		initial, _ := readUpdate(t, conn)
+		rows, _ := initial["1"].(map[string]interface{})
+		if items, _ := rows["d"].([]interface{}); len(items) != 4 {
+			t.Fatalf("Expected the initial tree to hold the first 4 rows, got %v", initial["1"])
+		}
+
+		// The rest follows as appends of at most 4 rows, in order
+		var streamed []string
+		for _, want := range []int{4, 2} {
+			update, _ := readUpdate(t, conn)
+			ops, _ := update["1"].([]interface{})
+			if len(ops) != 1 {
+				t.Fatalf("Expected one append op, got %v", update)
+			}
+			op, _ := ops[0].([]interface{})
+			if len(op) < 2 || op[0] != "a" {
+				t.Fatalf("Expected an append op, got %v", ops[0])
+			}
+			items, _ := op[1].([]interface{})
+			if len(items) != want {
+				t.Fatalf("Expected a batch of %d rows, got %v", want, op[1])
+			}
+			for _, item := range items {
+				streamed = append(streamed, item.(map[string]interface{})["0"].(string))
+			}
+		}
+		if got := strings.Join(streamed, ","); got != "row-4,row-5,row-6,row-7,row-8,row-9" {
+			t.Errorf("Expected the remaining rows in order, got %s", got)
+		}
+
+		// Once streamed, updates diff the whole range as usual
+		if err := conn.WriteJSON(map[string]interface{}{"action": "rename"}); err != nil {
+			t.Fatalf("Failed to send action: %v", err)
+		}
+		update, _ := readUpdate(t, conn)
+		if _, ok := update["1"]; ok || update["0"] != "Renamed" {
+			t.Errorf("Expected only the title to change, got %v", update)
+		}
+	})
+
+	t.Run("pages rendered with Execute hold the whole range", func(t *testing.T) {
+		html, err := tmpl.RenderString(store)
+		if err != nil {
+			t.Fatalf("RenderString failed: %v", err)
+		}
+		if !strings.Contains(html, "Row 9") {
+			t.Errorf("Expected every row in the page, got %s", html)
+		}
+	})
+}
+
+func TestTruncateRange(t *testing.T) {
+	rows := []ReportRow{{ID: "a"}, {ID: "b"}, {ID: "c"}}
+
+	tests := []struct {
+		name     string
+		data     interface{}
+		path     string
+		wantMore bool
+		wantLen  func(v reflect.Value) int
+	}{
+		{
+			name:     "struct pointer",
+			data:     &ReportState{Rows: rows},
+			path:     "Rows",
+			wantMore: true,
+			wantLen:  func(v reflect.Value) int { return len(v.Interface().(*ReportState).Rows) },
+		},
+		{
+			name:     "named stores",
+			data:     map[string]interface{}{"report": &ReportState{Rows: rows}},
+			path:     "report.Rows",
+			wantMore: true,
+			wantLen: func(v reflect.Value) int {
+				return len(v.Interface().(map[string]interface{})["report"].(*ReportState).Rows)
+			},
+		},
+		{
+			name: "short range",
+			data: &ReportState{Rows: rows[:2]},
+			path: "Rows",
+		},
+		{
+			name: "missing field",
+			data: &ReportState{Rows: rows},
+			path: "Items",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			truncated, more := truncateRange(reflect.ValueOf(tt.data), strings.Split(tt.path, "."), 2)
+			if more != tt.wantMore {
+				t.Fatalf("Expected more=%v, got %v", tt.wantMore, more)
+			}
+			if !more {
+				return
+			}
+			if n := tt.wantLen(truncated); n != 2 {
+				t.Errorf("Expected 2 items after truncation, got %d", n)
+			}
+			if n := len(rows); n != 3 {
+				t.Errorf("Truncation modified the original data")
+			}
+		})
+	}
+}
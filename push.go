@@ -0,0 +1,57 @@
+package livetemplate
+
+import "log"
+
+// pushBuffer is how many pushed actions a connection holds while it is busy with others;
+// pushes beyond it are dropped
+const pushBuffer = 16
+
+// push queues msg for the connection's message loop. Reports false when the connection
+// doesn't take pushed actions or its buffer is full.
+func (c *Connection) push(msg message) bool {
+	if c.pushes == nil {
+		return false
+	}
+	select {
+	case c.pushes <- msg:
+		return true
+	default:
+		return false
+	}
+}
+
+// PushToUser runs action with payload on every WebSocket connection of userID, whatever its
+// session group, e.g. to show a notification on each of a user's devices:
+//
+//	handler.PushToUser("user-123", "notify", map[string]interface{}{"text": "Build finished"})
+//
+// Each connection runs the action through its stores' Change as if its client had sent it,
+// in order with the client's own actions, and sends the resulting update. The action may
+// carry a store prefix as in client actions; payload is as for ActionContext.Dispatch.
+//
+// Returns the number of connections the action was queued on. Server-sent event connections
+// don't take pushed actions.
+//
+// Concurrency: This method is safe to call from multiple goroutines concurrently.
+func (h *liveHandler) PushToUser(userID, action string, payload interface{}) int {
+	data, err := payloadToData(payload)
+	if err != nil {
+		log.Printf("PushToUser: invalid payload for action %q: %v", action, err)
+		return 0
+	}
+
+	reached := 0
+	for _, conn := range h.registry.GetByUser(userID) {
+		// Each connection gets its own copy, as stores may modify the data
+		msg := message{Action: action, Data: make(map[string]interface{}, len(data))}
+		for k, v := range data {
+			msg.Data[k] = v
+		}
+		if conn.push(msg) {
+			reached++
+		} else {
+			log.Printf("PushToUser: connection of user %s in group %s didn't take action %q", userID, conn.GroupID, action)
+		}
+	}
+	return reached
+}
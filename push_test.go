@@ -0,0 +1,70 @@
+package livetemplate
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// deviceAuthenticator is a test authenticator taking the user from X-User and giving each
+// device (X-Device) its own session group
+type deviceAuthenticator struct{}
+
+func (deviceAuthenticator) Identify(r *http.Request) (string, error) {
+	return r.Header.Get("X-User"), nil
+}
+
+func (deviceAuthenticator) GetSessionGroup(r *http.Request, userID string) (string, error) {
+	return userID + "-" + r.Header.Get("X-Device"), nil
+}
+
+func TestLiveHandler_PushToUser(t *testing.T) {
+	tmpl := New("push-test", WithAuthenticator(deviceAuthenticator{}))
+	if _, err := tmpl.Parse("<p>Count: {{.Count}}</p>"); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	handler := tmpl.Handle(&SlowState{})
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	dial := func(t *testing.T, user, device string) *websocket.Conn {
+		t.Helper()
+		header := http.Header{"X-User": {user}, "X-Device": {device}}
+		conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), header)
+		if err != nil {
+			t.Fatalf("WebSocket dial failed: %v", err)
+		}
+		t.Cleanup(func() { conn.Close() })
+		readUpdate(t, conn)
+		return conn
+	}
+	phone := dial(t, "alice", "phone")
+	laptop := dial(t, "alice", "laptop")
+	other := dial(t, "bob", "phone")
+
+	if reached := handler.PushToUser("alice", "increment", nil); reached != 2 {
+		t.Fatalf("Expected the push to reach 2 connections, got %d", reached)
+	}
+	for name, conn := range map[string]*websocket.Conn{"phone": phone, "laptop": laptop} {
+		tree, meta := readUpdate(t, conn)
+		if tree["0"] != "1" {
+			t.Errorf("%s: expected the pushed action to increment, got %v", name, tree)
+		}
+		if meta == nil || meta.Action != "increment" {
+			t.Errorf("%s: expected the pushed action in meta, got %+v", name, meta)
+		}
+	}
+
+	_ = other.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+	if _, data, err := other.ReadMessage(); err == nil {
+		t.Errorf("Expected no push for another user, got %s", data)
+	}
+
+	if reached := handler.PushToUser("nobody", "increment", nil); reached != 0 {
+		t.Errorf("Expected no connections for an unknown user, got %d", reached)
+	}
+}
@@ -0,0 +1,136 @@
+package livetemplate
+
+import (
+	"reflect"
+	"strings"
+	"text/template/parse"
+)
+
+// itemLifecycle holds the callbacks registered with WithRangeItemLifecycle
+type itemLifecycle struct {
+	field    string
+	onAdd    func(key string)
+	onRemove func(key string)
+}
+
+// WithRangeItemLifecycle calls onAdd and onRemove with the key of every item an update adds
+// to or removes from the range over field, e.g. to subscribe to the rows a client shows:
+//
+//	WithRangeItemLifecycle("Rows", func(key string) {
+//	    feed.Subscribe(key)
+//	}, func(key string) {
+//	    feed.Unsubscribe(key)
+//	})
+//
+// field is the ranged-over field path as written in the template without the leading dot,
+// e.g. "Rows" for {{range .Rows}} or "table.Rows" for the stores of HandleNamed. Keys are
+// the item keys of the range operations: the key attribute's value, or a content hash for
+// items without one. The first render adds no items, as with WithFieldWatch; neither do
+// changes between a range and its {{else}} branch. Either callback may be nil.
+//
+// The live handler renders a template per connection, so the callbacks run once per
+// connection showing the change. They run synchronously during rendering and must not block.
+func WithRangeItemLifecycle(field string, onAdd, onRemove func(key string)) Option {
+	return func(c *Config) {
+		c.itemLifecycles = append(c.itemLifecycles, itemLifecycle{
+			field:    strings.TrimPrefix(field, "."),
+			onAdd:    onAdd,
+			onRemove: onRemove,
+		})
+	}
+}
+
+// rangeField returns the field path a range iterates over, e.g. "Items" for
+// {{range $i, $item := .Items}}
+func rangeField(pipe *parse.PipeNode) string {
+	if pipe == nil || len(pipe.Cmds) == 0 {
+		return ""
+	}
+	return strings.TrimPrefix(pipe.Cmds[len(pipe.Cmds)-1].String(), ".")
+}
+
+// startRender forgets the range nodes recorded two renders ago; those of the last render
+// stay known, as they make up the tree being diffed against
+func (kg *keyGenerator) startRender() {
+	if !kg.trackRanges {
+		return
+	}
+	kg.lastRangeFields = kg.rangeFields
+	kg.rangeFields = make(map[uintptr]string)
+}
+
+// recordRange remembers the field the range node tree was built from
+func (kg *keyGenerator) recordRange(node *parse.RangeNode, tree treeNode) {
+	if kg == nil || !kg.trackRanges || tree == nil {
+		return
+	}
+	kg.rangeFields[reflect.ValueOf(tree).Pointer()] = rangeField(node.Pipe)
+}
+
+// rangeFieldOf returns the field the range node value was built from, if recorded
+func (kg *keyGenerator) rangeFieldOf(value interface{}) (string, bool) {
+	v := reflect.ValueOf(value)
+	if kg == nil || v.Kind() != reflect.Map {
+		return "", false
+	}
+	ptr := v.Pointer()
+	if field, ok := kg.rangeFields[ptr]; ok {
+		return field, true
+	}
+	field, ok := kg.lastRangeFields[ptr]
+	return field, ok
+}
+
+// fireItemLifecycles calls the WithRangeItemLifecycle callbacks for the items added and
+// removed between two renders of a range
+func (t *Template) fireItemLifecycles(oldValue, newValue interface{}) {
+	if len(t.config.itemLifecycles) == 0 {
+		return
+	}
+	field, ok := t.keyGen.rangeFieldOf(newValue)
+	if !ok {
+		if field, ok = t.keyGen.rangeFieldOf(oldValue); !ok {
+			return
+		}
+	}
+
+	oldKeys := t.rangeItemKeys(oldValue)
+	newKeys := t.rangeItemKeys(newValue)
+	oldSet := make(map[string]bool, len(oldKeys))
+	for _, key := range oldKeys {
+		oldSet[key] = true
+	}
+	newSet := make(map[string]bool, len(newKeys))
+	for _, key := range newKeys {
+		newSet[key] = true
+	}
+
+	for _, lifecycle := range t.config.itemLifecycles {
+		if lifecycle.field != field {
+			continue
+		}
+		for _, key := range oldKeys {
+			if !newSet[key] && lifecycle.onRemove != nil {
+				lifecycle.onRemove(key)
+			}
+		}
+		for _, key := range newKeys {
+			if !oldSet[key] && lifecycle.onAdd != nil {
+				lifecycle.onAdd(key)
+			}
+		}
+	}
+}
+
+// rangeItemKeys returns the item keys of a range node, or nil for any other value
+func (t *Template) rangeItemKeys(value interface{}) []string {
+	if !isRangeConstruct(value) {
+		return nil
+	}
+	node, ok := value.(treeNode)
+	if !ok {
+		node, _ = value.(map[string]interface{})
+	}
+	items, _ := node["d"].([]interface{})
+	return extractItemKeys(items, node["s"], t.hasher())
+}
@@ -0,0 +1,56 @@
+package livetemplate
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestWithRangeItemLifecycle(t *testing.T) {
+	type item struct {
+		ID   string
+		Name string
+	}
+	var added, removed []string
+	tmpl := New("item-lifecycle-test", WithRangeItemLifecycle("Items",
+		func(key string) { added = append(added, key) },
+		func(key string) { removed = append(removed, key) }))
+	if _, err := tmpl.Parse(`<h1>{{.Title}}</h1><ul>{{range .Items}}<li data-key="{{.ID}}">{{.Name}}</li>{{end}}</ul>` +
+		`<ol>{{range .Other}}<li data-key="{{.ID}}">{{.Name}}</li>{{end}}</ol>`); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	render := func(t *testing.T, items, other []item) {
+		t.Helper()
+		added, removed = nil, nil
+		var buf bytes.Buffer
+		data := map[string]interface{}{"Title": "List", "Items": items, "Other": other}
+		if err := tmpl.ExecuteUpdates(&buf, data); err != nil {
+			t.Fatalf("ExecuteUpdates failed: %v", err)
+		}
+	}
+	expect := func(t *testing.T, wantAdded, wantRemoved []string) {
+		t.Helper()
+		if !reflect.DeepEqual(added, wantAdded) || !reflect.DeepEqual(removed, wantRemoved) {
+			t.Errorf("Expected added %v and removed %v, got %v and %v", wantAdded, wantRemoved, added, removed)
+		}
+	}
+
+	a, b, c := item{"a", "Alpha"}, item{"b", "Beta"}, item{"c", "Gamma"}
+
+	render(t, []item{a, b}, []item{a})
+	expect(t, nil, nil) // The first render adds nothing
+
+	render(t, []item{a, b, c}, []item{a})
+	expect(t, []string{"c"}, nil)
+
+	render(t, []item{b, c}, []item{a})
+	expect(t, nil, []string{"a"})
+
+	render(t, []item{c, a}, []item{a})
+	expect(t, []string{"a"}, []string{"b"})
+
+	// Only the configured range reports its items
+	render(t, []item{c, a}, []item{a, b})
+	expect(t, nil, nil)
+}
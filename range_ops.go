@@ -0,0 +1,105 @@
+package livetemplate
+
+// RangeOp is a differential operation on the items of a range in an update tree. Each op
+// marshals to the array form of the wire format, e.g. ["u", key, changes]; see
+// docs/specifications/tree-update-specification.md.
+type RangeOp interface {
+	// Opcode returns the wire opcode: "r", "u", "a", "i" or "o"
+	Opcode() string
+	// MarshalJSON writes the op as a JSON array
+	MarshalJSON() ([]byte, error)
+
+	// withoutStatics returns the op with statics stripped from its items, see stripStaticsRecursively
+	withoutStatics() RangeOp
+}
+
+// RemoveOp removes the item with Key: ["r", key], or ["r", key, {"animate": true}] to let
+// the client run an exit transition first, see WithAnimatedRemovals
+type RemoveOp struct {
+	Key     string
+	Animate bool
+}
+
+// UpdateOp changes dynamics of the item with Key: ["u", key, changes]. Changes left empty
+// by stripping statics are written as ["u", key].
+type UpdateOp struct {
+	Key     string
+	Changes map[string]interface{}
+}
+
+// AppendOp appends Items to the range: ["a", items] or, when the client has no statics
+// for the range yet, ["a", items, statics]
+type AppendOp struct {
+	Items   []interface{}
+	Statics interface{}
+}
+
+// InsertOp inserts Item relative to the item with Target: ["i", target, position, item].
+// Position is "before", "after", "start" or "end"; Target is empty (null on the wire) for
+// "start" and "end".
+type InsertOp struct {
+	Target   string
+	Position string
+	Item     interface{}
+}
+
+// OrderOp reorders the items to Keys: ["o", keys]
+type OrderOp struct {
+	Keys []string
+}
+
+func (RemoveOp) Opcode() string { return "r" }
+func (UpdateOp) Opcode() string { return "u" }
+func (AppendOp) Opcode() string { return "a" }
+func (InsertOp) Opcode() string { return "i" }
+func (OrderOp) Opcode() string  { return "o" }
+
+func (op RemoveOp) MarshalJSON() ([]byte, error) {
+	if op.Animate {
+		return marshalValue([]interface{}{"r", op.Key, map[string]bool{"animate": true}})
+	}
+	return marshalValue([]interface{}{"r", op.Key})
+}
+
+func (op UpdateOp) MarshalJSON() ([]byte, error) {
+	if len(op.Changes) == 0 {
+		return marshalValue([]interface{}{"u", op.Key})
+	}
+	return marshalValue([]interface{}{"u", op.Key, op.Changes})
+}
+
+func (op AppendOp) MarshalJSON() ([]byte, error) {
+	if op.Statics == nil {
+		return marshalValue([]interface{}{"a", op.Items})
+	}
+	return marshalValue([]interface{}{"a", op.Items, op.Statics})
+}
+
+func (op InsertOp) MarshalJSON() ([]byte, error) {
+	var target interface{}
+	if op.Target != "" {
+		target = op.Target
+	}
+	return marshalValue([]interface{}{"i", target, op.Position, op.Item})
+}
+
+func (op OrderOp) MarshalJSON() ([]byte, error) {
+	return marshalValue([]interface{}{"o", op.Keys})
+}
+
+func (op RemoveOp) withoutStatics() RangeOp { return op }
+func (op OrderOp) withoutStatics() RangeOp  { return op }
+
+func (op UpdateOp) withoutStatics() RangeOp {
+	changes, _ := stripStaticsRecursively(op.Changes).(map[string]interface{})
+	return UpdateOp{Key: op.Key, Changes: changes}
+}
+
+func (op AppendOp) withoutStatics() RangeOp {
+	items, _ := stripStaticsRecursively(op.Items).([]interface{})
+	return AppendOp{Items: items, Statics: op.Statics}
+}
+
+func (op InsertOp) withoutStatics() RangeOp {
+	return InsertOp{Target: op.Target, Position: op.Position, Item: stripStaticsRecursively(op.Item)}
+}
@@ -0,0 +1,133 @@
+package livetemplate
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestRangeOp_MarshalJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		op   RangeOp
+		want string
+	}{
+		{
+			name: "remove",
+			op:   RemoveOp{Key: "todo-1"},
+			want: `["r","todo-1"]`,
+		},
+		{
+			name: "animated remove",
+			op:   RemoveOp{Key: "todo-1", Animate: true},
+			want: `["r","todo-1",{"animate":true}]`,
+		},
+		{
+			name: "update",
+			op:   UpdateOp{Key: "todo-2", Changes: map[string]interface{}{"1": "Done"}},
+			want: `["u","todo-2",{"1":"Done"}]`,
+		},
+		{
+			name: "update with changes stripped",
+			op:   UpdateOp{Key: "todo-2"},
+			want: `["u","todo-2"]`,
+		},
+		{
+			name: "append",
+			op:   AppendOp{Items: []interface{}{map[string]interface{}{"0": "a"}}},
+			want: `["a",[{"0":"a"}]]`,
+		},
+		{
+			name: "append with statics",
+			op:   AppendOp{Items: []interface{}{map[string]interface{}{"0": "a"}}, Statics: []string{"<li>", "</li>"}},
+			want: `["a",[{"0":"a"}],["<li>","</li>"]]`,
+		},
+		{
+			name: "insert after",
+			op:   InsertOp{Target: "todo-1", Position: "after", Item: map[string]interface{}{"0": "b"}},
+			want: `["i","todo-1","after",{"0":"b"}]`,
+		},
+		{
+			name: "insert at start",
+			op:   InsertOp{Position: "start", Item: map[string]interface{}{"0": "c"}},
+			want: `["i",null,"start",{"0":"c"}]`,
+		},
+		{
+			name: "order",
+			op:   OrderOp{Keys: []string{"c", "a", "b"}},
+			want: `["o",["c","a","b"]]`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := marshalValue(tt.op)
+			if err != nil {
+				t.Fatalf("Marshal failed: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Marshal() = %s, want %s", got, tt.want)
+			}
+
+			var decoded []interface{}
+			if err := json.Unmarshal(got, &decoded); err != nil {
+				t.Fatalf("Op did not marshal to an array: %v", err)
+			}
+			if decoded[0] != tt.op.Opcode() {
+				t.Errorf("Opcode() = %q, wire opcode %v", tt.op.Opcode(), decoded[0])
+			}
+		})
+	}
+
+	// Ops in an update pass the wire validator
+	update := map[string]interface{}{"0": []interface{}{
+		RemoveOp{Key: "a", Animate: true},
+		UpdateOp{Key: "b", Changes: map[string]interface{}{"0": "B"}},
+		InsertOp{Target: "b", Position: "after", Item: map[string]interface{}{"0": "d"}},
+		OrderOp{Keys: []string{"b", "d"}},
+	}}
+	message, err := json.Marshal(update)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if err := ValidateWireMessage(message); err != nil {
+		t.Errorf("Update with typed ops failed validation: %v\n%s", err, message)
+	}
+}
+
+func TestWithAnimatedRemovals(t *testing.T) {
+	type Item struct{ ID, Name string }
+	type State struct{ Items []Item }
+
+	src := `<ul>{{range .Items}}<li data-key="{{.ID}}">{{.Name}}</li>{{end}}</ul>`
+	before := State{Items: []Item{{"a", "Apple"}, {"b", "Banana"}, {"c", "Cherry"}}}
+	after := State{Items: []Item{{"a", "Apple"}, {"c", "Cherry"}}}
+
+	for _, tt := range []struct {
+		name    string
+		options []Option
+		want    string
+	}{
+		{name: "disabled", want: `["r","b"]`},
+		{name: "enabled", options: []Option{WithAnimatedRemovals(true)}, want: `["r","b",{"animate":true}]`},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl := New("animated-removals-test", tt.options...)
+			if _, err := tmpl.Parse(src); err != nil {
+				t.Fatalf("Parse failed: %v", err)
+			}
+			var buf bytes.Buffer
+			if err := tmpl.ExecuteUpdates(&buf, before); err != nil {
+				t.Fatalf("Initial ExecuteUpdates failed: %v", err)
+			}
+			buf.Reset()
+			if err := tmpl.ExecuteUpdates(&buf, after); err != nil {
+				t.Fatalf("ExecuteUpdates failed: %v", err)
+			}
+			if !strings.Contains(buf.String(), tt.want) {
+				t.Errorf("Expected removal %s in update, got %s", tt.want, buf.String())
+			}
+		})
+	}
+}
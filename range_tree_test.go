@@ -3,6 +3,7 @@ package livetemplate
 import (
 	"bytes"
 	"encoding/json"
+	"strings"
 	"testing"
 )
 
@@ -156,3 +157,139 @@ func TestRangeTreeGeneration(t *testing.T) {
 		t.Log("Note: No insert operation found for second item (might be another operation type)")
 	}
 }
+
+// TestRangeTreeGeneration_MapKeyed verifies that ranging over a map renders keys in
+// sorted order and diffs items by map key, so changing one value yields one "u" op
+func TestRangeTreeGeneration_MapKeyed(t *testing.T) {
+	type State struct {
+		Scores map[string]int
+	}
+
+	tmpl := New("test")
+	if _, err := tmpl.Parse(`<ul>{{range $name, $score := .Scores}}<li>{{$name}}: {{$score}}</li>{{end}}</ul>`); err != nil {
+		t.Fatalf("Failed to parse template: %v", err)
+	}
+
+	scores := map[string]int{"carol": 3, "alice": 1, "dave": 4, "bob": 2}
+
+	var buf bytes.Buffer
+	if err := tmpl.ExecuteUpdates(&buf, &State{Scores: scores}); err != nil {
+		t.Fatalf("Failed initial ExecuteUpdates: %v", err)
+	}
+
+	var initialTree map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &initialTree); err != nil {
+		t.Fatalf("Failed to parse initial tree JSON: %v", err)
+	}
+
+	rangeNode, ok := initialTree["0"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("Expected range at key 0, got %s", buf.String())
+	}
+	items, _ := rangeNode["d"].([]interface{})
+	var names []string
+	for _, item := range items {
+		names = append(names, item.(map[string]interface{})["0"].(string))
+	}
+	if got, want := strings.Join(names, ","), "alice,bob,carol,dave"; got != want {
+		t.Errorf("Expected items in sorted key order %q, got %q", want, got)
+	}
+
+	// Re-render the same map several times: iteration order must not produce changes
+	for i := 0; i < 5; i++ {
+		buf.Reset()
+		if err := tmpl.ExecuteUpdates(&buf, &State{Scores: scores}); err != nil {
+			t.Fatalf("Failed ExecuteUpdates: %v", err)
+		}
+		if got := buf.String(); got != "{}" {
+			t.Fatalf("Expected no changes for unchanged map, got %s", got)
+		}
+	}
+
+	updated := map[string]int{"carol": 30, "alice": 1, "dave": 4, "bob": 2}
+	buf.Reset()
+	if err := tmpl.ExecuteUpdates(&buf, &State{Scores: updated}); err != nil {
+		t.Fatalf("Failed update ExecuteUpdates: %v", err)
+	}
+
+	var changes map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &changes); err != nil {
+		t.Fatalf("Failed to parse update JSON: %v", err)
+	}
+
+	var ops []interface{}
+	for _, v := range changes {
+		if opList, ok := v.([]interface{}); ok {
+			ops = opList
+		}
+	}
+	if len(ops) != 1 {
+		t.Fatalf("Expected exactly 1 range operation, got %s", buf.String())
+	}
+
+	op := ops[0].([]interface{})
+	if op[0] != "u" || op[1] != "carol" {
+		t.Errorf("Expected [\"u\", \"carol\", ...], got %v", op)
+	}
+	if !strings.Contains(buf.String(), "30") {
+		t.Errorf("Expected update to carry the new value, got %s", buf.String())
+	}
+}
+
+func TestRangeTreeGeneration_KeyAttributes(t *testing.T) {
+	type Row struct {
+		ID   string
+		Name string
+	}
+	type State struct {
+		Rows []Row
+	}
+
+	// data-row-id isn't a built-in key attribute; the class would otherwise be picked up
+	templateStr := `<table>{{range .Rows}}<tr class="row-{{.Name}}" data-row-id="{{.ID}}"><td>{{.Name}}</td></tr>{{end}}</table>`
+	rows := []Row{{"r1", "alpha"}, {"r2", "beta"}, {"r3", "gamma"}}
+
+	tmpl := New("test", WithKeyAttributes([]string{"data-row-id"}))
+	if _, err := tmpl.Parse(templateStr); err != nil {
+		t.Fatalf("Failed to parse template: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.ExecuteUpdates(&buf, &State{Rows: rows}); err != nil {
+		t.Fatalf("Failed initial ExecuteUpdates: %v", err)
+	}
+	if !strings.Contains(buf.String(), `"_k":"r1"`) {
+		t.Errorf("Expected items keyed by data-row-id, got %s", buf.String())
+	}
+
+	// Rename the middle row: the update must target it by its row ID
+	renamed := []Row{{"r1", "alpha"}, {"r2", "delta"}, {"r3", "gamma"}}
+	buf.Reset()
+	if err := tmpl.ExecuteUpdates(&buf, &State{Rows: renamed}); err != nil {
+		t.Fatalf("Failed update ExecuteUpdates: %v", err)
+	}
+
+	var changes map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &changes); err != nil {
+		t.Fatalf("Failed to parse update JSON: %v", err)
+	}
+	ops, _ := changes["0"].([]interface{})
+	if len(ops) != 1 {
+		t.Fatalf("Expected exactly 1 range operation, got %s", buf.String())
+	}
+	if op := ops[0].([]interface{}); op[0] != "u" || op[1] != "r2" {
+		t.Errorf("Expected [\"u\", \"r2\", ...], got %v", op)
+	}
+
+	// Removing a row targets its ID too
+	buf.Reset()
+	if err := tmpl.ExecuteUpdates(&buf, &State{Rows: []Row{{"r1", "alpha"}, {"r3", "gamma"}}}); err != nil {
+		t.Fatalf("Failed remove ExecuteUpdates: %v", err)
+	}
+	if !strings.Contains(buf.String(), `["r","r2"]`) {
+		t.Errorf("Expected remove operation for r2, got %s", buf.String())
+	}
+	if err := ValidateWireMessage(buf.Bytes()); err != nil {
+		t.Errorf("Update is not a valid wire message: %v", err)
+	}
+}
@@ -0,0 +1,191 @@
+package livetemplate
+
+import (
+	"sort"
+	"strings"
+	"text/template/parse"
+)
+
+// unknownDot marks a dot whose path can't be determined statically, e.g. inside
+// {{range lvt_ordered .Map}}. Fields read from it are not reported.
+const unknownDot = "\x00"
+
+// ReferencedFields returns the sorted field paths the template reads from its data, e.g.
+// [".Items", ".Items.Title", ".User", ".User.Name"]. Fields read inside {{range}} and
+// {{with}} are reported below the path of the collection or value, and the lvt namespace
+// is left out.
+//
+// Comparing the top-level paths to a store's fields in a test catches state no template
+// renders as well as fields the template expects but the store never sets.
+//
+// Named templates are resolved as in tree generation. Returns nil before Parse.
+func (t *Template) ReferencedFields() []string {
+	if t.tmpl == nil || t.tmpl.Tree == nil || t.tmpl.Tree.Root == nil {
+		return nil
+	}
+
+	root := t.tmpl.Tree.Root
+	if hasTemplateComposition(t.tmpl) {
+		flattened, err := flattenTemplateWithDepth(t.tmpl, t.maxTemplateDepth())
+		if err != nil {
+			return nil
+		}
+		tree := parse.New("referenced-fields")
+		tree.Mode = parse.SkipFuncCheck
+		if _, err := tree.Parse(flattened, "", "", map[string]*parse.Tree{}); err != nil {
+			return nil
+		}
+		root = tree.Root
+	}
+
+	c := &fieldCollector{fields: make(map[string]bool), vars: make(map[string]string)}
+	c.walk(root, "")
+
+	fields := make([]string, 0, len(c.fields))
+	for field := range c.fields {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// fieldCollector gathers the field paths read by a template AST. Paths are relative to the
+// template's data and written without the leading dot while collecting.
+type fieldCollector struct {
+	fields map[string]bool
+	vars   map[string]string // Path of the value each declared variable holds
+}
+
+// walk collects the fields read by node with dot at path dot
+func (c *fieldCollector) walk(node parse.Node, dot string) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			c.walk(child, dot)
+		}
+
+	case *parse.ActionNode:
+		c.pipe(n.Pipe, dot)
+		c.declare(n.Pipe, dot, false)
+
+	case *parse.IfNode:
+		c.pipe(n.Pipe, dot)
+		c.walk(n.List, dot)
+		c.walk(n.ElseList, dot)
+
+	case *parse.RangeNode:
+		c.pipe(n.Pipe, dot)
+		c.declare(n.Pipe, dot, true)
+		c.walk(n.List, c.pipePath(n.Pipe, dot))
+		c.walk(n.ElseList, dot)
+
+	case *parse.WithNode:
+		c.pipe(n.Pipe, dot)
+		c.declare(n.Pipe, dot, false)
+		c.walk(n.List, c.pipePath(n.Pipe, dot))
+		c.walk(n.ElseList, dot)
+
+	case *parse.TemplateNode:
+		c.pipe(n.Pipe, dot)
+	}
+}
+
+// declare records the paths of the variables declared by pipe. For a range, the last
+// variable holds an element of the collection.
+func (c *fieldCollector) declare(pipe *parse.PipeNode, dot string, isRange bool) {
+	if pipe == nil || len(pipe.Decl) == 0 {
+		return
+	}
+	path := c.pipePath(pipe, dot)
+	for i, v := range pipe.Decl {
+		if isRange && i < len(pipe.Decl)-1 {
+			c.vars[v.Ident[0]] = unknownDot // Index or key
+			continue
+		}
+		c.vars[v.Ident[0]] = path
+	}
+}
+
+// pipe collects the fields read by the commands of pipe
+func (c *fieldCollector) pipe(pipe *parse.PipeNode, dot string) {
+	if pipe == nil {
+		return
+	}
+	for _, cmd := range pipe.Cmds {
+		for _, arg := range cmd.Args {
+			c.arg(arg, dot)
+		}
+	}
+}
+
+// arg collects the fields read by a command argument
+func (c *fieldCollector) arg(node parse.Node, dot string) {
+	switch n := node.(type) {
+	case *parse.FieldNode:
+		c.add(dot, n.Ident)
+	case *parse.VariableNode:
+		if base, ok := c.variablePath(n.Ident[0]); ok && len(n.Ident) > 1 {
+			c.add(base, n.Ident[1:])
+		}
+	case *parse.ChainNode:
+		c.arg(n.Node, dot)
+	case *parse.PipeNode:
+		c.pipe(n, dot)
+	}
+}
+
+// pipePath returns the path of the value pipe evaluates to, or unknownDot if it is
+// anything but a single field, variable or dot
+func (c *fieldCollector) pipePath(pipe *parse.PipeNode, dot string) string {
+	if pipe == nil || len(pipe.Cmds) != 1 || len(pipe.Cmds[0].Args) != 1 {
+		return unknownDot
+	}
+	switch n := pipe.Cmds[0].Args[0].(type) {
+	case *parse.FieldNode:
+		return joinFieldPath(dot, n.Ident)
+	case *parse.VariableNode:
+		if base, ok := c.variablePath(n.Ident[0]); ok {
+			return joinFieldPath(base, n.Ident[1:])
+		}
+	case *parse.DotNode:
+		return dot
+	}
+	return unknownDot
+}
+
+// variablePath returns the path held by a variable; $ is the template's data
+func (c *fieldCollector) variablePath(name string) (string, bool) {
+	if name == "$" {
+		return "", true
+	}
+	path, ok := c.vars[name]
+	return path, ok
+}
+
+// add records the field path idents below dot, skipping the lvt namespace
+func (c *fieldCollector) add(dot string, idents []string) {
+	if dot == unknownDot || len(idents) == 0 || (dot == "" && idents[0] == "lvt") {
+		return
+	}
+	// Record each prefix, as reading .User.Name reads .User too
+	for i := range idents {
+		c.fields["."+joinFieldPath(dot, idents[:i+1])] = true
+	}
+}
+
+// joinFieldPath appends idents to path
+func joinFieldPath(path string, idents []string) string {
+	if path == unknownDot {
+		return unknownDot
+	}
+	if len(idents) == 0 {
+		return path
+	}
+	if path == "" {
+		return strings.Join(idents, ".")
+	}
+	return path + "." + strings.Join(idents, ".")
+}
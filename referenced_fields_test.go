@@ -0,0 +1,52 @@
+package livetemplate
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTemplate_ReferencedFields(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		want     []string
+	}{
+		{
+			name:     "fields and nested paths",
+			template: `<h1>{{.Title}}</h1><p>{{.User.Name}}</p>{{if .lvt.HasError "title"}}{{.lvt.Error "title"}}{{end}}`,
+			want:     []string{".Title", ".User", ".User.Name"},
+		},
+		{
+			name:     "range and with",
+			template: `{{range .Items}}<li>{{.Name}} by {{$.Owner}}</li>{{else}}{{.Empty}}{{end}}{{with .Settings}}{{.Theme}}{{end}}`,
+			want:     []string{".Empty", ".Items", ".Items.Name", ".Owner", ".Settings", ".Settings.Theme"},
+		},
+		{
+			name:     "variables and functions",
+			template: `{{range $i, $todo := .Todos}}{{$i}}:{{$todo.Text}}{{end}}{{$count := len .Todos}}{{if gt $count .Limit}}full{{end}}`,
+			want:     []string{".Limit", ".Todos", ".Todos.Text"},
+		},
+		{
+			name:     "named templates",
+			template: `{{define "row"}}<tr><td>{{.ID}}</td></tr>{{end}}<table>{{range .Rows}}{{template "row" .}}{{end}}</table>`,
+			want:     []string{".Rows", ".Rows.ID"},
+		},
+		{
+			name:     "unresolvable range",
+			template: `{{range lvt_ordered .Profile}}{{.Key}}{{end}}`,
+			want:     []string{".Profile"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl := New("referenced-fields-test")
+			if _, err := tmpl.Parse(tt.template); err != nil {
+				t.Fatalf("Parse failed: %v", err)
+			}
+			if got := tmpl.ReferencedFields(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ReferencedFields() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,7 +1,9 @@
 package livetemplate
 
 import (
+	"sort"
 	"sync"
+	"time"
 
 	"github.com/gorilla/websocket"
 )
@@ -19,15 +21,68 @@ type Connection struct {
 	UserID   string          // User identity ("" for anonymous)
 	Template *Template       // Per-connection template for tree diffing
 	Stores   Stores          // Reference to shared stores from session group
-	mu       sync.Mutex      // Protects writes to Conn
+	events   *sseWriter      // Server-sent event stream (nil for WebSocket connections)
+	token    string          // Resume token sent to the client, see handleDebugState
+	queue    *outboundQueue  // Messages waiting for the writer goroutine (nil = Send writes), see WithOutboundQueue
+	pushes   chan message    // Actions pushed by the server, see PushToUser (nil for SSE connections)
+	mu       sync.Mutex      // Protects writes to Conn and the traffic counters
+
+	connectedAt  time.Time // Set by ConnectionRegistry.Register
+	lastActivity time.Time // Last message read or written, see WithIdleTimeout
+	bytesSent    int64     // Total payload bytes written
+	updatesSent  int64     // Total messages written
+}
+
+// ConnStat is a snapshot of the traffic sent to a single connection
+type ConnStat struct {
+	GroupID      string
+	UserID       string
+	ConnectedAt  time.Time
+	LastActivity time.Time // Last message read from or written to the connection
+	BytesSent    int64     // Payload bytes, excluding WebSocket and SSE framing
+	UpdatesSent  int64
 }
 
 // Send sends a message to this connection.
+// On server-sent event connections, data is written as a single event and messageType is ignored.
+// With WithOutboundQueue, data is queued and Send returns without waiting for the write.
 // Thread-safe: multiple goroutines can call Send concurrently.
 func (c *Connection) Send(messageType int, data []byte) error {
+	if c.queue != nil {
+		return c.enqueue(messageType, data)
+	}
+
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	return c.Conn.WriteMessage(messageType, data)
+
+	var err error
+	if c.events != nil {
+		err = c.events.writeEvent(data)
+	} else {
+		err = c.Conn.WriteMessage(messageType, data)
+	}
+	if err != nil {
+		return err
+	}
+
+	c.bytesSent += int64(len(data))
+	c.updatesSent++
+	c.lastActivity = time.Now()
+	return nil
+}
+
+// stat returns a snapshot of the connection's traffic counters
+func (c *Connection) stat() ConnStat {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return ConnStat{
+		GroupID:      c.GroupID,
+		UserID:       c.UserID,
+		ConnectedAt:  c.connectedAt,
+		LastActivity: c.lastActivity,
+		BytesSent:    c.bytesSent,
+		UpdatesSent:  c.updatesSent,
+	}
 }
 
 // ConnectionRegistry tracks all active WebSocket connections with dual indexing.
@@ -63,6 +118,32 @@ func NewConnectionRegistry() *ConnectionRegistry {
 func (r *ConnectionRegistry) Register(conn *Connection) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
+	r.register(conn)
+}
+
+// TryRegister adds a connection to the registry unless its session group already has
+// limit connections, and reports whether it was added. The check and the insert happen
+// under one lock, so concurrent connections cannot exceed the limit. A limit of 0 or less
+// means no limit.
+func (r *ConnectionRegistry) TryRegister(conn *Connection, limit int) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if limit > 0 && len(r.byGroup[conn.GroupID]) >= limit {
+		return false
+	}
+	r.register(conn)
+	return true
+}
+
+// register indexes a connection; the caller holds r.mu
+func (r *ConnectionRegistry) register(conn *Connection) {
+	if conn.connectedAt.IsZero() {
+		conn.connectedAt = time.Now()
+	}
+	if conn.lastActivity.IsZero() {
+		conn.lastActivity = conn.connectedAt
+	}
 
 	// Add to byGroup index
 	r.byGroup[conn.GroupID] = append(r.byGroup[conn.GroupID], conn)
@@ -209,6 +290,20 @@ func (r *ConnectionRegistry) GetAll() []*Connection {
 	return result
 }
 
+// Stats returns traffic counters for all active connections, oldest connection first.
+func (r *ConnectionRegistry) Stats() []ConnStat {
+	conns := r.GetAll()
+
+	stats := make([]ConnStat, 0, len(conns))
+	for _, conn := range conns {
+		stats = append(stats, conn.stat())
+	}
+	sort.SliceStable(stats, func(i, j int) bool {
+		return stats[i].ConnectedAt.Before(stats[j].ConnectedAt)
+	})
+	return stats
+}
+
 // Count returns the total number of active connections.
 func (r *ConnectionRegistry) Count() int {
 	r.mu.RLock()
@@ -221,6 +316,13 @@ func (r *ConnectionRegistry) Count() int {
 	return count
 }
 
+// GroupSize returns the number of connections in a session group.
+func (r *ConnectionRegistry) GroupSize(groupID string) int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return len(r.byGroup[groupID])
+}
+
 // GroupCount returns the number of session groups.
 func (r *ConnectionRegistry) GroupCount() int {
 	r.mu.RLock()
@@ -266,6 +266,44 @@ func TestConnectionRegistry_UserCount(t *testing.T) {
 	}
 }
 
+// TestConnectionRegistry_TryRegister tests that concurrent registrations respect the group limit
+func TestConnectionRegistry_TryRegister(t *testing.T) {
+	const limit = 3
+	registry := NewConnectionRegistry()
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	accepted := 0
+	start := make(chan struct{})
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			if registry.TryRegister(&Connection{GroupID: "group-1", UserID: "alice"}, limit) {
+				mu.Lock()
+				accepted++
+				mu.Unlock()
+			}
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	if accepted != limit {
+		t.Errorf("TryRegister accepted %d connections, want %d", accepted, limit)
+	}
+	if size := registry.GroupSize("group-1"); size != limit {
+		t.Errorf("GroupSize() = %d, want %d", size, limit)
+	}
+	if !registry.TryRegister(&Connection{GroupID: "group-2", UserID: "alice"}, limit) {
+		t.Error("TryRegister rejected a connection in another group")
+	}
+	if !registry.TryRegister(&Connection{GroupID: "group-1", UserID: "alice"}, 0) {
+		t.Error("TryRegister rejected a connection without a limit")
+	}
+}
+
 // TestConnectionRegistry_ConcurrentAccess tests thread-safety
 func TestConnectionRegistry_ConcurrentAccess(t *testing.T) {
 	registry := NewConnectionRegistry()
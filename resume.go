@@ -0,0 +1,96 @@
+package livetemplate
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// defaultResumeWindow is used when WithResumeWindow isn't set
+const defaultResumeWindow = 2 * time.Minute
+
+// resumeHandshakeTimeout bounds how long a reconnecting client has to send its resume message
+const resumeHandshakeTimeout = 5 * time.Second
+
+// resumeMessage is the first message of a client reconnecting with ?lvt-resume:
+//
+//	{"type": "resume", "token": "...", "fingerprint": "...", "statics_version": "..."}
+//
+// Token and fingerprint are the last values the client received in ResponseMetadata, the
+// statics version is the page's data-lvt-sv (see Template.StaticsVersion).
+type resumeMessage struct {
+	Type           string `json:"type"`
+	Token          string `json:"token"`
+	Fingerprint    string `json:"fingerprint"`
+	StaticsVersion string `json:"statics_version,omitempty"` // Omitted by older clients
+}
+
+// parseResumeMessage reports whether data is a resume message
+func parseResumeMessage(data []byte) (resumeMessage, bool) {
+	var msg resumeMessage
+	if err := json.Unmarshal(data, &msg); err != nil || msg.Type != "resume" {
+		return resumeMessage{}, false
+	}
+	return msg, true
+}
+
+// resumeEntry is the diff baseline left behind by a disconnected connection
+type resumeEntry struct {
+	template *Template // Connection template; its last tree is what the client last received
+	groupID  string    // Session group the baseline belongs to
+	expires  time.Time
+}
+
+// resumeCache keeps the baselines of recently disconnected connections by resume token,
+// so a client that reconnects within the window only receives what changed meanwhile.
+//
+// Thread-safe: safe for concurrent access from multiple goroutines.
+type resumeCache struct {
+	mu      sync.Mutex
+	entries map[string]resumeEntry
+}
+
+// newResumeCache creates an empty resume cache
+func newResumeCache() *resumeCache {
+	return &resumeCache{
+		entries: make(map[string]resumeEntry),
+	}
+}
+
+// store keeps tmpl as the baseline for token until window elapses
+func (c *resumeCache) store(token string, tmpl *Template, groupID string, window time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	// Drop expired entries so abandoned baselines don't accumulate
+	for t, entry := range c.entries {
+		if now.After(entry.expires) {
+			delete(c.entries, t)
+		}
+	}
+
+	c.entries[token] = resumeEntry{
+		template: tmpl,
+		groupID:  groupID,
+		expires:  now.Add(window),
+	}
+}
+
+// take removes and returns the baseline for token.
+// Returns nil if the token is unknown, expired, or belongs to another session group.
+func (c *resumeCache) take(token, groupID string) *Template {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[token]
+	if !ok {
+		return nil
+	}
+	delete(c.entries, token)
+
+	if time.Now().After(entry.expires) || entry.groupID != groupID {
+		return nil
+	}
+	return entry.template
+}
@@ -0,0 +1,245 @@
+package livetemplate
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// dialResumeTest opens a WebSocket to server in the given session group
+func dialResumeTest(t *testing.T, server *httptest.Server, query, groupID string) *websocket.Conn {
+	t.Helper()
+
+	header := http.Header{}
+	header.Add("Cookie", (&http.Cookie{Name: "livetemplate-id", Value: groupID}).String())
+
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + query
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL, header)
+	if err != nil {
+		t.Fatalf("WebSocket dial failed: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return conn
+}
+
+// readUpdate reads the next response and returns its tree as a map
+func readUpdate(t *testing.T, conn *websocket.Conn) (map[string]interface{}, *ResponseMetadata) {
+	t.Helper()
+
+	if err := conn.SetReadDeadline(time.Now().Add(2 * time.Second)); err != nil {
+		t.Fatalf("Failed to set read deadline: %v", err)
+	}
+
+	var response UpdateResponse
+	if err := conn.ReadJSON(&response); err != nil {
+		t.Fatalf("Failed to read update: %v", err)
+	}
+	tree, _ := response.Tree.(map[string]interface{})
+	return tree, response.Meta
+}
+
+// waitForResumeEntry waits until the handler keeps a baseline for token
+func waitForResumeEntry(t *testing.T, h *liveHandler, token string) {
+	t.Helper()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		h.resume.mu.Lock()
+		_, ok := h.resume.entries[token]
+		h.resume.mu.Unlock()
+		if ok {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("Baseline for resume token was never stored")
+}
+
+func TestLiveHandler_Resume(t *testing.T) {
+	tmpl := New("resume-test")
+	if _, err := tmpl.Parse("<div><h1>Counter</h1><p>Count: {{.Count}}</p></div>"); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	handler := tmpl.Handle(&SlowState{}).(*liveHandler)
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	// disconnect connects, runs one action and drops the connection,
+	// returning the last resume token and fingerprint
+	disconnect := func(t *testing.T, groupID string) (string, string) {
+		conn := dialResumeTest(t, server, "", groupID)
+
+		initial, meta := readUpdate(t, conn)
+		if _, ok := initial["s"]; !ok {
+			t.Fatalf("Initial tree should include statics, got %v", initial)
+		}
+		if meta == nil || meta.ResumeToken == "" {
+			t.Fatalf("Initial response should include a resume token, got %+v", meta)
+		}
+		token := meta.ResumeToken
+
+		response := sendAction(t, conn, "increment", nil)
+		if response.Meta == nil || response.Meta.Fingerprint == "" {
+			t.Fatalf("Update should include a fingerprint, got %+v", response.Meta)
+		}
+
+		conn.Close()
+		waitForResumeEntry(t, handler, token)
+		return token, response.Meta.Fingerprint
+	}
+
+	t.Run("resume sends only changes", func(t *testing.T) {
+		token, fingerprint := disconnect(t, "group-changes")
+
+		// Another tab changes state while this one is disconnected
+		other := dialResumeTest(t, server, "", "group-changes")
+		readUpdate(t, other)
+		sendAction(t, other, "increment", nil)
+
+		conn := dialResumeTest(t, server, "?lvt-resume", "group-changes")
+		if err := conn.WriteJSON(map[string]string{"type": "resume", "token": token, "fingerprint": fingerprint}); err != nil {
+			t.Fatalf("Failed to send resume message: %v", err)
+		}
+
+		tree, meta := readUpdate(t, conn)
+		if _, ok := tree["s"]; ok {
+			t.Errorf("Resumed connection should not resend statics, got %v", tree)
+		}
+		if len(tree) != 1 || tree["0"] != "2" {
+			t.Errorf("Expected only the changed count, got %v", tree)
+		}
+		if meta == nil || meta.ResumeToken != token {
+			t.Errorf("Expected resume token to carry over, got %+v", meta)
+		}
+	})
+
+	t.Run("stale fingerprint gets full tree", func(t *testing.T) {
+		token, _ := disconnect(t, "group-stale")
+
+		conn := dialResumeTest(t, server, "?lvt-resume", "group-stale")
+		if err := conn.WriteJSON(map[string]string{"type": "resume", "token": token, "fingerprint": "0000000000000000"}); err != nil {
+			t.Fatalf("Failed to send resume message: %v", err)
+		}
+
+		tree, _ := readUpdate(t, conn)
+		if _, ok := tree["s"]; !ok {
+			t.Errorf("Expected full tree with statics, got %v", tree)
+		}
+	})
+
+	t.Run("stale statics version gets full tree", func(t *testing.T) {
+		token, fingerprint := disconnect(t, "group-statics")
+
+		// The client still holds statics of a previous deploy of the template
+		old := New("resume-test")
+		if _, err := old.Parse("<div><h1>Old counter</h1><p>Count: {{.Count}}</p></div>"); err != nil {
+			t.Fatalf("Parse failed: %v", err)
+		}
+
+		conn := dialResumeTest(t, server, "?lvt-resume", "group-statics")
+		if err := conn.WriteJSON(map[string]string{
+			"type":            "resume",
+			"token":           token,
+			"fingerprint":     fingerprint,
+			"statics_version": old.StaticsVersion(),
+		}); err != nil {
+			t.Fatalf("Failed to send resume message: %v", err)
+		}
+
+		tree, meta := readUpdate(t, conn)
+		if _, ok := tree["s"]; !ok {
+			t.Errorf("Expected full tree with statics, got %v", tree)
+		}
+		if meta == nil || meta.StaticsVersion != tmpl.StaticsVersion() {
+			t.Errorf("Expected current statics version %q, got %+v", tmpl.StaticsVersion(), meta)
+		}
+	})
+
+	t.Run("current statics version resumes", func(t *testing.T) {
+		token, fingerprint := disconnect(t, "group-statics-current")
+
+		conn := dialResumeTest(t, server, "?lvt-resume", "group-statics-current")
+		if err := conn.WriteJSON(map[string]string{
+			"type":            "resume",
+			"token":           token,
+			"fingerprint":     fingerprint,
+			"statics_version": tmpl.StaticsVersion(),
+		}); err != nil {
+			t.Fatalf("Failed to send resume message: %v", err)
+		}
+
+		tree, _ := readUpdate(t, conn)
+		if _, ok := tree["s"]; ok {
+			t.Errorf("Resumed connection should not resend statics, got %v", tree)
+		}
+	})
+
+	t.Run("other session group cannot resume", func(t *testing.T) {
+		token, fingerprint := disconnect(t, "group-owner")
+
+		conn := dialResumeTest(t, server, "?lvt-resume", "group-intruder")
+		if err := conn.WriteJSON(map[string]string{"type": "resume", "token": token, "fingerprint": fingerprint}); err != nil {
+			t.Fatalf("Failed to send resume message: %v", err)
+		}
+
+		tree, meta := readUpdate(t, conn)
+		if _, ok := tree["s"]; !ok {
+			t.Errorf("Expected full tree with statics, got %v", tree)
+		}
+		if meta != nil && meta.ResumeToken == token {
+			t.Errorf("Resume token should not be reused across session groups")
+		}
+	})
+
+	t.Run("action instead of resume message", func(t *testing.T) {
+		conn := dialResumeTest(t, server, "?lvt-resume", "group-action")
+		if err := conn.WriteJSON(map[string]interface{}{"action": "increment"}); err != nil {
+			t.Fatalf("Failed to send action: %v", err)
+		}
+
+		tree, _ := readUpdate(t, conn)
+		if _, ok := tree["s"]; !ok {
+			t.Errorf("Expected full tree with statics, got %v", tree)
+		}
+		_, meta := readUpdate(t, conn)
+		if meta == nil || meta.Action != "increment" {
+			t.Errorf("Expected the first message to be handled as an action, got %+v", meta)
+		}
+	})
+}
+
+func TestTemplate_StaticsVersion(t *testing.T) {
+	parse := func(src string) *Template {
+		tmpl := New("statics-version")
+		if _, err := tmpl.Parse(src); err != nil {
+			t.Fatalf("Parse failed: %v", err)
+		}
+		return tmpl
+	}
+
+	a := parse("<div><p>Count: {{.Count}}</p></div>")
+	b := parse("<div><p>Total: {{.Count}}</p></div>")
+
+	if a.StaticsVersion() == "" {
+		t.Fatalf("Expected a statics version after Parse")
+	}
+	if a.StaticsVersion() == b.StaticsVersion() {
+		t.Errorf("Changed template should have a different statics version, both are %q", a.StaticsVersion())
+	}
+	if again := parse("<div><p>Count: {{.Count}}</p></div>"); again.StaticsVersion() != a.StaticsVersion() {
+		t.Errorf("Same template should keep its statics version, got %q and %q", a.StaticsVersion(), again.StaticsVersion())
+	}
+
+	html, err := a.RenderString(struct{ Count int }{1})
+	if err != nil {
+		t.Fatalf("RenderString failed: %v", err)
+	}
+	if want := `data-lvt-sv="` + a.StaticsVersion() + `"`; !strings.Contains(html, want) {
+		t.Errorf("Expected wrapper to carry %s, got %s", want, html)
+	}
+}
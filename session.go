@@ -15,6 +15,8 @@ import (
 // For authenticated users: groupID is typically the userID (each user has isolated state).
 //
 // Thread-safety: All implementations must be safe for concurrent access from multiple goroutines.
+//
+// Persistent implementations serialize groups with SnapshotStores and RestoreStores.
 type SessionStore interface {
 	// Get retrieves the Stores for a session group.
 	// Returns nil if the group doesn't exist.
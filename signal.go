@@ -0,0 +1,68 @@
+package livetemplate
+
+import (
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// signalMessage is the wire form of an ephemeral signal (see ActionContext.Signal). It is
+// sent in place of an UpdateResponse and never touches the tree.
+type signalMessage struct {
+	Signal signalEvent `json:"signal"`
+}
+
+type signalEvent struct {
+	Name    string      `json:"name"`
+	Payload interface{} `json:"payload,omitempty"`
+	TTL     int64       `json:"ttl"` // Milliseconds until the client drops the signal
+}
+
+// signaler returns the ActionContext.Signal implementation for actions on state, sending
+// signals to every other connection in the group. A signal repeated within half its TTL is
+// dropped, so a continuously repeated signal (a keystroke per action) reaches the other
+// connections often enough to stay alive without one message per call.
+func (h *liveHandler) signaler(state *connState) func(name string, payload interface{}, ttl time.Duration) {
+	return func(name string, payload interface{}, ttl time.Duration) {
+		if !state.throttleSignal(name, ttl) {
+			return
+		}
+
+		data, err := json.Marshal(signalMessage{Signal: signalEvent{
+			Name:    name,
+			Payload: payload,
+			TTL:     ttl.Milliseconds(),
+		}})
+		if err != nil {
+			log.Printf("Signal %q: failed to marshal payload: %v", name, err)
+			return
+		}
+
+		for _, conn := range h.registry.GetByGroupExcept(state.groupID, state.conn) {
+			if conn.Conn == nil && conn.events == nil {
+				continue // Test connection without transport
+			}
+			if err := conn.Send(websocket.TextMessage, data); err != nil {
+				log.Printf("Signal %q: failed to send to connection in group %s: %v", name, state.groupID, err)
+			}
+		}
+	}
+}
+
+// throttleSignal reports whether a signal may be sent now, recording it if so
+func (c *connState) throttleSignal(name string, ttl time.Duration) bool {
+	c.signalsMu.Lock()
+	defer c.signalsMu.Unlock()
+
+	now := time.Now()
+	if last, ok := c.lastSignal[name]; ok && now.Sub(last) < ttl/2 {
+		return false
+	}
+	if c.lastSignal == nil {
+		c.lastSignal = make(map[string]time.Time)
+	}
+	c.lastSignal[name] = now
+	return true
+}
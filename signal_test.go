@@ -0,0 +1,114 @@
+package livetemplate
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// TypingState is a test store whose "type" action signals that a user is typing
+type TypingState struct {
+	Messages []string
+}
+
+func (s *TypingState) Change(ctx *ActionContext) error {
+	switch ctx.Action {
+	case "type":
+		ctx.Signal("typing", map[string]string{"user": ctx.GetString("user")}, 3*time.Second)
+	case "send":
+		s.Messages = append(s.Messages, ctx.GetString("text"))
+	}
+	return nil
+}
+
+func TestActionContext_Signal(t *testing.T) {
+	tmpl := New("signal-test")
+	if _, err := tmpl.Parse(`<ul>{{range .Messages}}<li>{{.}}</li>{{end}}</ul>`); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	store := &TypingState{}
+	handler := tmpl.Handle(store)
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	// Both tabs share the session group through the browser cookie
+	dial := func() *websocket.Conn {
+		header := http.Header{}
+		header.Set("Cookie", "livetemplate-id=signal-group")
+		conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), header)
+		if err != nil {
+			t.Fatalf("WebSocket dial failed: %v", err)
+		}
+		var initial UpdateResponse
+		if err := conn.ReadJSON(&initial); err != nil {
+			t.Fatalf("Failed to read initial tree: %v", err)
+		}
+		return conn
+	}
+	alice := dial()
+	defer alice.Close()
+	bob := dial()
+	defer bob.Close()
+
+	response := sendAction(t, alice, "type", map[string]interface{}{"user": "alice"})
+	if tree, _ := response.Tree.(map[string]interface{}); len(tree) != 0 {
+		t.Errorf("Expected an empty update for the signalling connection, got %v", response.Tree)
+	}
+
+	_ = bob.SetReadDeadline(time.Now().Add(2 * time.Second))
+	var message struct {
+		Signal *struct {
+			Name    string            `json:"name"`
+			Payload map[string]string `json:"payload"`
+			TTL     int64             `json:"ttl"`
+		} `json:"signal"`
+	}
+	if err := bob.ReadJSON(&message); err != nil {
+		t.Fatalf("Failed to read signal: %v", err)
+	}
+	if message.Signal == nil {
+		t.Fatal("Expected a signal message")
+	}
+	if message.Signal.Name != "typing" || message.Signal.Payload["user"] != "alice" || message.Signal.TTL != 3000 {
+		t.Errorf("Unexpected signal %+v", *message.Signal)
+	}
+
+	t.Run("not persisted", func(t *testing.T) {
+		stores := handler.(*liveHandler).config.SessionStore.Get("signal-group")
+		if got := stores[""].(*TypingState).Messages; len(got) != 0 {
+			t.Errorf("Expected no messages in the store, got %v", got)
+		}
+		if len(store.Messages) != 0 {
+			t.Errorf("Expected the template store to be untouched, got %v", store.Messages)
+		}
+	})
+
+	t.Run("throttled", func(t *testing.T) {
+		sendAction(t, alice, "type", map[string]interface{}{"user": "alice"})
+		sendAction(t, alice, "send", map[string]interface{}{"text": "hi"})
+
+		// The repeated signal is dropped, so bob only gets group updates until the new message
+		for {
+			_ = bob.SetReadDeadline(time.Now().Add(2 * time.Second))
+			_, data, err := bob.ReadMessage()
+			if err != nil {
+				t.Fatalf("Failed to read update: %v", err)
+			}
+			var next map[string]json.RawMessage
+			if err := json.Unmarshal(data, &next); err != nil {
+				t.Fatalf("Failed to decode message: %v", err)
+			}
+			if _, ok := next["signal"]; ok {
+				t.Fatalf("Expected the repeated signal to be throttled, got %s", data)
+			}
+			if strings.Contains(string(data), "hi") {
+				break
+			}
+		}
+	})
+}
@@ -0,0 +1,69 @@
+package livetemplate
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"regexp"
+	"strings"
+)
+
+// styleAttrPattern matches the start of a quoted style attribute value
+var styleAttrPattern = regexp.MustCompile(`(?i)\sstyle\s*=\s*(["'])`)
+
+// slotContext is the markup around a dynamic slot, so that the slot's value is escaped the way
+// html/template escapes it in the full page. The zero value is HTML text.
+type slotContext struct {
+	open  string // Markup before the slot, e.g. `<p style="width:`
+	close string // Markup after the slot, e.g. `">`
+}
+
+// slotContextAt returns the context of the next dynamic slot of a list whose statics so far
+// are statics. A slot inside a style attribute, as in <div style="width:{{.Pct}}%">, is
+// escaped as CSS: unsafe values render as ZgotmplZ, as they do in Execute.
+func slotContextAt(statics []string) slotContext {
+	// Earlier slots of the attribute only matter as CSS tokens, so any value does
+	text := strings.Join(statics, "0")
+
+	tagStart := strings.LastIndex(text, "<")
+	if tagStart < 0 || strings.LastIndex(text, ">") > tagStart {
+		return slotContext{}
+	}
+	tag := text[tagStart:]
+
+	matches := styleAttrPattern.FindAllStringSubmatchIndex(tag, -1)
+	if len(matches) == 0 {
+		return slotContext{}
+	}
+	last := matches[len(matches)-1]
+	quote := tag[last[2]:last[3]]
+	value := tag[last[1]:]
+	if strings.Contains(value, quote) {
+		return slotContext{} // The attribute is closed
+	}
+
+	return slotContext{
+		open:  "<p style=" + quote + value,
+		close: quote + ">",
+	}
+}
+
+// render executes the action text, e.g. "{{.Pct}}", with data and returns its output
+// escaped for the context
+func (c slotContext) render(action string, data interface{}) (string, error) {
+	tmpl, err := template.New("action").Funcs(builtinFuncs).Parse(c.open + action + c.close)
+	if err != nil {
+		return "", fmt.Errorf("action parse error: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("action execute error: %w", err)
+	}
+
+	out := buf.String()
+	if !strings.HasPrefix(out, c.open) || !strings.HasSuffix(out, c.close) || len(out) < len(c.open)+len(c.close) {
+		return "", fmt.Errorf("action execute error: unexpected output %q", out)
+	}
+	return out[len(c.open) : len(out)-len(c.close)], nil
+}
@@ -0,0 +1,87 @@
+package livetemplate
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestTemplate_StyleAttributeSlots(t *testing.T) {
+	type Task struct {
+		ID    string
+		Color string
+	}
+
+	tmpl := New("style-slot-test")
+	src := `<div class="progress"><div class="bar" style="width:{{.Pct}}%; color: {{.Color}}">{{.Label}}</div>` +
+		`<ul>{{range .Tasks}}<li data-key="{{.ID}}" style='color: {{.Color}}'>{{.ID}}</li>{{end}}</ul></div>`
+	if _, err := tmpl.Parse(src); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	update := func(t *testing.T, data map[string]interface{}) string {
+		t.Helper()
+		var buf bytes.Buffer
+		if err := tmpl.ExecuteUpdates(&buf, data); err != nil {
+			t.Fatalf("ExecuteUpdates failed: %v", err)
+		}
+		return buf.String()
+	}
+	data := func(pct int, color string) map[string]interface{} {
+		return map[string]interface{}{
+			"Pct": pct, "Color": color, "Label": "Uploading",
+			"Tasks": []Task{{"a", color}},
+		}
+	}
+
+	initial := update(t, data(10, "green"))
+	if !strings.Contains(initial, `style=\"width:","%; color: "`) {
+		t.Errorf("Expected the width to be a slot within the style attribute, got %s", initial)
+	}
+
+	t.Run("progress update sends only the width", func(t *testing.T) {
+		if got := update(t, data(55, "green")); got != `{"0":"55"}` {
+			t.Errorf("Expected only the width fragment, got %s", got)
+		}
+	})
+
+	t.Run("values are escaped as CSS like Execute", func(t *testing.T) {
+		unsafe := data(55, "expression(alert(1))")
+		got := update(t, unsafe)
+		if strings.Contains(got, "expression") || !strings.Contains(got, "ZgotmplZ") {
+			t.Errorf("Expected the unsafe CSS value to be filtered, got %s", got)
+		}
+
+		var page bytes.Buffer
+		if err := tmpl.Execute(&page, unsafe); err != nil {
+			t.Fatalf("Execute failed: %v", err)
+		}
+		if !strings.Contains(page.String(), "color: ZgotmplZ") {
+			t.Errorf("Expected Execute to filter the value too, got %s", page.String())
+		}
+	})
+}
+
+func TestSlotContextAt(t *testing.T) {
+	tests := []struct {
+		name    string
+		statics []string
+		want    slotContext
+	}{
+		{"text", []string{"<p>"}, slotContext{}},
+		{"other attribute", []string{`<p title="`}, slotContext{}},
+		{"style attribute", []string{`<div style="width:`}, slotContext{`<p style="width:`, `">`}},
+		{"single quotes", []string{`<div STYLE = 'width:`}, slotContext{`<p style='width:`, `'>`}},
+		{"after an earlier slot", []string{`<div style="width:`, `%; color: `}, slotContext{`<p style="width:0%; color: `, `">`}},
+		{"closed attribute", []string{`<div style="width:1px" title="`}, slotContext{}},
+		{"after the tag", []string{`<div style="width:1px">`}, slotContext{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := slotContextAt(tt.statics); got != tt.want {
+				t.Errorf("slotContextAt() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
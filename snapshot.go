@@ -0,0 +1,93 @@
+package livetemplate
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+// Snapshotter is an optional interface that stores can implement to control how their state
+// is serialized by SnapshotStores, e.g. to skip caches or to encode in a compact format.
+// Stores without it are snapshotted as JSON of their exported fields, honoring json tags.
+type Snapshotter interface {
+	Snapshot() ([]byte, error)
+	Restore(data []byte) error
+}
+
+// SnapshotStores serializes the state of the stores of a session group, for a SessionStore
+// persisting groups across restarts or instances, or to capture test fixtures. The snapshot
+// is a JSON object holding the snapshot of each store by name.
+func SnapshotStores(stores Stores) ([]byte, error) {
+	snapshots := make(map[string]json.RawMessage, len(stores))
+	for name, store := range stores {
+		data, err := snapshotStore(store)
+		if err != nil {
+			return nil, fmt.Errorf("failed to snapshot store %q: %w", name, err)
+		}
+		snapshots[name] = data
+	}
+	return json.Marshal(snapshots)
+}
+
+// RestoreStores returns new instances of prototypes, created like the stores of a new session
+// group, holding the state of a snapshot from SnapshotStores. Stores missing from the
+// snapshot keep their initial state. A persistent SessionStore calls it from Get with the
+// stores given to Handle:
+//
+//	func (s *RedisSessionStore) Get(groupID string) livetemplate.Stores {
+//	    data, err := s.client.Get(ctx, "lvt:"+groupID).Bytes()
+//	    if err != nil {
+//	        return nil
+//	    }
+//	    stores, err := livetemplate.RestoreStores(s.prototypes, data)
+//	    if err != nil {
+//	        return nil
+//	    }
+//	    return stores
+//	}
+func RestoreStores(prototypes Stores, data []byte) (Stores, error) {
+	var snapshots map[string]json.RawMessage
+	if err := json.Unmarshal(data, &snapshots); err != nil {
+		return nil, fmt.Errorf("invalid store snapshot: %w", err)
+	}
+
+	stores := make(Stores, len(prototypes))
+	for name, prototype := range prototypes {
+		store := cloneStore(prototype)
+		if snapshot, ok := snapshots[name]; ok {
+			if err := restoreStore(store, snapshot); err != nil {
+				return nil, fmt.Errorf("failed to restore store %q: %w", name, err)
+			}
+		}
+		stores[name] = store
+	}
+	return stores, nil
+}
+
+// snapshotStore serializes a single store with its Snapshot method, or as JSON. Snapshots
+// from Snapshot may be any bytes, so they are embedded as base64 strings.
+func snapshotStore(store Store) ([]byte, error) {
+	if snapshotter, ok := store.(Snapshotter); ok {
+		data, err := snapshotter.Snapshot()
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(data)
+	}
+	return json.Marshal(store)
+}
+
+// restoreStore loads a snapshot from snapshotStore into store
+func restoreStore(store Store, snapshot json.RawMessage) error {
+	if snapshotter, ok := store.(Snapshotter); ok {
+		var data []byte
+		if err := json.Unmarshal(snapshot, &data); err != nil {
+			return err
+		}
+		return snapshotter.Restore(data)
+	}
+	if reflect.ValueOf(store).Kind() != reflect.Ptr {
+		return fmt.Errorf("store %T must be a pointer to be restored", store)
+	}
+	return json.Unmarshal(snapshot, store)
+}
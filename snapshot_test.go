@@ -0,0 +1,76 @@
+package livetemplate
+
+import (
+	"strconv"
+	"testing"
+)
+
+// snapshotCounter is a store snapshotted with its own format
+type snapshotCounter struct {
+	count int
+}
+
+func (s *snapshotCounter) Change(ctx *ActionContext) error {
+	s.count++
+	return nil
+}
+
+func (s *snapshotCounter) Snapshot() ([]byte, error) {
+	return []byte(strconv.Itoa(s.count)), nil
+}
+
+func (s *snapshotCounter) Restore(data []byte) error {
+	count, err := strconv.Atoi(string(data))
+	s.count = count
+	return err
+}
+
+func TestSnapshotStores(t *testing.T) {
+	prototypes := Stores{
+		"counter": &SlowState{},
+		"custom":  &snapshotCounter{},
+	}
+
+	// A session group after a few actions
+	stores := Stores{
+		"counter": &SlowState{Count: 3},
+		"custom":  &snapshotCounter{count: 5},
+	}
+	data, err := SnapshotStores(stores)
+	if err != nil {
+		t.Fatalf("SnapshotStores failed: %v", err)
+	}
+
+	restored, err := RestoreStores(prototypes, data)
+	if err != nil {
+		t.Fatalf("RestoreStores failed: %v", err)
+	}
+	if got := restored["counter"].(*SlowState).Count; got != 3 {
+		t.Errorf("Restored counter = %d, want 3", got)
+	}
+	if got := restored["custom"].(*snapshotCounter).count; got != 5 {
+		t.Errorf("Restored custom counter = %d, want 5", got)
+	}
+	if restored["counter"] == prototypes["counter"] || prototypes["counter"].(*SlowState).Count != 0 {
+		t.Error("RestoreStores should restore into new instances, not the prototypes")
+	}
+
+	t.Run("stores missing from the snapshot keep their initial state", func(t *testing.T) {
+		restored, err := RestoreStores(Stores{"other": &SlowState{Count: 9}}, data)
+		if err != nil {
+			t.Fatalf("RestoreStores failed: %v", err)
+		}
+		if got := restored["other"].(*SlowState).Count; got != 9 {
+			t.Errorf("Restored other = %d, want its initial 9", got)
+		}
+	})
+
+	t.Run("invalid snapshot", func(t *testing.T) {
+		if _, err := RestoreStores(prototypes, []byte("not json")); err == nil {
+			t.Error("Expected an error for an invalid snapshot")
+		}
+		if _, err := RestoreStores(prototypes, []byte(`{"counter": "three"}`)); err == nil {
+			t.Error("Expected an error for a snapshot of the wrong shape")
+		}
+	})
+}
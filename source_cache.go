@@ -0,0 +1,104 @@
+package livetemplate
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"html/template"
+	"sync"
+	"text/template/parse"
+)
+
+// maxCachedSources bounds the source caches; a full cache is emptied. Applications
+// have a handful of templates, so this only matters for generated ones.
+const maxCachedSources = 512
+
+// parsedSource is what Parse derives from a template source before wrapping it, which
+// is the same for every instance parsing the source with the same options
+type parsedSource struct {
+	source         string // Normalized source, see Template.sources
+	text           string // Flattened text with action fallbacks, see Template.templateStr
+	isFullHTML     bool
+	staticsVersion string
+	rateLimits     map[string]ActionRateLimit
+}
+
+// parsedSources caches parsedSource by source and options, so per-connection clones of a
+// template skip flattening and only parse the wrapped text
+var parsedSources = struct {
+	sync.Mutex
+	entries map[[sha256.Size]byte]*parsedSource
+}{entries: make(map[[sha256.Size]byte]*parsedSource)}
+
+// sourceASTs caches the parse trees tree generation walks, by template text. The trees
+// are only read, so executions of every instance share them.
+var sourceASTs = struct {
+	sync.Mutex
+	entries map[[sha256.Size]byte]*parse.Tree
+}{entries: make(map[[sha256.Size]byte]*parse.Tree)}
+
+// parsedSourceKey identifies a source parsed by t: Parse's result also depends on the
+// template name and the options changing the text
+func (t *Template) parsedSourceKey(source string) [sha256.Size]byte {
+	return sha256.Sum256([]byte(fmt.Sprintf("%s\x00%t\x00%d\x00%s", t.name, t.config.ActionFallbacks, t.maxTemplateDepth(), source)))
+}
+
+func cachedParsedSource(key [sha256.Size]byte) *parsedSource {
+	parsedSources.Lock()
+	defer parsedSources.Unlock()
+	return parsedSources.entries[key]
+}
+
+func cacheParsedSource(key [sha256.Size]byte, parsed *parsedSource) {
+	parsedSources.Lock()
+	defer parsedSources.Unlock()
+	if len(parsedSources.entries) >= maxCachedSources {
+		parsedSources.entries = make(map[[sha256.Size]byte]*parsedSource)
+	}
+	parsedSources.entries[key] = parsed
+}
+
+// sourceAST returns the flattened parse tree of templateStr for tree generation
+func sourceAST(templateStr string) (*parse.Tree, error) {
+	key := sha256.Sum256([]byte(templateStr))
+	sourceASTs.Lock()
+	tree := sourceASTs.entries[key]
+	sourceASTs.Unlock()
+	if tree != nil {
+		return tree, nil
+	}
+
+	// Normalize template spacing
+	text := normalizeTemplateSpacing(templateStr)
+
+	// Parse template to get AST
+	tmpl, err := template.New("temp").Funcs(builtinFuncs).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("template parse error: %w", err)
+	}
+
+	// Check if template uses composition and flatten if needed
+	if hasTemplateComposition(tmpl) {
+		flattenedStr, err := flattenTemplate(tmpl)
+		if err != nil {
+			return nil, fmt.Errorf("template flatten error: %w", err)
+		}
+		// Re-parse flattened template
+		tmpl, err = template.New("temp-flattened").Funcs(builtinFuncs).Parse(flattenedStr)
+		if err != nil {
+			return nil, fmt.Errorf("flattened template parse error: %w", err)
+		}
+	}
+
+	// Verify we have a parse tree
+	if tmpl.Tree == nil || tmpl.Tree.Root == nil {
+		return nil, fmt.Errorf("template has no parse tree")
+	}
+
+	sourceASTs.Lock()
+	defer sourceASTs.Unlock()
+	if len(sourceASTs.entries) >= maxCachedSources {
+		sourceASTs.entries = make(map[[sha256.Size]byte]*parse.Tree)
+	}
+	sourceASTs.entries[key] = tmpl.Tree
+	return tmpl.Tree, nil
+}
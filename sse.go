@@ -0,0 +1,253 @@
+package livetemplate
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// defaultSSEHeartbeat is used when WithSSEHeartbeat isn't set
+const defaultSSEHeartbeat = 15 * time.Second
+
+// sseWriter writes server-sent event frames to a streaming HTTP response.
+// Callers serialize writes (Connection.Send holds the connection mutex).
+type sseWriter struct {
+	w       http.ResponseWriter
+	flusher http.Flusher
+	closed  bool // Set when the handler returns; the ResponseWriter must not be used after that
+}
+
+// writeEvent writes data as a single "data:" event and flushes it to the client
+func (s *sseWriter) writeEvent(data []byte) error {
+	if s.closed {
+		return fmt.Errorf("SSE stream closed")
+	}
+
+	var frame bytes.Buffer
+	// An event ends at the first blank line, so every line of data needs its own prefix
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		frame.WriteString("data: ")
+		frame.Write(line)
+		frame.WriteByte('\n')
+	}
+	frame.WriteByte('\n')
+
+	if _, err := s.w.Write(frame.Bytes()); err != nil {
+		return fmt.Errorf("SSE write failed: %w", err)
+	}
+	s.flusher.Flush()
+	return nil
+}
+
+// writeComment writes a comment line, which clients ignore but which keeps the stream alive
+func (s *sseWriter) writeComment(text string) error {
+	if s.closed {
+		return fmt.Errorf("SSE stream closed")
+	}
+	if _, err := fmt.Fprintf(s.w, ": %s\n\n", text); err != nil {
+		return fmt.Errorf("SSE write failed: %w", err)
+	}
+	s.flusher.Flush()
+	return nil
+}
+
+// heartbeat writes a keep-alive comment to a server-sent event connection
+func (c *Connection) heartbeat() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.events.writeComment("heartbeat")
+}
+
+// openEvents allows writes to a server-sent event connection once the response headers are written
+func (c *Connection) openEvents() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.events.closed = false
+}
+
+// closeEvents stops writes to a server-sent event connection, including from
+// broadcasts that fetched the connection before it was unregistered
+func (c *Connection) closeEvents() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.events.closed = true
+}
+
+// isEventStreamRequest reports whether r is a GET asking for a server-sent event stream
+func isEventStreamRequest(r *http.Request) bool {
+	if r.Method != http.MethodGet {
+		return false
+	}
+	for _, accept := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(accept))
+		if err == nil && mediaType == "text/event-stream" {
+			return true
+		}
+	}
+	return false
+}
+
+// handleSSE streams tree updates as server-sent events for clients that can't use WebSocket.
+//
+// The stream starts with the initial tree and then receives every update for the session
+// group: actions POSTed to the same endpoint and broadcasts are delivered just like they are
+// to WebSocket connections. Each event's data is an UpdateResponse.
+func (h *liveHandler) handleSSE(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	// Authenticate user and get session group
+	userID, err := h.config.Authenticator.Identify(r)
+	if err != nil {
+		log.Printf("SSE authentication failed: %v", err)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	groupID, err := h.config.Authenticator.GetSessionGroup(r, userID)
+	if err != nil {
+		log.Printf("Failed to get session group for SSE: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	roles, err := h.userRoles(r, userID)
+	if err != nil {
+		log.Printf("Failed to get roles for SSE: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	// Set session cookie if this is a new session (cookie doesn't exist)
+	setCookieIfNew(w, r, groupID)
+
+	// Clone template for this stream - ExecuteUpdates tracks per-connection diff state
+	connTmpl, err := h.config.Template.Clone()
+	if err != nil {
+		log.Printf("Failed to clone template: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	connTmpl.setUser(userID, roles)
+	connTmpl.setLocale(h.locale(r))
+	connTmpl.startProgressive()
+
+	// Get or create stores for this session group
+	stores := h.config.SessionStore.Get(groupID)
+	if stores == nil {
+		stores = h.cloneStores()
+		h.config.SessionStore.Set(groupID, stores)
+		log.Printf("SSE: Created new session group: %s", groupID)
+	}
+
+	connection := &Connection{
+		GroupID:  groupID,
+		UserID:   userID,
+		Template: connTmpl,
+		Stores:   stores,
+		events:   &sseWriter{w: w, flusher: flusher, closed: true}, // Opened once the headers are written
+	}
+
+	if limit := h.config.MaxGroupConns; !h.registry.TryRegister(connection, limit) {
+		log.Printf("Rejecting SSE connection: group %q already has %d connections", groupID, limit)
+		http.Error(w, fmt.Sprintf("Too many connections in session group (max %d)", limit), http.StatusTooManyRequests)
+		return
+	}
+	defer h.unregister(connection)
+	defer connection.closeEvents()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no") // Disable nginx response buffering
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	connection.openEvents()
+	log.Printf("SSE client connected: user=%q, group=%q (total: %d)", userID, groupID, h.registry.Count())
+
+	state := &connState{
+		stores:  stores,
+		errors:  make(map[string]string),
+		url:     r.URL,
+		groupID: groupID,
+		conn:    connection,
+	}
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	// Call OnConnect for stores that implement BroadcastAware
+	bc := &broadcaster{
+		conn:     connection,
+		template: connTmpl,
+		state:    state,
+		handler:  h,
+	}
+	for _, store := range state.stores {
+		if aware, ok := store.(BroadcastAware); ok {
+			if err := aware.OnConnect(ctx, bc); err != nil {
+				log.Printf("OnConnect failed for store: %v", err)
+			}
+			defer aware.OnDisconnect()
+		}
+	}
+
+	// Let stores load state for the requested route
+	h.mountStores(ctx, state)
+
+	// Send initial tree. Streams can't be resumed, so it carries no resume token.
+	var buf bytes.Buffer
+	if err := connTmpl.ExecuteUpdates(&buf, h.getTemplateData(state.stores), state.getErrors()); err != nil {
+		log.Printf("Failed to generate initial SSE tree: %v", err)
+		return
+	}
+	tree, err := parseUpdateTree(buf.Bytes())
+	if err != nil {
+		log.Printf("Failed to parse initial SSE tree: %v", err)
+		return
+	}
+	responseBytes, err := json.Marshal(h.initialResponse(connTmpl, state, tree, ""))
+	if err != nil {
+		log.Printf("Failed to marshal initial SSE response: %v", err)
+		return
+	}
+	if err := connection.Send(websocket.TextMessage, responseBytes); err != nil {
+		log.Printf("Failed to send initial SSE tree: %v", err)
+		return
+	}
+	if err := h.streamProgressiveRanges(connection, state); err != nil {
+		log.Printf("Failed to stream progressive ranges: %v", err)
+		return
+	}
+
+	interval := h.config.SSEHeartbeat
+	if interval <= 0 {
+		interval = defaultSSEHeartbeat
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Printf("SSE client disconnected: user=%q, group=%q", userID, groupID)
+			return
+		case <-ticker.C:
+			if err := connection.heartbeat(); err != nil {
+				log.Printf("SSE heartbeat failed: %v", err)
+				return
+			}
+		}
+	}
+}
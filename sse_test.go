@@ -0,0 +1,246 @@
+package livetemplate
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// readSSEFrame reads lines up to the next blank line and returns them without the trailing newline
+func readSSEFrame(t *testing.T, reader *bufio.Reader) []string {
+	t.Helper()
+
+	type result struct {
+		lines []string
+		err   error
+	}
+	done := make(chan result, 1)
+	go func() {
+		var lines []string
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				done <- result{lines, err}
+				return
+			}
+			line = strings.TrimSuffix(line, "\n")
+			if line == "" {
+				done <- result{lines, nil}
+				return
+			}
+			lines = append(lines, line)
+		}
+	}()
+
+	select {
+	case res := <-done:
+		if res.err != nil {
+			t.Fatalf("Failed to read SSE frame: %v", res.err)
+		}
+		return res.lines
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timed out waiting for SSE frame")
+		return nil
+	}
+}
+
+// sseUpdate is an UpdateResponse as decoded from an SSE data event
+type sseUpdate struct {
+	Tree map[string]interface{} `json:"tree"`
+	Meta *ResponseMetadata      `json:"meta"`
+}
+
+// readSSEUpdate reads frames until a data event arrives, skipping heartbeat comments
+func readSSEUpdate(t *testing.T, reader *bufio.Reader) sseUpdate {
+	t.Helper()
+
+	for {
+		lines := readSSEFrame(t, reader)
+		if len(lines) == 1 && strings.HasPrefix(lines[0], ":") {
+			continue
+		}
+
+		var data strings.Builder
+		for _, line := range lines {
+			if !strings.HasPrefix(line, "data: ") {
+				t.Fatalf("Expected data line, got %q", line)
+			}
+			data.WriteString(strings.TrimPrefix(line, "data: "))
+		}
+
+		var response sseUpdate
+		if err := json.Unmarshal([]byte(data.String()), &response); err != nil {
+			t.Fatalf("Failed to decode SSE data %q: %v", data.String(), err)
+		}
+		return response
+	}
+}
+
+func TestLiveHandler_SSE(t *testing.T) {
+	tmpl := New("sse-test", WithSSEHeartbeat(20*time.Millisecond))
+	if _, err := tmpl.Parse("<p>Count: {{.Count}}</p>"); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	server := httptest.NewServer(tmpl.Handle(&SlowState{}))
+	defer server.Close()
+
+	req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	req.Header.Set("Accept", "text/event-stream")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("SSE request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Expected text/event-stream, got %q", ct)
+	}
+	reader := bufio.NewReader(resp.Body)
+
+	t.Run("initial tree", func(t *testing.T) {
+		initial := readSSEUpdate(t, reader)
+		if _, ok := initial.Tree["s"]; !ok {
+			t.Errorf("Initial tree should include statics, got %v", initial.Tree)
+		}
+		if initial.Tree["0"] != "0" {
+			t.Errorf("Expected count 0, got %v", initial.Tree["0"])
+		}
+	})
+
+	t.Run("heartbeat", func(t *testing.T) {
+		lines := readSSEFrame(t, reader)
+		if len(lines) != 1 || lines[0] != ": heartbeat" {
+			t.Errorf("Expected heartbeat comment, got %q", lines)
+		}
+	})
+
+	t.Run("action update", func(t *testing.T) {
+		action, err := http.NewRequest(http.MethodPost, server.URL,
+			strings.NewReader(`{"action":"increment","data":{}}`))
+		if err != nil {
+			t.Fatalf("Failed to create action request: %v", err)
+		}
+		for _, cookie := range resp.Cookies() {
+			action.AddCookie(cookie)
+		}
+
+		actionResp, err := http.DefaultClient.Do(action)
+		if err != nil {
+			t.Fatalf("Action request failed: %v", err)
+		}
+		actionResp.Body.Close()
+
+		update := readSSEUpdate(t, reader)
+		if _, ok := update.Tree["s"]; ok {
+			t.Errorf("Update should not resend statics, got %v", update.Tree)
+		}
+		if update.Tree["0"] != "1" {
+			t.Errorf("Expected count 1, got %v", update.Tree)
+		}
+	})
+}
+
+func TestIsEventStreamRequest(t *testing.T) {
+	tests := []struct {
+		method string
+		accept string
+		want   bool
+	}{
+		{http.MethodGet, "text/event-stream", true},
+		{http.MethodGet, "text/html, text/event-stream;q=0.9", true},
+		{http.MethodGet, "text/html", false},
+		{http.MethodGet, "", false},
+		{http.MethodPost, "text/event-stream", false},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, "/", nil)
+		if tt.accept != "" {
+			req.Header.Set("Accept", tt.accept)
+		}
+		if got := isEventStreamRequest(req); got != tt.want {
+			t.Errorf("isEventStreamRequest(%s, %q) = %v, want %v", tt.method, tt.accept, got, tt.want)
+		}
+	}
+}
+
+// sseMountState is a test store that sets the title in Mount and fails for a missing product
+type sseMountState struct {
+	Product string
+}
+
+func (s *sseMountState) Mount(ctx *ActionContext) error {
+	s.Product = ctx.Query().Get("product")
+	ctx.SetTitle(s.Product + " | Shop")
+	if s.Product == "" {
+		return errors.New("no product selected")
+	}
+	return nil
+}
+
+func (s *sseMountState) Change(ctx *ActionContext) error {
+	return nil
+}
+
+func TestLiveHandler_SSEInitialResponse(t *testing.T) {
+	tmpl := New("sse-initial-test", WithMaxConnectionsPerGroup(1))
+	if _, err := tmpl.Parse("<h1>{{.Product}}</h1>"); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	server := httptest.NewServer(tmpl.Handle(&sseMountState{}))
+	defer server.Close()
+
+	// open starts a stream in the session group group
+	open := func(t *testing.T, query, group string) *http.Response {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodGet, server.URL+query, nil)
+		if err != nil {
+			t.Fatalf("Failed to create request: %v", err)
+		}
+		req.Header.Set("Accept", "text/event-stream")
+		req.AddCookie(&http.Cookie{Name: "livetemplate-id", Value: group})
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("SSE request failed: %v", err)
+		}
+		t.Cleanup(func() { resp.Body.Close() })
+		return resp
+	}
+
+	t.Run("head and Mount errors", func(t *testing.T) {
+		initial := readSSEUpdate(t, bufio.NewReader(open(t, "/", "group-sse-missing").Body))
+		if initial.Meta == nil || initial.Meta.Success || len(initial.Meta.Errors) == 0 {
+			t.Fatalf("Expected the Mount error in the initial response, got %+v", initial.Meta)
+		}
+		if initial.Meta.Head == nil || initial.Meta.Head.Title != " | Shop" {
+			t.Errorf("Expected the title set in Mount, got %+v", initial.Meta.Head)
+		}
+		if initial.Meta.StaticsVersion == "" {
+			t.Errorf("Expected the statics version in the initial response, got %+v", initial.Meta)
+		}
+
+		initial = readSSEUpdate(t, bufio.NewReader(open(t, "/?product=Lamp", "group-sse-lamp").Body))
+		if !initial.Meta.Success || initial.Meta.Head == nil || initial.Meta.Head.Title != "Lamp | Shop" {
+			t.Errorf("Expected a successful Mount with its title, got %+v", initial.Meta)
+		}
+	})
+
+	t.Run("group connection limit", func(t *testing.T) {
+		first := open(t, "/?product=Lamp", "group-sse-limit")
+		readSSEUpdate(t, bufio.NewReader(first.Body))
+
+		if resp := open(t, "/?product=Lamp", "group-sse-limit"); resp.StatusCode != http.StatusTooManyRequests {
+			t.Errorf("Expected a second stream in the group to be refused, got %d", resp.StatusCode)
+		}
+	})
+}
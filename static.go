@@ -0,0 +1,124 @@
+package livetemplate
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"html/template"
+	"text/template/parse"
+)
+
+// builtinFuncs are the template functions available to every LiveTemplate template
+var builtinFuncs = template.FuncMap{
+	"lvt_static":          lvtStatic,
+	"lvt_recursion_limit": recursionLimit,
+	"lvt_value":           lvtValue,
+	"lvt_ordered":         lvtOrdered,
+	"lvt_number":          lvtNumber,
+	"lvt_date":            lvtDate,
+	"lvt_locale_number":   formatNumber,
+	"lvt_locale_date":     formatDate,
+	"lvt_id":              lvtID,
+	"lvt_scoped_id":       lvtScopedID,
+}
+
+// lvtStatic implements {{lvt_static .HTML [key...]}}: it outputs pre-rendered HTML unescaped
+// and marks it as a single static blob in the update tree.
+//
+// The blob is sent with the statics of its slot and only re-sent when its cache key changes.
+// Without a key the content itself is the key, so the blob is re-sent whenever it changes:
+//
+//	{{lvt_static .RenderedMarkdown}}
+//	{{lvt_static .RenderedMarkdown .Post.UpdatedAt}}
+func lvtStatic(content interface{}, key ...interface{}) template.HTML {
+	switch c := content.(type) {
+	case template.HTML:
+		return c
+	case string:
+		return template.HTML(c)
+	default:
+		return template.HTML(fmt.Sprint(c))
+	}
+}
+
+// staticBlobKey hashes the cache key of a blob into a fingerprint ("f") value
+func staticBlobKey(content template.HTML, key []interface{}) string {
+	hasher := md5.New()
+	if len(key) == 0 {
+		hasher.Write([]byte(content))
+	} else {
+		fmt.Fprint(hasher, key...)
+	}
+	return hex.EncodeToString(hasher.Sum(nil))[:16]
+}
+
+// isStaticBlobAction reports whether node is a {{lvt_static ...}} action
+func isStaticBlobAction(node *parse.ActionNode) bool {
+	return isFuncAction(node, "lvt_static")
+}
+
+// isFuncAction reports whether node is a single call of the named function, e.g. {{name .Arg}}
+func isFuncAction(node *parse.ActionNode, name string) bool {
+	if node.Pipe == nil || len(node.Pipe.Decl) > 0 || len(node.Pipe.Cmds) != 1 {
+		return false
+	}
+	args := node.Pipe.Cmds[0].Args
+	if len(args) == 0 {
+		return false
+	}
+	ident, ok := args[0].(*parse.IdentifierNode)
+	return ok && ident.Ident == name
+}
+
+// handleStaticBlobAction renders a {{lvt_static ...}} action as a nested static-only node.
+// The node's "f" holds the cache key so compareTreesAndGetChanges can skip unchanged blobs.
+func handleStaticBlobAction(node *parse.ActionNode, data interface{}) (treeNode, error) {
+	var key string
+	funcs := template.FuncMap{
+		"lvt_static": func(content interface{}, cacheKey ...interface{}) template.HTML {
+			html := lvtStatic(content, cacheKey...)
+			key = staticBlobKey(html, cacheKey)
+			return html
+		},
+	}
+
+	tmpl, err := template.New("static").Funcs(funcs).Parse(node.String())
+	if err != nil {
+		return nil, fmt.Errorf("static blob parse error: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("static blob execute error: %w", err)
+	}
+
+	return treeNode{
+		"s": []string{"", ""},
+		"0": treeNode{
+			"s": []string{buf.String()},
+			"f": key,
+		},
+	}, nil
+}
+
+// staticBlobFingerprint returns the cache key of a node built by handleStaticBlobAction
+func staticBlobFingerprint(value interface{}) (string, bool) {
+	var node map[string]interface{}
+	switch v := value.(type) {
+	case treeNode:
+		node = v
+	case map[string]interface{}:
+		node = v
+	default:
+		return "", false
+	}
+	if len(node) != 2 {
+		return "", false
+	}
+	if statics, ok := node["s"].([]string); !ok || len(statics) != 1 {
+		return "", false
+	}
+	fingerprint, ok := node["f"].(string)
+	return fingerprint, ok
+}
@@ -0,0 +1,152 @@
+package livetemplate
+
+import (
+	"fmt"
+	"html/template"
+	"strings"
+)
+
+// staticRegionAttr marks an element whose content is rendered once and then never diffed
+const staticRegionAttr = "data-lvt-static"
+
+// freezeStaticRegions renders the elements marked data-lvt-static with data and folds the
+// output into the template text, so the regions become statics of the tree and no later
+// update carries their content, whatever data they read:
+//
+//	<nav data-lvt-static>{{range .Menu}}<a href="{{.URL}}">{{.Label}}</a>{{end}}</nav>
+//
+// It runs on the first render of t; clones render their regions again. The template of
+// a handler renders pages for every session, so it renders the regions on each call and
+// only its per-connection clones freeze them. A region is rendered with the template's
+// data as dot, so it must not be inside a {{range}} or {{with}}, and must contain whole
+// actions. The client leaves elements carrying the attribute untouched. Called with t.mu
+// held.
+func (t *Template) freezeStaticRegions(data interface{}, errMap map[string]string) error {
+	if t.shared || t.unfrozen != "" || !strings.Contains(t.templateStr, staticRegionAttr) {
+		return nil
+	}
+	if errMap == nil {
+		errMap = make(map[string]string)
+	}
+
+	text := t.templateStr
+	var frozen strings.Builder
+	for {
+		start, end, ok := nextStaticRegion(text)
+		if !ok {
+			break
+		}
+		region := text[start:end]
+		tmpl, err := template.New(t.name + "-static").Funcs(builtinFuncs).Option(t.missingKeyOption()).Parse(t.localizeCalls(region))
+		if err != nil {
+			return fmt.Errorf("%s region %q: %w", staticRegionAttr, region, err)
+		}
+		html, err := executeTemplateWithContext(tmpl, data, t.templateContext(errMap))
+		if err != nil {
+			return fmt.Errorf("%s region %q: %w", staticRegionAttr, region, err)
+		}
+		frozen.WriteString(text[:start])
+		// Braces in the output would start actions when the text is parsed again
+		frozen.WriteString(strings.ReplaceAll(string(html), "{{", `{{"{{"}}`))
+		text = text[end:]
+	}
+	frozen.WriteString(text)
+
+	if frozen.Len() == len(t.templateStr) && frozen.String() == t.templateStr {
+		return nil
+	}
+	parsed := &parsedSource{
+		text:           frozen.String(),
+		isFullHTML:     strings.Contains(t.templateStr, "<!DOCTYPE") || strings.Contains(t.templateStr, "<html"),
+		staticsVersion: staticsVersionOf(frozen.String()),
+		rateLimits:     t.rateLimits,
+	}
+	unfrozen, sources, definitions := t.templateStr, t.sources, t.definitions
+	if _, err := t.parseWrapped(parsed); err != nil {
+		return err
+	}
+	t.unfrozen, t.sources, t.definitions = unfrozen, sources, definitions
+	return nil
+}
+
+// nextStaticRegion returns the bounds of the first element of text marked data-lvt-static,
+// from its opening tag to the end of its closing tag
+func nextStaticRegion(text string) (start, end int, ok bool) {
+	for offset := 0; ; {
+		idx := strings.Index(text[offset:], staticRegionAttr)
+		if idx < 0 {
+			return 0, 0, false
+		}
+		pos := offset + idx
+		offset = pos + len(staticRegionAttr)
+		if !isStaticRegionAttr(text, pos) {
+			continue
+		}
+
+		start = strings.LastIndex(text[:pos], "<")
+		openEnd := strings.Index(text[pos:], ">")
+		if start < 0 || openEnd < 0 {
+			return 0, 0, false
+		}
+		openEnd += pos + 1
+		name := tagName(text[start+1:])
+		if name == "" {
+			continue
+		}
+		if strings.HasSuffix(text[:openEnd], "/>") || isVoidHTMLElement(name) {
+			return start, openEnd, true
+		}
+		if closeEnd, found := matchingCloseTag(text, openEnd, name); found {
+			return start, closeEnd, true
+		}
+		return 0, 0, false
+	}
+}
+
+// isStaticRegionAttr reports whether the data-lvt-static at pos of text is an attribute name
+func isStaticRegionAttr(text string, pos int) bool {
+	if pos == 0 || !strings.ContainsRune(" \t\n\r", rune(text[pos-1])) {
+		return false
+	}
+	after := pos + len(staticRegionAttr)
+	return after < len(text) && strings.ContainsRune(" \t\n\r=/>", rune(text[after]))
+}
+
+// tagName returns the element name at the start of s, the text following a "<"
+func tagName(s string) string {
+	end := strings.IndexAny(s, " \t\n\r/>")
+	if end <= 0 {
+		return ""
+	}
+	return strings.ToLower(s[:end])
+}
+
+// matchingCloseTag returns the end of the closing tag of a name element whose content
+// starts at offset, skipping nested elements of the same name
+func matchingCloseTag(text string, offset int, name string) (int, bool) {
+	lower := strings.ToLower(text)
+	depth := 1
+	for offset < len(lower) {
+		next := strings.Index(lower[offset:], "<")
+		if next < 0 {
+			return 0, false
+		}
+		pos := offset + next
+		rest := lower[pos+1:]
+		switch {
+		case strings.HasPrefix(rest, "/"+name) && tagName(rest[1:]) == name:
+			depth--
+			closeEnd := strings.Index(lower[pos:], ">")
+			if closeEnd < 0 {
+				return 0, false
+			}
+			if depth == 0 {
+				return pos + closeEnd + 1, true
+			}
+		case tagName(rest) == name:
+			depth++
+		}
+		offset = pos + 1
+	}
+	return 0, false
+}
@@ -0,0 +1,138 @@
+package livetemplate
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type navState struct {
+	User  string
+	Count int
+}
+
+func (s *navState) Change(ctx *ActionContext) error { return nil }
+
+// sessionNavState is a navState whose user comes from the page request
+type sessionNavState struct {
+	navState
+}
+
+func (s *sessionNavState) Mount(ctx *ActionContext) error {
+	s.User = ctx.Query().Get("user")
+	return nil
+}
+
+func TestStaticRegions(t *testing.T) {
+	const source = `<nav data-lvt-static class="top"><span>{{.User}}</span><nav>{{.User}}!</nav></nav>
+<p>Count: {{.Count}}</p>
+<footer>{{.User}}</footer>`
+
+	t.Run("updates", func(t *testing.T) {
+		tmpl := New("static-region-test")
+		if _, err := tmpl.Parse(source); err != nil {
+			t.Fatalf("Parse failed: %v", err)
+		}
+
+		var initial bytes.Buffer
+		if err := tmpl.ExecuteUpdates(&initial, &navState{User: "ann", Count: 1}); err != nil {
+			t.Fatalf("ExecuteUpdates failed: %v", err)
+		}
+		var tree map[string]interface{}
+		if err := json.Unmarshal(initial.Bytes(), &tree); err != nil {
+			t.Fatalf("Failed to decode the initial tree: %v", err)
+		}
+		statics := fmt.Sprint(tree["s"])
+		if !strings.Contains(statics, "<span>ann</span><nav>ann!</nav></nav>") {
+			t.Errorf("Expected the static region folded into the statics, got %s", statics)
+		}
+
+		var update bytes.Buffer
+		if err := tmpl.ExecuteUpdates(&update, &navState{User: "bob", Count: 2}); err != nil {
+			t.Fatalf("ExecuteUpdates failed: %v", err)
+		}
+		if strings.Contains(update.String(), "ann") || strings.Count(update.String(), "bob") != 1 {
+			t.Errorf("Expected only the footer to carry the new user, got %s", update.String())
+		}
+		if !strings.Contains(update.String(), `"2"`) {
+			t.Errorf("Expected the count in the update, got %s", update.String())
+		}
+	})
+
+	t.Run("execute and clone", func(t *testing.T) {
+		tmpl := New("static-region-execute-test")
+		if _, err := tmpl.Parse(source); err != nil {
+			t.Fatalf("Parse failed: %v", err)
+		}
+		var html bytes.Buffer
+		if err := tmpl.Execute(&html, &navState{User: "ann"}); err != nil {
+			t.Fatalf("Execute failed: %v", err)
+		}
+		if !strings.Contains(html.String(), `<nav data-lvt-static class="top"><span>ann</span>`) {
+			t.Errorf("Expected the region rendered with its marker, got %s", html.String())
+		}
+
+		clone, err := tmpl.Clone()
+		if err != nil {
+			t.Fatalf("Clone failed: %v", err)
+		}
+		html.Reset()
+		if err := clone.Execute(&html, &navState{User: "bob"}); err != nil {
+			t.Fatalf("Execute failed: %v", err)
+		}
+		if !strings.Contains(html.String(), "<span>bob</span>") {
+			t.Errorf("Expected a clone to render its own static region, got %s", html.String())
+		}
+	})
+
+	t.Run("handler sessions", func(t *testing.T) {
+		tmpl := New("static-region-handler-test")
+		if _, err := tmpl.Parse(source); err != nil {
+			t.Fatalf("Parse failed: %v", err)
+		}
+		server := httptest.NewServer(tmpl.Handle(&sessionNavState{}))
+		defer server.Close()
+
+		// Without a session cookie, each request starts a session group of its own
+		for _, user := range []string{"ann", "bob"} {
+			resp, err := http.Get(server.URL + "?user=" + user)
+			if err != nil {
+				t.Fatalf("GET failed: %v", err)
+			}
+			body, err := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if err != nil {
+				t.Fatalf("Failed to read body: %v", err)
+			}
+			if want := "<span>" + user + "</span><nav>" + user + "!</nav>"; !strings.Contains(string(body), want) {
+				t.Errorf("Expected the static region of %s's session, got %s", user, body)
+			}
+		}
+	})
+
+	t.Run("region bounds", func(t *testing.T) {
+		for _, tc := range []struct {
+			text string
+			want string
+		}{
+			{`<p>a</p><nav data-lvt-static>{{.X}}</nav><p>b</p>`, `<nav data-lvt-static>{{.X}}</nav>`},
+			{`<div data-lvt-static="true"><div>{{.X}}</div></div>tail`, `<div data-lvt-static="true"><div>{{.X}}</div></div>`},
+			{`<img data-lvt-static src="{{.X}}">rest`, `<img data-lvt-static src="{{.X}}">`},
+			{`<p data-lvt-statics>{{.X}}</p>`, ""},
+		} {
+			start, end, ok := nextStaticRegion(tc.text)
+			got := ""
+			if ok {
+				got = tc.text[start:end]
+			}
+			if got != tc.want {
+				t.Errorf("nextStaticRegion(%q) = %q, want %q", tc.text, got, tc.want)
+			}
+		}
+	})
+}
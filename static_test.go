@@ -0,0 +1,100 @@
+package livetemplate
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestLvtStatic(t *testing.T) {
+	type Post struct {
+		Title   string
+		Body    string
+		Version int
+	}
+
+	tmpl := New("static-test")
+	_, err := tmpl.Parse(`<h1>{{.Title}}</h1><article>{{lvt_static .Body .Version}}</article>`)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	// update renders data and returns the update as JSON
+	update := func(t *testing.T, post *Post) string {
+		t.Helper()
+		var buf bytes.Buffer
+		if err := tmpl.ExecuteUpdates(&buf, post); err != nil {
+			t.Fatalf("ExecuteUpdates failed: %v", err)
+		}
+		return buf.String()
+	}
+
+	const body = "<p>Rendered <em>markdown</em></p>"
+
+	t.Run("initial render keeps blob in statics", func(t *testing.T) {
+		initial := update(t, &Post{Title: "Hello", Body: body, Version: 1})
+
+		var tree map[string]interface{}
+		if err := json.Unmarshal([]byte(initial), &tree); err != nil {
+			t.Fatalf("Invalid JSON: %v", err)
+		}
+		blob, ok := tree["1"].(map[string]interface{})
+		if !ok {
+			t.Fatalf("Expected blob as a nested node, got %v", tree["1"])
+		}
+		statics, _ := blob["s"].([]interface{})
+		if len(statics) != 1 || statics[0] != body {
+			t.Errorf("Expected unescaped blob as the only static, got %v", blob["s"])
+		}
+		if len(blob) != 2 || blob["f"] == nil {
+			t.Errorf("Blob should have no dynamics, got %v", blob)
+		}
+	})
+
+	t.Run("unchanged key is not re-sent", func(t *testing.T) {
+		changes := update(t, &Post{Title: "Hello again", Body: body + "<p>edited</p>", Version: 1})
+		if strings.Contains(changes, "markdown") || strings.Contains(changes, "edited") {
+			t.Errorf("Blob should not be re-sent while its key is unchanged, got %s", changes)
+		}
+		if !strings.Contains(changes, "Hello again") {
+			t.Errorf("Title change should still be sent, got %s", changes)
+		}
+	})
+
+	t.Run("changed key re-sends blob", func(t *testing.T) {
+		changes := update(t, &Post{Title: "Hello again", Body: "<p>v2</p>", Version: 2})
+		if !strings.Contains(changes, `"s":["<p>v2</p>"]`) {
+			t.Errorf("Expected blob with new statics, got %s", changes)
+		}
+		if err := ValidateWireMessage([]byte(changes)); err != nil {
+			t.Errorf("Update is not a valid wire message: %v", err)
+		}
+	})
+
+	t.Run("content is the key by default", func(t *testing.T) {
+		plain := New("static-default")
+		if _, err := plain.Parse(`<div>{{lvt_static .Body}}</div>`); err != nil {
+			t.Fatalf("Parse failed: %v", err)
+		}
+
+		var buf bytes.Buffer
+		if err := plain.ExecuteUpdates(&buf, &Post{Body: "<b>one</b>"}); err != nil {
+			t.Fatalf("ExecuteUpdates failed: %v", err)
+		}
+		buf.Reset()
+		if err := plain.ExecuteUpdates(&buf, &Post{Body: "<b>one</b>"}); err != nil {
+			t.Fatalf("ExecuteUpdates failed: %v", err)
+		}
+		if strings.Contains(buf.String(), "one") {
+			t.Errorf("Unchanged blob should not be re-sent, got %s", buf.String())
+		}
+		buf.Reset()
+		if err := plain.ExecuteUpdates(&buf, &Post{Body: "<b>two</b>"}); err != nil {
+			t.Fatalf("ExecuteUpdates failed: %v", err)
+		}
+		if !strings.Contains(buf.String(), "two") {
+			t.Errorf("Changed blob should be re-sent, got %s", buf.String())
+		}
+	})
+}
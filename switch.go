@@ -0,0 +1,181 @@
+package livetemplate
+
+import (
+	"reflect"
+	"text/template/parse"
+)
+
+// Switch nodes
+//
+// Templates emulate a switch with an if chain comparing one value against constants:
+//
+//	{{if eq .Type "a"}}...{{else if eq .Type "b"}}...{{else}}...{{end}}
+//
+// Such a chain is rendered as a switch node: the selected branch's tree plus "b", the index of
+// the branch, and "bs", the statics of every branch (the else branch last, [""] without one).
+// When the value changes, the update carries only the new "b" and the new branch's dynamics,
+// and the client takes the branch's statics from the "bs" it already holds.
+const (
+	switchBranchKey  = "b"  // Index of the selected branch
+	switchStaticsKey = "bs" // Statics of every branch
+)
+
+// switchCases returns the conditions of node when it is an if chain comparing one value
+// against constants, in order, with the body of the final else (nil without one). Returns
+// nil for a chain of less than two cases.
+func switchCases(node *parse.IfNode) ([]*parse.IfNode, *parse.ListNode) {
+	var cases []*parse.IfNode
+	subject := ""
+	current := node
+	for {
+		s, ok := switchSubject(current.Pipe)
+		if !ok || (subject != "" && s != subject) {
+			return nil, nil
+		}
+		subject = s
+		cases = append(cases, current)
+
+		// {{else if ...}} parses as an else list holding a single if node
+		if current.ElseList == nil || len(current.ElseList.Nodes) != 1 {
+			break
+		}
+		next, ok := current.ElseList.Nodes[0].(*parse.IfNode)
+		if !ok {
+			break
+		}
+		current = next
+	}
+
+	if len(cases) < 2 {
+		return nil, nil
+	}
+	return cases, current.ElseList
+}
+
+// switchSubject returns the value compared by a condition of the form `eq <value> <constant>`
+func switchSubject(pipe *parse.PipeNode) (string, bool) {
+	if pipe == nil || len(pipe.Decl) > 0 || len(pipe.Cmds) != 1 {
+		return "", false
+	}
+	args := pipe.Cmds[0].Args
+	if len(args) != 3 {
+		return "", false
+	}
+	if ident, ok := args[0].(*parse.IdentifierNode); !ok || ident.Ident != "eq" {
+		return "", false
+	}
+	switch args[2].(type) {
+	case *parse.StringNode, *parse.NumberNode, *parse.BoolNode:
+		return args[1].String(), true
+	}
+	return "", false
+}
+
+// branchStatics returns the statics a branch body renders to, whatever the data. Reports false
+// for bodies whose statics depend on the data, e.g. a {{with}} or a range with an {{else}}.
+func branchStatics(list *parse.ListNode) ([]string, bool) {
+	statics := []string{""}
+	if list == nil {
+		return statics, true
+	}
+	for _, child := range list.Nodes {
+		switch n := child.(type) {
+		case *parse.TextNode:
+			statics[len(statics)-1] += string(n.Text)
+		case *parse.ActionNode, *parse.IfNode:
+			statics = append(statics, "")
+		case *parse.RangeNode:
+			// A lone range becomes the branch's tree itself, and an else replaces it
+			if len(list.Nodes) == 1 || n.ElseList != nil {
+				return nil, false
+			}
+			statics = append(statics, "")
+		default:
+			return nil, false
+		}
+	}
+	return statics, true
+}
+
+// handleSwitchNode renders an if chain returned by switchCases as a switch node. Reports false
+// when a branch can't be rendered with fixed statics, for the chain to be handled as nested
+// conditionals.
+func handleSwitchNode(cases []*parse.IfNode, elseList *parse.ListNode, data interface{}, keyGen *keyGenerator) (treeNode, bool, error) {
+	branches := make([][]string, 0, len(cases)+1)
+	for _, c := range cases {
+		statics, ok := branchStatics(c.List)
+		if !ok {
+			return nil, false, nil
+		}
+		branches = append(branches, statics)
+	}
+	statics, ok := branchStatics(elseList)
+	if !ok {
+		return nil, false, nil
+	}
+	branches = append(branches, statics)
+
+	selected, list := len(cases), elseList
+	for i, c := range cases {
+		matched, err := evaluateCondition(c.Pipe, data)
+		if err != nil {
+			return nil, false, err
+		}
+		if matched {
+			selected, list = i, c.List
+			break
+		}
+	}
+
+	branch := treeNode{"s": []string{""}}
+	if list != nil {
+		var err error
+		if branch, err = buildTreeFromAST(list, data, keyGen); err != nil {
+			return nil, false, err
+		}
+	}
+	if !reflect.DeepEqual(branch["s"], branches[selected]) {
+		return nil, false, nil
+	}
+
+	branch[switchBranchKey] = selected
+	branch[switchStaticsKey] = branches
+	return treeNode{
+		"s": []string{"", ""},
+		"0": branch,
+	}, true, nil
+}
+
+// switchBranch returns the selected branch of a switch node
+func switchBranch(node treeNode) (int, bool) {
+	switch b := node[switchBranchKey].(type) {
+	case int:
+		return b, true
+	case float64: // Trees decoded from JSON
+		return int(b), true
+	}
+	return 0, false
+}
+
+// isSameSwitch reports whether two trees are renders of the same switch node
+func isSameSwitch(oldTree, newTree treeNode) bool {
+	if _, ok := switchBranch(oldTree); !ok {
+		return false
+	}
+	if _, ok := switchBranch(newTree); !ok {
+		return false
+	}
+	return deepEqual(oldTree[switchStaticsKey], newTree[switchStaticsKey])
+}
+
+// switchBranchUpdate returns the update selecting the branch of a switch node: its index and
+// dynamics, whose nested trees keep their statics since the client hasn't seen them
+func switchBranchUpdate(node treeNode) treeNode {
+	update := make(treeNode, len(node))
+	for k, v := range node {
+		if k != "s" && k != "f" && k != switchStaticsKey {
+			update[k] = v
+		}
+	}
+	return update
+}
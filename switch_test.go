@@ -0,0 +1,118 @@
+package livetemplate
+
+import (
+	"bytes"
+	"encoding/json"
+	"html/template"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTemplate_SwitchNode(t *testing.T) {
+	src := `<div><h1>{{.Title}}</h1>` +
+		`{{if eq .Type "a"}}<p class="a">{{.A}}</p>` +
+		`{{else if eq .Type "b"}}<section>{{.B}} by {{.Title}}</section>` +
+		`{{else}}<em>none</em>{{end}}</div>`
+
+	tmpl := New("switch-test")
+	if _, err := tmpl.Parse(src); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	// update renders data and returns the update sent for the switch's slot
+	update := func(t *testing.T, data map[string]interface{}) map[string]interface{} {
+		t.Helper()
+		var buf bytes.Buffer
+		if err := tmpl.ExecuteUpdates(&buf, data); err != nil {
+			t.Fatalf("ExecuteUpdates failed: %v", err)
+		}
+		if err := ValidateWireMessage(buf.Bytes()); err != nil {
+			t.Fatalf("Update failed wire validation: %v\n%s", err, buf.String())
+		}
+		var tree map[string]interface{}
+		if err := json.Unmarshal(buf.Bytes(), &tree); err != nil {
+			t.Fatalf("Invalid JSON: %v", err)
+		}
+		slot, _ := tree["1"].(map[string]interface{})
+		return slot
+	}
+
+	initial := update(t, map[string]interface{}{"Title": "T", "Type": "a", "A": "apple", "B": "banana"})
+	if initial["b"] != float64(0) {
+		t.Errorf("Expected branch 0 in the initial tree, got %v", initial["b"])
+	}
+	branches, _ := initial["bs"].([]interface{})
+	if len(branches) != 3 {
+		t.Fatalf("Expected statics of 3 branches in the initial tree, got %v", initial["bs"])
+	}
+	if want := []interface{}{"<section>", " by ", "</section>"}; !reflect.DeepEqual(branches[1], want) {
+		t.Errorf("Statics of branch 1 = %v, want %v", branches[1], want)
+	}
+
+	t.Run("switching branch sends the selector and dynamics", func(t *testing.T) {
+		slot := update(t, map[string]interface{}{"Title": "T", "Type": "b", "A": "apple", "B": "banana"})
+		got, _ := json.Marshal(slot)
+		if string(got) != `{"0":"banana","1":"T","b":1}` {
+			t.Errorf("Expected a compact branch switch, got %s", got)
+		}
+	})
+
+	t.Run("same branch sends changed dynamics only", func(t *testing.T) {
+		slot := update(t, map[string]interface{}{"Title": "T", "Type": "b", "A": "apple", "B": "cherry"})
+		got, _ := json.Marshal(slot)
+		if string(got) != `{"0":"cherry"}` {
+			t.Errorf("Expected only the changed dynamic, got %s", got)
+		}
+	})
+
+	t.Run("else branch", func(t *testing.T) {
+		slot := update(t, map[string]interface{}{"Title": "T", "Type": "z", "A": "apple", "B": "cherry"})
+		got, _ := json.Marshal(slot)
+		if string(got) != `{"b":2}` {
+			t.Errorf("Expected a switch to the static else branch, got %s", got)
+		}
+	})
+}
+
+func TestSwitchCases(t *testing.T) {
+	tests := []struct {
+		name     string
+		src      string
+		isSwitch bool
+	}{
+		{"eq chain", `{{if eq .Type "a"}}A{{else if eq .Type "b"}}B{{end}}`, true},
+		{"eq chain with else", `{{if eq .N 1}}one{{else if eq .N 2}}two{{else}}many{{end}}`, true},
+		{"single case", `{{if eq .Type "a"}}A{{else}}B{{end}}`, false},
+		{"different subjects", `{{if eq .Type "a"}}A{{else if eq .Kind "b"}}B{{end}}`, false},
+		{"non-constant", `{{if eq .Type .Other}}A{{else if eq .Type "b"}}B{{end}}`, false},
+		{"other comparison", `{{if eq .Type "a"}}A{{else if ne .Type "b"}}B{{end}}`, false},
+		{"data-dependent statics", `{{if eq .Type "a"}}{{with .A}}A{{end}}{{else if eq .Type "b"}}B{{end}}`, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := map[string]interface{}{"Type": "b", "Kind": "b", "Other": "x", "N": 2, "A": "a"}
+			tree, err := parseTemplateToTree(tt.src, data, newKeyGenerator())
+			if err != nil {
+				t.Fatalf("parseTemplateToTree failed: %v", err)
+			}
+			encoded, _ := json.Marshal(tree)
+			if got := strings.Contains(string(encoded), `"bs"`); got != tt.isSwitch {
+				t.Errorf("Switch node = %v, want %v: %s", got, tt.isSwitch, encoded)
+			}
+
+			html, err := renderTreeToHTML(tree)
+			if err != nil {
+				t.Fatalf("renderTreeToHTML failed: %v", err)
+			}
+			var want bytes.Buffer
+			if err := template.Must(template.New("direct").Parse(tt.src)).Execute(&want, data); err != nil {
+				t.Fatalf("Execute failed: %v", err)
+			}
+			if html != want.String() {
+				t.Errorf("Tree renders %q, want %q", html, want.String())
+			}
+		})
+	}
+}
@@ -88,9 +88,11 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"hash"
 	"html/template"
 	"io"
 	"log"
+	"maps"
 	"net/http"
 	"os"
 	"path/filepath"
@@ -99,6 +101,8 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/gorilla/websocket"
 )
@@ -110,9 +114,47 @@ type Config struct {
 	Authenticator     Authenticator // User authentication and session grouping
 	AllowedOrigins    []string      // Allowed WebSocket origins (empty = allow all in dev, restrict in prod)
 	WebSocketDisabled bool
-	LoadingDisabled   bool     // Disables automatic loading indicator on page load
-	TemplateFiles     []string // If set, overrides auto-discovery
-	DevMode           bool     // Development mode - use local client library instead of CDN
+	LoadingDisabled   bool          // Disables automatic loading indicator on page load
+	TemplateFiles     []string      // If set, overrides auto-discovery
+	DevMode           bool          // Development mode - use local client library instead of CDN
+	ActionTimeout     time.Duration // Maximum time a Change may run before the client gets a timeout error (0 = no limit)
+	SSEHeartbeat      time.Duration // Interval between keep-alive comments on server-sent event streams
+	ResumeWindow      time.Duration // How long a disconnected client can resume from its last update
+	NonceWindow       time.Duration // How long action nonces are remembered to skip retried actions
+	DevOverlay        bool          // Forward tree analyzer warnings to the client in DevMode
+	MaxTemplateDepth  int           // How deep a recursive {{template}} may nest inside itself
+	KeyAttributes     []string      // Attribute names identifying range items, in priority order (nil = built-in list)
+	SuppressUnchanged bool          // Write nothing from ExecuteUpdates when the content is unchanged
+	AnimatedRemovals  bool          // Mark removals of range items so the client animates them out
+	ActionFallbacks   bool          // Wrap lvt-click buttons in forms posting their action, for pages without JavaScript
+	StrictData        bool          // Fail Execute and ExecuteUpdates on references to fields missing from the data
+	ClientPreloadURL  string        // Client library URL announced with a preload Link header on the initial page
+	BroadcastCoalesce time.Duration // Merge broadcasts to a connection within this window into one update (0 = send each)
+	OutboundQueue     int           // Messages queued per WebSocket connection before a slow client is dropped (0 = no queue)
+	IdleTimeout       time.Duration // Close WebSocket connections without activity for this long (0 = never)
+	MaxGroupConns     int           // WebSocket connections allowed per session group (0 = no limit)
+	MaxPayloadSize    int           // Bytes of data a single action may carry (0 = no limit)
+	BranchStatics     bool          // Reference statics the client cached instead of re-sending them, see WithBranchStaticsCache
+	IDGenerator       func() string // Generates wrapper IDs (nil = random "lvt-" IDs)
+	ExistingRoot      string        // Selector of a template element used as the root instead of a wrapper div
+	MainTemplate      string        // Definition or file used as the main template of ParseFiles ("" = first file)
+
+	LocaleResolver func(r *http.Request) string // Resolves the locale exposed as lvt.Locale for a request
+	JSONEncoder    JSONEncoder                  // Encodes tree values in updates (nil = encoding/json)
+	Hasher         func() hash.Hash             // Hashes tree fingerprints and content-based item keys (nil = MD5)
+	Reconnect      *ReconnectPolicy             // Reconnect backoff sent to clients (nil = client default)
+	CSRFSecret     []byte                       // Keys per-session CSRF tokens (nil = no CSRF tokens), see WithCSRFProtection
+	fieldWatches   []fieldWatch                 // Callbacks for changed field values, see WithFieldWatch
+	itemLifecycles []itemLifecycle              // Callbacks for added and removed range items, see WithRangeItemLifecycle
+
+	OnUpdate  func(name string, tree TreeNode, bytes int) // Called after ExecuteUpdates writes an update
+	ViewModel func(store Store) interface{}               // Shapes a store into the data its template renders, see WithViewModel
+
+	FullReplacementThreshold float64 // Send the full tree when diff size exceeds this ratio of the HTML size (0 = never)
+
+	ProgressiveRanges map[string]int // Range fields streamed in batches of the given size, see WithProgressiveRange
+
+	ActionPayloadSizes map[string]int // Per-action overrides of MaxPayloadSize, see WithActionPayloadSize
 }
 
 // Template represents a live template with caching and tree-based optimization capabilities.
@@ -132,6 +174,32 @@ type Template struct {
 	keyGen          *keyGenerator       // Per-template key generation for wrapper approach
 	config          Config              // Template configuration
 	analyzer        *TreeUpdateAnalyzer // Tree efficiency analyzer (enabled in DevMode)
+	baselines       *baselineCache      // Initial trees by fingerprint for CatchUpTree, shared with clones
+	lastWarnings    []string            // Analyzer warnings for the last ExecuteUpdates call
+	lastInputBound  []string            // Paths of input-bound slots in the last update, see lvtValue
+	lastChanged     bool                // Whether the last ExecuteUpdates call produced an update
+	userID          string              // Authenticated user for lvt.UserID, see setUser
+	userRoles       []string            // Roles of the authenticated user for lvt.HasRole
+	locale          string              // Locale for lvt.Locale, see WithLocaleResolver
+	watchedValues   map[int]interface{} // Last value of each field watch by index, see WithFieldWatch
+	staticsVersion  string              // Hash of the template source, see StaticsVersion
+	defaultData     interface{}         // Data rendered when Execute or ExecuteUpdates get nil, see SetDefaultData
+	sources         []string            // Template sources as parsed, before flattening, see WithLayout
+	pages           *layoutNavigation   // Navigation between the pages using t as their layout, see WithLayout
+	navigation      *layoutNavigation   // Navigation of the layout t is a page of
+	progress        map[string]int      // Items of each progressive range sent so far, see WithProgressiveRange
+	sentStatics     map[string]bool     // IDs of the statics the client cached, see cacheStatics
+	pendingStatics  map[string]bool     // IDs of statics in the update being built, see commitStatics
+	definitions     map[string]string   // File providing each {{define}}/{{block}} body, see Definitions
+	unfrozen        string              // Template text before data-lvt-static regions were rendered, see freezeStaticRegions
+	shared          bool                // Renders for every client of a handler, see freezeStaticRegions
+
+	rateLimits map[string]ActionRateLimit // Rate limits declared per action, see ActionRateLimits
+	rangeDiffs map[string]RangeDiff       // Diffs of the ranges the data's stores changed, see RangeDiffer
+
+	// mu serializes renders, as Execute and ExecuteUpdates mutate the diff state
+	// (lastData, lastHTML, lastTree, ...) shared by every caller of the template
+	mu sync.Mutex
 }
 
 // UpdateResponse wraps a tree update with metadata for form lifecycle.
@@ -146,6 +214,51 @@ type ResponseMetadata struct {
 	Success bool              `json:"success"` // true if no validation errors
 	Errors  map[string]string `json:"errors"`  // field errors
 	Action  string            `json:"action,omitempty"`
+
+	ResumeToken    string   `json:"resume_token,omitempty"`    // Sent on connect; presented in a resume message after reconnecting
+	Fingerprint    string   `json:"fingerprint,omitempty"`     // Identifies the tree after this update; presented with the resume token
+	StaticsVersion string   `json:"statics_version,omitempty"` // Statics version of the template, sent on connect; see Template.StaticsVersion
+	Warnings       []string `json:"warnings,omitempty"`        // Tree analyzer warnings for this update (DevMode with WithDevOverlay only)
+	InputBound     []string `json:"input_bound,omitempty"`     // Paths of changed slots rendered with lvt_value
+
+	Config *ClientConfig `json:"config,omitempty"` // Client settings, sent with the initial tree only
+	Head   *HeadMeta     `json:"head,omitempty"`   // Document title and meta tags set by the action or Mount
+
+	Redirect string `json:"redirect,omitempty"` // URL the client navigates to, see ActionContext.Redirect
+
+	// CorrelationID echoes the correlation_id the client sent with the action this update
+	// answers, so a client that applied the action optimistically can match the update and
+	// confirm its change, or roll it back when the update reports errors
+	CorrelationID string `json:"correlation_id,omitempty"`
+}
+
+// HeadMeta carries changes to the document head, which is outside the wrapper and so not
+// part of the tree. See ActionContext.SetTitle and SetMeta.
+type HeadMeta struct {
+	Title string            `json:"title,omitempty"`
+	Meta  map[string]string `json:"meta,omitempty"` // Content of <meta name="..."> tags by name
+}
+
+// ClientConfig carries server-side settings for the client library in the initial envelope
+type ClientConfig struct {
+	Reconnect *ReconnectPolicy `json:"reconnect,omitempty"` // See WithReconnectPolicy
+}
+
+// ReconnectPolicy is an exponential backoff for WebSocket reconnects: the first attempt
+// waits Min, each further attempt Factor times longer, up to Max
+type ReconnectPolicy struct {
+	Min    time.Duration
+	Max    time.Duration
+	Factor float64
+}
+
+// MarshalJSON writes the delays in milliseconds, as the client's timers expect
+func (p ReconnectPolicy) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		MinMs  int64   `json:"min_ms"`
+		MaxMs  int64   `json:"max_ms"`
+		Factor float64 `json:"factor"`
+	}{p.Min.Milliseconds(), p.Max.Milliseconds(), p.Factor})
 }
 
 // Option is a functional option for configuring a Template
@@ -193,6 +306,293 @@ func WithDevMode(enabled bool) Option {
 	}
 }
 
+// WithDevOverlay forwards tree analyzer warnings to the browser in ResponseMetadata.Warnings,
+// where the client library shows them in a dev overlay. It only has an effect together
+// with WithDevMode(true); without it the analyzer only logs server-side.
+func WithDevOverlay(enabled bool) Option {
+	return func(c *Config) {
+		c.DevOverlay = enabled
+	}
+}
+
+// WithMaxTemplateDepth sets how many levels a recursive template may nest inside itself,
+// e.g. a comment template invoking itself for its replies:
+//
+//	{{define "comment"}}<li>{{.Text}}<ul>{{range .Replies}}{{template "comment" .}}{{end}}</ul></li>{{end}}
+//
+// Recursion is unrolled at parse time, so each level becomes a nested range in the tree.
+// Rendering data that nests deeper than the limit fails with an error.
+//
+// Default: 10
+func WithMaxTemplateDepth(depth int) Option {
+	return func(c *Config) {
+		c.MaxTemplateDepth = depth
+	}
+}
+
+// WithKeyAttributes overrides which attributes identify range items, in priority order.
+//
+// By default items are keyed by data-lvt-key, data-key, key or id, and fall back to a hash
+// of their content, so apps with other conventions get keys that change with every edit.
+// With WithKeyAttributes([]string{"data-row-id"}), <tr data-row-id="{{.ID}}"> keys each
+// item by its ID and updates are sent as targeted range operations.
+func WithKeyAttributes(attrs []string) Option {
+	return func(c *Config) {
+		c.KeyAttributes = attrs
+	}
+}
+
+// WithSuppressUnchanged makes ExecuteUpdates write nothing when the rendered content is
+// identical to the last update, and Changed report false. Item keys are ignored for the
+// comparison, so identical data never produces a key-only update.
+//
+// Callers must handle an empty write; the live handler skips such broadcasts and answers
+// actions with an empty tree.
+func WithSuppressUnchanged(enabled bool) Option {
+	return func(c *Config) {
+		c.SuppressUnchanged = enabled
+	}
+}
+
+// WithAnimatedRemovals marks the removal of range items as animatable, so the client runs
+// an exit transition before taking the item out of the page: a fade, or the animation named
+// by the item's lvt-animate attribute, for lvt-animate-duration milliseconds (default 300).
+// On the wire the remove op carries a flag: ["r", key, {"animate": true}].
+func WithAnimatedRemovals(enabled bool) Option {
+	return func(c *Config) {
+		c.AnimatedRemovals = enabled
+	}
+}
+
+// WithStrictData makes Execute and ExecuteUpdates return an error when the template
+// references a field missing from the data, instead of rendering it empty, to catch
+// typos such as {{.Titel}}. It sets Option("missingkey=error") on the underlying template;
+// data is converted to a map before execution, so this covers struct fields too.
+func WithStrictData(enabled bool) Option {
+	return func(c *Config) {
+		c.StrictData = enabled
+	}
+}
+
+// WithClientPreload adds a Link header to the initial HTML response so browsers start
+// fetching the client library before they parse the page, e.g.
+//
+//	WithClientPreload("/livetemplate-client.js")
+//
+// sends "Link: </livetemplate-client.js>; rel=preload; as=script". HTTP/2 servers and CDNs
+// that support it can turn the header into a server push or 103 Early Hints.
+func WithClientPreload(url string) Option {
+	return func(c *Config) {
+		c.ClientPreloadURL = url
+	}
+}
+
+// WithIDGenerator replaces the random source of wrapper IDs (data-lvt-id), which are
+// generated when a template is parsed. Use it for deterministic IDs in tests and golden
+// files, or to draw IDs from an approved entropy source:
+//
+//	var n int
+//	tmpl := livetemplate.New("app", livetemplate.WithIDGenerator(func() string {
+//	    n++
+//	    return fmt.Sprintf("lvt-%d", n)
+//	}))
+//
+// IDs are written into an HTML attribute as-is, so they must not contain quotes or markup.
+// Templates parsed concurrently call the generator concurrently.
+func WithIDGenerator(generate func() string) Option {
+	return func(c *Config) {
+		c.IDGenerator = generate
+	}
+}
+
+// WithLocaleResolver sets the function resolving the BCP 47 locale of a connection or HTTP
+// request, e.g. from the Accept-Language header, a cookie or the user's profile. The
+// formatting functions use it, and templates can read it as lvt.Locale:
+//
+//	tmpl := livetemplate.New("shop", livetemplate.WithLocaleResolver(func(r *http.Request) string {
+//	    return r.Header.Get("Accept-Language")
+//	}))
+//
+//	<td>{{lvt_number .Price}}</td><td>{{lvt_date .OrderedAt}}</td>
+//
+// Values that fail to parse as a locale format as English.
+func WithLocaleResolver(resolve func(r *http.Request) string) Option {
+	return func(c *Config) {
+		c.LocaleResolver = resolve
+	}
+}
+
+// WithReconnectPolicy sets the backoff clients use to reconnect a dropped WebSocket, so it
+// can be tuned centrally instead of in every page's client options. The first attempt
+// waits min, each further attempt factor times longer, up to max; the delay resets once a
+// connection succeeds:
+//
+//	WithReconnectPolicy(500*time.Millisecond, 30*time.Second, 2)
+//
+// The policy is sent in the metadata of the initial tree (meta.config.reconnect).
+// A factor below 1 is treated as 1, i.e. a fixed delay of min.
+func WithReconnectPolicy(min, max time.Duration, factor float64) Option {
+	return func(c *Config) {
+		if factor < 1 {
+			factor = 1
+		}
+		if max < min {
+			max = min
+		}
+		c.Reconnect = &ReconnectPolicy{Min: min, Max: max, Factor: factor}
+	}
+}
+
+// JSONEncoder marshals the values of update trees, see WithJSONEncoder
+type JSONEncoder interface {
+	// Marshal returns the JSON encoding of v without HTML escaping (so "<" stays "<")
+	// and without a trailing newline
+	Marshal(v interface{}) ([]byte, error)
+}
+
+// stdJSONEncoder is the default JSONEncoder, based on encoding/json
+type stdJSONEncoder struct{}
+
+func (stdJSONEncoder) Marshal(v interface{}) ([]byte, error) {
+	return marshalValue(v)
+}
+
+// WithJSONEncoder replaces encoding/json for the values of update trees, e.g. with a faster
+// encoder or one with custom number handling:
+//
+//	type jsoniterEncoder struct{}
+//
+//	func (jsoniterEncoder) Marshal(v interface{}) ([]byte, error) {
+//	    var buf bytes.Buffer
+//	    enc := jsoniter.ConfigCompatibleWithStandardLibrary.NewEncoder(&buf)
+//	    enc.SetEscapeHTML(false)
+//	    err := enc.Encode(v)
+//	    return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), err
+//	}
+//
+//	tmpl := livetemplate.New("app", livetemplate.WithJSONEncoder(jsoniterEncoder{}))
+//
+// The top-level keys of a tree are still written in order by LiveTemplate, so updates stay
+// deterministic as long as the encoder writes map keys in a stable order.
+func WithJSONEncoder(enc JSONEncoder) Option {
+	return func(c *Config) {
+		c.JSONEncoder = enc
+	}
+}
+
+// WithHasher sets the hash used for tree fingerprints and for the keys of range items
+// without a key attribute, which are hashed from their content. MD5 is the default; a
+// faster non-cryptographic hash speeds up large ranges:
+//
+//	tmpl := livetemplate.New("feed", livetemplate.WithHasher(func() hash.Hash { return fnv.New64a() }))
+//
+// The hash must produce at least 64 bits. Fingerprints only need to match within a
+// deployment, so changing the hash makes resuming clients fetch a full tree once.
+func WithHasher(newHash func() hash.Hash) Option {
+	return func(c *Config) {
+		c.Hasher = newHash
+	}
+}
+
+// WithOnUpdate registers a callback invoked after every update ExecuteUpdates writes
+// successfully, including those the live handler sends to clients. It receives the template
+// name, the update tree and the number of bytes written - useful for analytics or cache warming.
+//
+// The callback runs synchronously on the rendering goroutine, so keep it fast and don't
+// modify the tree.
+func WithOnUpdate(fn func(name string, tree TreeNode, bytes int)) Option {
+	return func(c *Config) {
+		c.OnUpdate = fn
+	}
+}
+
+// WithActionTimeout bounds how long a store's Change may run for a single action.
+//
+// When the timeout elapses, the client receives an error response (reported under the
+// "_general" field) and the connection keeps processing messages. The timed-out Change
+// is not interrupted - it keeps running in the background and its head changes and
+// redirect are discarded - so handlers doing slow I/O should still honor their own
+// deadlines. Later actions of the session group, over any connection or request, wait for
+// it to return before using the stores, so its writes to the store appear in the next
+// update.
+//
+// Default: 0 (no timeout)
+func WithActionTimeout(d time.Duration) Option {
+	return func(c *Config) {
+		c.ActionTimeout = d
+	}
+}
+
+// WithFullReplacementThreshold makes ExecuteUpdates send the full tree, statics included,
+// when a diff grows larger than ratio times the size of the rendered HTML.
+//
+// Many scattered changes can produce a diff that costs more to send and apply than
+// re-rendering. With a ratio of 0.5, any update whose JSON is more than half the size of
+// the page is replaced by a full-replacement update, which clients apply like the
+// initial render.
+//
+// Default: 0 (always send diffs)
+func WithFullReplacementThreshold(ratio float64) Option {
+	return func(c *Config) {
+		c.FullReplacementThreshold = ratio
+	}
+}
+
+// WithSSEHeartbeat sets how often a comment line is written to idle server-sent event
+// streams, so proxies and load balancers don't close them.
+//
+// Default: 15 seconds
+func WithSSEHeartbeat(d time.Duration) Option {
+	return func(c *Config) {
+		c.SSEHeartbeat = d
+	}
+}
+
+// WithResumeWindow sets how long the server keeps a disconnected WebSocket client's last
+// tree so it can resume after a network blip.
+//
+// A client that reconnects within the window with its resume token and fingerprint
+// receives only what changed while it was away, instead of the full tree with statics.
+// After the window, or if the fingerprint doesn't match, it gets a full render.
+//
+// Default: 2 minutes
+func WithResumeWindow(d time.Duration) Option {
+	return func(c *Config) {
+		c.ResumeWindow = d
+	}
+}
+
+// WithBroadcastCoalesce merges the broadcasts a connection receives within window into a
+// single update. Each broadcast carries the full data, so the connection gets one diff
+// against the latest data instead of one frame per broadcast - useful when shared state
+// changes in bursts, like a busy chat room.
+//
+// This applies to Broadcast, BroadcastToUsers, BroadcastToGroup and the automatic sync of
+// other tabs after an action; the response to the acting connection is never delayed. That
+// response renders the connection's current state, so a broadcast still pending for it is
+// dropped rather than sent later over newer state.
+//
+// Default: 0 (each broadcast is sent immediately)
+func WithBroadcastCoalesce(window time.Duration) Option {
+	return func(c *Config) {
+		c.BroadcastCoalesce = window
+	}
+}
+
+// WithMaxConnectionsPerGroup limits the WebSocket connections and server-sent event
+// streams of a session group, so one user can't hold thousands of tabs open. WebSocket
+// connections over the limit are closed right after the upgrade with close code 1008
+// (policy violation), which the client library doesn't reconnect from; streams over the
+// limit are refused with 429 Too Many Requests. The limit is checked as the connection registers, so connections
+// opened at the same moment can't exceed it.
+//
+// Default: 0 (no limit)
+func WithMaxConnectionsPerGroup(n int) Option {
+	return func(c *Config) {
+		c.MaxGroupConns = n
+	}
+}
+
 // WithAuthenticator sets a custom authenticator for user identification and session grouping.
 //
 // The authenticator determines:
@@ -314,10 +714,11 @@ func New(name string, opts ...Option) *Template {
 	analyzer.Enabled = config.DevMode
 
 	tmpl := &Template{
-		name:     name,
-		keyGen:   newKeyGenerator(),
-		config:   config,
-		analyzer: analyzer,
+		name:      name,
+		keyGen:    newKeyGeneratorFor(config),
+		config:    config,
+		analyzer:  analyzer,
+		baselines: newBaselineCache(),
 	}
 
 	// Auto-discover and parse templates if not explicitly provided
@@ -345,22 +746,31 @@ func (t *Template) Clone() (*Template, error) {
 	analyzer := NewTreeUpdateAnalyzer()
 	analyzer.Enabled = t.config.DevMode
 
+	templateStr := t.templateStr
+	if t.unfrozen != "" {
+		templateStr = t.unfrozen // The clone renders its own data-lvt-static regions
+	}
+
 	clone := &Template{
 		name:        t.name,
-		templateStr: t.templateStr,
+		templateStr: templateStr,
 		wrapperID:   t.wrapperID, // Share wrapper ID
-		keyGen:      newKeyGenerator(),
+		keyGen:      newKeyGeneratorFor(t.config),
 		config:      t.config, // Preserve configuration
 		analyzer:    analyzer,
+		baselines:   t.baselines, // Share remembered initial trees
+		defaultData: t.defaultData,
 		// Don't copy lastData, lastHTML, lastTree, etc. - start fresh
 	}
 
 	// Re-parse the template from source
-	if t.templateStr != "" {
-		_, err := clone.Parse(t.templateStr)
+	if templateStr != "" {
+		_, err := clone.Parse(templateStr)
 		if err != nil {
 			return nil, fmt.Errorf("failed to re-parse template: %w", err)
 		}
+		clone.sources = t.sources // Keep the unflattened sources, so a cloned layout still has its blocks
+		clone.definitions = t.definitions
 	}
 
 	return clone, nil
@@ -369,18 +779,25 @@ func (t *Template) Clone() (*Template, error) {
 // Parse parses text as a template body for the template t.
 // This matches the signature of html/template.Template.Parse().
 func (t *Template) Parse(text string) (*Template, error) {
+	// Always generate wrapper ID for consistent update targeting
+	t.wrapperID = t.newWrapperID()
+
+	// Instances parsing the same source, like per-connection clones, only wrap it
+	key := t.parsedSourceKey(text)
+	if parsed := cachedParsedSource(key); parsed != nil {
+		return t.parseWrapped(parsed)
+	}
+
 	// Normalize template spacing to handle formatter-added spaces
 	// This prevents issues when formatters add spaces like "{{ range" instead of "{{range"
 	text = normalizeTemplateSpacing(text)
+	source := text
 
 	// Determine if this is a full HTML document
 	isFullHTML := strings.Contains(text, "<!DOCTYPE") || strings.Contains(text, "<html")
 
-	// Always generate wrapper ID for consistent update targeting
-	t.wrapperID = generateRandomID()
-
 	// First, parse WITHOUT wrapper to check if flattening is needed
-	tmpl, err := template.New(t.name).Parse(text)
+	tmpl, err := template.New(t.name).Funcs(builtinFuncs).Parse(text)
 	if err != nil {
 		return nil, fmt.Errorf("template parse error: %w", err)
 	}
@@ -388,7 +805,7 @@ func (t *Template) Parse(text string) (*Template, error) {
 	// Check if template uses composition features and flatten if needed
 	if hasTemplateComposition(tmpl) {
 		// Flatten the template to resolve all {{define}}/{{template}}/{{block}}
-		flattenedStr, err := flattenTemplate(tmpl)
+		flattenedStr, err := flattenTemplateWithDepth(tmpl, t.maxTemplateDepth())
 		if err != nil {
 			return nil, fmt.Errorf("template flattening failed: %w", err)
 		}
@@ -398,22 +815,37 @@ func (t *Template) Parse(text string) (*Template, error) {
 		text = flattenedStr
 	}
 
-	// Now add wrapper to the (possibly flattened) template for execution
-	var templateContent string
-	if isFullHTML {
-		// Inject wrapper div around body content
-		templateContent = injectWrapperDiv(text, t.wrapperID, t.config.LoadingDisabled)
-	} else {
-		// For standalone templates, wrap the entire content
-		loadingAttr := ""
-		if !t.config.LoadingDisabled {
-			loadingAttr = ` data-lvt-loading="true"`
-		}
-		templateContent = fmt.Sprintf(`<div data-lvt-id="%s"%s>%s</div>`, t.wrapperID, loadingAttr, text)
+	// Forms posting the actions of buttons for browsers without JavaScript
+	if t.config.ActionFallbacks {
+		text = addActionFallbacks(text)
+	}
+
+	parsed := &parsedSource{
+		source:         source,
+		text:           text,
+		isFullHTML:     isFullHTML,
+		staticsVersion: staticsVersionOf(text),
+		rateLimits:     parseActionRateLimits(text),
+	}
+	if _, err := t.parseWrapped(parsed); err != nil {
+		return nil, err
+	}
+	cacheParsedSource(key, parsed)
+	return t, nil
+}
+
+// parseWrapped adds the wrapper to the (possibly flattened) text of parsed and parses it
+// for execution
+func (t *Template) parseWrapped(parsed *parsedSource) (*Template, error) {
+	t.staticsVersion = parsed.staticsVersion
+	text := t.scopeElementIDs(parsed.text)
+	templateContent, err := t.wrap(text, parsed.isFullHTML)
+	if err != nil {
+		return nil, err
 	}
 
 	// Parse the template with wrapper for execution
-	tmpl, err = template.New(t.name).Parse(templateContent)
+	tmpl, err := template.New(t.name).Funcs(builtinFuncs).Option(t.missingKeyOption()).Parse(templateContent)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse template with wrapper: %w", err)
 	}
@@ -421,6 +853,11 @@ func (t *Template) Parse(text string) (*Template, error) {
 	// Store the template text for tree generation (flattened if it had composition)
 	t.templateStr = text
 	t.tmpl = tmpl
+	t.bindLocale()
+	t.rateLimits = parsed.rateLimits
+	t.sources = []string{parsed.source}
+	t.definitions = nil
+	t.unfrozen = ""
 
 	// Validate that tree generation works with this template
 	// This ensures templates with {{define}}/{{block}} are caught during initialization
@@ -433,15 +870,30 @@ func (t *Template) Parse(text string) (*Template, error) {
 
 // ParseFiles parses the named files and associates the resulting templates with t.
 // This matches the signature of html/template.Template.ParseFiles().
+//
+// The first file is the main template, unless WithMainTemplate names another. When several
+// files provide a body for the same name, the flattened template uses, in order of
+// precedence:
+//  1. an explicit {{define}}, over any {{block}} default of that name;
+//  2. of several {{define}}s, the one in the later file;
+//  3. of several {{block}} defaults, the one in the later file.
+//
+// So a page's {{define "content"}} replaces a layout's {{block "content" .}} whether it is
+// listed before or after the layout. Definitions reports which file won for each name.
+//
+// A syntax error in one of the files is returned as a ParseError with its file and line.
 func (t *Template) ParseFiles(filenames ...string) (*Template, error) {
 	if len(filenames) == 0 {
 		return nil, fmt.Errorf("no files specified")
 	}
 
-	// Read the first file as the main template
-	content, err := os.ReadFile(filenames[0])
-	if err != nil {
-		return nil, fmt.Errorf("failed to read file %s: %w", filenames[0], err)
+	texts := make([]string, len(filenames))
+	for i, filename := range filenames {
+		content, err := os.ReadFile(filename)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read file %s: %w", filename, err)
+		}
+		texts[i] = string(content)
 	}
 
 	// Use the first file's base name as template name if not already set
@@ -449,41 +901,65 @@ func (t *Template) ParseFiles(filenames ...string) (*Template, error) {
 		t.name = filepath.Base(filenames[0])
 	}
 
-	// Normalize template spacing
-	text := normalizeTemplateSpacing(string(content))
-
-	// Determine if this is a full HTML document
-	isFullHTML := strings.Contains(text, "<!DOCTYPE") || strings.Contains(text, "<html")
+	names, texts, err := t.selectMainTemplate(filenames, texts)
+	if err != nil {
+		return nil, err
+	}
 
 	// Always generate wrapper ID for consistent update targeting
-	t.wrapperID = generateRandomID()
+	t.wrapperID = t.newWrapperID()
+
+	return t.parseSources(names, texts)
+}
+
+// parseSources parses texts, named for errors, as one template set whose first text is the
+// main template, and wraps it in the wrapper div with t.wrapperID
+func (t *Template) parseSources(names, texts []string) (*Template, error) {
+	// Normalize template spacing
+	text := normalizeTemplateSpacing(texts[0])
 
 	// First, parse WITHOUT wrapper to check if flattening is needed
-	tmpl, err := template.New(t.name).Parse(text)
+	tmpl, err := template.New(t.name).Funcs(builtinFuncs).Parse(text)
 	if err != nil {
-		return nil, fmt.Errorf("template parse error: %w", err)
+		return nil, newParseError(names[0], err)
 	}
 
-	// Parse additional files if provided (for template composition)
-	if len(filenames) > 1 {
-		for _, filename := range filenames[1:] {
-			content, err := os.ReadFile(filename)
-			if err != nil {
-				return nil, fmt.Errorf("failed to read file %s: %w", filename, err)
-			}
+	// Track {{block}} defaults and {{define}} overrides so explicit definitions win
+	// regardless of file order
+	overrides := newBlockOverrides()
+	if err := overrides.record(t.name, names[0], text); err != nil {
+		return nil, newParseError(names[0], err)
+	}
 
-			// Parse additional templates into the same template set
-			_, err = tmpl.Parse(string(content))
-			if err != nil {
-				return nil, fmt.Errorf("failed to parse file %s: %w", filename, err)
-			}
+	// Parse additional files if provided (for template composition). Their top-level text
+	// would replace the body of the main template, which is restored afterwards.
+	mainTree := tmpl.Tree
+	for i, content := range texts[1:] {
+		// Parse additional templates into the same template set
+		_, err = tmpl.Parse(content)
+		if err != nil {
+			return nil, newParseError(names[i+1], err)
+		}
+
+		if err := overrides.record(t.name, names[i+1], content); err != nil {
+			return nil, newParseError(names[i+1], err)
+		}
+	}
+
+	if tmpl.Tree != mainTree {
+		if tmpl, err = tmpl.AddParseTree(t.name, mainTree); err != nil {
+			return nil, fmt.Errorf("failed to restore main template: %w", err)
 		}
 	}
 
+	if err := overrides.apply(tmpl); err != nil {
+		return nil, err
+	}
+
 	// Now that all files are parsed, check if we need to flatten
 	if hasTemplateComposition(tmpl) {
 		// Flatten the complete template set to resolve all {{define}}/{{template}}/{{block}}
-		flattenedStr, err := flattenTemplate(tmpl)
+		flattenedStr, err := flattenTemplateWithDepth(tmpl, t.maxTemplateDepth())
 		if err != nil {
 			return nil, fmt.Errorf("template flattening failed: %w", err)
 		}
@@ -492,22 +968,25 @@ func (t *Template) ParseFiles(filenames ...string) (*Template, error) {
 		text = flattenedStr
 	}
 
+	// Forms posting the actions of buttons for browsers without JavaScript
+	if t.config.ActionFallbacks {
+		text = addActionFallbacks(text)
+	}
+
+	// Determine if this is a full HTML document, also when the main template only
+	// invokes the one that is (see WithMainTemplate)
+	isFullHTML := strings.Contains(text, "<!DOCTYPE") || strings.Contains(text, "<html")
+
 	// Now add wrapper to the (possibly flattened) template for execution
-	var templateContent string
-	if isFullHTML {
-		// Inject wrapper div around body content
-		templateContent = injectWrapperDiv(text, t.wrapperID, t.config.LoadingDisabled)
-	} else {
-		// For standalone templates, wrap the entire content
-		loadingAttr := ""
-		if !t.config.LoadingDisabled {
-			loadingAttr = ` data-lvt-loading="true"`
-		}
-		templateContent = fmt.Sprintf(`<div data-lvt-id="%s"%s>%s</div>`, t.wrapperID, loadingAttr, text)
+	t.staticsVersion = staticsVersionOf(text)
+	text = t.scopeElementIDs(text)
+	templateContent, err := t.wrap(text, isFullHTML)
+	if err != nil {
+		return nil, err
 	}
 
 	// Parse the template with wrapper for execution
-	tmpl, err = template.New(t.name).Parse(templateContent)
+	tmpl, err = template.New(t.name).Funcs(builtinFuncs).Option(t.missingKeyOption()).Parse(templateContent)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse template with wrapper: %w", err)
 	}
@@ -515,6 +994,11 @@ func (t *Template) ParseFiles(filenames ...string) (*Template, error) {
 	// Store the template text for tree generation (flattened if it had composition)
 	t.templateStr = text
 	t.tmpl = tmpl
+	t.bindLocale()
+	t.rateLimits = parseActionRateLimits(text)
+	t.sources = texts
+	t.definitions = overrides.origins
+	t.unfrozen = ""
 
 	// Validate that tree generation works with this template
 	if err := t.validateTreeGeneration(); err != nil {
@@ -539,6 +1023,20 @@ func (t *Template) ParseGlob(pattern string) (*Template, error) {
 	return t.ParseFiles(filenames...)
 }
 
+// Definitions returns the file whose body is used for each {{define}} and {{block}} name
+// of a template parsed with ParseFiles or ParseGlob, following the precedence documented
+// on ParseFiles. Returns nil for templates parsed from a string.
+func (t *Template) Definitions() map[string]string {
+	return maps.Clone(t.definitions)
+}
+
+// Source returns the template text that t renders: the text given to Parse, or the main
+// file of ParseFiles, after {{define}}/{{template}}/{{block}} are flattened and without the
+// wrapper div. Returns "" before the template is parsed.
+func (t *Template) Source() string {
+	return t.templateStr
+}
+
 // Execute applies a parsed template to the specified data object,
 // writing the output to wr. The template is rendered as a complete HTML page
 // with wrapper injection for full HTML documents.
@@ -552,6 +1050,12 @@ func (t *Template) Execute(wr io.Writer, data interface{}, errors ...map[string]
 	if t.tmpl == nil {
 		return fmt.Errorf("template not parsed")
 	}
+	if data == nil {
+		data = t.defaultData
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
 
 	var errMap map[string]string
 	if len(errors) > 0 {
@@ -560,9 +1064,12 @@ func (t *Template) Execute(wr io.Writer, data interface{}, errors ...map[string]
 	if errMap == nil {
 		errMap = make(map[string]string)
 	}
+	if err := t.freezeStaticRegions(data, errMap); err != nil {
+		return err
+	}
 
 	// Execute the template with wrapper injection and lvt context
-	htmlBytes, err := executeTemplateWithContext(t.tmpl, data, errMap, t.config.DevMode)
+	htmlBytes, err := executeTemplateWithContext(t.tmpl, data, t.templateContext(errMap))
 	if err != nil {
 		return err
 	}
@@ -570,19 +1077,52 @@ func (t *Template) Execute(wr io.Writer, data interface{}, errors ...map[string]
 	if err != nil {
 		return err
 	}
+	t.checkFieldWatches(data)
 
 	// Initialize caching state for future ExecuteUpdates calls
+	t.setBaseline(data, errMap)
+	return nil
+}
+
+// SetData makes data the baseline the next ExecuteUpdates diffs against, without writing
+// anything. It is for state that changed outside an action, e.g. in a background job, when
+// the client already shows it:
+//
+//	tmpl.SetData(state)          // client already shows state
+//	tmpl.ExecuteUpdates(w, next) // sends only what changed since state
+//
+// Like Execute, it resets the baseline to a full render of data.
+func (t *Template) SetData(data interface{}) {
+	if t.tmpl == nil {
+		return
+	}
+	if data == nil {
+		data = t.defaultData
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if err := t.freezeStaticRegions(data, nil); err != nil {
+		return
+	}
+	t.checkFieldWatches(data)
+	t.setBaseline(data, make(map[string]string))
+}
+
+// setBaseline renders data as the baseline for ExecuteUpdates. Called with t.mu held.
+func (t *Template) setBaseline(data interface{}, errMap map[string]string) {
 	// Execute template again to get HTML for caching
 	currentHTML, execErr := t.executeTemplateWithErrors(data, errMap)
 	if execErr != nil {
 		// Don't fail the main Execute call if caching setup fails
-		return nil
+		return
 	}
 
 	// Extract content from wrapper for consistent caching
 	var contentToCache string
 	if t.wrapperID != "" {
-		contentToCache = extractTemplateContent(currentHTML, t.wrapperID)
+		contentToCache = extractTemplateContent(currentHTML, t.wrapperID, t.rootID())
 	} else {
 		contentToCache = currentHTML
 	}
@@ -590,15 +1130,124 @@ func (t *Template) Execute(wr io.Writer, data interface{}, errors ...map[string]
 	// Set up caching state
 	t.lastData = data
 	t.lastHTML = contentToCache
+	t.progress = nil // The client holds every item
 
 	// Generate and cache initial tree structure
 	_, treeErr := t.generateInitialTree(currentHTML, data)
 	if treeErr != nil {
 		// Don't fail if tree generation fails, just skip caching
-		return nil
+		return
 	}
+	t.recordNavigation()
+}
 
-	return nil
+// RenderString is Execute into a string, for tests and caching rendered pages.
+// Like Execute, it records the render as the baseline for ExecuteUpdates.
+func (t *Template) RenderString(data interface{}, errors ...map[string]string) (string, error) {
+	var buf strings.Builder
+	if err := t.Execute(&buf, data, errors...); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// SetDefaultData sets the data rendered when Execute or ExecuteUpdates is called with nil
+// data, so a static-heavy page can produce its initial tree without a store:
+//
+//	tmpl.SetDefaultData(PageData{Title: "Pricing"})
+//	tmpl.ExecuteUpdates(w, nil)
+//
+// Clones keep the default data.
+func (t *Template) SetDefaultData(data interface{}) {
+	t.defaultData = data
+}
+
+// StaticsVersion identifies the statics of the template: it changes whenever the template
+// source does, e.g. with a deploy. It is written into the wrapper as data-lvt-sv, so a page
+// served from a CDN or cache tells which statics it was rendered with. A client resuming
+// with an older version than the server's gets a full tree with fresh statics instead of a
+// diff against statics it doesn't have. Returns "" before Parse.
+func (t *Template) StaticsVersion() string {
+	return t.staticsVersion
+}
+
+// wrapperAttrs returns the attributes of the wrapper div after data-lvt-id
+func (t *Template) wrapperAttrs() string {
+	attrs := fmt.Sprintf(` data-lvt-sv="%s"`, t.staticsVersion)
+	if !t.config.LoadingDisabled {
+		attrs += ` data-lvt-loading="true"`
+	}
+	return attrs
+}
+
+// Fingerprint identifies the tree a client holds after applying the last update from
+// ExecuteUpdates. Returns "" before the first render. See CatchUpTree.
+func (t *Template) Fingerprint() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.lastTree == nil {
+		return ""
+	}
+	return calculateFingerprint(t.lastTree, t.hasher())
+}
+
+// newWrapperID returns the ID of the wrapper div, from the generator set with WithIDGenerator if any
+func (t *Template) newWrapperID() string {
+	if t.config.IDGenerator != nil {
+		return t.config.IDGenerator()
+	}
+	return generateRandomID()
+}
+
+// missingKeyOption returns the html/template option for missing data keys, see WithStrictData
+func (t *Template) missingKeyOption() string {
+	if t.config.StrictData {
+		return "missingkey=error"
+	}
+	return "missingkey=default"
+}
+
+// hasher returns the constructor of the hash set with WithHasher, or MD5
+func (t *Template) hasher() func() hash.Hash {
+	if t.config.Hasher != nil {
+		return t.config.Hasher
+	}
+	return md5.New
+}
+
+// maxTemplateDepth returns the configured recursion depth for flattening
+func (t *Template) maxTemplateDepth() int {
+	if t.config.MaxTemplateDepth > 0 {
+		return t.config.MaxTemplateDepth
+	}
+	return defaultMaxTemplateDepth
+}
+
+// InputBoundSlots returns the paths of the input-bound slots ({{lvt_value}}) in the last
+// update written by ExecuteUpdates, e.g. ["2"] or ["1.0"] for a slot in a nested node
+func (t *Template) InputBoundSlots() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.lastInputBound
+}
+
+// Changed reports whether the last ExecuteUpdates call produced an update, i.e. wrote
+// something other than an empty tree
+func (t *Template) Changed() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.lastChanged
+}
+
+// overlayWarnings returns the analyzer warnings of the last ExecuteUpdates call for
+// ResponseMetadata, or nil unless both DevMode and DevOverlay are enabled
+func (t *Template) overlayWarnings() []string {
+	if !t.config.DevMode || !t.config.DevOverlay {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.lastWarnings
 }
 
 // ExecuteUpdates generates a tree structure of static and dynamic content
@@ -613,42 +1262,145 @@ func (t *Template) Execute(wr io.Writer, data interface{}, errors ...map[string]
 // 2. Runtime: Dynamic parts are hydrated with data and compared with previous state
 //
 // Optional errors parameter provides error context for template via lvt namespace.
+//
+// Calls on one Template are serialized, so sharing it between goroutines is memory-safe.
+// The diff state is still shared though: each call diffs against whatever the previous
+// caller rendered, so connections should each use their own Clone.
 func (t *Template) ExecuteUpdates(wr io.Writer, data interface{}, errors ...map[string]string) error {
+	return t.executeUpdates(wr, data, WireFormatJSON, "", errors...)
+}
+
+// ExecuteUpdatesIndent is like ExecuteUpdates but writes the update as indented JSON,
+// which is easier to read when logging updates during development:
+//
+//	if devMode {
+//	    tmpl.ExecuteUpdatesIndent(os.Stderr, state)
+//	}
+//
+// The update is the same as the compact one, so it advances the template's diff state
+// just like ExecuteUpdates. The live handler always sends compact updates.
+func (t *Template) ExecuteUpdatesIndent(wr io.Writer, data interface{}, errors ...map[string]string) error {
+	return t.executeUpdates(wr, data, WireFormatJSON, "  ", errors...)
+}
+
+// ExecuteUpdatesAs is like ExecuteUpdates but writes the update in format, e.g. as
+// MessagePack for a binary WebSocket frame, which is smaller for numeric-heavy pages:
+//
+//	var buf bytes.Buffer
+//	tmpl.ExecuteUpdatesAs(&buf, state, livetemplate.WireFormatMsgPack)
+//	conn.WriteMessage(websocket.BinaryMessage, buf.Bytes())
+//
+// The tree is the same in every format. The live handler and the client library use JSON.
+func (t *Template) ExecuteUpdatesAs(wr io.Writer, data interface{}, format WireFormat, errors ...map[string]string) error {
+	return t.executeUpdates(wr, data, format, "", errors...)
+}
+
+// executeUpdates implements ExecuteUpdates, writing the update in format and indenting
+// JSON when indent is not empty
+func (t *Template) executeUpdates(wr io.Writer, data interface{}, format WireFormat, indent string, errors ...map[string]string) error {
 	if t.tmpl == nil {
 		return fmt.Errorf("template not parsed")
 	}
+	if data == nil {
+		data = t.defaultData
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
 
 	var errMap map[string]string
 	if len(errors) > 0 {
 		errMap = errors[0]
 	}
+	if err := t.freezeStaticRegions(data, errMap); err != nil {
+		return err
+	}
+
+	// Content fingerprint of the tree the client holds, see WithSuppressUnchanged
+	prevTree := t.lastTree
+	var prevContent string
+	suppress := t.config.SuppressUnchanged && t.lastData != nil && prevTree != nil
+	if suppress {
+		prevContent = contentFingerprint(prevTree, t.hasher())
+	}
+
+	// A page of a layout diffs against the page the client navigated from
+	t.discardStatics()
+	t.adoptNavigation()
+	data = t.progressiveData(data)
+	t.collectRangeDiffs(data)
 
 	tree, err := t.generateTreeInternalWithErrors(data, errMap)
 	if err != nil {
 		return fmt.Errorf("tree generation failed: %w", err)
 	}
+	t.checkFieldWatches(data)
+	defer t.recordNavigation()
+
+	if suppress && contentFingerprint(t.lastTree, t.hasher()) == prevContent {
+		// Keep the client's baseline so later diffs reference the item keys it knows
+		t.lastTree = prevTree
+		t.lastChanged = false
+		t.lastInputBound = nil
+		t.lastWarnings = nil
+		t.discardStatics()
+		return nil
+	}
+	t.lastChanged = len(tree) > 0
+
+	t.lastInputBound = inputBoundPaths(tree)
 
 	// Analyze tree for efficiency issues (only in DevMode)
+	t.lastWarnings = nil
 	if t.analyzer != nil && t.analyzer.Enabled {
-		t.analyzer.AnalyzeUpdate(tree, t.name, t.templateStr)
+		t.lastWarnings = t.analyzer.AnalyzeUpdate(tree, t.name, t.templateStr)
+	}
+
+	if format == WireFormatMsgPack {
+		msgpackBytes, err := marshalMsgPack(tree, t.jsonEncoder())
+		if err != nil {
+			return fmt.Errorf("MessagePack encoding failed: %w", err)
+		}
+		return t.writeUpdate(wr, tree, msgpackBytes)
 	}
 
 	// Convert tree to ordered JSON with readable HTML (no escape sequences)
-	jsonBytes, err := marshalOrderedJSON(tree)
+	jsonBytes, err := marshalOrderedJSON(tree, t.jsonEncoder())
 	if err != nil {
 		return fmt.Errorf("JSON encoding failed: %w", err)
 	}
+	if indent != "" {
+		var indented bytes.Buffer
+		if err := json.Indent(&indented, jsonBytes, "", indent); err != nil {
+			return fmt.Errorf("JSON indentation failed: %w", err)
+		}
+		jsonBytes = indented.Bytes()
+	}
 
-	_, err = wr.Write(jsonBytes)
-	return err
+	return t.writeUpdate(wr, tree, jsonBytes)
+}
+
+// writeUpdate writes an encoded update and reports it to the OnUpdate callback
+func (t *Template) writeUpdate(wr io.Writer, tree treeNode, encoded []byte) error {
+	n, err := wr.Write(encoded)
+	if err != nil {
+		return err
+	}
+	t.commitStatics()
+
+	if t.config.OnUpdate != nil {
+		t.config.OnUpdate(t.name, TreeNode(tree), n)
+	}
+	return nil
 }
 
 // generateTreeInternalWithErrors is the internal implementation that returns treeNode with error context
 func (t *Template) generateTreeInternalWithErrors(data interface{}, errors map[string]string) (treeNode, error) {
 	// Initialize key generator if needed (but don't reset - keys should increment globally)
 	if t.keyGen == nil {
-		t.keyGen = newKeyGenerator()
+		t.keyGen = newKeyGeneratorFor(t.config)
 	}
+	t.keyGen.startRender()
 
 	// Convert data to include lvt context for consistent template execution
 	dataWithLvt := t.addLvtToData(data, errors)
@@ -669,7 +1421,7 @@ func (t *Template) generateTreeInternalWithErrors(data interface{}, errors map[s
 		// Extract content from wrapper for consistent caching
 		var contentToCache string
 		if t.wrapperID != "" {
-			contentToCache = extractTemplateContent(currentHTML, t.wrapperID)
+			contentToCache = extractTemplateContent(currentHTML, t.wrapperID, t.rootID())
 		} else {
 			contentToCache = currentHTML
 		}
@@ -690,46 +1442,37 @@ func (t *Template) addLvtToData(data interface{}, errors map[string]string) inte
 	}
 
 	// Use the same logic as executeTemplateWithContext to convert data
-	lvtContext := &TemplateContext{
-		errors:  errors,
-		DevMode: t.config.DevMode,
-	}
-
 	templateData := make(map[string]interface{})
-	templateData["lvt"] = lvtContext
-
-	val := reflect.ValueOf(data)
-	if val.Kind() == reflect.Ptr {
-		val = val.Elem()
-	}
+	templateData["lvt"] = t.templateContext(errors)
 
-	if val.Kind() == reflect.Struct {
-		typ := val.Type()
-		for i := 0; i < val.NumField(); i++ {
-			field := typ.Field(i)
+	copyTemplateFields(templateData, data)
 
-			if !field.IsExported() {
-				continue
-			}
+	return templateData
+}
 
-			fieldName := field.Name
-			if jsonTag := field.Tag.Get("json"); jsonTag != "" {
-				if commaIdx := strings.Index(jsonTag, ","); commaIdx > 0 {
-					fieldName = jsonTag[:commaIdx]
-				} else if jsonTag != "-" {
-					fieldName = jsonTag
-				}
-			}
-			templateData[fieldName] = val.Field(i).Interface()
-			templateData[field.Name] = val.Field(i).Interface()
-		}
-	} else if val.Kind() == reflect.Map {
-		for _, key := range val.MapKeys() {
-			templateData[key.String()] = val.MapIndex(key).Interface()
-		}
+// templateContext returns the lvt namespace for an execution with errors
+func (t *Template) templateContext(errors map[string]string) *TemplateContext {
+	return &TemplateContext{
+		errors:  errors,
+		roles:   t.userRoles,
+		DevMode: t.config.DevMode,
+		UserID:  t.userID,
+		Locale:  t.locale,
 	}
+}
 
-	return templateData
+// setUser makes the authenticated user available to the template as lvt.UserID and
+// lvt.HasRole. Handlers only set it on templates that serve a single user.
+func (t *Template) setUser(userID string, roles []string) {
+	t.userID = userID
+	t.userRoles = roles
+}
+
+// setLocale sets the locale available to the template as lvt.Locale, and used by
+// lvt_number and lvt_date
+func (t *Template) setLocale(locale string) {
+	t.locale = locale
+	t.bindLocale()
 }
 
 // executeTemplateWithErrors executes the template with given data and errors for lvt context
@@ -740,7 +1483,7 @@ func (t *Template) executeTemplateWithErrors(data interface{}, errors map[string
 	}
 
 	// Execute with lvt context
-	htmlBytes, err := executeTemplateWithContext(t.tmpl, data, errors, t.config.DevMode)
+	htmlBytes, err := executeTemplateWithContext(t.tmpl, data, t.templateContext(errors))
 	if err != nil {
 		return "", err
 	}
@@ -752,7 +1495,7 @@ func (t *Template) generateInitialTree(html string, data interface{}) (treeNode,
 	// Extract content from wrapper if we have one
 	var contentToAnalyze string
 	if t.wrapperID != "" {
-		contentToAnalyze = extractTemplateContent(html, t.wrapperID)
+		contentToAnalyze = extractTemplateContent(html, t.wrapperID, t.rootID())
 	} else {
 		contentToAnalyze = html
 	}
@@ -761,9 +1504,9 @@ func (t *Template) generateInitialTree(html string, data interface{}) (treeNode,
 	// We need the template source, not rendered HTML, so parseTemplateToTree can identify dynamics
 	var templateContent string
 	if t.wrapperID != "" {
-		// For templates with <body> tags, extract body content
+		// For templates with an existing root or <body> tags, extract the root or body content
 		// For templates without <body> tags (including flattened templates), use template as-is
-		bodyContent := extractTemplateBodyContent(t.templateStr)
+		bodyContent := t.treeSource()
 		// extractTemplateBodyContent returns the full template if no <body> tag found
 		// So we can use it directly - it will be the flattened template content without wrapper
 
@@ -784,12 +1527,14 @@ func (t *Template) generateInitialTree(html string, data interface{}) (treeNode,
 	// Cache the initial structure for future dynamics-only updates
 	t.initialTree = tree
 	t.hasInitialTree = true
+	t.sentStatics = nil
 
 	// Store complete tree as the baseline for comparison
 	t.lastTree = tree
 
 	// Calculate and store initial fingerprint for change detection
-	t.lastFingerprint = calculateFingerprint(tree)
+	t.lastFingerprint = calculateFingerprint(tree, t.hasher())
+	t.baselines.add(t.lastFingerprint, tree)
 
 	// Add fingerprint to tree for client-side tracking
 	return addFingerprintToTree(tree), nil
@@ -800,8 +1545,8 @@ func (t *Template) generateDiffBasedTree(oldHTML, newHTML string, oldData, newDa
 	// Extract content from wrapper if we have one for proper comparison
 	var oldContent, newContent string
 	if t.wrapperID != "" {
-		oldContent = extractTemplateContent(oldHTML, t.wrapperID)
-		newContent = extractTemplateContent(newHTML, t.wrapperID)
+		oldContent = extractTemplateContent(oldHTML, t.wrapperID, t.rootID())
+		newContent = extractTemplateContent(newHTML, t.wrapperID, t.rootID())
 	} else {
 		oldContent = oldHTML
 		newContent = newHTML
@@ -812,7 +1557,7 @@ func (t *Template) generateDiffBasedTree(oldHTML, newHTML string, oldData, newDa
 		// Generate complete tree with current data using the template instance's keyGen
 		// to ensure consistent key mapping across renders
 		// Don't strip scripts - they may contain template logic
-		bodyContent := extractTemplateBodyContent(t.templateStr)
+		bodyContent := t.treeSource()
 		templateContent := bodyContent
 
 		newTree, err := parseTemplateToTree(templateContent, newData, t.keyGen)
@@ -833,6 +1578,12 @@ func (t *Template) generateDiffBasedTree(oldHTML, newHTML string, oldData, newDa
 		t.lastHTML = newContent
 		t.lastTree = newTree
 
+		// Large diffs are cheaper to send as a full replacement
+		if t.exceedsFullReplacementThreshold(changedTree, newContent) {
+			t.discardStatics()
+			return addFingerprintToTree(newTree), nil
+		}
+
 		return changedTree, nil
 	}
 
@@ -843,7 +1594,7 @@ func (t *Template) generateDiffBasedTree(oldHTML, newHTML string, oldData, newDa
 	}
 
 	// Calculate and store fingerprint for the new tree
-	newFingerprint := calculateFingerprint(tree)
+	newFingerprint := calculateFingerprint(tree, t.hasher())
 	t.lastFingerprint = newFingerprint
 
 	// Update cached state AFTER successful tree generation (use extracted content)
@@ -854,6 +1605,21 @@ func (t *Template) generateDiffBasedTree(oldHTML, newHTML string, oldData, newDa
 	return addFingerprintToTree(tree), nil
 }
 
+// exceedsFullReplacementThreshold reports whether the encoded diff is larger than the
+// configured fraction of the rendered HTML (see WithFullReplacementThreshold)
+func (t *Template) exceedsFullReplacementThreshold(changes treeNode, html string) bool {
+	if t.config.FullReplacementThreshold <= 0 || len(html) == 0 {
+		return false
+	}
+
+	diffJSON, err := marshalOrderedJSON(changes, t.jsonEncoder())
+	if err != nil {
+		return false
+	}
+
+	return float64(len(diffJSON)) > t.config.FullReplacementThreshold*float64(len(html))
+}
+
 // stripStaticsRecursively removes all "s" and "f" keys from a tree node recursively
 // Also removes fields that become empty after stripping (empty strings or empty maps)
 func stripStaticsRecursively(node interface{}) interface{} {
@@ -861,7 +1627,7 @@ func stripStaticsRecursively(node interface{}) interface{} {
 	case treeNode:
 		result := make(map[string]interface{})
 		for k, val := range v {
-			if k == "s" || k == "f" {
+			if k == "s" || k == "f" || k == switchStaticsKey {
 				continue // Skip statics and fingerprint
 			}
 			stripped := stripStaticsRecursively(val)
@@ -874,7 +1640,7 @@ func stripStaticsRecursively(node interface{}) interface{} {
 	case map[string]interface{}:
 		result := make(map[string]interface{})
 		for k, val := range v {
-			if k == "s" || k == "f" {
+			if k == "s" || k == "f" || k == switchStaticsKey {
 				continue // Skip statics and fingerprint
 			}
 			stripped := stripStaticsRecursively(val)
@@ -884,6 +1650,8 @@ func stripStaticsRecursively(node interface{}) interface{} {
 			}
 		}
 		return result
+	case RangeOp:
+		return v.withoutStatics()
 	case []interface{}:
 		result := make([]interface{}, 0, len(v))
 		for _, item := range v {
@@ -940,7 +1708,8 @@ func (t *Template) compareTreesAndGetChangesWithPath(oldTree, newTree treeNode,
 		if _, isMatched := rangeMatches[currentPath]; isMatched {
 			// Generate differential operations for the entire range
 			shouldStripStatics := hasRangeItems(oldTree)
-			diffOps := generateRangeDifferentialOperations(oldTree, newTree, shouldStripStatics)
+			t.fireItemLifecycles(oldTree, newTree)
+			diffOps := t.animateRemovals(t.rangeOperations(oldTree, newTree, shouldStripStatics))
 
 			if len(diffOps) > 0 {
 				// Return the operations directly - the entire tree is the range
@@ -971,6 +1740,17 @@ func (t *Template) compareTreesAndGetChangesWithPath(oldTree, newTree treeNode,
 		}
 
 		oldValue, exists := oldTree[k]
+
+		// Static blobs ({{lvt_static}}) are only re-sent when their cache key changes
+		if oldKey, ok := staticBlobFingerprint(oldValue); ok && exists {
+			if newKey, ok := staticBlobFingerprint(newValue); ok {
+				if oldKey != newKey {
+					changes[k] = newValue
+				}
+				continue
+			}
+		}
+
 		if !exists {
 			// Field is NEW compared to last update
 			// If we're inside a new structure, client has never seen this, so include statics
@@ -1053,9 +1833,10 @@ func (t *Template) compareTreesAndGetChangesWithPath(oldTree, newTree treeNode,
 				// Only strip statics if BOTH old and new are range constructs AND old range has items
 				// Empty ranges {"d": [], "s": [""]} have never shown item templates to client
 				shouldStripStatics := isRangeConstruct(oldValue) && hasRangeItems(oldValue)
+				t.fireItemLifecycles(oldValue, newValue)
 
 				// Generate differential operations for matched range constructs
-				diffOps := generateRangeDifferentialOperations(oldValue, newValue, shouldStripStatics)
+				diffOps := t.animateRemovals(t.rangeOperations(oldValue, newValue, shouldStripStatics))
 				if len(diffOps) > 0 {
 					changes[k] = diffOps
 				} else {
@@ -1106,6 +1887,15 @@ func (t *Template) compareTreesAndGetChangesWithPath(oldTree, newTree treeNode,
 				if oldIsTree && newIsTree {
 					// Both are tree nodes - recursively compare them
 
+					// A switch node selecting another branch only sends the branch and its dynamics
+					if isSameSwitch(oldTreeNode, newTreeNode) {
+						oldBranch, _ := switchBranch(oldTreeNode)
+						if newBranch, _ := switchBranch(newTreeNode); newBranch != oldBranch {
+							changes[k] = switchBranchUpdate(newTreeNode)
+							continue
+						}
+					}
+
 					// Check if this is a fundamental structure change (not part of a range match)
 					// If the structures are completely different, treat nested content as new
 					_, isRangeMatch := rangeMatches[fieldPath]
@@ -1120,7 +1910,7 @@ func (t *Template) compareTreesAndGetChangesWithPath(oldTree, newTree treeNode,
 					if structureChanged && !(oldHasRange && newHasRange) {
 						// Structure changed and this isn't just range item updates
 						// This includes: non-range → non-range, non-range → range, range → non-range
-						changes[k] = newValue
+						changes[k] = t.cacheStatics(newValue)
 					} else {
 						// Structure similar, do normal diff
 						nestedChanges := t.compareTreesAndGetChangesWithPath(oldTreeNode, newTreeNode, insideNewStructure || structureChanged, fieldPath, rangeMatches)
@@ -1219,7 +2009,7 @@ func (t *Template) compareTreesAndGetChangesWithPath(oldTree, newTree treeNode,
 						}
 					} else {
 						// Client doesn't have structure - send WITH statics
-						changes[k] = newValue
+						changes[k] = t.cacheStatics(newValue)
 					}
 				} else {
 					// At least one is a primitive value or type changed - send new value as-is
@@ -1265,6 +2055,11 @@ func (t *Template) fieldExistsInTree(key string, tree treeNode) bool {
 // areStructuresSimilar checks if two tree structures are fundamentally similar
 // Returns true if they have similar structure (same static keys), false if completely different
 func areStructuresSimilar(oldTree, newTree treeNode) bool {
+	// Renders of a switch node share the statics of every branch, whichever is selected
+	if isSameSwitch(oldTree, newTree) {
+		return true
+	}
+
 	// Check if both have statics - if statics differ, structures are different
 	oldStatics, oldHasS := oldTree["s"]
 	newStatics, newHasS := newTree["s"]
@@ -1693,7 +2488,7 @@ func findKeyPositionFromStatics(statics interface{}) int {
 }
 
 // getItemKey extracts the key from a range item using the statics structure
-func getItemKey(itemMap map[string]interface{}, statics interface{}) (string, bool) {
+func getItemKey(itemMap map[string]interface{}, statics interface{}, newHash func() hash.Hash) (string, bool) {
 	// First, check for reserved auto-generated key field
 	if autoKey, exists := itemMap["_k"]; exists {
 		if keyStr, ok := autoKey.(string); ok {
@@ -1712,12 +2507,12 @@ func getItemKey(itemMap map[string]interface{}, statics interface{}) (string, bo
 
 	// If no explicit key found, generate a content-based hash
 	// This ensures items have stable keys even without template key attributes
-	return generateItemHash(itemMap), true
+	return generateItemHash(itemMap, newHash), true
 }
 
-// generateItemHash creates a stable hash for a range item based on its content
+// generateItemHash creates a stable hash for a range item based on its content, with newHash
 // This is used when no explicit key attribute is provided in the template
-func generateItemHash(itemMap map[string]interface{}) string {
+func generateItemHash(itemMap map[string]interface{}, newHash func() hash.Hash) string {
 	// Create a canonical JSON representation for hashing
 	// Sort keys to ensure deterministic ordering
 	keys := make([]string, 0, len(itemMap))
@@ -1739,7 +2534,7 @@ func generateItemHash(itemMap map[string]interface{}) string {
 
 	// Hash the canonical representation
 	content := strings.Join(parts, "|")
-	hasher := md5.New()
+	hasher := newHash()
 	hasher.Write([]byte(content))
 	hash := hex.EncodeToString(hasher.Sum(nil))
 
@@ -1751,11 +2546,11 @@ func generateItemHash(itemMap map[string]interface{}) string {
 }
 
 // extractItemKeys extracts the keys from a slice of range items using the statics structure
-func extractItemKeys(items []interface{}, statics interface{}) []string {
+func extractItemKeys(items []interface{}, statics interface{}, newHash func() hash.Hash) []string {
 	var keys []string
 	for _, item := range items {
 		if itemMap, ok := item.(map[string]interface{}); ok {
-			if key, ok := getItemKey(itemMap, statics); ok {
+			if key, ok := getItemKey(itemMap, statics, newHash); ok {
 				keys = append(keys, key)
 			}
 		}
@@ -1783,7 +2578,7 @@ func detectPositionField(itemsByKey map[string]interface{}) string {
 }
 
 // isPureReordering checks if the items are the same but just in different order
-func isPureReordering(oldItems, newItems []interface{}, oldKeys, newKeys []string, statics interface{}) bool {
+func isPureReordering(oldItems, newItems []interface{}, oldKeys, newKeys []string, statics interface{}, newHash func() hash.Hash) bool {
 	// Must have same number of items
 	if len(oldKeys) != len(newKeys) {
 		return false
@@ -1816,7 +2611,7 @@ func isPureReordering(oldItems, newItems []interface{}, oldKeys, newKeys []strin
 
 	for _, item := range oldItems {
 		if itemMap, ok := item.(map[string]interface{}); ok {
-			if key, ok := getItemKey(itemMap, statics); ok {
+			if key, ok := getItemKey(itemMap, statics, newHash); ok {
 				oldItemsByKey[key] = item
 			}
 		}
@@ -1824,7 +2619,7 @@ func isPureReordering(oldItems, newItems []interface{}, oldKeys, newKeys []strin
 
 	for _, item := range newItems {
 		if itemMap, ok := item.(map[string]interface{}); ok {
-			if key, ok := getItemKey(itemMap, statics); ok {
+			if key, ok := getItemKey(itemMap, statics, newHash); ok {
 				newItemsByKey[key] = item
 			}
 		}
@@ -1895,7 +2690,7 @@ func isPureReordering(oldItems, newItems []interface{}, oldKeys, newKeys []strin
 // generateRangeDifferentialOperations generates differential operations for range constructs
 // stripStatics: if true, removes "s" keys from operations (client has cached them)
 // if false, keeps "s" keys (client hasn't seen this structure yet)
-func generateRangeDifferentialOperations(oldValue, newValue interface{}, stripStatics bool) []interface{} {
+func generateRangeDifferentialOperations(oldValue, newValue interface{}, stripStatics bool, newHash func() hash.Hash) []interface{} {
 	var operations []interface{}
 
 	// Try to extract map[string]interface{} from both treeNode and map[string]interface{} types
@@ -1962,12 +2757,12 @@ func generateRangeDifferentialOperations(oldValue, newValue interface{}, stripSt
 	statics := newRange["s"]
 
 	// First, check if this is a pure reordering (same items, different order)
-	oldKeys := extractItemKeys(oldItems, statics)
-	newKeys := extractItemKeys(newItems, statics)
+	oldKeys := extractItemKeys(oldItems, statics, newHash)
+	newKeys := extractItemKeys(newItems, statics, newHash)
 
-	if isPureReordering(oldItems, newItems, oldKeys, newKeys, statics) {
+	if isPureReordering(oldItems, newItems, oldKeys, newKeys, statics, newHash) {
 		// Generate ordering operation
-		return []interface{}{[]interface{}{"o", newKeys}}
+		return []interface{}{OrderOp{Keys: newKeys}}
 	}
 
 	// Create maps for easy lookup by keys
@@ -1977,7 +2772,7 @@ func generateRangeDifferentialOperations(oldValue, newValue interface{}, stripSt
 	// Map old items by their auto-generated keys
 	for _, item := range oldItems {
 		if itemMap, ok := item.(map[string]interface{}); ok {
-			if key, ok := getItemKey(itemMap, statics); ok {
+			if key, ok := getItemKey(itemMap, statics, newHash); ok {
 				oldItemsByKey[key] = item
 			}
 		}
@@ -1986,7 +2781,7 @@ func generateRangeDifferentialOperations(oldValue, newValue interface{}, stripSt
 	// Map new items by their auto-generated keys
 	for _, item := range newItems {
 		if itemMap, ok := item.(map[string]interface{}); ok {
-			if key, ok := getItemKey(itemMap, statics); ok {
+			if key, ok := getItemKey(itemMap, statics, newHash); ok {
 				newItemsByKey[key] = item
 			}
 		}
@@ -2002,7 +2797,7 @@ func generateRangeDifferentialOperations(oldValue, newValue interface{}, stripSt
 
 	for _, key := range sortedOldKeys {
 		if _, exists := newItemsByKey[key]; !exists {
-			operations = append(operations, []interface{}{"r", key})
+			operations = append(operations, RemoveOp{Key: key})
 		}
 	}
 
@@ -2025,16 +2820,16 @@ func generateRangeDifferentialOperations(oldValue, newValue interface{}, stripSt
 					// Empty key suggests an issue - log the item
 					_ = newItem // Placeholder to inspect in debugger
 				}
-				operations = append(operations, []interface{}{"u", key, changes})
+				operations = append(operations, UpdateOp{Key: key, Changes: changes})
 			}
 		}
 	}
 
 	// Smart insertion pattern detection for added items
-	addedKeys := findNewItems(oldItems, newItems, statics)
+	addedKeys := findNewItems(oldItems, newItems, statics, newHash)
 	if len(addedKeys) > 0 {
 		// Check if it's a complex pattern that should fall back to full state
-		if isComplexInsertionPattern(addedKeys, oldItems, newItems, statics) {
+		if isComplexInsertionPattern(addedKeys, oldItems, newItems, statics, newHash) {
 			// Fall back to full state replacement - return empty operations to trigger fallback
 			return operations
 		}
@@ -2046,21 +2841,30 @@ func generateRangeDifferentialOperations(oldValue, newValue interface{}, stripSt
 			itemsToAppend := append([]interface{}{}, newItems...)
 			// Use 'a' operation with statics so client can initialize range state
 			if !stripStatics {
-				operations = append(operations, []interface{}{"a", itemsToAppend, statics})
+				operations = append(operations, AppendOp{Items: itemsToAppend, Statics: statics})
 			} else {
-				operations = append(operations, []interface{}{"a", itemsToAppend})
+				operations = append(operations, AppendOp{Items: itemsToAppend})
 			}
 		} else {
 			// Range has existing items, use 'i' (insert) operations
 			// Check if all items are at the same position (single-point insertion)
-			if isSamePosition, targetKey, position := areAllItemsAtSamePosition(addedKeys, oldItems, newItems, statics); isSamePosition {
+			if isSamePosition, targetKey, position := areAllItemsAtSamePosition(addedKeys, oldItems, newItems, statics, newHash); isSamePosition && position == "after" && targetKey == lastItemKey(oldItems, statics, newHash) {
+				// Items added after the last one are appended in order with a single 'a'
+				itemsToAppend := make([]interface{}, 0, len(addedKeys))
+				for _, key := range addedKeys {
+					if item, exists := newItemsByKey[key]; exists {
+						itemsToAppend = append(itemsToAppend, item)
+					}
+				}
+				operations = append(operations, AppendOp{Items: itemsToAppend})
+			} else if isSamePosition {
 				// Generate individual insert operations for each item
 				for _, key := range addedKeys {
 					if item, exists := newItemsByKey[key]; exists {
 						if targetKey == "" {
-							operations = append(operations, []interface{}{"i", nil, position, item})
+							operations = append(operations, InsertOp{Position: position, Item: item})
 						} else {
-							operations = append(operations, []interface{}{"i", targetKey, position, item})
+							operations = append(operations, InsertOp{Target: targetKey, Position: position, Item: item})
 						}
 					}
 				}
@@ -2071,15 +2875,15 @@ func generateRangeDifferentialOperations(oldValue, newValue interface{}, stripSt
 						// Find position for this specific item
 						for i, item := range newItems {
 							if itemMap, ok := item.(map[string]interface{}); ok {
-								if itemKey, ok := getItemKey(itemMap, statics); ok && itemKey == key {
+								if itemKey, ok := getItemKey(itemMap, statics, newHash); ok && itemKey == key {
 									// Determine insertion position using 'i' operation (spec-compliant)
 									if i == 0 {
-										operations = append(operations, []interface{}{"i", nil, "start", newItem})
+										operations = append(operations, InsertOp{Position: "start", Item: newItem})
 									} else {
 										// Find the item before this one
 										if prevItem, ok := newItems[i-1].(map[string]interface{}); ok {
-											if prevKey, ok := getItemKey(prevItem, statics); ok {
-												operations = append(operations, []interface{}{"i", prevKey, "after", newItem})
+											if prevKey, ok := getItemKey(prevItem, statics, newHash); ok {
+												operations = append(operations, InsertOp{Target: prevKey, Position: "after", Item: newItem})
 											}
 										}
 									}
@@ -2104,6 +2908,20 @@ func generateRangeDifferentialOperations(oldValue, newValue interface{}, stripSt
 	return operations
 }
 
+// animateRemovals flags the remove ops among operations as animatable with WithAnimatedRemovals
+func (t *Template) animateRemovals(operations []interface{}) []interface{} {
+	if !t.config.AnimatedRemovals {
+		return operations
+	}
+	for i, op := range operations {
+		if remove, ok := op.(RemoveOp); ok {
+			remove.Animate = true
+			operations[i] = remove
+		}
+	}
+	return operations
+}
+
 // compareRangeItemsForChanges compares two range items and returns a map of field changes
 func compareRangeItemsForChanges(oldItem, newItem interface{}, statics interface{}) map[string]interface{} {
 	changes := make(map[string]interface{})
@@ -2182,11 +3000,11 @@ func compareRangeItemsForChanges(oldItem, newItem interface{}, statics interface
 // Smart pattern detection functions for enhanced insertion operations
 
 // findNewItems returns keys of items that exist in new but not in old
-func findNewItems(oldItems, newItems []interface{}, statics interface{}) []string {
+func findNewItems(oldItems, newItems []interface{}, statics interface{}, newHash func() hash.Hash) []string {
 	oldKeys := make(map[string]bool)
 	for _, item := range oldItems {
 		if itemMap, ok := item.(map[string]interface{}); ok {
-			if key, ok := getItemKey(itemMap, statics); ok {
+			if key, ok := getItemKey(itemMap, statics, newHash); ok {
 				oldKeys[key] = true
 			}
 		}
@@ -2195,7 +3013,7 @@ func findNewItems(oldItems, newItems []interface{}, statics interface{}) []strin
 	var newKeys []string
 	for _, item := range newItems {
 		if itemMap, ok := item.(map[string]interface{}); ok {
-			if key, ok := getItemKey(itemMap, statics); ok {
+			if key, ok := getItemKey(itemMap, statics, newHash); ok {
 				if !oldKeys[key] {
 					newKeys = append(newKeys, key)
 				}
@@ -2224,7 +3042,7 @@ func findNewItems(oldItems, newItems []interface{}, statics interface{}) []strin
 //
 // 		// Get the item at this position in newItems
 // 		if itemMap, ok := newItems[expectedIndex].(map[string]interface{}); ok {
-// 			if keyStr, ok := getItemKey(itemMap, statics); ok {
+// 			if keyStr, ok := getItemKey(itemMap, statics, newHash); ok {
 // 				if keyStr != key {
 // 					return false
 // 				}
@@ -2239,8 +3057,21 @@ func findNewItems(oldItems, newItems []interface{}, statics interface{}) []strin
 // 	return true
 // }
 
+// lastItemKey returns the key of the last item of a range, or "" when it has none
+func lastItemKey(items []interface{}, statics interface{}, newHash func() hash.Hash) string {
+	if len(items) == 0 {
+		return ""
+	}
+	if itemMap, ok := items[len(items)-1].(map[string]interface{}); ok {
+		if key, ok := getItemKey(itemMap, statics, newHash); ok {
+			return key
+		}
+	}
+	return ""
+}
+
 // areAllItemsAtSamePosition checks if all new items are inserted at the same position
-func areAllItemsAtSamePosition(newKeys []string, oldItems, newItems []interface{}, statics interface{}) (bool, string, string) {
+func areAllItemsAtSamePosition(newKeys []string, oldItems, newItems []interface{}, statics interface{}, newHash func() hash.Hash) (bool, string, string) {
 	if len(newKeys) <= 1 {
 		return false, "", "" // Single items don't need this optimization
 	}
@@ -2252,7 +3083,7 @@ func areAllItemsAtSamePosition(newKeys []string, oldItems, newItems []interface{
 
 	for i, item := range newItems {
 		if itemMap, ok := item.(map[string]interface{}); ok {
-			if keyStr, ok := getItemKey(itemMap, statics); ok {
+			if keyStr, ok := getItemKey(itemMap, statics, newHash); ok {
 				// Check if this is a new key
 				for _, newKey := range newKeys {
 					if newKey == keyStr {
@@ -2262,7 +3093,7 @@ func areAllItemsAtSamePosition(newKeys []string, oldItems, newItems []interface{
 							if i > 0 {
 								// Check the item before
 								if prevItem, ok := newItems[i-1].(map[string]interface{}); ok {
-									if prevKeyStr, ok := getItemKey(prevItem, statics); ok {
+									if prevKeyStr, ok := getItemKey(prevItem, statics, newHash); ok {
 										targetKey = prevKeyStr
 										position = "after"
 									}
@@ -2292,7 +3123,7 @@ func areAllItemsAtSamePosition(newKeys []string, oldItems, newItems []interface{
 		}
 
 		if itemMap, ok := newItems[expectedIndex].(map[string]interface{}); ok {
-			if keyStr, ok := getItemKey(itemMap, statics); ok {
+			if keyStr, ok := getItemKey(itemMap, statics, newHash); ok {
 				if keyStr != newKey {
 					return false, "", ""
 				}
@@ -2308,7 +3139,7 @@ func areAllItemsAtSamePosition(newKeys []string, oldItems, newItems []interface{
 }
 
 // isComplexInsertionPattern checks if the insertion pattern is too complex for simple operations
-func isComplexInsertionPattern(newKeys []string, oldItems, newItems []interface{}, statics interface{}) bool {
+func isComplexInsertionPattern(newKeys []string, oldItems, newItems []interface{}, statics interface{}, newHash func() hash.Hash) bool {
 	// Consider it complex if there are more than 3 separate insertion points
 	const maxInsertionPoints = 3
 
@@ -2317,10 +3148,14 @@ func isComplexInsertionPattern(newKeys []string, oldItems, newItems []interface{
 	}
 
 	insertionPoints := make(map[string]bool)
+	added := make(map[string]bool, len(newKeys))
+	for _, key := range newKeys {
+		added[key] = true
+	}
 
 	for i, item := range newItems {
 		if itemMap, ok := item.(map[string]interface{}); ok {
-			if keyStr, ok := getItemKey(itemMap, statics); ok {
+			if keyStr, ok := getItemKey(itemMap, statics, newHash); ok {
 				// Check if this is a new key
 				for _, newKey := range newKeys {
 					if newKey == keyStr {
@@ -2328,7 +3163,10 @@ func isComplexInsertionPattern(newKeys []string, oldItems, newItems []interface{
 						var insertionPoint string
 						if i > 0 {
 							if prevItem, ok := newItems[i-1].(map[string]interface{}); ok {
-								if prevKeyStr, ok := getItemKey(prevItem, statics); ok {
+								if prevKeyStr, ok := getItemKey(prevItem, statics, newHash); ok {
+									if added[prevKeyStr] {
+										break // Continues the block inserted at the previous item's point
+									}
 									insertionPoint = prevKeyStr + ":after"
 								}
 							}
@@ -2497,16 +3335,17 @@ func findCommonSuffix(s1, s2 string) string {
 	return s1[len1-minLen:]
 }
 
-// marshalOrderedJSON marshals a treeNode to JSON with keys in sorted order
-func marshalOrderedJSON(tree treeNode) ([]byte, error) {
-	if len(tree) == 0 {
-		return []byte("{}"), nil
+// jsonEncoder returns the encoder set with WithJSONEncoder, or the encoding/json default
+func (t *Template) jsonEncoder() JSONEncoder {
+	if t.config.JSONEncoder != nil {
+		return t.config.JSONEncoder
 	}
+	return stdJSONEncoder{}
+}
 
-	var buf bytes.Buffer
-	buf.WriteByte('{')
-
-	// Sort keys numerically for proper ordering
+// sortedTreeKeys returns the keys of a tree node in wire order: "s" first, numeric keys
+// numerically, then the rest lexicographically
+func sortedTreeKeys(tree map[string]interface{}) []string {
 	keys := make([]string, 0, len(tree))
 	for k := range tree {
 		keys = append(keys, k)
@@ -2534,6 +3373,20 @@ func marshalOrderedJSON(tree treeNode) ([]byte, error) {
 
 		return keys[i] < keys[j]
 	})
+	return keys
+}
+
+// marshalOrderedJSON marshals a treeNode to JSON with keys in sorted order, encoding the
+// values with enc
+func marshalOrderedJSON(tree treeNode, enc JSONEncoder) ([]byte, error) {
+	if len(tree) == 0 {
+		return []byte("{}"), nil
+	}
+
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+
+	keys := sortedTreeKeys(tree)
 
 	first := true
 	for _, key := range keys {
@@ -2551,7 +3404,7 @@ func marshalOrderedJSON(tree treeNode) ([]byte, error) {
 		buf.WriteByte(':')
 
 		// Write value with no HTML escaping
-		valueBytes, err := marshalValue(tree[key])
+		valueBytes, err := enc.Marshal(tree[key])
 		if err != nil {
 			return nil, err
 		}
@@ -2597,25 +3450,71 @@ func (t *Template) loadExistingKeyMappings(lastTree treeNode) {
 // For single store: actions like "increment", "decrement"
 // For multiple stores: actions like "counterstate.increment", "userstate.logout"
 // Store names are automatically derived from struct type names (case-insensitive matching).
+// Handle panics if two stores get the same name, e.g. two stores of the same type;
+// use HandleNamed to name them explicitly.
 func (t *Template) Handle(stores ...Store) LiveHandler {
 	if len(stores) == 0 {
 		panic("Handle requires at least one store")
 	}
 
-	// Build stores map with auto-derived names
+	// Single store mode - use empty key
+	if len(stores) == 1 {
+		return t.handle(Stores{"": stores[0]}, true)
+	}
+
+	// Multi-store mode - derive names from struct types
 	storesMap := make(Stores)
-	isSingleStore := len(stores) == 1
+	positions := make(map[string]int)
+	for i, store := range stores {
+		name := getStoreName(store)
+		if j, exists := positions[name]; exists {
+			panic(fmt.Sprintf("Handle: stores %d and %d are both named %q (type %T); use HandleNamed to give them distinct names", j, i, name, store))
+		}
+		positions[name] = i
+		storesMap[name] = store
+	}
+	return t.handle(storesMap, false)
+}
 
-	if isSingleStore {
-		// Single store mode - use empty key
-		storesMap[""] = stores[0]
-	} else {
-		// Multi-store mode - derive names from struct types
-		for _, store := range stores {
-			name := getStoreName(store)
-			storesMap[name] = store
+// HandleNamed creates an http.Handler for the template with explicitly named stores,
+// for several stores of the same type or names other than the type name:
+//
+//	handler := tmpl.HandleNamed(map[string]livetemplate.Store{
+//	    "inbox":   &MailboxState{Folder: "inbox"},
+//	    "archive": &MailboxState{Folder: "archive"},
+//	})
+//
+// Actions are addressed as "inbox.refresh" and the template accesses {{.inbox.Count}}.
+// Names are matched case-insensitively, so they must differ in more than case.
+func (t *Template) HandleNamed(stores map[string]Store) LiveHandler {
+	if len(stores) == 0 {
+		panic("HandleNamed requires at least one store")
+	}
+
+	names := make(map[string]string, len(stores))
+	for name := range stores {
+		if name == "" {
+			panic("HandleNamed: store names cannot be empty")
 		}
+		if other, exists := names[normalizeStoreName(name)]; exists {
+			panic(fmt.Sprintf("HandleNamed: store names %q and %q differ only in case", other, name))
+		}
+		names[normalizeStoreName(name)] = name
+	}
+
+	storesMap := make(Stores, len(stores))
+	for name, store := range stores {
+		storesMap[name] = store
 	}
+	return t.handle(storesMap, false)
+}
+
+// handle creates the LiveHandler for Handle and HandleNamed
+func (t *Template) handle(storesMap Stores, isSingleStore bool) LiveHandler {
+	// Anonymous page loads render on t itself, so it must not keep their static regions
+	t.mu.Lock()
+	t.shared = true
+	t.mu.Unlock()
 
 	// Create WebSocket upgrader with origin validation
 	upgrader := t.config.Upgrader
@@ -2651,12 +3550,38 @@ func (t *Template) Handle(stores ...Store) LiveHandler {
 		Authenticator:     t.config.Authenticator,
 		AllowedOrigins:    t.config.AllowedOrigins,
 		WebSocketDisabled: t.config.WebSocketDisabled,
-	}
-
-	return &liveHandler{
+		ActionTimeout:     t.config.ActionTimeout,
+		SSEHeartbeat:      t.config.SSEHeartbeat,
+		ResumeWindow:      t.config.ResumeWindow,
+		NonceWindow:       t.config.NonceWindow,
+		ClientPreloadURL:  t.config.ClientPreloadURL,
+		BroadcastCoalesce: t.config.BroadcastCoalesce,
+		OutboundQueue:     t.config.OutboundQueue,
+		IdleTimeout:       t.config.IdleTimeout,
+		MaxGroupConns:     t.config.MaxGroupConns,
+		MaxPayloadSize:    t.config.MaxPayloadSize,
+		ActionFallbacks:   t.config.ActionFallbacks,
+		LocaleResolver:    t.config.LocaleResolver,
+		Reconnect:         t.config.Reconnect,
+		CSRFSecret:        t.config.CSRFSecret,
+		ViewModel:         t.config.ViewModel,
+
+		ActionPayloadSizes: t.config.ActionPayloadSizes,
+	}
+
+	h := &liveHandler{
 		config:   config,
 		registry: NewConnectionRegistry(),
+		resume:   newResumeCache(),
+		nonces:   newNonceCache(config.NonceWindow),
 	}
+	if config.BroadcastCoalesce > 0 {
+		h.coalesce = newBroadcastCoalescer(config.BroadcastCoalesce, h.writeUpdate)
+	}
+	if len(config.CSRFSecret) > 0 {
+		h.csrf = &csrfGuard{secret: config.CSRFSecret}
+	}
+	return h
 }
 
 // validateTreeGeneration validates that tree generation works with this template
@@ -2668,6 +3593,38 @@ func (t *Template) validateTreeGeneration() error {
 	return nil
 }
 
+// Validate checks that the template executes against the given sample data and that
+// tree generation succeeds for it. It is intended for tests and CI, to catch
+// template/data mismatches (such as references to fields the data doesn't have)
+// before they surface as runtime errors or fallbacks.
+//
+// Validate doesn't modify the template's update state.
+func (t *Template) Validate(sample interface{}) error {
+	if t.tmpl == nil {
+		return fmt.Errorf("template %q not parsed", t.name)
+	}
+
+	// Missing keys must fail validation instead of rendering as empty values,
+	// since data is converted to a map before execution
+	strict, err := template.New(t.name).Funcs(builtinFuncs).Option("missingkey=error").Parse(t.templateStr)
+	if err != nil {
+		return fmt.Errorf("template %q failed to parse for validation: %w", t.name, err)
+	}
+
+	data := t.addLvtToData(sample, nil)
+
+	var buf bytes.Buffer
+	if err := strict.Execute(&buf, data); err != nil {
+		return fmt.Errorf("template %q does not execute against sample data of type %T: %w", t.name, sample, err)
+	}
+
+	if _, err := parseTemplateToTree(t.treeSource(), data, newKeyGenerator()); err != nil {
+		return fmt.Errorf("template %q does not support tree generation for sample data of type %T: %w", t.name, sample, err)
+	}
+
+	return nil
+}
+
 // getStoreName derives the store name from the struct type
 func getStoreName(store Store) string {
 	t := reflect.TypeOf(store)
@@ -4,13 +4,111 @@ import (
 	"bytes"
 	"fmt"
 	"html/template"
+	"regexp"
 	"strings"
 	"text/template/parse"
 )
 
-// flattenTemplate resolves all {{define}}/{{template}}/{{block}} constructs into a single template
-// This allows tree generation to work with templates that use Go's template composition features
+// blockDeclPattern matches {{block "name" ...}} declarations in template source
+var blockDeclPattern = regexp.MustCompile(`\{\{-?\s*block\s+"([^"]+)"`)
+
+// blockOverrides tracks {{block}} defaults and explicit {{define}} bodies across files
+// so that an explicit definition always replaces a block default, regardless of the
+// order in which the files are parsed. Go's template set simply keeps the last-parsed
+// body, which lets a layout parsed after a page silently restore its defaults.
+//
+// The resulting precedence, documented on ParseFiles, is:
+//  1. an explicit {{define}} beats a {{block}} default of the same name;
+//  2. among explicit definitions, the one in the later file wins;
+//  3. among {{block}} defaults without a definition, the one in the later file wins.
+type blockOverrides struct {
+	blocks  map[string]bool        // Names declared with {{block}}
+	defines map[string]*parse.Tree // Latest explicit {{define}} body per name
+	origins map[string]string      // File whose body wins per name, see Template.Definitions
+}
+
+// newBlockOverrides creates an empty override tracker
+func newBlockOverrides() *blockOverrides {
+	return &blockOverrides{
+		blocks:  make(map[string]bool),
+		defines: make(map[string]*parse.Tree),
+		origins: make(map[string]string),
+	}
+}
+
+// record scans the source of file for {{block}} declarations and explicit {{define}} bodies
+func (b *blockOverrides) record(name, file, text string) error {
+	declared := make(map[string]bool)
+	for _, match := range blockDeclPattern.FindAllStringSubmatch(text, -1) {
+		declared[match[1]] = true
+		b.blocks[match[1]] = true
+		if _, overridden := b.defines[match[1]]; !overridden {
+			b.origins[match[1]] = file
+		}
+	}
+
+	// Parse the source on its own to see which definitions it contributes
+	scratch, err := template.New(name).Funcs(builtinFuncs).Parse(text)
+	if err != nil {
+		return err
+	}
+
+	for _, t := range scratch.Templates() {
+		if t.Name() == name || declared[t.Name()] || t.Tree == nil || t.Tree.Root == nil {
+			continue
+		}
+		b.defines[t.Name()] = t.Tree
+		b.origins[t.Name()] = file
+	}
+
+	return nil
+}
+
+// apply re-associates the explicit definition of every overridden block with tmpl
+func (b *blockOverrides) apply(tmpl *template.Template) error {
+	for name := range b.blocks {
+		tree, overridden := b.defines[name]
+		if !overridden {
+			continue
+		}
+		if _, err := tmpl.AddParseTree(name, tree); err != nil {
+			return fmt.Errorf("failed to apply override for block %q: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// defaultMaxTemplateDepth is used when WithMaxTemplateDepth isn't set
+const defaultMaxTemplateDepth = 10
+
+// flattenState carries the template definitions and recursion bookkeeping through walkAndFlatten
+type flattenState struct {
+	templates map[string]*template.Template
+	maxDepth  int            // Maximum nesting of a template inside itself
+	depth     map[string]int // Current nesting per template name
+	base      []string       // Field path dot is rebased onto inside an inlined template, see rebasePipe
+}
+
+// recursionLimit is executed in place of a recursive {{template}} invocation nested deeper
+// than the flattening depth limit; it fails execution with a descriptive error
+func recursionLimit(name string, maxDepth int) (string, error) {
+	return "", fmt.Errorf("template %q exceeds maximum recursion depth of %d (see WithMaxTemplateDepth)", name, maxDepth)
+}
+
+// flattenTemplate flattens tmpl with the default recursion depth, see flattenTemplateWithDepth
 func flattenTemplate(tmpl *template.Template) (string, error) {
+	return flattenTemplateWithDepth(tmpl, defaultMaxTemplateDepth)
+}
+
+// flattenTemplateWithDepth resolves all {{define}}/{{template}}/{{block}} constructs into a single template
+// This allows tree generation to work with templates that use Go's template composition features.
+// A {{block}} is parsed as a definition plus an invocation, so it is inlined with whichever body
+// is associated with its name: the default, or an override applied via blockOverrides.
+//
+// Recursive templates (e.g. a comment invoking itself for its replies) are unrolled up to
+// maxDepth levels, so each level becomes a nested range in the tree. Past that, the invocation
+// is replaced by lvt_recursion_limit, which fails execution only if the data actually nests deeper.
+func flattenTemplateWithDepth(tmpl *template.Template, maxDepth int) (string, error) {
 	// The main template is the one that was explicitly named when calling New()
 	// This is the entry point for execution
 	mainTemplate := tmpl
@@ -56,9 +154,15 @@ func flattenTemplate(tmpl *template.Template) (string, error) {
 		templates[t.Name()] = t
 	}
 
+	state := &flattenState{
+		templates: templates,
+		maxDepth:  maxDepth,
+		depth:     map[string]int{mainTemplate.Name(): 1},
+	}
+
 	// Walk the tree and flatten
 	var buf bytes.Buffer
-	if err := walkAndFlatten(mainTemplate.Tree.Root, templates, &buf); err != nil {
+	if err := walkAndFlatten(mainTemplate.Tree.Root, state, &buf); err != nil {
 		return "", err
 	}
 
@@ -106,7 +210,7 @@ func hasExecutableContent(node *parse.ListNode) bool {
 }
 
 // walkAndFlatten recursively walks the AST and builds flattened template string
-func walkAndFlatten(node parse.Node, templates map[string]*template.Template, buf *bytes.Buffer) error {
+func walkAndFlatten(node parse.Node, state *flattenState, buf *bytes.Buffer) error {
 	if node == nil {
 		return nil
 	}
@@ -115,7 +219,7 @@ func walkAndFlatten(node parse.Node, templates map[string]*template.Template, bu
 	case *parse.ListNode:
 		// Process all child nodes
 		for _, child := range n.Nodes {
-			if err := walkAndFlatten(child, templates, buf); err != nil {
+			if err := walkAndFlatten(child, state, buf); err != nil {
 				return err
 			}
 		}
@@ -125,24 +229,24 @@ func walkAndFlatten(node parse.Node, templates map[string]*template.Template, bu
 		buf.Write(n.Text)
 
 	case *parse.ActionNode:
-		// {{.Field}}, {{.Method}}, etc. - copy as-is
+		// {{.Field}}, {{.Method}}, etc. - copy as-is, rebased inside an inlined template
 		buf.WriteString("{{")
-		buf.WriteString(n.String()[2 : len(n.String())-2]) // Remove outer {{ }}
+		buf.WriteString(rebasePipe(n.Pipe, state.base).String())
 		buf.WriteString("}}")
 
 	case *parse.IfNode:
 		// {{if}}...{{else}}...{{end}}
 		buf.WriteString("{{if ")
-		buf.WriteString(formatPipe(n.Pipe))
+		buf.WriteString(formatPipe(rebasePipe(n.Pipe, state.base)))
 		buf.WriteString("}}")
 
-		if err := walkAndFlatten(n.List, templates, buf); err != nil {
+		if err := walkAndFlatten(n.List, state, buf); err != nil {
 			return err
 		}
 
 		if n.ElseList != nil {
 			buf.WriteString("{{else}}")
-			if err := walkAndFlatten(n.ElseList, templates, buf); err != nil {
+			if err := walkAndFlatten(n.ElseList, state, buf); err != nil {
 				return err
 			}
 		}
@@ -152,16 +256,17 @@ func walkAndFlatten(node parse.Node, templates map[string]*template.Template, bu
 	case *parse.RangeNode:
 		// {{range}}...{{else}}...{{end}}
 		buf.WriteString("{{range ")
-		buf.WriteString(formatPipe(n.Pipe))
+		buf.WriteString(formatPipe(rebasePipe(n.Pipe, state.base)))
 		buf.WriteString("}}")
 
-		if err := walkAndFlatten(n.List, templates, buf); err != nil {
+		// Dot is the item in the body, so it isn't rebased
+		if err := walkWithBase(n.List, nil, state, buf); err != nil {
 			return err
 		}
 
 		if n.ElseList != nil {
 			buf.WriteString("{{else}}")
-			if err := walkAndFlatten(n.ElseList, templates, buf); err != nil {
+			if err := walkAndFlatten(n.ElseList, state, buf); err != nil {
 				return err
 			}
 		}
@@ -171,16 +276,16 @@ func walkAndFlatten(node parse.Node, templates map[string]*template.Template, bu
 	case *parse.WithNode:
 		// {{with}}...{{else}}...{{end}}
 		buf.WriteString("{{with ")
-		buf.WriteString(formatPipe(n.Pipe))
+		buf.WriteString(formatPipe(rebasePipe(n.Pipe, state.base)))
 		buf.WriteString("}}")
 
-		if err := walkAndFlatten(n.List, templates, buf); err != nil {
+		if err := walkWithBase(n.List, nil, state, buf); err != nil {
 			return err
 		}
 
 		if n.ElseList != nil {
 			buf.WriteString("{{else}}")
-			if err := walkAndFlatten(n.ElseList, templates, buf); err != nil {
+			if err := walkAndFlatten(n.ElseList, state, buf); err != nil {
 				return err
 			}
 		}
@@ -189,7 +294,7 @@ func walkAndFlatten(node parse.Node, templates map[string]*template.Template, bu
 
 	case *parse.TemplateNode:
 		// {{template "name" .}} - inline the template
-		refTemplate, exists := templates[n.Name]
+		refTemplate, exists := state.templates[n.Name]
 		if !exists {
 			return fmt.Errorf("template %q not defined", n.Name)
 		}
@@ -198,36 +303,33 @@ func walkAndFlatten(node parse.Node, templates map[string]*template.Template, bu
 			return fmt.Errorf("template %q has no parse tree", n.Name)
 		}
 
-		// Handle data context changes
-		// If template invocation passes a different context (e.g., {{template "name" .Field}}),
-		// we need to wrap the inlined template in {{with}} to change the context
-		needsContextWrapper := false
-		if n.Pipe != nil {
-			pipeStr := formatPipe(n.Pipe)
-			// Only wrap if the pipe is not "." (which means same context)
-			if pipeStr != "." {
-				needsContextWrapper = true
-			}
+		// Stop unrolling recursion at the depth limit
+		if state.depth[n.Name] >= state.maxDepth {
+			fmt.Fprintf(buf, "{{lvt_recursion_limit %q %d}}", n.Name, state.maxDepth)
+			return nil
+		}
+		state.depth[n.Name]++
+		defer func() { state.depth[n.Name]-- }()
+
+		// {{template "name" .Field}} passes a sub-value as dot: the inlined body is rebased
+		// onto the argument, so {{.Title}} becomes {{.Field.Title}}. This keeps the fields
+		// of cards invoked in a range relative to the item, and renders falsy arguments
+		// (e.g. a zero count) as Go does. Other arguments are bound with {{with}}.
+		pipe := rebasePipe(n.Pipe, state.base)
+		if base, ok := pipeFieldPath(pipe, state.base); ok {
+			return walkWithBase(refTemplate.Tree.Root, base, state, buf)
 		}
 
-		if needsContextWrapper {
-			// Wrap template body in {{with}} to change context
-			buf.WriteString("{{with ")
-			buf.WriteString(formatPipe(n.Pipe))
-			buf.WriteString("}}")
-
-			if err := walkAndFlatten(refTemplate.Tree.Root, templates, buf); err != nil {
-				return err
-			}
+		buf.WriteString("{{with ")
+		buf.WriteString(formatPipe(pipe))
+		buf.WriteString("}}")
 
-			buf.WriteString("{{end}}")
-		} else {
-			// No context change needed - inline as-is
-			if err := walkAndFlatten(refTemplate.Tree.Root, templates, buf); err != nil {
-				return err
-			}
+		if err := walkWithBase(refTemplate.Tree.Root, nil, state, buf); err != nil {
+			return err
 		}
 
+		buf.WriteString("{{end}}")
+
 	default:
 		// For any node type we don't explicitly handle, try to preserve as-is
 		// This includes BranchNode and other internal nodes
@@ -237,6 +339,65 @@ func walkAndFlatten(node parse.Node, templates map[string]*template.Template, bu
 	return nil
 }
 
+// walkWithBase flattens node with dot rebased onto base, see rebasePipe
+func walkWithBase(node parse.Node, base []string, state *flattenState, buf *bytes.Buffer) error {
+	saved := state.base
+	state.base = base
+	defer func() { state.base = saved }()
+	return walkAndFlatten(node, state, buf)
+}
+
+// pipeFieldPath returns the field path a {{template}} argument selects from dot: the
+// current base for "." (or no argument) and the field's path for .A.B. Reports false for
+// any other argument, e.g. a variable or function call.
+func pipeFieldPath(pipe *parse.PipeNode, base []string) ([]string, bool) {
+	if pipe == nil {
+		return base, true
+	}
+	if len(pipe.Decl) > 0 || len(pipe.Cmds) != 1 || len(pipe.Cmds[0].Args) != 1 {
+		return nil, false
+	}
+	switch arg := pipe.Cmds[0].Args[0].(type) {
+	case *parse.DotNode:
+		return base, true
+	case *parse.FieldNode:
+		return arg.Ident, true
+	}
+	return nil, false
+}
+
+// rebasePipe returns a copy of pipe reading its fields from base instead of dot, e.g.
+// {{.Title}} becomes {{.Item.Title}} and {{.}} becomes {{.Item}} for base [Item].
+// Variables, including $, are left as they are.
+func rebasePipe(pipe *parse.PipeNode, base []string) *parse.PipeNode {
+	if pipe == nil || len(base) == 0 {
+		return pipe
+	}
+	rebased := pipe.CopyPipe()
+	for _, cmd := range rebased.Cmds {
+		for i, arg := range cmd.Args {
+			cmd.Args[i] = rebaseArg(arg, base)
+		}
+	}
+	return rebased
+}
+
+// rebaseArg rebases a command argument of a copied pipe, see rebasePipe
+func rebaseArg(arg parse.Node, base []string) parse.Node {
+	switch a := arg.(type) {
+	case *parse.FieldNode:
+		ident := append(append([]string{}, base...), a.Ident...)
+		return &parse.FieldNode{NodeType: parse.NodeField, Pos: a.Pos, Ident: ident}
+	case *parse.DotNode:
+		return &parse.FieldNode{NodeType: parse.NodeField, Pos: a.Pos, Ident: append([]string{}, base...)}
+	case *parse.ChainNode:
+		a.Node = rebaseArg(a.Node, base)
+	case *parse.PipeNode:
+		return rebasePipe(a, base)
+	}
+	return arg
+}
+
 // formatPipe converts a pipe to its string representation
 func formatPipe(pipe *parse.PipeNode) string {
 	if pipe == nil {
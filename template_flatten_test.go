@@ -1,7 +1,12 @@
 package livetemplate
 
 import (
+	"bytes"
+	"encoding/json"
 	"html/template"
+	"os"
+	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 )
@@ -413,3 +418,312 @@ func TestFlattenTemplate_ErrorCases(t *testing.T) {
 		})
 	}
 }
+
+func TestFlattenTemplate_BlockOverride(t *testing.T) {
+	// Base layout declares a block with default content; the page overrides it
+	layout := `<!DOCTYPE html>
+<html>
+<head><title>{{.Title}}</title></head>
+<body>
+<main>{{block "content" .}}<p>Default content</p>{{end}}</main>
+</body>
+</html>`
+	page := `{{define "content"}}<h1>{{.Title}}</h1><p>Count: {{.Count}}</p>{{end}}`
+
+	dir := t.TempDir()
+	layoutFile := filepath.Join(dir, "layout.tmpl")
+	pageFile := filepath.Join(dir, "page.tmpl")
+	if err := os.WriteFile(layoutFile, []byte(layout), 0644); err != nil {
+		t.Fatalf("Failed to write layout: %v", err)
+	}
+	if err := os.WriteFile(pageFile, []byte(page), 0644); err != nil {
+		t.Fatalf("Failed to write page: %v", err)
+	}
+
+	orders := map[string][]string{
+		"layout first": {layoutFile, pageFile},
+		"page first":   {pageFile, layoutFile},
+	}
+
+	for name, files := range orders {
+		t.Run(name, func(t *testing.T) {
+			tmpl := New("layout", WithParseFiles(files...))
+
+			if strings.Contains(tmpl.templateStr, "Default content") {
+				t.Errorf("Block default should be replaced by override. Got: %s", tmpl.templateStr)
+			}
+			if !strings.Contains(tmpl.templateStr, "Count: {{.Count}}") {
+				t.Fatalf("Flattened template missing override content. Got: %s", tmpl.templateStr)
+			}
+
+			var buf bytes.Buffer
+			if err := tmpl.ExecuteUpdates(&buf, map[string]interface{}{"Title": "Home", "Count": 1}); err != nil {
+				t.Fatalf("First ExecuteUpdates failed: %v", err)
+			}
+
+			var first map[string]interface{}
+			if err := json.Unmarshal(buf.Bytes(), &first); err != nil {
+				t.Fatalf("Failed to parse first update: %v", err)
+			}
+			if _, hasStatics := first["s"]; !hasStatics {
+				t.Errorf("First update should include statics, got: %s", buf.String())
+			}
+
+			buf.Reset()
+			if err := tmpl.ExecuteUpdates(&buf, map[string]interface{}{"Title": "Home", "Count": 2}); err != nil {
+				t.Fatalf("Second ExecuteUpdates failed: %v", err)
+			}
+
+			var second map[string]interface{}
+			if err := json.Unmarshal(buf.Bytes(), &second); err != nil {
+				t.Fatalf("Failed to parse second update: %v", err)
+			}
+			if _, hasStatics := second["s"]; hasStatics {
+				t.Errorf("Update should contain dynamics only, got: %s", buf.String())
+			}
+			if len(second) != 1 {
+				t.Errorf("Expected exactly one changed dynamic, got: %s", buf.String())
+			}
+			if !strings.Contains(buf.String(), `"2"`) {
+				t.Errorf("Update missing new count value, got: %s", buf.String())
+			}
+		})
+	}
+}
+
+func TestFlattenTemplate_OverridePrecedence(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+		return path
+	}
+	base := write("base.tmpl", `<main>{{block "content" .}}<p>Default content</p>{{end}}</main>`+
+		`<footer>{{template "footer" .}}</footer>{{define "footer"}}Base footer{{end}}`)
+	first := write("first.tmpl", `{{define "footer"}}First footer{{end}}`)
+	second := write("second.tmpl", `{{define "footer"}}Second footer{{end}}{{define "content"}}<p>Override</p>{{end}}`)
+
+	tests := []struct {
+		name        string
+		files       []string
+		wantFooter  string
+		wantOrigins map[string]string
+	}{
+		{
+			name:        "later file wins",
+			files:       []string{base, first, second},
+			wantFooter:  "Second footer",
+			wantOrigins: map[string]string{"content": second, "footer": second},
+		},
+		{
+			name:        "reversed order",
+			files:       []string{base, second, first},
+			wantFooter:  "First footer",
+			wantOrigins: map[string]string{"content": second, "footer": first},
+		},
+		{
+			name:        "block default without override",
+			files:       []string{base, first},
+			wantFooter:  "First footer",
+			wantOrigins: map[string]string{"content": base, "footer": first},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl := New("base")
+			if _, err := tmpl.ParseFiles(tt.files...); err != nil {
+				t.Fatalf("ParseFiles failed: %v", err)
+			}
+
+			source := tmpl.Source()
+			if !strings.Contains(source, tt.wantFooter) {
+				t.Errorf("Expected the flattened template to use %q, got: %s", tt.wantFooter, source)
+			}
+			if tt.wantOrigins["content"] == second && !strings.Contains(source, "<p>Override</p>") {
+				t.Errorf("Expected the override of the content block, got: %s", source)
+			}
+			if got := tmpl.Definitions(); !reflect.DeepEqual(got, tt.wantOrigins) {
+				t.Errorf("Definitions() = %v, want %v", got, tt.wantOrigins)
+			}
+		})
+	}
+}
+
+func TestFlattenTemplate_Recursive(t *testing.T) {
+	type Comment struct {
+		Text    string
+		Replies []Comment
+	}
+	type Thread struct {
+		Comments []Comment
+	}
+
+	templateStr := `{{define "comment"}}<li>{{.Text}}{{if .Replies}}<ul>{{range .Replies}}{{template "comment" .}}{{end}}</ul>{{end}}</li>{{end}}` +
+		`<ul>{{range .Comments}}{{template "comment" .}}{{end}}</ul>`
+
+	thread := &Thread{Comments: []Comment{
+		{Text: "First", Replies: []Comment{
+			{Text: "Reply", Replies: []Comment{
+				{Text: "Nested reply"},
+			}},
+		}},
+		{Text: "Second"},
+	}}
+
+	t.Run("renders nested comments", func(t *testing.T) {
+		tmpl := New("recursive-test", WithMaxTemplateDepth(3))
+		if _, err := tmpl.Parse(templateStr); err != nil {
+			t.Fatalf("Parse failed: %v", err)
+		}
+
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, thread); err != nil {
+			t.Fatalf("Execute failed: %v", err)
+		}
+		want := "<ul><li>First<ul><li>Reply<ul><li>Nested reply</li></ul></li></ul></li><li>Second</li></ul>"
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("Expected rendered thread %q, got %q", want, buf.String())
+		}
+
+		// Fresh template, so the first update is the full tree
+		tmpl = New("recursive-test", WithMaxTemplateDepth(3))
+		if _, err := tmpl.Parse(templateStr); err != nil {
+			t.Fatalf("Parse failed: %v", err)
+		}
+		buf.Reset()
+		if err := tmpl.ExecuteUpdates(&buf, thread); err != nil {
+			t.Fatalf("ExecuteUpdates failed: %v", err)
+		}
+		var tree map[string]interface{}
+		if err := json.Unmarshal(buf.Bytes(), &tree); err != nil {
+			t.Fatalf("Invalid JSON: %v", err)
+		}
+		comments, _ := tree["0"].(map[string]interface{})
+		if _, ok := comments["d"]; !ok {
+			t.Errorf("Expected comments as a range, got %s", buf.String())
+		}
+		if strings.Count(buf.String(), `"d":`) < 3 {
+			t.Errorf("Expected a nested range per reply level, got %s", buf.String())
+		}
+		for _, text := range []string{"First", "Reply", "Nested reply", "Second"} {
+			if !strings.Contains(buf.String(), `"`+text+`"`) {
+				t.Errorf("Expected %q in tree, got %s", text, buf.String())
+			}
+		}
+
+		// Updating a nested reply only sends that change
+		thread.Comments[0].Replies[0].Replies[0].Text = "Edited reply"
+		buf.Reset()
+		if err := tmpl.ExecuteUpdates(&buf, thread); err != nil {
+			t.Fatalf("ExecuteUpdates failed: %v", err)
+		}
+		if !strings.Contains(buf.String(), "Edited reply") || strings.Contains(buf.String(), "Second") {
+			t.Errorf("Expected only the edited reply in the update, got %s", buf.String())
+		}
+	})
+
+	t.Run("exceeding depth errors", func(t *testing.T) {
+		tmpl := New("recursive-limit-test", WithMaxTemplateDepth(2))
+		if _, err := tmpl.Parse(templateStr); err != nil {
+			t.Fatalf("Parse failed: %v", err)
+		}
+
+		var buf bytes.Buffer
+		err := tmpl.Execute(&buf, thread)
+		if err == nil || !strings.Contains(err.Error(), "maximum recursion depth") {
+			t.Errorf("Expected recursion depth error, got %v", err)
+		}
+		if err := tmpl.ExecuteUpdates(&buf, thread); err == nil {
+			t.Error("Expected ExecuteUpdates to fail past the depth limit")
+		}
+
+		// Data within the limit still renders
+		buf.Reset()
+		shallow := &Thread{Comments: []Comment{{Text: "Only", Replies: []Comment{{Text: "One reply"}}}}}
+		if err := tmpl.Execute(&buf, shallow); err != nil {
+			t.Fatalf("Execute within depth limit failed: %v", err)
+		}
+	})
+}
+
+func TestFlattenTemplate_SubValueArgument(t *testing.T) {
+	type Product struct {
+		ID    string
+		Name  string
+		Stock int
+	}
+	type Row struct {
+		Product Product
+	}
+	type State struct {
+		Rows []Row
+	}
+
+	templateStr := `{{define "card"}}<div class="card" data-key="{{.ID}}"><h3>{{.Name}}</h3>{{template "stock" .Stock}}</div>{{end}}` +
+		`{{define "stock"}}<span>{{.}} left</span>{{end}}` +
+		`<section>{{range .Rows}}{{template "card" .Product}}{{end}}</section>`
+
+	t.Run("fields are rebased onto the argument", func(t *testing.T) {
+		tmpl, err := template.New(t.Name()).Parse(templateStr)
+		if err != nil {
+			t.Fatalf("Failed to parse template: %v", err)
+		}
+		flattened, err := flattenTemplate(tmpl)
+		if err != nil {
+			t.Fatalf("Failed to flatten template: %v", err)
+		}
+		for _, want := range []string{"{{.Product.ID}}", "{{.Product.Name}}", "{{.Product.Stock}} left"} {
+			if !strings.Contains(flattened, want) {
+				t.Errorf("Flattened template missing %s. Got: %s", want, flattened)
+			}
+		}
+		if strings.Contains(flattened, "{{with") {
+			t.Errorf("Field arguments should not be bound with {{with}}. Got: %s", flattened)
+		}
+	})
+
+	t.Run("cards in a range diff per item", func(t *testing.T) {
+		tmpl := New("cards")
+		if _, err := tmpl.Parse(templateStr); err != nil {
+			t.Fatalf("Failed to parse template: %v", err)
+		}
+
+		state := State{Rows: []Row{
+			{Product{"p1", "Lamp", 3}},
+			{Product{"p2", "Desk", 0}},
+		}}
+		var buf bytes.Buffer
+		if err := tmpl.ExecuteUpdates(&buf, state); err != nil {
+			t.Fatalf("Initial ExecuteUpdates failed: %v", err)
+		}
+		// A zero argument still renders the template it is passed to
+		if !strings.Contains(buf.String(), `"2":"0"`) || !strings.Contains(buf.String(), ` left</span>`) {
+			t.Errorf("Expected the zero stock to be rendered, got %s", buf.String())
+		}
+
+		state.Rows[1].Product.Stock = 7
+		buf.Reset()
+		if err := tmpl.ExecuteUpdates(&buf, state); err != nil {
+			t.Fatalf("Update ExecuteUpdates failed: %v", err)
+		}
+
+		var update map[string]interface{}
+		if err := json.Unmarshal(buf.Bytes(), &update); err != nil {
+			t.Fatalf("Failed to parse update JSON: %v", err)
+		}
+		ops, _ := update["0"].([]interface{})
+		if len(ops) != 1 {
+			t.Fatalf("Expected one range operation, got %s", buf.String())
+		}
+		op, _ := ops[0].([]interface{})
+		if len(op) != 3 || op[0] != "u" || op[1] != "p2" {
+			t.Errorf(`Expected ["u","p2",...], got %s`, buf.String())
+		}
+		if !strings.Contains(buf.String(), `"7"`) {
+			t.Errorf("Expected the new stock in the update, got %s", buf.String())
+		}
+	})
+}
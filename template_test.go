@@ -2,8 +2,20 @@ package livetemplate
 
 import (
 	"bytes"
+	"crypto/md5"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/fnv"
+	"html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
 	"strings"
+	"sync"
 	"testing"
 )
 
@@ -167,6 +179,163 @@ func TestTemplate_ParseFiles(t *testing.T) {
 	}
 }
 
+func TestTemplate_ParseFilesError(t *testing.T) {
+	dir := t.TempDir()
+	layout := filepath.Join(dir, "layout.html")
+	page := filepath.Join(dir, "page.html")
+	if err := os.WriteFile(layout, []byte("<main>\n{{block \"content\" .}}{{end}}\n</main>"), 0644); err != nil {
+		t.Fatalf("Failed to write layout: %v", err)
+	}
+	if err := os.WriteFile(page, []byte("{{define \"content\"}}\n<p>{{.Title}}</p>\n<p>{{if .Flash}}</p>\n{{end}}"), 0644); err != nil {
+		t.Fatalf("Failed to write page: %v", err)
+	}
+
+	_, err := New("parse-error-test").ParseFiles(layout, page)
+	var parseErr ParseError
+	if !errors.As(err, &parseErr) {
+		t.Fatalf("Expected a ParseError, got %T: %v", err, err)
+	}
+	if parseErr.File != page || parseErr.Line != 4 || parseErr.Msg == "" {
+		t.Errorf("Expected the error in %s at line 4, got %+v", page, parseErr)
+	}
+	if !strings.Contains(err.Error(), page+":4: ") {
+		t.Errorf("Expected the message to name the file and line, got %q", err.Error())
+	}
+}
+
+func TestWithMainTemplate(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+		return path
+	}
+	// The page comes first, as from a glob, and has top-level text like a stray newline
+	page := write("a_page.html", "{{define \"content\"}}<p>{{.Title}}</p>{{end}}\n<!-- page -->\n")
+	layout := write("layout.html", "<!DOCTYPE html><html><body><main>{{block \"content\" .}}{{end}}</main></body></html>")
+	shell := write("shell.html", "{{define \"shell\"}}<!DOCTYPE html><html><body><nav>{{.Title}}</nav>{{template \"content\" .}}</body></html>{{end}}")
+
+	render := func(t *testing.T, main string, files ...string) (*Template, string) {
+		t.Helper()
+		tmpl := New("main-template-test", WithMainTemplate(main))
+		if _, err := tmpl.ParseFiles(files...); err != nil {
+			t.Fatalf("ParseFiles failed: %v", err)
+		}
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, map[string]interface{}{"Title": "Home"}); err != nil {
+			t.Fatalf("Execute failed: %v", err)
+		}
+		return tmpl, buf.String()
+	}
+
+	t.Run("file", func(t *testing.T) {
+		for _, main := range []string{"layout.html", "layout"} {
+			tmpl, html := render(t, main, page, layout)
+			if !strings.Contains(html, "<main><p>Home</p></main>") || strings.Contains(html, "<!-- page -->") {
+				t.Errorf("Expected the layout as main template for %q, got %s", main, html)
+			}
+			if !strings.Contains(html, `<body><div data-lvt-id="`) {
+				t.Errorf("Expected the wrapper inside the body for %q, got %s", main, html)
+			}
+			if got := tmpl.Definitions()["content"]; got != page {
+				t.Errorf("Expected the page to provide the content, got %q", got)
+			}
+		}
+	})
+
+	t.Run("definition", func(t *testing.T) {
+		tmpl, html := render(t, "shell", page, shell)
+		if !strings.Contains(html, "<nav>Home</nav><p>Home</p>") {
+			t.Errorf("Expected the shell definition as main template, got %s", html)
+		}
+		if !strings.Contains(html, `<body><div data-lvt-id="`) {
+			t.Errorf("Expected the invoked document to get the wrapper inside the body, got %s", html)
+		}
+
+		var update bytes.Buffer
+		if err := tmpl.ExecuteUpdates(&update, map[string]interface{}{"Title": "About"}); err != nil {
+			t.Fatalf("ExecuteUpdates failed: %v", err)
+		}
+		if strings.Count(update.String(), "About") != 2 {
+			t.Errorf("Expected both titles in the update, got %s", update.String())
+		}
+	})
+
+	t.Run("unknown", func(t *testing.T) {
+		if _, err := New("main-template-test", WithMainTemplate("missing")).ParseFiles(page, layout); err == nil {
+			t.Error("Expected ParseFiles to fail for an unknown main template")
+		}
+	})
+}
+
+func TestTemplate_ParseFullHTMLWithoutBody(t *testing.T) {
+	tests := []struct {
+		name    string
+		source  string
+		wantErr string
+	}{
+		{"no body", `<!DOCTYPE html><html><head><title>{{.Title}}</title></head><main>{{.Title}}</main></html>`, "no <body> element"},
+		{"unterminated body tag", `<!DOCTYPE html><html><body class="app"`, "unterminated <body> tag"},
+		{"no closing body tag", `<!DOCTYPE html><html><body><main>{{.Title}}</main></html>`, "no </body> closing tag"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New("no-body-test").Parse(tt.source)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Expected an error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+
+	t.Run("body", func(t *testing.T) {
+		html, err := injectWrapperDiv(`<html><body class="app"><p>x</p><script src="a.js"></script></body></html>`, "lvt-1", "")
+		if err != nil {
+			t.Fatalf("injectWrapperDiv failed: %v", err)
+		}
+		if want := `<html><body class="app"><div data-lvt-id="lvt-1"><p>x</p></div><script src="a.js"></script></body></html>`; html != want {
+			t.Errorf("Expected %s, got %s", want, html)
+		}
+	})
+}
+
+func TestTemplate_Source(t *testing.T) {
+	if got := New("unparsed-test").Source(); got != "" {
+		t.Errorf("Source() of an unparsed template = %q, want empty", got)
+	}
+
+	src := `<div><h1>{{.Title}}</h1><p>{{.Body}}</p></div>`
+	tmpl := New("source-test")
+	if _, err := tmpl.Parse(src); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if got := tmpl.Source(); got != src {
+		t.Errorf("Source() = %q, want %q", got, src)
+	}
+
+	clone, err := tmpl.Clone()
+	if err != nil {
+		t.Fatalf("Clone failed: %v", err)
+	}
+	if got := clone.Source(); got != src {
+		t.Errorf("Clone().Source() = %q, want %q", got, src)
+	}
+
+	composed := New("source-composed-test")
+	if _, err := composed.Parse(`{{define "title"}}<h1>{{.}}</h1>{{end}}<div>{{template "title" .Title}}</div>`); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	got := composed.Source()
+	if strings.Contains(got, "{{define") || strings.Contains(got, "{{template") || !strings.Contains(got, "<h1>") {
+		t.Errorf("Expected Source() to be flattened, got %q", got)
+	}
+	if strings.Contains(got, "data-lvt-id") {
+		t.Errorf("Source() should not include the wrapper, got %q", got)
+	}
+}
+
 func TestTemplate_Execute(t *testing.T) {
 	tests := []struct {
 		name         string
@@ -646,6 +815,205 @@ func BenchmarkTemplate_ExecuteUpdates(b *testing.B) {
 	}
 }
 
+// cloneBenchSource composes named templates, so parsing it includes flattening
+const cloneBenchSource = `{{define "row"}}<li data-key="{{.ID}}">{{.Name}} <button lvt-click="remove" lvt-data-id="{{.ID}}">x</button></li>{{end}}` +
+	`<h1>{{.Title}}</h1>{{if .Items}}<ul>{{range .Items}}{{template "row" .}}{{end}}</ul>{{else}}<p>Empty</p>{{end}}`
+
+func BenchmarkTemplate_Clone(b *testing.B) {
+	tmpl := New("benchmark")
+	if _, err := tmpl.Parse(cloneBenchSource); err != nil {
+		b.Fatalf("Parse failed: %v", err)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := tmpl.Clone(); err != nil {
+			b.Fatalf("Clone failed: %v", err)
+		}
+	}
+}
+
+// BenchmarkTemplate_ParseUncached parses a new source every time, as Clone did before
+// parsed sources were cached
+func BenchmarkTemplate_ParseUncached(b *testing.B) {
+	tmpl := New("benchmark")
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := tmpl.Parse(cloneBenchSource + "<!-- " + strconv.Itoa(i) + " -->"); err != nil {
+			b.Fatalf("Parse failed: %v", err)
+		}
+	}
+}
+
+func TestTemplate_CloneReusesParsedSource(t *testing.T) {
+	tmpl := New("clone-cache-test")
+	if _, err := tmpl.Parse(cloneBenchSource); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	clone, err := tmpl.Clone()
+	if err != nil {
+		t.Fatalf("Clone failed: %v", err)
+	}
+
+	if clone.templateStr != tmpl.templateStr || clone.StaticsVersion() != tmpl.StaticsVersion() {
+		t.Errorf("Expected the clone to have the same text and statics version")
+	}
+
+	data := map[string]interface{}{
+		"Title": "Todos",
+		"Items": []map[string]interface{}{{"ID": "a", "Name": "Alpha"}, {"ID": "b", "Name": "Beta"}},
+	}
+	var original, cloned bytes.Buffer
+	if err := tmpl.ExecuteUpdates(&original, data); err != nil {
+		t.Fatalf("ExecuteUpdates failed: %v", err)
+	}
+	if err := clone.ExecuteUpdates(&cloned, data); err != nil {
+		t.Fatalf("Clone ExecuteUpdates failed: %v", err)
+	}
+	if original.String() != cloned.String() {
+		t.Errorf("Expected identical initial trees, got\n%s\n%s", original.String(), cloned.String())
+	}
+
+	// Clones parse the text of tmpl; once cached, later clones reuse that parsed source
+	// instead of flattening it again (see BenchmarkTemplate_Clone for the timing)
+	key := clone.parsedSourceKey(tmpl.templateStr)
+	parsed := cachedParsedSource(key)
+	if parsed == nil {
+		t.Fatal("Expected the clone to cache its parsed source")
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := tmpl.Clone(); err != nil {
+			t.Fatalf("Clone failed: %v", err)
+		}
+		if cachedParsedSource(key) != parsed {
+			t.Fatalf("Expected clone %d to reuse the cached parsed source", i+2)
+		}
+	}
+}
+
+func TestTemplate_Validate(t *testing.T) {
+	type Profile struct {
+		Name string
+	}
+
+	t.Run("sample data matches template", func(t *testing.T) {
+		tmpl := New("validate-ok")
+		if _, err := tmpl.Parse(`<p>{{.Name}}</p>`); err != nil {
+			t.Fatalf("Parse failed: %v", err)
+		}
+
+		if err := tmpl.Validate(Profile{Name: "Ada"}); err != nil {
+			t.Errorf("Validate() unexpected error: %v", err)
+		}
+	})
+
+	t.Run("template references missing field", func(t *testing.T) {
+		tmpl := New("validate-missing")
+		if _, err := tmpl.Parse(`<p>{{.Name}}</p><span>{{.Missing}}</span>`); err != nil {
+			t.Fatalf("Parse failed: %v", err)
+		}
+
+		err := tmpl.Validate(Profile{Name: "Ada"})
+		if err == nil {
+			t.Fatal("Validate() expected error for missing field, got nil")
+		}
+		if !strings.Contains(err.Error(), "Missing") {
+			t.Errorf("Validate() error should name the missing field, got: %v", err)
+		}
+		if !strings.Contains(err.Error(), "validate-missing") {
+			t.Errorf("Validate() error should name the template, got: %v", err)
+		}
+	})
+
+	t.Run("validation does not affect update state", func(t *testing.T) {
+		tmpl := New("validate-state")
+		if _, err := tmpl.Parse(`<p>{{.Name}}</p>`); err != nil {
+			t.Fatalf("Parse failed: %v", err)
+		}
+
+		if err := tmpl.Validate(Profile{Name: "Ada"}); err != nil {
+			t.Fatalf("Validate() unexpected error: %v", err)
+		}
+
+		var buf bytes.Buffer
+		if err := tmpl.ExecuteUpdates(&buf, Profile{Name: "Ada"}); err != nil {
+			t.Fatalf("ExecuteUpdates failed: %v", err)
+		}
+		if !strings.Contains(buf.String(), `"s"`) {
+			t.Errorf("First update after Validate should include statics, got: %s", buf.String())
+		}
+	})
+}
+
+func TestTemplate_FullReplacementThreshold(t *testing.T) {
+	type Page struct {
+		A, B, C, D, E string
+	}
+
+	const templateStr = `<p>{{.A}}</p><p>{{.B}}</p><p>{{.C}}</p><p>{{.D}}</p><p>{{.E}}</p>`
+
+	render := func(t *testing.T, tmpl *Template, page *Page) map[string]interface{} {
+		t.Helper()
+		var buf bytes.Buffer
+		if err := tmpl.ExecuteUpdates(&buf, page); err != nil {
+			t.Fatalf("ExecuteUpdates failed: %v", err)
+		}
+		var tree map[string]interface{}
+		if err := json.Unmarshal(buf.Bytes(), &tree); err != nil {
+			t.Fatalf("Failed to parse update JSON: %v", err)
+		}
+		return tree
+	}
+
+	initial := &Page{A: "a", B: "b", C: "c", D: "d", E: "e"}
+
+	t.Run("large change sends full tree", func(t *testing.T) {
+		tmpl := New("test", WithFullReplacementThreshold(0.5))
+		if _, err := tmpl.Parse(templateStr); err != nil {
+			t.Fatalf("Parse failed: %v", err)
+		}
+		render(t, tmpl, initial)
+
+		update := render(t, tmpl, &Page{A: "alpha", B: "bravo", C: "charlie", D: "delta", E: "echo"})
+		if _, ok := update["s"]; !ok {
+			t.Fatalf("Expected full-replacement update with statics, got %v", update)
+		}
+		if update["2"] != "charlie" {
+			t.Errorf("Expected full tree to carry new values, got %v", update)
+		}
+	})
+
+	t.Run("small change sends diff", func(t *testing.T) {
+		tmpl := New("test", WithFullReplacementThreshold(0.5))
+		if _, err := tmpl.Parse(templateStr); err != nil {
+			t.Fatalf("Parse failed: %v", err)
+		}
+		render(t, tmpl, initial)
+
+		update := render(t, tmpl, &Page{A: "x", B: "b", C: "c", D: "d", E: "e"})
+		if _, ok := update["s"]; ok {
+			t.Errorf("Expected diff without statics, got %v", update)
+		}
+		if len(update) != 1 {
+			t.Errorf("Expected a single changed dynamic, got %v", update)
+		}
+	})
+
+	t.Run("disabled by default", func(t *testing.T) {
+		tmpl := New("test")
+		if _, err := tmpl.Parse(templateStr); err != nil {
+			t.Fatalf("Parse failed: %v", err)
+		}
+		render(t, tmpl, initial)
+
+		update := render(t, tmpl, &Page{A: "alpha", B: "bravo", C: "charlie", D: "delta", E: "echo"})
+		if _, ok := update["s"]; ok {
+			t.Errorf("Expected diff without statics when threshold is unset, got %v", update)
+		}
+	})
+}
+
 // Test configuration options
 func TestTemplate_WithAuthenticator(t *testing.T) {
 	// Test default authenticator
@@ -788,3 +1156,683 @@ func TestTemplate_CombinedOptions(t *testing.T) {
 		}
 	})
 }
+
+func TestTemplate_CatchUpTree(t *testing.T) {
+	type Page struct {
+		Title string
+		Count int
+		Items []string
+	}
+
+	tmpl := New("catch-up-test")
+	_, err := tmpl.Parse(`<h1>{{.Title}}</h1><p>Count: {{.Count}}</p><ul>{{range .Items}}<li>{{.}}</li>{{end}}</ul>`)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.ExecuteUpdates(&buf, &Page{Title: "Todos", Count: 0, Items: []string{"a", "b"}}); err != nil {
+		t.Fatalf("Initial ExecuteUpdates failed: %v", err)
+	}
+	fingerprint := tmpl.Fingerprint()
+	if fingerprint == "" {
+		t.Fatal("Expected a fingerprint after the initial render")
+	}
+
+	// The template moves on; the baseline must stay available
+	for i := 1; i <= 2; i++ {
+		buf.Reset()
+		if err := tmpl.ExecuteUpdates(&buf, &Page{Title: "Todos", Count: i, Items: []string{"a", "b"}}); err != nil {
+			t.Fatalf("ExecuteUpdates failed: %v", err)
+		}
+	}
+
+	t.Run("dynamics only", func(t *testing.T) {
+		changes, err := tmpl.CatchUpTree(fingerprint, &Page{Title: "Todos", Count: 5, Items: []string{"a", "b", "c"}})
+		if err != nil {
+			t.Fatalf("CatchUpTree failed: %v", err)
+		}
+		if _, ok := changes["s"]; ok {
+			t.Errorf("Catch-up should not include statics, got %v", changes)
+		}
+		if changes["0"] != nil {
+			t.Errorf("Unchanged title should not be sent, got %v", changes["0"])
+		}
+		if changes["1"] != "5" {
+			t.Errorf("Expected count 5, got %v", changes["1"])
+		}
+		if changes["2"] == nil {
+			t.Errorf("Expected range changes, got %v", changes)
+		}
+
+		encoded, err := json.Marshal(changes)
+		if err != nil {
+			t.Fatalf("Failed to encode catch-up: %v", err)
+		}
+		if err := ValidateWireMessage(encoded); err != nil {
+			t.Errorf("Catch-up is not a valid wire message: %v\n%s", err, encoded)
+		}
+	})
+
+	t.Run("no changes", func(t *testing.T) {
+		changes, err := tmpl.CatchUpTree(fingerprint, &Page{Title: "Todos", Count: 0, Items: []string{"a", "b"}})
+		if err != nil {
+			t.Fatalf("CatchUpTree failed: %v", err)
+		}
+		if len(changes) != 0 {
+			t.Errorf("Expected empty catch-up, got %v", changes)
+		}
+	})
+
+	t.Run("shared with clones", func(t *testing.T) {
+		clone, err := tmpl.Clone()
+		if err != nil {
+			t.Fatalf("Clone failed: %v", err)
+		}
+		changes, err := clone.CatchUpTree(fingerprint, &Page{Title: "Done", Count: 0, Items: []string{"a", "b"}})
+		if err != nil {
+			t.Fatalf("CatchUpTree on clone failed: %v", err)
+		}
+		if len(changes) != 1 || changes["0"] != "Done" {
+			t.Errorf("Expected only the title change, got %v", changes)
+		}
+	})
+
+	t.Run("own diff state unchanged", func(t *testing.T) {
+		buf.Reset()
+		if err := tmpl.ExecuteUpdates(&buf, &Page{Title: "Todos", Count: 3, Items: []string{"a", "b"}}); err != nil {
+			t.Fatalf("ExecuteUpdates failed: %v", err)
+		}
+		var update map[string]interface{}
+		if err := json.Unmarshal(buf.Bytes(), &update); err != nil {
+			t.Fatalf("Failed to parse update: %v", err)
+		}
+		if len(update) != 1 || update["1"] != "3" {
+			t.Errorf("Expected only count 2 -> 3 against the template's own baseline, got %v", update)
+		}
+	})
+
+	t.Run("unknown fingerprint", func(t *testing.T) {
+		_, err := tmpl.CatchUpTree("0000000000000000", &Page{})
+		if !errors.Is(err, ErrUnknownFingerprint) {
+			t.Errorf("Expected ErrUnknownFingerprint, got %v", err)
+		}
+	})
+}
+
+func TestTemplate_OnUpdate(t *testing.T) {
+	type call struct {
+		name  string
+		tree  TreeNode
+		bytes int
+	}
+	var calls []call
+
+	tmpl := New("on-update-test", WithOnUpdate(func(name string, tree TreeNode, bytes int) {
+		calls = append(calls, call{name, tree, bytes})
+	}))
+	if _, err := tmpl.Parse(`<p>First: {{index .Items 0}}</p>`); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	for i, first := range []string{"a", "b"} {
+		var buf bytes.Buffer
+		if err := tmpl.ExecuteUpdates(&buf, map[string][]string{"Items": {first}}); err != nil {
+			t.Fatalf("ExecuteUpdates failed: %v", err)
+		}
+
+		if len(calls) != i+1 {
+			t.Fatalf("Expected %d callbacks, got %d", i+1, len(calls))
+		}
+		last := calls[i]
+		if last.name != "on-update-test" {
+			t.Errorf("Expected template name %q, got %q", "on-update-test", last.name)
+		}
+		if last.bytes != buf.Len() {
+			t.Errorf("Expected %d bytes, got %d", buf.Len(), last.bytes)
+		}
+		if last.tree["0"] != first {
+			t.Errorf("Expected tree with %q, got %v", first, last.tree)
+		}
+	}
+
+	// Failed renders don't fire the callback
+	var buf bytes.Buffer
+	if err := tmpl.ExecuteUpdates(&buf, map[string][]string{"Items": {}}); err == nil {
+		t.Fatal("Expected ExecuteUpdates to fail for an empty list")
+	}
+	if len(calls) != 2 {
+		t.Errorf("Callback should not fire for a failed update, got %d calls", len(calls))
+	}
+}
+
+func TestTemplate_StrictData(t *testing.T) {
+	const src = `<h1>{{.Title}}</h1><p>{{.Titel}}</p>`
+	data := map[string]interface{}{"Title": "Hello"}
+
+	t.Run("strict", func(t *testing.T) {
+		tmpl := New("strict-test", WithStrictData(true))
+		if _, err := tmpl.Parse(src); err != nil {
+			t.Fatalf("Parse failed: %v", err)
+		}
+
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, data); err == nil || !strings.Contains(err.Error(), "Titel") {
+			t.Errorf("Expected Execute to fail on the missing key, got %v", err)
+		}
+		if err := tmpl.ExecuteUpdates(&buf, data); err == nil || !strings.Contains(err.Error(), "Titel") {
+			t.Errorf("Expected ExecuteUpdates to fail on the missing key, got %v", err)
+		}
+
+		clone, err := tmpl.Clone()
+		if err != nil {
+			t.Fatalf("Clone failed: %v", err)
+		}
+		if err := clone.Execute(&buf, data); err == nil {
+			t.Error("Expected a clone to keep strict mode")
+		}
+
+		buf.Reset()
+		if err := tmpl.Execute(&buf, map[string]interface{}{"Title": "Hello", "Titel": "typo"}); err != nil {
+			t.Errorf("Execute failed with every key present: %v", err)
+		}
+	})
+
+	t.Run("default", func(t *testing.T) {
+		tmpl := New("lenient-test")
+		if _, err := tmpl.Parse(src); err != nil {
+			t.Fatalf("Parse failed: %v", err)
+		}
+
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, data); err != nil {
+			t.Fatalf("Execute failed: %v", err)
+		}
+		// html/template escapes the missing value to nothing rather than "<no value>"
+		if !strings.Contains(buf.String(), "<h1>Hello</h1><p></p>") {
+			t.Errorf("Expected the missing key to render empty, got %s", buf.String())
+		}
+	})
+}
+
+func TestTemplate_SuppressUnchanged(t *testing.T) {
+	type Item struct {
+		ID   string
+		Name string
+	}
+	const src = `<ul>{{range .Items}}<li data-key="{{.ID}}">{{.Name}}</li>{{end}}</ul>`
+	data := map[string][]Item{"Items": {{"1", "Milk"}, {"2", "Eggs"}}}
+
+	tmpl := New("suppress-test", WithSuppressUnchanged(true))
+	if _, err := tmpl.Parse(src); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.ExecuteUpdates(&buf, data); err != nil {
+		t.Fatalf("ExecuteUpdates failed: %v", err)
+	}
+	if buf.Len() == 0 || !tmpl.Changed() {
+		t.Fatal("Initial render should always be written")
+	}
+
+	buf.Reset()
+	if err := tmpl.ExecuteUpdates(&buf, data); err != nil {
+		t.Fatalf("ExecuteUpdates failed: %v", err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("Identical data should write nothing, got %s", buf.String())
+	}
+	if tmpl.Changed() {
+		t.Error("Changed() should be false for identical data")
+	}
+
+	buf.Reset()
+	data["Items"][1].Name = "Bread"
+	if err := tmpl.ExecuteUpdates(&buf, data); err != nil {
+		t.Fatalf("ExecuteUpdates failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), "Bread") || !tmpl.Changed() {
+		t.Errorf("Changed data should be written, got %q", buf.String())
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		tmpl := New("suppress-disabled-test")
+		if _, err := tmpl.Parse(src); err != nil {
+			t.Fatalf("Parse failed: %v", err)
+		}
+		for i := 0; i < 2; i++ {
+			buf.Reset()
+			if err := tmpl.ExecuteUpdates(&buf, data); err != nil {
+				t.Fatalf("ExecuteUpdates failed: %v", err)
+			}
+		}
+		if buf.String() != "{}" || tmpl.Changed() {
+			t.Errorf("Expected an empty update without the option, got %q", buf.String())
+		}
+	})
+}
+
+func TestContentFingerprint_IgnoresItemKeys(t *testing.T) {
+	tree := func(keys ...string) treeNode {
+		items := make([]interface{}, len(keys))
+		for i, key := range keys {
+			items[i] = map[string]interface{}{"0": "item", "_k": key}
+		}
+		return treeNode{"s": []string{"<ul>", "</ul>"}, "0": treeNode{"s": []string{"<li>", "</li>"}, "d": items}}
+	}
+
+	if contentFingerprint(tree("1", "2"), md5.New) != contentFingerprint(tree("7", "8"), md5.New) {
+		t.Error("Trees differing only in item keys should have the same content fingerprint")
+	}
+	if calculateFingerprint(tree("1", "2"), md5.New) == calculateFingerprint(tree("7", "8"), md5.New) {
+		t.Error("calculateFingerprint should still distinguish item keys")
+	}
+	if contentFingerprint(tree("1", "2"), md5.New) == contentFingerprint(tree("1"), md5.New) {
+		t.Error("Trees with different items should have different content fingerprints")
+	}
+}
+
+func TestWithHasher(t *testing.T) {
+	var calls int
+	newHash := func() hash.Hash {
+		calls++
+		return fnv.New64a()
+	}
+
+	t.Run("identical content produces identical keys", func(t *testing.T) {
+		item := map[string]interface{}{"0": "Milk", "1": "2"}
+		key := generateItemHash(item, newHash)
+		if len(key) != 12 {
+			t.Errorf("Expected a 12 character key, got %q", key)
+		}
+		if again := generateItemHash(map[string]interface{}{"1": "2", "0": "Milk"}, newHash); again != key {
+			t.Errorf("Identical content should produce the same key, got %q and %q", key, again)
+		}
+		if other := generateItemHash(map[string]interface{}{"0": "Eggs", "1": "2"}, newHash); other == key {
+			t.Errorf("Different content should produce a different key, got %q for both", key)
+		}
+	})
+
+	t.Run("updates match the default hash", func(t *testing.T) {
+		const src = `<h1>{{.Title}}</h1><ul>{{range .Items}}<li>{{.}}</li>{{end}}</ul>`
+		states := []map[string]interface{}{
+			{"Title": "List", "Items": []string{"a", "b", "c"}},
+			{"Title": "List", "Items": []string{"a", "b", "c", "d"}},
+			{"Title": "List", "Items": []string{"d", "c", "b", "a"}},
+			{"Title": "Shorter", "Items": []string{"d", "a"}},
+		}
+
+		run := func(opts ...Option) []string {
+			tmpl := New("hasher-test", opts...)
+			if _, err := tmpl.Parse(src); err != nil {
+				t.Fatalf("Parse failed: %v", err)
+			}
+			var updates []string
+			for _, state := range states {
+				var buf bytes.Buffer
+				if err := tmpl.ExecuteUpdates(&buf, state); err != nil {
+					t.Fatalf("ExecuteUpdates failed: %v", err)
+				}
+				updates = append(updates, buf.String())
+			}
+			return updates
+		}
+
+		calls = 0
+		want := run()
+		got := run(WithHasher(newHash))
+		if calls == 0 {
+			t.Error("Expected the custom hasher to be used")
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Updates differ with a custom hasher:\n got %v\nwant %v", got, want)
+		}
+	})
+}
+
+func BenchmarkGenerateItemHash(b *testing.B) {
+	item := map[string]interface{}{"0": "Buy milk", "1": "2024-01-01", "2": "pending", "3": "42"}
+	for _, bc := range []struct {
+		name    string
+		newHash func() hash.Hash
+	}{
+		{"md5", md5.New},
+		{"fnv64a", func() hash.Hash { return fnv.New64a() }},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				generateItemHash(item, bc.newHash)
+			}
+		})
+	}
+}
+
+func TestTemplate_ExecuteUpdatesIndent(t *testing.T) {
+	const src = `<h1>{{.Title}}</h1><ul>{{range .Items}}<li>{{.}}</li>{{end}}</ul>`
+	states := []map[string]interface{}{
+		{"Title": "Todos", "Items": []string{"a <b>", "c"}},
+		{"Title": "Todos (3)", "Items": []string{"a <b>", "c", "d"}},
+	}
+
+	compact, indented := New("compact-test"), New("indent-test")
+	for _, tmpl := range []*Template{compact, indented} {
+		if _, err := tmpl.Parse(src); err != nil {
+			t.Fatalf("Parse failed: %v", err)
+		}
+	}
+
+	for i, state := range states {
+		var compactBuf, indentBuf bytes.Buffer
+		if err := compact.ExecuteUpdates(&compactBuf, state); err != nil {
+			t.Fatalf("ExecuteUpdates failed: %v", err)
+		}
+		if err := indented.ExecuteUpdatesIndent(&indentBuf, state); err != nil {
+			t.Fatalf("ExecuteUpdatesIndent failed: %v", err)
+		}
+
+		if strings.Contains(compactBuf.String(), "\n") {
+			t.Errorf("Update %d: compact output should be a single line, got %s", i, compactBuf.String())
+		}
+		if !strings.Contains(indentBuf.String(), "\n  ") {
+			t.Errorf("Update %d: expected indented output, got %s", i, indentBuf.String())
+		}
+
+		var want, got interface{}
+		if err := json.Unmarshal(compactBuf.Bytes(), &want); err != nil {
+			t.Fatalf("Update %d: invalid compact JSON: %v", i, err)
+		}
+		if err := json.Unmarshal(indentBuf.Bytes(), &got); err != nil {
+			t.Fatalf("Update %d: invalid indented JSON: %v", i, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Update %d: indented output %s doesn't match compact output %s", i, indentBuf.String(), compactBuf.String())
+		}
+	}
+}
+
+func TestTemplate_TrustedHTML(t *testing.T) {
+	type Comment struct {
+		Author string
+		Text   template.HTML
+	}
+	type Post struct {
+		Title    string
+		Body     template.HTML
+		Comments []Comment
+	}
+
+	tmpl := New("trusted-html-test")
+	_, err := tmpl.Parse(`<h1>{{.Title}}</h1><article>{{.Body}}</article><ul>{{range .Comments}}<li>{{.Author}}: {{.Text}}</li>{{end}}</ul>`)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	post := &Post{
+		Title:    "<script>alert(1)</script>",
+		Body:     "<p>Rendered <em>markdown</em></p>",
+		Comments: []Comment{{Author: "<b>eve</b>", Text: "<strong>Nice</strong>"}},
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.ExecuteUpdates(&buf, post); err != nil {
+		t.Fatalf("ExecuteUpdates failed: %v", err)
+	}
+	var tree struct {
+		Title    string `json:"0"`
+		Body     string `json:"1"`
+		Comments struct {
+			D []map[string]string `json:"d"`
+		} `json:"2"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &tree); err != nil {
+		t.Fatalf("Invalid update JSON %s: %v", buf.String(), err)
+	}
+
+	if tree.Body != string(post.Body) {
+		t.Errorf("template.HTML slot should be unescaped, got %q", tree.Body)
+	}
+	if tree.Title != "&lt;script&gt;alert(1)&lt;/script&gt;" {
+		t.Errorf("String slot should be escaped, got %q", tree.Title)
+	}
+	if len(tree.Comments.D) != 1 {
+		t.Fatalf("Expected one comment, got %s", buf.String())
+	}
+	if comment := tree.Comments.D[0]; comment["0"] != "&lt;b&gt;eve&lt;/b&gt;" || comment["1"] != "<strong>Nice</strong>" {
+		t.Errorf("Expected escaped author and unescaped text in range item, got %v", comment)
+	}
+
+	// Updates to a trusted slot stay unescaped
+	buf.Reset()
+	post.Body = "<p>Edited</p>"
+	if err := tmpl.ExecuteUpdates(&buf, post); err != nil {
+		t.Fatalf("ExecuteUpdates failed: %v", err)
+	}
+	if buf.String() != `{"1":"<p>Edited</p>"}` {
+		t.Errorf("Expected unescaped body update, got %s", buf.String())
+	}
+}
+
+func TestTemplate_IDGenerator(t *testing.T) {
+	var n int
+	generator := WithIDGenerator(func() string {
+		n++
+		return "lvt-test-" + strconv.Itoa(n)
+	})
+
+	seen := make(map[string]bool)
+	for _, src := range []string{
+		"<p>{{.Value}}</p>",
+		"<!DOCTYPE html><html><body><p>{{.Value}}</p></body></html>",
+	} {
+		tmpl := New("id-generator-test", generator)
+		if _, err := tmpl.Parse(src); err != nil {
+			t.Fatalf("Parse failed: %v", err)
+		}
+
+		// Each parse draws a new ID; the wrapper uses the last one
+		want := `data-lvt-id="lvt-test-` + strconv.Itoa(n) + `"`
+		if seen[want] {
+			t.Errorf("Wrapper ID %s was generated twice", want)
+		}
+		seen[want] = true
+
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, &Counter{Value: 1}); err != nil {
+			t.Fatalf("Execute failed: %v", err)
+		}
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("Expected wrapper %s, got %s", want, buf.String())
+		}
+	}
+}
+
+// countingEncoder is a JSONEncoder that counts its calls
+type countingEncoder struct {
+	calls int
+}
+
+func (e *countingEncoder) Marshal(v interface{}) ([]byte, error) {
+	e.calls++
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+func TestTemplate_JSONEncoder(t *testing.T) {
+	const src = `<h1>{{.Title}}</h1><ul>{{range .Items}}<li>{{.}}</li>{{end}}</ul>`
+	states := []map[string]interface{}{
+		{"Title": "Todos", "Items": []string{"a", "b"}},
+		{"Title": "Todos (3)", "Items": []string{"a", "b", "c"}},
+	}
+
+	enc := &countingEncoder{}
+	standard, custom := New("std-encoder-test"), New("custom-encoder-test", WithJSONEncoder(enc))
+	for _, tmpl := range []*Template{standard, custom} {
+		if _, err := tmpl.Parse(src); err != nil {
+			t.Fatalf("Parse failed: %v", err)
+		}
+	}
+
+	for i, state := range states {
+		calls := enc.calls
+		var standardBuf, customBuf bytes.Buffer
+		if err := standard.ExecuteUpdates(&standardBuf, state); err != nil {
+			t.Fatalf("ExecuteUpdates failed: %v", err)
+		}
+		if err := custom.ExecuteUpdates(&customBuf, state); err != nil {
+			t.Fatalf("ExecuteUpdates failed: %v", err)
+		}
+
+		if enc.calls == calls {
+			t.Errorf("Update %d: custom encoder was not used", i)
+		}
+		if customBuf.String() != standardBuf.String() {
+			t.Errorf("Update %d: custom encoder output %s differs from %s", i, customBuf.String(), standardBuf.String())
+		}
+	}
+}
+
+func TestTemplate_RenderString(t *testing.T) {
+	tmpl := New("render-string-test")
+	if _, err := tmpl.Parse(`<h1>{{.Title}}</h1>{{if .lvt.HasError "title"}}<p>{{.lvt.Error "title"}}</p>{{end}}`); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	data := map[string]interface{}{"Title": "Hello <world>"}
+	errs := map[string]string{"title": "Too short"}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data, errs); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	got, err := tmpl.RenderString(data, errs)
+	if err != nil {
+		t.Fatalf("RenderString failed: %v", err)
+	}
+	if got != buf.String() {
+		t.Errorf("RenderString() = %q, want Execute output %q", got, buf.String())
+	}
+	if !strings.Contains(got, "Hello &lt;world&gt;") || !strings.Contains(got, "Too short") {
+		t.Errorf("Unexpected output %s", got)
+	}
+
+	if _, err := New("unparsed-test").RenderString(data); err == nil {
+		t.Error("Expected an error for an unparsed template")
+	}
+}
+
+func TestTemplate_SetDefaultData(t *testing.T) {
+	tmpl := New("default-data-test")
+	if _, err := tmpl.Parse(`<h1>{{.Title}}</h1><p>{{.Subtitle}}</p>`); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	tmpl.SetDefaultData(map[string]interface{}{"Title": "Pricing", "Subtitle": "Plans for every team"})
+
+	clone, err := tmpl.Clone()
+	if err != nil {
+		t.Fatalf("Clone failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.ExecuteUpdates(&buf, nil); err != nil {
+		t.Fatalf("ExecuteUpdates failed: %v", err)
+	}
+	for _, want := range []string{"Pricing", "Plans for every team"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("Expected tree to render default %q, got %s", want, buf.String())
+		}
+	}
+
+	html, err := clone.RenderString(nil)
+	if err != nil {
+		t.Fatalf("RenderString failed: %v", err)
+	}
+	if !strings.Contains(html, "<h1>Pricing</h1>") {
+		t.Errorf("Expected clone to render default data, got %s", html)
+	}
+
+	// Explicit data still wins
+	buf.Reset()
+	if err := tmpl.ExecuteUpdates(&buf, map[string]interface{}{"Title": "Checkout", "Subtitle": ""}); err != nil {
+		t.Fatalf("ExecuteUpdates failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), "Checkout") {
+		t.Errorf("Expected explicit data to be rendered, got %s", buf.String())
+	}
+}
+
+func TestTemplate_SetData(t *testing.T) {
+	tmpl := New("set-data-test")
+	if _, err := tmpl.Parse(`<p>{{.Status}}</p><p>{{.Progress}}</p>`); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.ExecuteUpdates(&buf, map[string]interface{}{"Status": "queued", "Progress": 0}); err != nil {
+		t.Fatalf("ExecuteUpdates failed: %v", err)
+	}
+
+	// A background job advances the state the client already shows
+	tmpl.SetData(map[string]interface{}{"Status": "running", "Progress": 50})
+
+	buf.Reset()
+	if err := tmpl.ExecuteUpdates(&buf, map[string]interface{}{"Status": "running", "Progress": 50}); err != nil {
+		t.Fatalf("ExecuteUpdates failed: %v", err)
+	}
+	if tmpl.Changed() || strings.Contains(buf.String(), "running") {
+		t.Errorf("Expected no changes since SetData, got %s", buf.String())
+	}
+
+	buf.Reset()
+	if err := tmpl.ExecuteUpdates(&buf, map[string]interface{}{"Status": "running", "Progress": 100}); err != nil {
+		t.Fatalf("ExecuteUpdates failed: %v", err)
+	}
+	update := buf.String()
+	if strings.Contains(update, `"s"`) {
+		t.Errorf("Expected a diff against the baseline, got a full tree %s", update)
+	}
+	if !strings.Contains(update, "100") {
+		t.Errorf("Expected the progress change in the update, got %s", update)
+	}
+	if strings.Contains(update, "running") {
+		t.Errorf("Status is unchanged since SetData and shouldn't be sent, got %s", update)
+	}
+}
+
+func TestTemplate_ConcurrentExecuteUpdates(t *testing.T) {
+	tmpl := New("concurrent-test")
+	if _, err := tmpl.Parse(`<ul>{{range .Items}}<li>{{.}}</li>{{end}}</ul><p>{{.Count}}</p>`); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 25; i++ {
+				data := map[string]interface{}{
+					"Items": []string{fmt.Sprint("item-", g), fmt.Sprint("item-", i)},
+					"Count": g*100 + i,
+				}
+				if err := tmpl.ExecuteUpdates(io.Discard, data); err != nil {
+					errs <- err
+					return
+				}
+				_ = tmpl.Fingerprint()
+				_ = tmpl.Changed()
+			}
+		}(g)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Errorf("ExecuteUpdates failed: %v", err)
+	}
+}
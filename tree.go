@@ -6,6 +6,7 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"hash"
 	"reflect"
 	"regexp"
 	"sort"
@@ -18,12 +19,17 @@ import (
 // treeNode represents the tree-based static/dynamic structure (internal use only)
 type treeNode map[string]interface{}
 
-// calculateFingerprint calculates a 64-bit fingerprint (MD5 hash) for a tree's statics and dynamics
+// TreeNode is a tree update in the wire format, as returned by CatchUpTree.
+// See docs/specifications/tree-update-specification.md.
+type TreeNode = map[string]interface{}
+
+// calculateFingerprint calculates a 64-bit fingerprint for a tree's statics and dynamics,
+// hashed with newHash (MD5 unless set with WithHasher)
 // This allows detecting when a subtree has changed, similar to LiveView's optimization #2
-func calculateFingerprint(tree treeNode) string {
+func calculateFingerprint(tree treeNode, newHash func() hash.Hash) string {
 	// Create a canonical representation of the tree for hashing
 	// Include both statics (template structure) and dynamics (data values)
-	hasher := md5.New()
+	hasher := newHash()
 
 	// Add statics to hash (template structure)
 	if statics, exists := tree["s"]; exists {
@@ -65,6 +71,49 @@ func calculateFingerprint(tree treeNode) string {
 	return fullHash
 }
 
+// staticsVersionOf returns the statics version of template source: a hash that changes
+// whenever the source, and so possibly any static, changes
+func staticsVersionOf(templateStr string) string {
+	sum := md5.Sum([]byte(templateStr))
+	return hex.EncodeToString(sum[:])[:16]
+}
+
+// contentFingerprint is calculateFingerprint ignoring range item keys ("_k"), so trees
+// that only differ in key numbering have the same content fingerprint
+func contentFingerprint(tree treeNode, newHash func() hash.Hash) string {
+	return calculateFingerprint(withoutItemKeys(tree).(treeNode), newHash)
+}
+
+// withoutItemKeys returns a copy of value with the "_k" entries of all nested nodes removed
+func withoutItemKeys(value interface{}) interface{} {
+	switch v := value.(type) {
+	case treeNode:
+		node := make(treeNode, len(v))
+		for k, child := range v {
+			if k != "_k" {
+				node[k] = withoutItemKeys(child)
+			}
+		}
+		return node
+	case map[string]interface{}:
+		return map[string]interface{}(withoutItemKeys(treeNode(v)).(treeNode))
+	case []interface{}:
+		items := make([]interface{}, len(v))
+		for i, item := range v {
+			items[i] = withoutItemKeys(item)
+		}
+		return items
+	case []map[string]interface{}:
+		items := make([]interface{}, len(v))
+		for i, item := range v {
+			items[i] = withoutItemKeys(item)
+		}
+		return items
+	default:
+		return value
+	}
+}
+
 // addFingerprintToTree adds the fingerprint to the tree for client-side tracking
 // NOTE: This should be internal-only for conditional branch detection
 func addFingerprintToTree(tree treeNode) treeNode {
@@ -85,27 +134,29 @@ func generateRandomID() string {
 	return "lvt-" + hex.EncodeToString(b)
 }
 
-// injectWrapperDiv injects a wrapper div around body content with the specified ID
-// Excludes <script> tags from the wrapper to prevent them from being part of the dynamic content
-func injectWrapperDiv(htmlDoc string, wrapperID string, loadingDisabled bool) string {
+// injectWrapperDiv injects a wrapper div around body content with the specified ID and
+// attributes (see Template.wrapperAttrs).
+// Excludes <script> tags from the wrapper to prevent them from being part of the dynamic content.
+// A document without a complete <body> element has nowhere to put the wrapper, so updates
+// couldn't target it: that is an error rather than a document returned unwrapped.
+func injectWrapperDiv(htmlDoc string, wrapperID string, attrs string) (string, error) {
 	// Find the body opening tag and extract the content between <body> and </body>
 	bodyStart := strings.Index(htmlDoc, "<body")
 	if bodyStart == -1 {
-		// No body tag found, return as-is
-		return htmlDoc
+		return "", fmt.Errorf("full HTML template has no <body> element to inject the wrapper into; add <body>...</body> around the page content")
 	}
 
 	// Find the end of the body opening tag
 	bodyTagEnd := strings.Index(htmlDoc[bodyStart:], ">")
 	if bodyTagEnd == -1 {
-		return htmlDoc
+		return "", fmt.Errorf("full HTML template has an unterminated <body> tag at offset %d", bodyStart)
 	}
 	bodyTagEnd += bodyStart + 1
 
 	// Find the closing body tag
 	bodyEnd := strings.LastIndex(htmlDoc, "</body>")
-	if bodyEnd == -1 {
-		return htmlDoc
+	if bodyEnd < bodyTagEnd {
+		return "", fmt.Errorf("full HTML template has no </body> closing tag after its <body> tag")
 	}
 
 	// Extract the body content
@@ -124,19 +175,13 @@ func injectWrapperDiv(htmlDoc string, wrapperID string, loadingDisabled bool) st
 		scriptsSection = ""
 	}
 
-	// Add loading attribute if not disabled
-	loadingAttr := ""
-	if !loadingDisabled {
-		loadingAttr = ` data-lvt-loading="true"`
-	}
-
-	// Create the wrapper div with the specified ID and optional loading attribute
-	wrappedContent := fmt.Sprintf(`<div data-lvt-id="%s"%s>%s</div>%s`, wrapperID, loadingAttr, contentToWrap, scriptsSection)
+	// Create the wrapper div with the specified ID and attributes
+	wrappedContent := fmt.Sprintf(`<div data-lvt-id="%s"%s>%s</div>%s`, wrapperID, attrs, contentToWrap, scriptsSection)
 
 	// Reconstruct the HTML with the wrapper
 	result := htmlDoc[:bodyTagEnd] + wrappedContent + htmlDoc[bodyEnd:]
 
-	return result
+	return result, nil
 }
 
 // extractTemplateBodyContent extracts only the body content from a full HTML template
@@ -158,8 +203,10 @@ func extractTemplateBodyContent(templateStr string) string {
 	return strings.TrimSpace(templateStr[bodyStart:bodyEnd])
 }
 
-// extractTemplateContent extracts template content using wrapper ID with proper HTML parsing
-func extractTemplateContent(input string, wrapperID string) string {
+// extractTemplateContent extracts template content using wrapper ID with proper HTML parsing.
+// With an existing root (see WithExistingRoot), the content of the element with rootID is
+// extracted instead of the wrapper div's.
+func extractTemplateContent(input string, wrapperID, rootID string) string {
 	if wrapperID == "" {
 		// For standalone templates without wrapper, return as-is
 		return input
@@ -172,8 +219,13 @@ func extractTemplateContent(input string, wrapperID string) string {
 		return input
 	}
 
-	// Find the div with the matching data-lvt-id
-	wrapperDiv := findElementByDataLvtID(doc, wrapperID)
+	// Find the root element, or else the div with the matching data-lvt-id
+	var wrapperDiv *html.Node
+	if rootID != "" {
+		wrapperDiv = findElementByAttr(doc, "id", rootID)
+	} else {
+		wrapperDiv = findElementByAttr(doc, "data-lvt-id", wrapperID)
+	}
 	if wrapperDiv == nil {
 		// If wrapper not found, return the input as-is (shouldn't happen with proper injection)
 		return input
@@ -188,18 +240,18 @@ func extractTemplateContent(input string, wrapperID string) string {
 	return result.String()
 }
 
-// findElementByDataLvtID recursively searches for an element with the given data-lvt-id
-func findElementByDataLvtID(n *html.Node, targetID string) *html.Node {
+// findElementByAttr recursively searches for an element whose key attribute has the given value
+func findElementByAttr(n *html.Node, key, val string) *html.Node {
 	if n.Type == html.ElementNode {
 		for _, attr := range n.Attr {
-			if attr.Key == "data-lvt-id" && attr.Val == targetID {
+			if attr.Key == key && attr.Val == val {
 				return n
 			}
 		}
 	}
 
 	for child := n.FirstChild; child != nil; child = child.NextSibling {
-		if found := findElementByDataLvtID(child, targetID); found != nil {
+		if found := findElementByAttr(child, key, val); found != nil {
 			return found
 		}
 	}
@@ -313,6 +365,7 @@ func getFieldValue(data interface{}, fieldName string) (interface{}, error) {
 // keyAttributeConfig defines which attributes to check for explicit keys (internal use only)
 type keyAttributeConfig struct {
 	AttributeNames []string
+	Explicit       bool // Set via WithKeyAttributes: slice range items are keyed with "_k"
 }
 
 // defaultKeyAttributes provides sensible defaults for key attribute names (internal use only)
@@ -335,6 +388,10 @@ type keyGenerator struct {
 	usedKeys     map[string]bool    // Track used keys to prevent duplicates
 	fallbackKeys []string           // Position-based fallback keys
 	keyConfig    keyAttributeConfig // Configuration for key attribute names
+
+	trackRanges     bool               // Record the fields of range nodes, see WithRangeItemLifecycle
+	rangeFields     map[uintptr]string // Field of each range node of the current render
+	lastRangeFields map[uintptr]string // Field of each range node of the last render
 }
 
 // newKeyGenerator creates a new key generator for a template instance
@@ -347,6 +404,56 @@ func newKeyGenerator() *keyGenerator {
 	}
 }
 
+// newKeyGeneratorFor creates a key generator using the key attributes configured with WithKeyAttributes
+func newKeyGeneratorFor(config Config) *keyGenerator {
+	kg := newKeyGenerator()
+	if len(config.KeyAttributes) > 0 {
+		kg.keyConfig = keyAttributeConfig{
+			AttributeNames: config.KeyAttributes,
+			Explicit:       true,
+		}
+	}
+	if len(config.itemLifecycles) > 0 {
+		kg.trackRanges = true
+		kg.rangeFields = make(map[uintptr]string)
+	}
+	return kg
+}
+
+// explicitKeyPosition returns the dynamic position holding the item key for statics, using the
+// attributes configured with WithKeyAttributes in priority order. Returns false when no custom
+// attributes are configured or none of them appears in statics.
+func (kg *keyGenerator) explicitKeyPosition(statics []string) (int, bool) {
+	if kg == nil || !kg.keyConfig.Explicit {
+		return 0, false
+	}
+	for _, attr := range kg.keyConfig.AttributeNames {
+		for i, static := range statics {
+			if hasAttributeStart(static, attr) {
+				return i, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// hasAttributeStart reports whether static contains attr=" as a whole attribute name,
+// so "id" doesn't match data-row-id="
+func hasAttributeStart(static, attr string) bool {
+	needle := attr + `="`
+	for offset := 0; ; {
+		idx := strings.Index(static[offset:], needle)
+		if idx < 0 {
+			return false
+		}
+		pos := offset + idx
+		if pos == 0 || static[pos-1] == ' ' || static[pos-1] == '\t' || static[pos-1] == '\n' {
+			return true
+		}
+		offset = pos + 1
+	}
+}
+
 // nextKey generates the next sequential key
 func (kg *keyGenerator) nextKey() string {
 	kg.counter++
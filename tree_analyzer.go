@@ -22,14 +22,24 @@ func NewTreeUpdateAnalyzer() *TreeUpdateAnalyzer {
 	}
 }
 
-// AnalyzeUpdate analyzes a tree update and logs warnings about inefficiencies
+// AnalyzeUpdate analyzes a tree update, logs warnings about inefficiencies and returns them
 // Output is optimized for LLM consumption to provide context-rich recommendations
-func (a *TreeUpdateAnalyzer) AnalyzeUpdate(tree treeNode, templateName string, templateSource string) {
+func (a *TreeUpdateAnalyzer) AnalyzeUpdate(tree treeNode, templateName string, templateSource string) []string {
 	if !a.Enabled {
-		return
+		return nil
 	}
 
 	issues := a.findDetailedIssues(tree, "", templateSource)
+	unkeyed := a.findUnkeyedRanges(tree, "")
+	if len(unkeyed) > 0 {
+		log.Println("=== LIVETEMPLATE TREE ANALYZER ===")
+		log.Printf("Template: %s\n", templateName)
+		log.Println("ISSUE: Range items without a key attribute")
+		for _, issue := range unkeyed {
+			log.Println(issue)
+		}
+		log.Println("=== END ANALYZER OUTPUT ===")
+	}
 	if len(issues) > 0 {
 		log.Println("=== LIVETEMPLATE TREE ANALYZER ===")
 		log.Printf("Template: %s\n", templateName)
@@ -52,6 +62,7 @@ func (a *TreeUpdateAnalyzer) AnalyzeUpdate(tree treeNode, templateName string, t
 		log.Println("Provide the template source to an LLM with this analysis for specific restructuring suggestions.")
 		log.Println("=== END ANALYZER OUTPUT ===")
 	}
+	return append(issues, unkeyed...)
 }
 
 // TreeIssue describes a tree efficiency issue
@@ -136,6 +147,11 @@ func (a *TreeUpdateAnalyzer) findDetailedIssues(tree treeNode, path string, temp
 			operationCount := 0
 
 			for i, item := range rangeSlice {
+				if _, ok := item.(RangeOp); ok {
+					operationCount++
+					continue
+				}
+
 				// Check if this is an operation array like ["i", key, data] or ["u", key, data]
 				if opSlice, ok := item.([]interface{}); ok && len(opSlice) > 0 {
 					if opType, ok := opSlice[0].(string); ok {
@@ -190,6 +206,98 @@ func (a *TreeUpdateAnalyzer) findDetailedIssues(tree treeNode, path string, temp
 	return issues
 }
 
+// findUnkeyedRanges recursively finds ranges whose items have no key attribute. Such items
+// are keyed by a hash of their content, so editing an item changes its key and the update
+// removes and re-inserts it instead of patching it. Ranges are only checked when their
+// statics are sent, i.e. once per client rather than on every update.
+func (a *TreeUpdateAnalyzer) findUnkeyedRanges(tree treeNode, path string) []string {
+	var issues []string
+
+	if items, ok := tree["d"].([]interface{}); ok && len(items) > 0 && !rangeHasKeys(tree["s"], items) {
+		rangePath := path
+		if rangePath == "" {
+			rangePath = "root"
+		}
+		issues = append(issues, fmt.Sprintf(
+			"Range at '%s': Items have no key attribute\n"+
+				"  Problem: Items are keyed by a hash of their content, which changes whenever the content does\n"+
+				"  Impact: Editing an item removes and re-inserts it instead of updating the changed values\n"+
+				"  Fix: Add a stable key to the item's root element, e.g. <li data-lvt-key=\"{{.ID}}\">",
+			rangePath,
+		))
+	}
+
+	for key, value := range tree {
+		if key == "s" || key == "f" {
+			continue
+		}
+		childPath := path + "." + key
+		if path == "" {
+			childPath = key
+		}
+
+		if key == "d" {
+			items, _ := value.([]interface{})
+			for i, item := range items {
+				issues = append(issues, a.findUnkeyedRangesIn(item, fmt.Sprintf("%s[%d]", childPath, i))...)
+			}
+			continue
+		}
+		issues = append(issues, a.findUnkeyedRangesIn(value, childPath)...)
+	}
+
+	return issues
+}
+
+// findUnkeyedRangesIn checks a tree value for unkeyed ranges when it is a nested node
+func (a *TreeUpdateAnalyzer) findUnkeyedRangesIn(value interface{}, path string) []string {
+	switch v := value.(type) {
+	case treeNode:
+		return a.findUnkeyedRanges(v, path)
+	case map[string]interface{}:
+		return a.findUnkeyedRanges(v, path)
+	}
+	return nil
+}
+
+// rangeHasKeys reports whether the items of a range with statics are keyed: by a key
+// attribute in the statics, or explicitly with "_k" (map ranges, WithKeyAttributes).
+// Ranges without statics are updates to a range the client already holds.
+func rangeHasKeys(statics interface{}, items []interface{}) bool {
+	var staticStrs []string
+	switch s := statics.(type) {
+	case []string:
+		staticStrs = s
+	case []interface{}:
+		for _, static := range s {
+			if str, ok := static.(string); ok {
+				staticStrs = append(staticStrs, str)
+			}
+		}
+	default:
+		return true
+	}
+
+	switch item := items[0].(type) {
+	case treeNode:
+		if _, ok := item["_k"]; ok {
+			return true
+		}
+	case map[string]interface{}:
+		if _, ok := item["_k"]; ok {
+			return true
+		}
+	}
+	for _, static := range staticStrs {
+		for _, attr := range defaultKeyAttributes.AttributeNames {
+			if hasAttributeStart(static, attr) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 // findIssues recursively finds efficiency issues in a tree (simple version for tests)
 func (a *TreeUpdateAnalyzer) findIssues(tree treeNode, path string) []string {
 	var issues []string
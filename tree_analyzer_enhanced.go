@@ -242,6 +242,10 @@ func (a *EnhancedTreeAnalyzer) validateRangeGranularity(tree treeNode, complianc
 						operations := 0
 
 						for _, item := range rangeSlice {
+							if _, ok := item.(RangeOp); ok {
+								operations++
+								continue
+							}
 							if op, ok := item.([]interface{}); ok && len(op) > 0 {
 								if opType, ok := op[0].(string); ok {
 									if opType == "i" || opType == "u" || opType == "r" || opType == "o" || opType == "a" {
@@ -433,6 +437,10 @@ func (a *EnhancedTreeAnalyzer) countRangeOperations(tree interface{}) int {
 		switch v := node.(type) {
 		case []interface{}:
 			for _, item := range v {
+				if _, ok := item.(RangeOp); ok {
+					count++
+					continue
+				}
 				if op, ok := item.([]interface{}); ok && len(op) > 0 {
 					if opType, ok := op[0].(string); ok {
 						if opType == "i" || opType == "u" || opType == "r" || opType == "o" || opType == "a" {
@@ -123,6 +123,48 @@ func TestTreeAnalyzer_Disabled(t *testing.T) {
 	t.Log("Analyzer disabled - no warnings should appear")
 }
 
+// TestTreeAnalyzer_UnkeyedRange tests that ranges without a key attribute are reported
+func TestTreeAnalyzer_UnkeyedRange(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		wantWarn bool
+	}{
+		{"unkeyed", `<ul>{{range .Items}}<li>{{.Name}}</li>{{end}}</ul>`, true},
+		{"unkeyed in conditional", `{{if .Items}}<ul>{{range .Items}}<li>{{.Name}}</li>{{end}}</ul>{{end}}`, true},
+		{"data-lvt-key", `<ul>{{range .Items}}<li data-lvt-key="{{.ID}}">{{.Name}}</li>{{end}}</ul>`, false},
+		{"id", `<ul>{{range .Items}}<li id="item-{{.ID}}">{{.Name}}</li>{{end}}</ul>`, false},
+	}
+
+	data := map[string]interface{}{
+		"Items": []map[string]interface{}{{"ID": "1", "Name": "Alpha"}, {"ID": "2", "Name": "Beta"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl := New("test", WithDevMode(true))
+			if _, err := tmpl.Parse(tt.template); err != nil {
+				t.Fatalf("Parse error: %v", err)
+			}
+
+			var buf bytes.Buffer
+			if err := tmpl.ExecuteUpdates(&buf, data); err != nil {
+				t.Fatalf("ExecuteUpdates error: %v", err)
+			}
+
+			warned := false
+			for _, warning := range tmpl.lastWarnings {
+				if strings.Contains(warning, "no key attribute") && strings.Contains(warning, "data-lvt-key") {
+					warned = true
+				}
+			}
+			if warned != tt.wantWarn {
+				t.Errorf("Expected unkeyed range warning: %v, got warnings %v", tt.wantWarn, tmpl.lastWarnings)
+			}
+		})
+	}
+}
+
 // TestAnalyzeTemplateStructure tests template structure analysis
 func TestAnalyzeTemplateStructure(t *testing.T) {
 	analyzer := NewTreeUpdateAnalyzer()
@@ -4,7 +4,9 @@ import (
 	"bytes"
 	"fmt"
 	"html/template"
+	"io"
 	"reflect"
+	"sort"
 	"strings"
 	"text/template/parse"
 )
@@ -96,35 +98,14 @@ func parseTemplateToTreeAST(templateStr string, data interface{}, keyGen *keyGen
 		}
 	}()
 
-	// Normalize template spacing
-	templateStr = normalizeTemplateSpacing(templateStr)
-
-	// Parse template to get AST
-	tmpl, err := template.New("temp").Parse(templateStr)
+	// Parse template to get AST, shared by every execution of the same text
+	ast, err := sourceAST(templateStr)
 	if err != nil {
-		return nil, fmt.Errorf("template parse error: %w", err)
-	}
-
-	// Check if template uses composition and flatten if needed
-	if hasTemplateComposition(tmpl) {
-		flattenedStr, err := flattenTemplate(tmpl)
-		if err != nil {
-			return nil, fmt.Errorf("template flatten error: %w", err)
-		}
-		// Re-parse flattened template
-		tmpl, err = template.New("temp-flattened").Parse(flattenedStr)
-		if err != nil {
-			return nil, fmt.Errorf("flattened template parse error: %w", err)
-		}
-	}
-
-	// Verify we have a parse tree
-	if tmpl.Tree == nil || tmpl.Tree.Root == nil {
-		return nil, fmt.Errorf("template has no parse tree")
+		return nil, err
 	}
 
 	// Build tree by walking AST
-	tree, err = buildTreeFromAST(tmpl.Tree.Root, data, keyGen)
+	tree, err = buildTreeFromAST(ast.Root, data, keyGen)
 	if err != nil {
 		return nil, fmt.Errorf("AST walk error: %w", err)
 	}
@@ -148,13 +129,15 @@ func buildTreeFromAST(node parse.Node, data interface{}, keyGen *keyGenerator) (
 		return treeNode{"s": []string{string(n.Text)}}, nil
 
 	case *parse.ActionNode:
-		return handleActionNode(n, data, keyGen)
+		return handleActionNode(n, data, slotContext{})
 
 	case *parse.IfNode:
 		return handleIfNode(n, data, keyGen)
 
 	case *parse.RangeNode:
-		return handleRangeNode(n, data, keyGen)
+		tree, err := handleRangeNode(n, data, keyGen)
+		keyGen.recordRange(n, tree)
+		return tree, err
 
 	case *parse.WithNode:
 		return handleWithNode(n, data, keyGen)
@@ -184,7 +167,16 @@ func buildTreeFromList(node *parse.ListNode, data interface{}, keyGen *keyGenera
 	statics = append(statics, "")
 
 	for _, child := range node.Nodes {
-		childTree, err := buildTreeFromAST(child, data, keyGen)
+		var childTree treeNode
+		var err error
+		if action, ok := child.(*parse.ActionNode); ok {
+			// Escape the value for where it lands, e.g. inside a style attribute
+			childTree, err = handleActionNode(action, data, slotContextAt(statics))
+		} else if ifNode, ok := child.(*parse.IfNode); ok && isAttributeToggle(ifNode, statics) {
+			childTree, err = handleAttributeToggle(ifNode, data)
+		} else {
+			childTree, err = buildTreeFromAST(child, data, keyGen)
+		}
 		if err != nil {
 			return nil, err
 		}
@@ -239,43 +231,44 @@ func buildTreeFromList(node *parse.ListNode, data interface{}, keyGen *keyGenera
 }
 
 // handleActionNode processes {{.Field}} or {{.Method}} expressions
-func handleActionNode(node *parse.ActionNode, data interface{}, keyGen *keyGenerator) (treeNode, error) {
-	// Execute the action to get its value
-	nodeStr := node.String()
-	tmpl, err := template.New("action").Parse(nodeStr)
-	if err != nil {
-		return nil, fmt.Errorf("action parse error: %w", err)
+func handleActionNode(node *parse.ActionNode, data interface{}, ctx slotContext) (treeNode, error) {
+	if isStaticBlobAction(node) {
+		return handleStaticBlobAction(node, data)
+	}
+	if isFuncAction(node, "lvt_value") {
+		return handleInputBoundAction(node, data)
 	}
 
-	var buf bytes.Buffer
-	if err := tmpl.Execute(&buf, data); err != nil {
-		return nil, fmt.Errorf("action execute error: %w", err)
+	// Execute the action to get its value
+	value, err := ctx.render(node.String(), data)
+	if err != nil {
+		return nil, err
 	}
 
 	// Create tree with one dynamic value
 	return treeNode{
 		"s": []string{"", ""},
-		"0": buf.String(),
+		"0": value,
 	}, nil
 }
 
 // handleIfNode processes {{if}}...{{else}}...{{end}} constructs
 func handleIfNode(node *parse.IfNode, data interface{}, keyGen *keyGenerator) (treeNode, error) {
-	// Evaluate condition by executing just the if part
-	condTmpl := fmt.Sprintf("{{if %s}}true{{else}}false{{end}}", formatPipe(node.Pipe))
-	tmpl, err := template.New("cond").Parse(condTmpl)
-	if err != nil {
-		return nil, fmt.Errorf("condition parse error: %w", err)
+	// A chain comparing one value against constants becomes a switch node
+	if cases, elseList := switchCases(node); cases != nil {
+		if tree, ok, err := handleSwitchNode(cases, elseList, data, keyGen); err != nil || ok {
+			return tree, err
+		}
 	}
 
-	var condBuf bytes.Buffer
-	if err := tmpl.Execute(&condBuf, data); err != nil {
-		return nil, fmt.Errorf("condition execute error: %w", err)
+	matched, err := evaluateCondition(node.Pipe, data)
+	if err != nil {
+		return nil, err
 	}
 
 	// Choose branch based on condition
 	var branch *parse.ListNode
-	if condBuf.String() == "true" {
+	if matched {
 		branch = node.List
 	} else if node.ElseList != nil {
 		branch = node.ElseList
@@ -302,6 +295,22 @@ func handleIfNode(node *parse.IfNode, data interface{}, keyGen *keyGenerator) (t
 	}, nil
 }
 
+// evaluateCondition reports whether the condition of an {{if}} holds for data
+func evaluateCondition(pipe *parse.PipeNode, data interface{}) (bool, error) {
+	// Evaluate condition by executing just the if part
+	condTmpl := fmt.Sprintf("{{if %s}}true{{else}}false{{end}}", formatPipe(pipe))
+	tmpl, err := template.New("cond").Funcs(builtinFuncs).Parse(condTmpl)
+	if err != nil {
+		return false, fmt.Errorf("condition parse error: %w", err)
+	}
+
+	var condBuf bytes.Buffer
+	if err := tmpl.Execute(&condBuf, data); err != nil {
+		return false, fmt.Errorf("condition execute error: %w", err)
+	}
+	return condBuf.String() == "true", nil
+}
+
 // handleRangeNode processes {{range}}...{{end}} constructs
 func handleRangeNode(node *parse.RangeNode, data interface{}, keyGen *keyGenerator) (treeNode, error) {
 	// For range with variable declarations like {{range $i, $v := .Items}}
@@ -318,8 +327,8 @@ func handleRangeNode(node *parse.RangeNode, data interface{}, keyGen *keyGenerat
 		if len(node.Pipe.Cmds) > 0 {
 			lastCmd := node.Pipe.Cmds[len(node.Pipe.Cmds)-1]
 			if len(lastCmd.Args) > 0 {
-				// Get the field/expression being ranged over
-				collectionExpr := lastCmd.Args[0].String()
+				// Get the field/expression being ranged over, including function arguments
+				collectionExpr := lastCmd.String()
 				collection, err = evaluatePipe(collectionExpr, data)
 				if err != nil {
 					return nil, fmt.Errorf("range evaluation error: %w", err)
@@ -372,9 +381,10 @@ func handleRangeNode(node *parse.RangeNode, data interface{}, keyGen *keyGenerat
 
 	// Iterate based on collection type
 	if kind == reflect.Map {
-		// For maps, iterate over keys
+		// For maps, iterate over keys in sorted order (matching text/template)
+		// so unchanged maps produce identical trees
 		iter := 0
-		for _, key := range collectionValue.MapKeys() {
+		for _, key := range sortedMapKeys(collectionValue) {
 			item := collectionValue.MapIndex(key).Interface()
 
 			var itemTree treeNode
@@ -415,6 +425,9 @@ func handleRangeNode(node *parse.RangeNode, data interface{}, keyGen *keyGenerat
 				}
 			}
 
+			// The map key identifies the item, so value changes diff as "u" ops
+			itemDynamics["_k"] = fmt.Sprint(key.Interface())
+
 			itemTrees = append(itemTrees, itemDynamics)
 			iter++
 		}
@@ -463,6 +476,21 @@ func handleRangeNode(node *parse.RangeNode, data interface{}, keyGen *keyGenerat
 				}
 			}
 
+			// Entries of lvt_ordered are keyed by their map key, like a range over the map
+			if entry, ok := item.(MapEntry); ok {
+				itemDynamics["_k"] = fmt.Sprint(entry.Key)
+			}
+
+			// Custom key attributes (WithKeyAttributes) aren't known to the client,
+			// so the key is sent explicitly like map keys
+			if statics, ok := itemTree["s"].([]string); ok {
+				if pos, ok := keyGen.explicitKeyPosition(statics); ok {
+					if key, ok := itemDynamics[fmt.Sprintf("%d", pos)].(string); ok {
+						itemDynamics["_k"] = key
+					}
+				}
+			}
+
 			itemTrees = append(itemTrees, itemDynamics)
 		}
 	}
@@ -474,6 +502,33 @@ func handleRangeNode(node *parse.RangeNode, data interface{}, keyGen *keyGenerat
 	}, nil
 }
 
+// sortedMapKeys returns the keys of a map in the order text/template ranges over them
+func sortedMapKeys(m reflect.Value) []reflect.Value {
+	keys := m.MapKeys()
+	sort.Slice(keys, func(i, j int) bool {
+		return lessMapKey(keys[i], keys[j])
+	})
+	return keys
+}
+
+// lessMapKey orders map keys of basic kinds by value, anything else by its printed form
+func lessMapKey(a, b reflect.Value) bool {
+	switch a.Kind() {
+	case reflect.String:
+		return a.String() < b.String()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return a.Int() < b.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return a.Uint() < b.Uint()
+	case reflect.Float32, reflect.Float64:
+		return a.Float() < b.Float()
+	case reflect.Bool:
+		return !a.Bool() && b.Bool()
+	default:
+		return fmt.Sprint(a.Interface()) < fmt.Sprint(b.Interface())
+	}
+}
+
 // executeRangeBodyWithVars executes a range body with variable declarations
 // This properly handles {{range $i, $v := .Collection}} by executing the body
 // within a template context that has the variables defined
@@ -512,15 +567,16 @@ func executeRangeBodyWithVarsMap(node *parse.RangeNode, key interface{}, item in
 		dot:    item,
 	}
 
-	// Populate variables from declarations
+	// Populate variables from declarations. Variable names are stored without
+	// the leading "$", which evaluateActionWithVars adds back when matching.
 	if len(node.Pipe.Decl) == 1 {
 		// {{range $v := ...}} - single variable (value)
-		varName := node.Pipe.Decl[0].Ident[0]
+		varName := strings.TrimPrefix(node.Pipe.Decl[0].Ident[0], "$")
 		varCtx.vars.Set(varName, item)
 	} else if len(node.Pipe.Decl) >= 2 {
 		// {{range $k, $v := ...}} - key and value
-		keyVar := node.Pipe.Decl[0].Ident[0]
-		valueVar := node.Pipe.Decl[1].Ident[0]
+		keyVar := strings.TrimPrefix(node.Pipe.Decl[0].Ident[0], "$")
+		valueVar := strings.TrimPrefix(node.Pipe.Decl[1].Ident[0], "$")
 		varCtx.vars.Set(keyVar, key)
 		varCtx.vars.Set(valueVar, item)
 	}
@@ -550,14 +606,16 @@ func buildTreeFromASTWithVars(node parse.Node, varCtx *varContext, keyGen *keyGe
 		return treeNode{"s": []string{string(n.Text)}}, nil
 
 	case *parse.ActionNode:
-		return handleActionNodeWithVars(n, varCtx, keyGen)
+		return handleActionNodeWithVars(n, varCtx, slotContext{})
 
 	case *parse.IfNode:
 		return handleIfNodeWithVars(n, varCtx, keyGen)
 
 	case *parse.RangeNode:
 		// Nested range - handle recursively
-		return handleRangeNode(n, varCtx.dot, keyGen)
+		tree, err := handleRangeNode(n, varCtx.dot, keyGen)
+		keyGen.recordRange(n, tree)
+		return tree, err
 
 	case *parse.WithNode:
 		return handleWithNode(n, varCtx.dot, keyGen)
@@ -579,11 +637,28 @@ func buildTreeFromListWithVars(node *parse.ListNode, varCtx *varContext, keyGen
 	statics = append(statics, "")
 
 	for _, child := range node.Nodes {
-		childTree, err := buildTreeFromASTWithVars(child, varCtx, keyGen)
+		var childTree treeNode
+		var err error
+		if action, ok := child.(*parse.ActionNode); ok {
+			childTree, err = handleActionNodeWithVars(action, varCtx, slotContextAt(statics))
+		} else if ifNode, ok := child.(*parse.IfNode); ok && isAttributeToggle(ifNode, statics) && !usesVariables(ifNode) {
+			childTree, err = handleAttributeToggle(ifNode, varCtx.dot)
+		} else {
+			childTree, err = buildTreeFromASTWithVars(child, varCtx, keyGen)
+		}
 		if err != nil {
 			return nil, err
 		}
 
+		// Nested range comprehension (e.g. replies inside a comment) - embed it as a
+		// nested structure like buildTreeFromList does, keeping its statics and items
+		if _, hasD := childTree["d"]; hasD {
+			tree[fmt.Sprintf("%d", dynamicIndex)] = childTree
+			dynamicIndex++
+			statics = append(statics, "")
+			continue
+		}
+
 		// Merge child tree
 		childStatics, ok := childTree["s"].([]string)
 		if !ok || len(childStatics) == 0 {
@@ -614,7 +689,7 @@ func buildTreeFromListWithVars(node *parse.ListNode, varCtx *varContext, keyGen
 }
 
 // handleActionNodeWithVars handles {{.Field}} or {{$var}} with variable context
-func handleActionNodeWithVars(node *parse.ActionNode, varCtx *varContext, keyGen *keyGenerator) (treeNode, error) {
+func handleActionNodeWithVars(node *parse.ActionNode, varCtx *varContext, ctx slotContext) (treeNode, error) {
 	// For actions with variable references, we need to execute them in a context
 	// where the variables are defined. We can't just create a mini-template because
 	// Go templates don't allow defining variables inline.
@@ -639,20 +714,22 @@ func handleActionNodeWithVars(node *parse.ActionNode, varCtx *varContext, keyGen
 	}
 
 	if !hasVars {
-		// No variables - execute normally with dot context
-		tmpl, err := template.New("action").Parse(nodeStr)
-		if err != nil {
-			return nil, fmt.Errorf("action parse error: %w", err)
+		if isStaticBlobAction(node) {
+			return handleStaticBlobAction(node, varCtx.dot)
+		}
+		if isFuncAction(node, "lvt_value") {
+			return handleInputBoundAction(node, varCtx.dot)
 		}
 
-		var buf bytes.Buffer
-		if err := tmpl.Execute(&buf, varCtx.dot); err != nil {
-			return nil, fmt.Errorf("action execute error: %w", err)
+		// No variables - execute normally with dot context
+		value, err := ctx.render(nodeStr, varCtx.dot)
+		if err != nil {
+			return nil, err
 		}
 
 		return treeNode{
 			"s": []string{"", ""},
-			"0": buf.String(),
+			"0": value,
 		}, nil
 	}
 
@@ -663,7 +740,7 @@ func handleActionNodeWithVars(node *parse.ActionNode, varCtx *varContext, keyGen
 
 	// Better approach: Build a mini data structure that wraps the variables
 	// and execute the action after transforming variable references to field references
-	result := evaluateActionWithVars(nodeStr, varCtx)
+	result := evaluateActionWithVars(nodeStr, varCtx, ctx)
 
 	return treeNode{
 		"s": []string{"", ""},
@@ -767,7 +844,7 @@ func mergeFieldsIntoMap(value interface{}, target map[string]interface{}) error
 
 // evaluateActionWithVars evaluates an action string that contains variable references
 // It does this by building a wrapper template that defines the variables using a range
-func evaluateActionWithVars(actionStr string, varCtx *varContext) string {
+func evaluateActionWithVars(actionStr string, varCtx *varContext, ctx slotContext) string {
 	// Build a wrapper template that defines the variables
 	// For {{$index | printf "#%d"}}, if $index=0, we build:
 	// {{range $i := slice 0}}{{$i | printf "#%d"}}{{end}}
@@ -829,17 +906,12 @@ func evaluateActionWithVars(actionStr string, varCtx *varContext) string {
 	}
 
 	// Execute the wrapper template
-	tmpl, err := template.New("varAction").Parse(transformedAction)
+	result, err := ctx.render(transformedAction, execData)
 	if err != nil {
 		return fmt.Sprintf("ERROR: %v", err)
 	}
 
-	var buf bytes.Buffer
-	if err := tmpl.Execute(&buf, execData); err != nil {
-		return fmt.Sprintf("ERROR: %v", err)
-	}
-
-	return buf.String()
+	return result
 }
 
 // handleIfNodeWithVars handles if/else with variable context
@@ -859,7 +931,7 @@ func handleIfNodeWithVars(node *parse.IfNode, varCtx *varContext, keyGen *keyGen
 
 	// If no variables or root, execute with dot context
 	if !usesVars && !usesRoot {
-		tmpl, err := template.New("cond").Parse(condStr)
+		tmpl, err := template.New("cond").Funcs(builtinFuncs).Parse(condStr)
 		if err != nil {
 			return nil, fmt.Errorf("condition parse error: %w", err)
 		}
@@ -921,7 +993,7 @@ func handleIfNodeWithVars(node *parse.IfNode, varCtx *varContext, keyGen *keyGen
 
 	// Execute condition with transformed template
 	condTmplStr := fmt.Sprintf("{{if %s}}true{{else}}false{{end}}", transformedCond)
-	tmpl, err := template.New("cond").Parse(condTmplStr)
+	tmpl, err := template.New("cond").Funcs(builtinFuncs).Parse(condTmplStr)
 	if err != nil {
 		return nil, fmt.Errorf("condition parse error: %w", err)
 	}
@@ -986,7 +1058,7 @@ func handleWithNode(node *parse.WithNode, data interface{}, keyGen *keyGenerator
 func evaluatePipe(pipeStr string, data interface{}) (interface{}, error) {
 	// Create a template with the pipe expression
 	tmplStr := fmt.Sprintf("{{%s}}", pipeStr)
-	tmpl, err := template.New("pipe").Parse(tmplStr)
+	tmpl, err := template.New("pipe").Funcs(builtinFuncs).Parse(tmplStr)
 	if err != nil {
 		return nil, err
 	}
@@ -1012,10 +1084,35 @@ func evaluatePipe(pipeStr string, data interface{}) (interface{}, error) {
 		}
 	}
 
+	// Function calls like lvt_ordered .Fields "name" need their actual value
+	if value, ok := capturePipeValue(pipeStr, data); ok {
+		return value, nil
+	}
+
 	// Fall back to string representation
 	return buf.String(), nil
 }
 
+// capturePipeValue evaluates a pipe and returns its value instead of its printed form
+func capturePipeValue(pipeStr string, data interface{}) (interface{}, bool) {
+	var captured interface{}
+	capture := template.FuncMap{
+		"lvt_capture": func(v interface{}) string {
+			captured = v
+			return ""
+		},
+	}
+
+	tmpl, err := template.New("capture").Funcs(builtinFuncs).Funcs(capture).Parse(fmt.Sprintf("{{lvt_capture (%s)}}", pipeStr))
+	if err != nil {
+		return nil, false
+	}
+	if err := tmpl.Execute(io.Discard, data); err != nil {
+		return nil, false
+	}
+	return captured, true
+}
+
 // isZeroValue checks if a reflect.Value is the zero value for its type
 func isZeroValue(v reflect.Value) bool {
 	if !v.IsValid() {
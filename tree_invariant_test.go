@@ -277,7 +277,7 @@ func checkTreeInvariant(tree treeNode, context string) error {
 	// Count dynamics (exclude 's' and 'f')
 	dynamicsCount := 0
 	for k := range tree {
-		if k != "s" && k != "f" { // Skip statics and fingerprint
+		if k != "s" && k != "f" && k != switchBranchKey && k != switchStaticsKey { // Skip statics, fingerprint and switch branches
 			dynamicsCount++
 		}
 	}
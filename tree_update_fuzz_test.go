@@ -185,6 +185,18 @@ func (v *UpdateValidator) validateRangeOperations(value interface{}) error {
 	// Check if this is a range operation array
 	if ops, ok := value.([]interface{}); ok {
 		for _, op := range ops {
+			// Typed ops are checked in the wire form the client receives
+			if rangeOp, ok := op.(RangeOp); ok {
+				encoded, err := rangeOp.MarshalJSON()
+				if err != nil {
+					return fmt.Errorf("range op %T failed to marshal: %w", rangeOp, err)
+				}
+				var wire []interface{}
+				if err := json.Unmarshal(encoded, &wire); err != nil || len(wire) == 0 {
+					return fmt.Errorf("range op %T marshals to %s, not an operation array", rangeOp, encoded)
+				}
+				op = wire
+			}
 			if opArray, ok := op.([]interface{}); ok && len(opArray) > 0 {
 				opType, _ := opArray[0].(string)
 				switch opType {
@@ -740,6 +752,27 @@ func BenchmarkUserJourney(b *testing.B) {
 	}
 }
 
+// brokenRangeOp is a RangeOp whose wire form isn't an operation array
+type brokenRangeOp struct{}
+
+func (brokenRangeOp) Opcode() string               { return "r" }
+func (brokenRangeOp) MarshalJSON() ([]byte, error) { return []byte(`{"r":"todo-1"}`), nil }
+func (op brokenRangeOp) withoutStatics() RangeOp   { return op }
+
+// TestUpdateValidator_TypedRangeOps tests that typed range ops are validated in their wire form
+func TestUpdateValidator_TypedRangeOps(t *testing.T) {
+	validator := NewUpdateValidator()
+	validator.UpdateCount = 2
+
+	valid := []interface{}{RemoveOp{Key: "todo-1"}, UpdateOp{Key: "todo-2", Changes: map[string]interface{}{"0": "x"}}, OrderOp{Keys: []string{"todo-2"}}}
+	if err := validator.validateRangeOperations(valid); err != nil {
+		t.Errorf("Expected typed ops to pass, got %v", err)
+	}
+	if err := validator.validateRangeOperations([]interface{}{brokenRangeOp{}}); err == nil {
+		t.Error("Expected a typed op not marshalling to an operation array to fail")
+	}
+}
+
 // TestEdgeCases tests various edge cases
 func TestEdgeCases(t *testing.T) {
 	tests := []struct {
@@ -0,0 +1,22 @@
+package livetemplate
+
+// WithViewModel renders the value fn returns for a store instead of the store itself, so
+// data derived for display (totals, pagination, formatted dates) doesn't have to live in
+// the store:
+//
+//	WithViewModel(func(store Store) interface{} {
+//	    todos := store.(*TodoState)
+//	    return TodoView{TodoState: todos, Pages: todos.PageCount()}
+//	})
+//
+// fn runs before every render of the live handler, on the session's store. In multi-store
+// mode it runs once per store and its result is the value under the store's name. The
+// view model replaces FieldVisibility and `lvt:"-"` filtering, which apply only to stores
+// rendered as they are.
+//
+// Default: nil (templates render the stores)
+func WithViewModel(fn func(store Store) interface{}) Option {
+	return func(c *Config) {
+		c.ViewModel = fn
+	}
+}
@@ -0,0 +1,57 @@
+package livetemplate
+
+import (
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// slowStateView is a view model over SlowState with a derived field
+type slowStateView struct {
+	*SlowState
+	Doubled int
+}
+
+func TestWithViewModel(t *testing.T) {
+	var seen []Store
+	tmpl := New("view-model-test", WithViewModel(func(store Store) interface{} {
+		seen = append(seen, store)
+		state := store.(*SlowState)
+		return slowStateView{SlowState: state, Doubled: state.Count * 2}
+	}))
+	if _, err := tmpl.Parse("<p>{{.Count}} doubled is {{.Doubled}}</p>"); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	handler := tmpl.Handle(&SlowState{Count: 2})
+
+	t.Run("initial render", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+		body, _ := io.ReadAll(rec.Body)
+		if !strings.Contains(string(body), "2 doubled is 4") {
+			t.Errorf("Expected the derived field in the page, got:\n%s", body)
+		}
+	})
+
+	t.Run("updates", func(t *testing.T) {
+		conn := dialTestHandler(t, handler)
+		response := sendAction(t, conn, "increment", nil)
+		tree, ok := response.Tree.(map[string]interface{})
+		if !ok {
+			t.Fatalf("Expected a tree, got %T", response.Tree)
+		}
+		if tree["0"] != "3" || tree["1"] != "6" {
+			t.Errorf("Expected the count and its derived double to update, got %v", tree)
+		}
+	})
+
+	if len(seen) == 0 {
+		t.Fatal("Expected the view model to be called")
+	}
+	for _, store := range seen {
+		if _, ok := store.(*SlowState); !ok {
+			t.Errorf("Expected the view model to receive the store, got %T", store)
+		}
+	}
+}
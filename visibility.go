@@ -0,0 +1,126 @@
+package livetemplate
+
+import (
+	"reflect"
+	"strings"
+)
+
+// copyTemplateFields copies the fields of a struct, or the entries of a map, into dst for
+// template execution. Struct fields are added under their json name and their Go name,
+// except unexported fields and fields hidden by FieldVisibility or an `lvt:"-"` tag. Fields
+// promoted from embedded structs are added unless a field of the outer struct shadows them.
+func copyTemplateFields(dst map[string]interface{}, data interface{}) {
+	val := reflect.ValueOf(data)
+	if val.Kind() == reflect.Ptr {
+		val = val.Elem()
+	}
+
+	switch val.Kind() {
+	case reflect.Struct:
+		copyStructFields(dst, val, visibleFieldSet(data))
+	case reflect.Map:
+		for _, key := range val.MapKeys() {
+			dst[key.String()] = val.MapIndex(key).Interface()
+		}
+	}
+}
+
+// copyStructFields copies the visible fields of a struct value into dst, followed by the
+// fields promoted from its embedded structs that none of its own fields shadow
+func copyStructFields(dst map[string]interface{}, val reflect.Value, visible map[string]bool) {
+	typ := val.Type()
+	var embedded []reflect.Value
+	for i := 0; i < val.NumField(); i++ {
+		field := typ.Field(i)
+		if field.Anonymous {
+			inner := val.Field(i)
+			if inner.Kind() == reflect.Ptr && !inner.IsNil() {
+				inner = inner.Elem()
+			}
+			if inner.Kind() == reflect.Struct {
+				embedded = append(embedded, inner)
+			}
+		}
+		name := jsonFieldName(field)
+		if !isFieldVisible(field, name, visible) {
+			continue
+		}
+		dst[name] = val.Field(i).Interface()
+		// Also add with original field name for templates that use {{.FieldName}}
+		dst[field.Name] = val.Field(i).Interface()
+	}
+
+	for _, inner := range embedded {
+		promoted := make(map[string]interface{})
+		copyStructFields(promoted, inner, visible)
+		for name, value := range promoted {
+			if _, shadowed := dst[name]; !shadowed {
+				dst[name] = value
+			}
+		}
+	}
+}
+
+// storeTemplateData returns the value templates see for a store in multi-store mode: the
+// store itself, or a map of its visible fields if it hides any (see FieldVisibility)
+func storeTemplateData(store Store) interface{} {
+	if !hidesFields(store) {
+		return store
+	}
+	fields := make(map[string]interface{})
+	copyTemplateFields(fields, store)
+	return fields
+}
+
+// hidesFields reports whether data implements FieldVisibility or has `lvt:"-"` fields
+func hidesFields(data interface{}) bool {
+	if _, ok := data.(FieldVisibility); ok {
+		return true
+	}
+	typ := reflect.TypeOf(data)
+	if typ == nil {
+		return false
+	}
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ.Kind() != reflect.Struct {
+		return false
+	}
+	for i := 0; i < typ.NumField(); i++ {
+		if typ.Field(i).Tag.Get("lvt") == "-" {
+			return true
+		}
+	}
+	return false
+}
+
+// visibleFieldSet returns the fields whitelisted by FieldVisibility, or nil if data doesn't implement it
+func visibleFieldSet(data interface{}) map[string]bool {
+	v, ok := data.(FieldVisibility)
+	if !ok {
+		return nil
+	}
+	visible := make(map[string]bool)
+	for _, name := range v.LiveVisible() {
+		visible[name] = true
+	}
+	return visible
+}
+
+// isFieldVisible reports whether a struct field can be seen by templates
+func isFieldVisible(field reflect.StructField, jsonName string, visible map[string]bool) bool {
+	if !field.IsExported() || field.Tag.Get("lvt") == "-" {
+		return false
+	}
+	return visible == nil || visible[field.Name] || visible[jsonName]
+}
+
+// jsonFieldName returns the json tag name of a field, or its Go name without one
+func jsonFieldName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "" || name == "-" {
+		return field.Name
+	}
+	return name
+}
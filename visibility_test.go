@@ -0,0 +1,132 @@
+package livetemplate
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+type accountState struct {
+	Name     string
+	APIToken string `json:"-" lvt:"-"`
+}
+
+type billingState struct {
+	Plan       string `json:"plan"`
+	CardNumber string
+}
+
+func (s *billingState) LiveVisible() []string { return []string{"plan"} }
+
+func (s *accountState) Change(ctx *ActionContext) error { return nil }
+func (s *billingState) Change(ctx *ActionContext) error { return nil }
+
+func TestFieldVisibility(t *testing.T) {
+	const secret = "sk-live-1234"
+
+	t.Run("lvt tag", func(t *testing.T) {
+		tmpl := New("visibility-tag-test")
+		if _, err := tmpl.Parse(`<p>{{.Name}}</p><p>{{.APIToken}}</p>`); err != nil {
+			t.Fatalf("Parse failed: %v", err)
+		}
+		state := &accountState{Name: "Ann", APIToken: secret}
+
+		var update, html bytes.Buffer
+		if err := tmpl.ExecuteUpdates(&update, state); err != nil {
+			t.Fatalf("ExecuteUpdates failed: %v", err)
+		}
+		if err := tmpl.Execute(&html, state); err != nil {
+			t.Fatalf("Execute failed: %v", err)
+		}
+		for _, out := range []string{update.String(), html.String()} {
+			if strings.Contains(out, secret) {
+				t.Errorf("Hidden field leaked into output: %s", out)
+			}
+			if !strings.Contains(out, "Ann") {
+				t.Errorf("Visible field missing from output: %s", out)
+			}
+		}
+
+		if err := tmpl.Validate(state); err == nil {
+			t.Error("Validate should reject templates using hidden fields")
+		}
+	})
+
+	t.Run("LiveVisible whitelist", func(t *testing.T) {
+		tmpl := New("visibility-whitelist-test")
+		if _, err := tmpl.Parse(`<p>{{.plan}}</p><p>{{.CardNumber}}</p>`); err != nil {
+			t.Fatalf("Parse failed: %v", err)
+		}
+
+		var update bytes.Buffer
+		if err := tmpl.ExecuteUpdates(&update, &billingState{Plan: "pro", CardNumber: secret}); err != nil {
+			t.Fatalf("ExecuteUpdates failed: %v", err)
+		}
+		if strings.Contains(update.String(), secret) || !strings.Contains(update.String(), "pro") {
+			t.Errorf("Expected only whitelisted fields in the update, got %s", update.String())
+		}
+	})
+
+	t.Run("multi-store handler", func(t *testing.T) {
+		tmpl := New("visibility-handler-test")
+		if _, err := tmpl.Parse(`<p>{{.accountState.Name}} {{.accountState.APIToken}} {{.billingState.plan}} {{.billingState.CardNumber}}</p>`); err != nil {
+			t.Fatalf("Parse failed: %v", err)
+		}
+		handler := tmpl.Handle(&accountState{Name: "Ann", APIToken: secret}, &billingState{Plan: "pro", CardNumber: secret})
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		if body := rec.Body.String(); strings.Contains(body, secret) || !strings.Contains(body, "pro") {
+			t.Errorf("Expected hidden store fields to be stripped from the page, got %s", body)
+		}
+
+		server := httptest.NewServer(handler)
+		defer server.Close()
+		wsURL := "ws" + strings.TrimPrefix(server.URL, "http")
+		conn, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+		if err != nil {
+			t.Fatalf("WebSocket dial failed: %v", err)
+		}
+		defer conn.Close()
+		_, initial, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatalf("Failed to read initial tree: %v", err)
+		}
+		if strings.Contains(string(initial), secret) || !strings.Contains(string(initial), "Ann") {
+			t.Errorf("Expected hidden store fields to be stripped from the tree, got %s", initial)
+		}
+	})
+}
+
+func TestCopyTemplateFields_Embedded(t *testing.T) {
+	type accountView struct {
+		*accountState
+		Name     string
+		Initials string
+	}
+	type pageView struct {
+		accountView
+		Title string
+	}
+	data := pageView{
+		accountView: accountView{accountState: &accountState{Name: "Ann", APIToken: "sk-live-1234"}, Name: "Ann Lee", Initials: "AL"},
+		Title:       "Profile",
+	}
+
+	fields := make(map[string]interface{})
+	copyTemplateFields(fields, data)
+
+	if fields["Title"] != "Profile" || fields["Initials"] != "AL" {
+		t.Errorf("Expected own and promoted fields, got %v", fields)
+	}
+	if fields["Name"] != "Ann Lee" {
+		t.Errorf("Expected the shallower Name to shadow the embedded one, got %v", fields["Name"])
+	}
+	if _, ok := fields["APIToken"]; ok {
+		t.Errorf("Expected a hidden field of an embedded struct to stay hidden, got %v", fields)
+	}
+}
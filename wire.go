@@ -0,0 +1,333 @@
+package livetemplate
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+)
+
+// fingerprintPattern matches the "f" value produced by calculateFingerprint
+var fingerprintPattern = regexp.MustCompile(`^[0-9a-f]{16}$`)
+
+// dynamicKeyPattern matches the numeric keys used for dynamic slots
+var dynamicKeyPattern = regexp.MustCompile(`^[0-9]+$`)
+
+// ValidateWireMessage checks that b is a well-formed message in the LiveTemplate wire format.
+//
+// It accepts either a bare tree, as written by ExecuteUpdates, or the WebSocket/HTTP envelope
+// {"tree": ..., "meta": ...}. The check is structural: statics must be string arrays, dynamic
+// slots numeric keys, fingerprints 16 hex characters, switch branches ("b") indexes into the
+// branch statics ("bs"), statics IDs and references ("si", "sr") non-empty strings, and range
+// operations must use a known opcode ("a", "i", "r", "u", "o") with the documented arguments.
+//
+// It is intended for conformance tests of client and server implementations in other languages.
+// See docs/specifications/tree-update-specification.md for the format.
+func ValidateWireMessage(b []byte) error {
+	var msg map[string]interface{}
+	if err := json.Unmarshal(b, &msg); err != nil {
+		return fmt.Errorf("message is not a JSON object: %w", err)
+	}
+
+	if _, isEnvelope := msg["tree"]; !isEnvelope {
+		return validateWireNode("tree", msg)
+	}
+
+	for key, value := range msg {
+		switch key {
+		case "tree":
+			tree, ok := value.(map[string]interface{})
+			if !ok {
+				return fmt.Errorf("tree: must be an object, got %s", jsonType(value))
+			}
+			if err := validateWireNode("tree", tree); err != nil {
+				return err
+			}
+		case "meta":
+			if value == nil {
+				continue
+			}
+			if err := validateWireMeta(value); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("unexpected envelope key %q", key)
+		}
+	}
+
+	return nil
+}
+
+// validateWireMeta checks the ResponseMetadata part of an envelope
+func validateWireMeta(value interface{}) error {
+	meta, ok := value.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("meta: must be an object, got %s", jsonType(value))
+	}
+
+	for key, field := range meta {
+		switch key {
+		case "success":
+			if _, ok := field.(bool); !ok {
+				return fmt.Errorf("meta.success: must be a boolean, got %s", jsonType(field))
+			}
+		case "action":
+			if _, ok := field.(string); !ok {
+				return fmt.Errorf("meta.action: must be a string, got %s", jsonType(field))
+			}
+		case "errors":
+			errs, ok := field.(map[string]interface{})
+			if !ok {
+				return fmt.Errorf("meta.errors: must be an object, got %s", jsonType(field))
+			}
+			for name, message := range errs {
+				if _, ok := message.(string); !ok {
+					return fmt.Errorf("meta.errors[%q]: must be a string, got %s", name, jsonType(message))
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// validateWireNode checks a tree node: statics, fingerprint, range data, switch branches and
+// dynamic slots
+func validateWireNode(path string, node map[string]interface{}) error {
+	for key, value := range node {
+		keyPath := fmt.Sprintf("%s[%q]", path, key)
+
+		switch {
+		case key == "s":
+			if err := validateWireStatics(keyPath, value); err != nil {
+				return err
+			}
+		case key == "f":
+			fingerprint, ok := value.(string)
+			if !ok || !fingerprintPattern.MatchString(fingerprint) {
+				return fmt.Errorf("%s: fingerprint must be 16 lowercase hex characters, got %v", keyPath, value)
+			}
+		case key == "d":
+			if err := validateWireRangeData(keyPath, value); err != nil {
+				return err
+			}
+		case key == switchBranchKey:
+			branch, ok := value.(float64)
+			if !ok || branch < 0 || branch != float64(int(branch)) {
+				return fmt.Errorf("%s: branch must be a non-negative integer, got %v", keyPath, value)
+			}
+		case key == staticsIDKey || key == staticsRefKey:
+			if id, ok := value.(string); !ok || id == "" {
+				return fmt.Errorf("%s: statics ID must be a non-empty string, got %v", keyPath, value)
+			}
+		case key == switchStaticsKey:
+			branches, ok := value.([]interface{})
+			if !ok || len(branches) == 0 {
+				return fmt.Errorf("%s: branch statics must be a non-empty array, got %s", keyPath, jsonType(value))
+			}
+			for i, statics := range branches {
+				if err := validateWireStatics(fmt.Sprintf("%s[%d]", keyPath, i), statics); err != nil {
+					return err
+				}
+			}
+		case dynamicKeyPattern.MatchString(key):
+			if err := validateWireDynamic(keyPath, value); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("%s: unexpected key", keyPath)
+		}
+	}
+
+	return nil
+}
+
+// validateWireStatics checks an "s" array: non-empty and strings only
+func validateWireStatics(path string, value interface{}) error {
+	statics, ok := value.([]interface{})
+	if !ok {
+		return fmt.Errorf("%s: statics must be an array, got %s", path, jsonType(value))
+	}
+	if len(statics) == 0 {
+		return fmt.Errorf("%s: statics must not be empty", path)
+	}
+	for i, static := range statics {
+		if _, ok := static.(string); !ok {
+			return fmt.Errorf("%s[%d]: statics must be strings, got %s", path, i, jsonType(static))
+		}
+	}
+	return nil
+}
+
+// validateWireDynamic checks the value of a dynamic slot
+func validateWireDynamic(path string, value interface{}) error {
+	switch v := value.(type) {
+	case nil, string, float64, bool:
+		return nil
+	case map[string]interface{}:
+		return validateWireNode(path, v)
+	case []interface{}:
+		return validateWireOperations(path, v)
+	default:
+		return fmt.Errorf("%s: unsupported value of type %s", path, jsonType(value))
+	}
+}
+
+// validateWireRangeData checks a "d" value: either full range items or a list of operations
+func validateWireRangeData(path string, value interface{}) error {
+	list, ok := value.([]interface{})
+	if !ok {
+		return fmt.Errorf("%s: range data must be an array, got %s", path, jsonType(value))
+	}
+	if len(list) > 0 {
+		if _, isOp := list[0].([]interface{}); isOp {
+			return validateWireOperations(path, list)
+		}
+	}
+	return validateWireItems(path, list)
+}
+
+// validateWireItems checks a list of range items
+func validateWireItems(path string, items []interface{}) error {
+	for i, raw := range items {
+		if err := validateWireItem(fmt.Sprintf("%s[%d]", path, i), raw); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateWireItem checks a single range item: dynamic slots plus an optional "_k" key
+func validateWireItem(path string, raw interface{}) error {
+	item, ok := raw.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("%s: range item must be an object, got %s", path, jsonType(raw))
+	}
+
+	for key, value := range item {
+		keyPath := fmt.Sprintf("%s[%q]", path, key)
+		switch {
+		case key == "_k":
+			if _, ok := value.(string); !ok {
+				return fmt.Errorf("%s: item key must be a string, got %s", keyPath, jsonType(value))
+			}
+		case dynamicKeyPattern.MatchString(key):
+			if err := validateWireDynamic(keyPath, value); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("%s: unexpected key in range item", keyPath)
+		}
+	}
+	return nil
+}
+
+// validateWireOperations checks a list of range operations
+func validateWireOperations(path string, ops []interface{}) error {
+	for i, raw := range ops {
+		opPath := fmt.Sprintf("%s[%d]", path, i)
+
+		op, ok := raw.([]interface{})
+		if !ok || len(op) == 0 {
+			return fmt.Errorf("%s: range operation must be a non-empty array, got %v", opPath, raw)
+		}
+
+		opcode, _ := op[0].(string)
+		switch opcode {
+		case "r": // ["r", key] or ["r", key, options]
+			if len(op) != 2 && len(op) != 3 {
+				return fmt.Errorf("%s: \"r\" takes 1 or 2 arguments, got %d", opPath, len(op)-1)
+			}
+			if _, ok := op[1].(string); !ok {
+				return fmt.Errorf("%s: \"r\" key must be a string, got %s", opPath, jsonType(op[1]))
+			}
+			if len(op) == 3 {
+				if _, ok := op[2].(map[string]interface{}); !ok {
+					return fmt.Errorf("%s: \"r\" options must be an object, got %s", opPath, jsonType(op[2]))
+				}
+			}
+
+		case "u": // ["u", key, changes]
+			if len(op) != 3 {
+				return fmt.Errorf("%s: \"u\" takes 2 arguments, got %d", opPath, len(op)-1)
+			}
+			if _, ok := op[1].(string); !ok {
+				return fmt.Errorf("%s: \"u\" key must be a string, got %s", opPath, jsonType(op[1]))
+			}
+			if err := validateWireItem(opPath+"[2]", op[2]); err != nil {
+				return err
+			}
+
+		case "a": // ["a", items] or ["a", items, statics]
+			if len(op) != 2 && len(op) != 3 {
+				return fmt.Errorf("%s: \"a\" takes 1 or 2 arguments, got %d", opPath, len(op)-1)
+			}
+			items, ok := op[1].([]interface{})
+			if !ok {
+				return fmt.Errorf("%s: \"a\" items must be an array, got %s", opPath, jsonType(op[1]))
+			}
+			if err := validateWireItems(opPath+"[1]", items); err != nil {
+				return err
+			}
+			if len(op) == 3 {
+				if err := validateWireStatics(opPath+"[2]", op[2]); err != nil {
+					return err
+				}
+			}
+
+		case "i": // ["i", targetKey|null, position, item]
+			if len(op) != 4 {
+				return fmt.Errorf("%s: \"i\" takes 3 arguments, got %d", opPath, len(op)-1)
+			}
+			if _, ok := op[1].(string); !ok && op[1] != nil {
+				return fmt.Errorf("%s: \"i\" target must be a string or null, got %s", opPath, jsonType(op[1]))
+			}
+			switch op[2] {
+			case "before", "after", "start", "end":
+			default:
+				return fmt.Errorf("%s: \"i\" position must be before, after, start or end, got %v", opPath, op[2])
+			}
+			if err := validateWireItem(opPath+"[3]", op[3]); err != nil {
+				return err
+			}
+
+		case "o": // ["o", [keys]]
+			if len(op) != 2 {
+				return fmt.Errorf("%s: \"o\" takes 1 argument, got %d", opPath, len(op)-1)
+			}
+			keys, ok := op[1].([]interface{})
+			if !ok {
+				return fmt.Errorf("%s: \"o\" keys must be an array, got %s", opPath, jsonType(op[1]))
+			}
+			for j, key := range keys {
+				if _, ok := key.(string); !ok {
+					return fmt.Errorf("%s[1][%d]: \"o\" keys must be strings, got %s", opPath, j, jsonType(key))
+				}
+			}
+
+		default:
+			return fmt.Errorf("%s: unknown range opcode %v", opPath, op[0])
+		}
+	}
+
+	return nil
+}
+
+// jsonType names the JSON type of a decoded value for error messages
+func jsonType(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "boolean"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
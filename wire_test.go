@@ -0,0 +1,122 @@
+package livetemplate
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestValidateWireMessage_GeneratedUpdates(t *testing.T) {
+	type Item struct {
+		ID   string
+		Text string
+	}
+	type State struct {
+		Title  string
+		Show   bool
+		Items  []Item
+		Scores map[string]int
+	}
+
+	tmpl := New("wire-test")
+	_, err := tmpl.Parse(`<h1>{{.Title}}</h1>` +
+		`{{if .Show}}<p>Visible</p>{{end}}` +
+		`<ul>{{range .Items}}<li data-key="{{.ID}}">{{.Text}}</li>{{end}}</ul>` +
+		`<dl>{{range $name, $score := .Scores}}<dt>{{$name}}</dt><dd>{{$score}}</dd>{{end}}</dl>`)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	states := []*State{
+		{Title: "Start", Scores: map[string]int{"a": 1}},
+		{Title: "Start", Show: true, Items: []Item{{ID: "1", Text: "One"}}, Scores: map[string]int{"a": 1, "b": 2}},
+		{Title: "Next", Show: true, Items: []Item{{ID: "0", Text: "Zero"}, {ID: "1", Text: "One!"}, {ID: "2", Text: "Two"}}, Scores: map[string]int{"a": 5}},
+		{Title: "Next", Items: []Item{{ID: "2", Text: "Two"}, {ID: "0", Text: "Zero"}}},
+		{Title: "Done"},
+	}
+
+	for i, state := range states {
+		var buf bytes.Buffer
+		if err := tmpl.ExecuteUpdates(&buf, state); err != nil {
+			t.Fatalf("ExecuteUpdates %d failed: %v", i, err)
+		}
+
+		if err := ValidateWireMessage(buf.Bytes()); err != nil {
+			t.Errorf("Update %d rejected: %v\n%s", i, err, buf.String())
+		}
+
+		// The same tree wrapped in the WebSocket envelope
+		var tree treeNode
+		if err := json.Unmarshal(buf.Bytes(), &tree); err != nil {
+			t.Fatalf("Failed to parse update %d: %v", i, err)
+		}
+		envelope, err := json.Marshal(UpdateResponse{
+			Tree: tree,
+			Meta: &ResponseMetadata{Success: true, Action: "save", Errors: map[string]string{}},
+		})
+		if err != nil {
+			t.Fatalf("Failed to marshal envelope: %v", err)
+		}
+		if err := ValidateWireMessage(envelope); err != nil {
+			t.Errorf("Envelope %d rejected: %v\n%s", i, err, envelope)
+		}
+	}
+}
+
+func TestValidateWireMessage(t *testing.T) {
+	tests := []struct {
+		name    string
+		message string
+		wantErr string // empty means the message is valid
+	}{
+		{name: "empty update", message: `{}`},
+		{name: "first render", message: `{"s":["<p>","</p>"],"0":"hi"}`},
+		{name: "fingerprint", message: `{"s":["<p>","</p>"],"0":"hi","f":"0123456789abcdef"}`},
+		{name: "nested conditional", message: `{"0":{"s":["<b>","</b>"],"0":"x"}}`},
+		{name: "range comprehension", message: `{"0":{"s":["<li>","</li>"],"d":[{"0":"a"},{"0":"b","_k":"b"}]}}`},
+		{name: "all range ops", message: `{"0":[["r","a"],["u","b",{"0":"B"}],["i",null,"start",{"0":"c"}],["i","b","after",{"0":"d"}],["a",[{"0":"e"}]],["a",[{"0":"f"}],["<li>","</li>"]],["o",["c","b","d"]]]}`},
+		{name: "animated remove", message: `{"0":[["r","a",{"animate":true}]]}`},
+		{name: "statics ID", message: `{"0":{"s":["<div>","</div>"],"si":"0123456789abcdef","0":"x"}}`},
+		{name: "statics reference", message: `{"0":{"sr":"0123456789abcdef","0":"x"}}`},
+		{name: "envelope", message: `{"tree":{"0":"1"},"meta":{"success":false,"errors":{"name":"required"},"action":"save"}}`},
+		{name: "envelope without meta", message: `{"tree":{}}`},
+
+		{name: "not an object", message: `[1,2]`, wantErr: "not a JSON object"},
+		{name: "invalid JSON", message: `{"0":`, wantErr: "not a JSON object"},
+		{name: "unknown key", message: `{"x":"1"}`, wantErr: "unexpected key"},
+		{name: "statics not array", message: `{"s":"<p>"}`, wantErr: "statics must be an array"},
+		{name: "statics with number", message: `{"s":["<p>",1]}`, wantErr: "statics must be strings"},
+		{name: "empty statics", message: `{"s":[]}`, wantErr: "statics must not be empty"},
+		{name: "bad fingerprint", message: `{"f":"xyz"}`, wantErr: "fingerprint"},
+		{name: "empty statics reference", message: `{"0":{"sr":""}}`, wantErr: "statics ID"},
+		{name: "unknown opcode", message: `{"0":[["x","a"]]}`, wantErr: "unknown range opcode"},
+		{name: "remove without key", message: `{"0":[["r"]]}`, wantErr: `"r" takes 1 or 2 arguments`},
+		{name: "remove with non-object options", message: `{"0":[["r","a",true]]}`, wantErr: `"r" options must be an object`},
+		{name: "update with non-object", message: `{"0":[["u","a","text"]]}`, wantErr: "range item must be an object"},
+		{name: "insert with bad position", message: `{"0":[["i","a","middle",{"0":"x"}]]}`, wantErr: "position"},
+		{name: "order with numbers", message: `{"0":[["o",[1,2]]]}`, wantErr: "keys must be strings"},
+		{name: "append with bad statics", message: `{"0":[["a",[{"0":"x"}],[1]]]}`, wantErr: "statics must be strings"},
+		{name: "item with unknown key", message: `{"0":{"s":["",""],"d":[{"name":"x"}]}}`, wantErr: "unexpected key in range item"},
+		{name: "envelope with bad meta", message: `{"tree":{},"meta":{"success":"yes"}}`, wantErr: "meta.success"},
+		{name: "envelope with extra key", message: `{"tree":{},"extra":1}`, wantErr: "unexpected envelope key"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateWireMessage([]byte(tt.message))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Expected valid message, got error: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("Expected error containing %q, got nil", tt.wantErr)
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}